@@ -0,0 +1,194 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// The stats of an account are aggregated in the days of UTC
+const SecondsPerDay = 24 * 60 * 60
+
+// The trades of an account in a market. The stock bought through the trades is held as Position, whose cost
+// is tracked by AvgEntryPrice. Selling the position realizes the difference between the deal price and
+// AvgEntryPrice, while selling the stock which is not bought through the trades realizes nothing.
+// The fees are paid in CET when the orders are filled or canceled
+type AccountStats struct {
+	Market        string  `json:"market"`
+	BuyStock      sdk.Int `json:"buy_stock"`
+	BuyMoney      sdk.Int `json:"buy_money"`
+	SellStock     sdk.Int `json:"sell_stock"`
+	SellMoney     sdk.Int `json:"sell_money"`
+	DealCount     int64   `json:"deal_count"`
+	RealizedPnL   sdk.Dec `json:"realized_pnl"`
+	Commission    sdk.Int `json:"commission"`
+	FeatureFee    sdk.Int `json:"feature_fee"`
+	Position      sdk.Int `json:"position"`
+	AvgEntryPrice sdk.Dec `json:"avg_entry_price"`
+}
+
+func newAccountStats(market string) AccountStats {
+	return AccountStats{
+		Market:        market,
+		BuyStock:      sdk.ZeroInt(),
+		BuyMoney:      sdk.ZeroInt(),
+		SellStock:     sdk.ZeroInt(),
+		SellMoney:     sdk.ZeroInt(),
+		RealizedPnL:   sdk.ZeroDec(),
+		Commission:    sdk.ZeroInt(),
+		FeatureFee:    sdk.ZeroInt(),
+		Position:      sdk.ZeroInt(),
+		AvgEntryPrice: sdk.ZeroDec(),
+	}
+}
+
+func (stats *AccountStats) addTrade(side byte, stock, money int64) {
+	stats.DealCount++
+	if side == BUY {
+		stats.BuyStock = stats.BuyStock.AddRaw(stock)
+		stats.BuyMoney = stats.BuyMoney.AddRaw(money)
+		cost := stats.AvgEntryPrice.MulInt(stats.Position).Add(sdk.NewDec(money))
+		stats.Position = stats.Position.AddRaw(stock)
+		stats.AvgEntryPrice = cost.QuoInt(stats.Position)
+		return
+	}
+	stats.SellStock = stats.SellStock.AddRaw(stock)
+	stats.SellMoney = stats.SellMoney.AddRaw(money)
+	closed := sdk.MinInt(sdk.NewInt(stock), stats.Position)
+	if !closed.IsPositive() {
+		return
+	}
+	price := sdk.NewDec(money).QuoInt64(stock)
+	stats.RealizedPnL = stats.RealizedPnL.Add(price.Sub(stats.AvgEntryPrice).MulInt(closed))
+	if stats.Position = stats.Position.Sub(closed); stats.Position.IsZero() {
+		stats.AvgEntryPrice = sdk.ZeroDec()
+	}
+}
+
+// Adds the flows of other, which is later than stats, and takes its position
+func (stats *AccountStats) merge(other *AccountStats) {
+	stats.BuyStock = stats.BuyStock.Add(other.BuyStock)
+	stats.BuyMoney = stats.BuyMoney.Add(other.BuyMoney)
+	stats.SellStock = stats.SellStock.Add(other.SellStock)
+	stats.SellMoney = stats.SellMoney.Add(other.SellMoney)
+	stats.DealCount += other.DealCount
+	stats.RealizedPnL = stats.RealizedPnL.Add(other.RealizedPnL)
+	stats.Commission = stats.Commission.Add(other.Commission)
+	stats.FeatureFee = stats.FeatureFee.Add(other.FeatureFee)
+	stats.Position, stats.AvgEntryPrice = other.Position, other.AvgEntryPrice
+}
+
+// The stats of an account in a market in one day. Height is the last block which updates it,
+// such that the blocks which are consumed again after restart are not counted twice
+type accountStatsRecord struct {
+	Height int64 `json:"height"`
+	AccountStats
+}
+
+// Returns the record of the current day, which is updated in the current block and saved at commit.
+// Returns nil if the current block has been counted in the record
+func (hub *Hub) getAccountStatsRecord(addr, market string) *accountStatsRecord {
+	dayTime := hub.currBlockTime.Unix() / SecondsPerDay * SecondsPerDay
+	key := getAccountStatsKey(addr, market, dayTime)
+	if rec, ok := hub.accountStats[string(key)]; ok {
+		return rec
+	}
+	rec := &accountStatsRecord{Height: hub.currBlockHeight, AccountStats: newAccountStats(market)}
+	// the position is carried from the latest record
+	iter := hub.db.ReverseIterator(key[:len(key)-8], append(key, 0))
+	if iter.Valid() {
+		var last accountStatsRecord
+		if err := json.Unmarshal(iter.Value(), &last); err != nil {
+			hub.Log(fmt.Sprintf("Error in Unmarshal AccountStats: %v", err))
+		} else if last.Height >= hub.currBlockHeight {
+			rec = nil
+		} else if string(iter.Key()) == string(key) {
+			last.Height = hub.currBlockHeight
+			rec = &last
+		} else {
+			rec.Position, rec.AvgEntryPrice = last.Position, last.AvgEntryPrice
+		}
+	}
+	iter.Close()
+	hub.accountStats[string(key)] = rec
+	return rec
+}
+
+func (hub *Hub) updateAccountStatsForTrade(addr, market string, side byte, stock, money int64) {
+	if stock <= 0 {
+		return
+	}
+	if rec := hub.getAccountStatsRecord(addr, market); rec != nil {
+		rec.addTrade(side, stock, money)
+	}
+}
+
+func (hub *Hub) updateAccountStatsForFee(addr, market string, commission, featureFee int64) {
+	if commission == 0 && featureFee == 0 {
+		return
+	}
+	if rec := hub.getAccountStatsRecord(addr, market); rec != nil {
+		rec.Commission = rec.Commission.AddRaw(commission)
+		rec.FeatureFee = rec.FeatureFee.AddRaw(featureFee)
+	}
+}
+
+func (hub *Hub) commitForAccountStats() {
+	for key, rec := range hub.accountStats {
+		if rec == nil {
+			continue
+		}
+		bz, err := json.Marshal(rec)
+		if err != nil {
+			hub.Log(fmt.Sprintf("Error in Marshal AccountStats: %v", err))
+			continue
+		}
+		hub.batch.Set([]byte(key), bz)
+	}
+	hub.accountStats = make(map[string]*accountStatsRecord)
+}
+
+// Sums the stats of the days which overlap [from, to] in every market of the account, or only in the
+// given market. Zero from or to means unbounded. The position is the one at the end of the last day
+func (hub *Hub) QueryAccountStats(account, market string, from, to int64) []AccountStats {
+	prefix := getAccountStatsKey(account, market, 0)
+	if market == "" {
+		prefix = prefix[:1+1+len(account)+1]
+	} else {
+		prefix = prefix[:len(prefix)-8]
+	}
+	end := append([]byte{}, prefix...)
+	end[len(end)-1]++
+
+	hub.dbMutex.RLock()
+	defer hub.dbMutex.RUnlock()
+	iter := hub.db.Iterator(prefix, end)
+	defer iter.Close()
+	statsMap := make(map[string]*AccountStats)
+	for ; iter.Valid(); iter.Next() {
+		key := iter.Key()
+		dayTime := BigEndianBytesToInt64(key[len(key)-8:])
+		if (from != 0 && dayTime+SecondsPerDay <= from) || (to != 0 && dayTime > to) {
+			continue
+		}
+		var rec accountStatsRecord
+		if err := json.Unmarshal(iter.Value(), &rec); err != nil {
+			hub.Log(fmt.Sprintf("Error in Unmarshal AccountStats: %v", err))
+			continue
+		}
+		if stats, ok := statsMap[rec.Market]; ok {
+			stats.merge(&rec.AccountStats)
+		} else {
+			statsMap[rec.Market] = &rec.AccountStats
+		}
+	}
+
+	res := make([]AccountStats, 0, len(statsMap))
+	for _, stats := range statsMap {
+		res = append(res, *stats)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Market < res[j].Market })
+	return res
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/require"
+	dbm "github.com/tendermint/tm-db"
+)
+
+func TestAccountStats(t *testing.T) {
+	acc, _ := simpleAddr("00001")
+	addr := acc.String()
+	db := dbm.NewMemDB()
+	hub := NewHub(db, &MocSubscribeManager{}, 99999, 0, 0, 0, "", 0)
+	hub.currBlockHeight = 999
+	fill := func(seq int, side byte, stock, money int64) {
+		bz, _ := json.Marshal(&FillOrderInfo{
+			OrderID:     fmt.Sprintf("%s-%d", addr, seq),
+			TradingPair: "abc/cet",
+			Height:      hub.currBlockHeight,
+			Side:        side,
+			Price:       sdk.NewDec(1),
+			DealStock:   stock,
+			DealMoney:   money,
+			CurrStock:   stock,
+			CurrMoney:   money,
+			FillPrice:   sdk.NewDec(money).QuoInt64(stock),
+		})
+		hub.ConsumeMessage("fill_order_info", bz)
+	}
+	cancel := func(seq int, commission, featureFee int64) {
+		bz, _ := json.Marshal(&CancelOrderInfo{
+			OrderID:        fmt.Sprintf("%s-%d", addr, seq),
+			TradingPair:    "abc/cet",
+			Height:         hub.currBlockHeight,
+			Price:          sdk.NewDec(1),
+			UsedCommission: commission,
+			UsedFeatureFee: featureFee,
+		})
+		hub.ConsumeMessage("del_order_info", bz)
+	}
+	newBlock := func(height, timestamp int64, actions func()) {
+		bz, _ := json.Marshal(&NewHeightInfo{Height: height, TimeStamp: timestamp, ChainID: "coinex-test"})
+		hub.ConsumeMessage("height_info", bz)
+		actions()
+		hub.ConsumeMessage("commit", nil)
+	}
+	day := int64(SecondsPerDay)
+	// buy 200 at the average price 12, then sell 150 at 14
+	newBlock(1000, day+10, func() {
+		fill(1, BUY, 100, 1000)
+		fill(2, BUY, 100, 1400)
+	})
+	block1001 := func() {
+		fill(3, SELL, 150, 2100)
+		cancel(1, 20, 0)
+		cancel(3, 30, 5)
+	}
+	newBlock(1001, day+20, block1001)
+	// sell 100 at 10 on the next day, 50 of which are not bought through the trades
+	newBlock(1002, 2*day+10, func() {
+		fill(4, SELL, 100, 1000)
+	})
+	bz, _ := json.Marshal(&MsgBancorTradeInfoForKafka{Sender: addr, Stock: "abc", Money: "cet", Amount: 10,
+		Side: BUY, TxPrice: sdk.NewDec(11), BlockHeight: 1003, UsedCommission: 7})
+	newBlock(1003, 3*day+10, func() {
+		hub.ConsumeMessage("bancor_trade", bz)
+	})
+
+	check := func(from, to int64, expected AccountStats) {
+		stats := hub.QueryAccountStats(addr, "abc/cet", from, to)
+		require.EqualValues(t, 1, len(stats))
+		res, _ := json.Marshal(stats[0])
+		exp, _ := json.Marshal(expected)
+		require.Equal(t, string(exp), string(res), fmt.Sprintf("[%d, %d]", from, to))
+	}
+	expected := newAccountStats("abc/cet")
+	expected.BuyStock, expected.BuyMoney = sdk.NewInt(200), sdk.NewInt(2400)
+	expected.SellStock, expected.SellMoney = sdk.NewInt(150), sdk.NewInt(2100)
+	expected.DealCount = 3
+	expected.RealizedPnL = sdk.NewDec(300)
+	expected.Commission, expected.FeatureFee = sdk.NewInt(50), sdk.NewInt(5)
+	expected.Position, expected.AvgEntryPrice = sdk.NewInt(50), sdk.NewDec(12)
+	check(0, day+30, expected)
+	check(day, day, expected)
+
+	expected.SellStock, expected.SellMoney = sdk.NewInt(250), sdk.NewInt(3100)
+	expected.DealCount = 4
+	expected.RealizedPnL = sdk.NewDec(200)
+	expected.Position, expected.AvgEntryPrice = sdk.ZeroInt(), sdk.ZeroDec()
+	check(0, 2*day, expected)
+
+	expected.BuyStock, expected.BuyMoney = sdk.NewInt(210), sdk.NewInt(2510)
+	expected.DealCount = 5
+	expected.Commission = sdk.NewInt(57)
+	expected.Position, expected.AvgEntryPrice = sdk.NewInt(10), sdk.NewDec(11)
+	check(0, 0, expected)
+
+	latest := newAccountStats("abc/cet")
+	latest.BuyStock, latest.BuyMoney = sdk.NewInt(10), sdk.NewInt(110)
+	latest.DealCount = 1
+	latest.Commission = sdk.NewInt(7)
+	latest.Position, latest.AvgEntryPrice = sdk.NewInt(10), sdk.NewDec(11)
+	check(3*day+1, 0, latest)
+	require.EqualValues(t, 0, len(hub.QueryAccountStats(addr, "abc/cet", 4*day, 0)))
+	require.EqualValues(t, 1, len(hub.QueryAccountStats(addr, "", 0, 0)))
+	require.EqualValues(t, 0, len(hub.QueryAccountStats(addr, "xyz/cet", 0, 0)))
+
+	// the block consumed again after restart is not counted twice
+	hub = NewHub(db, &MocSubscribeManager{}, 99999, 0, 0, 1000, "", 0)
+	newBlock(1001, day+20, block1001)
+	check(0, 0, expected)
+}
@@ -0,0 +1,91 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// The account topic 'account:<addr>' joins the address-keyed streams of an address, which are
+// order, income, txs, send_lock_coins, unlock, unbonding, redelegation and bancor-trade.
+// The pushed messages keep their original types, and the full information of all these streams
+// is pushed as one 'account_full' message, whose payload maps the message types to their data
+
+// Returns the subscribers of an address-keyed topic together with the subscribers of the account topic,
+// a connection subscribing both of them gets the message only once
+func (hub *Hub) getAccountSubscribers(info map[string][]Subscriber, addr string) []Subscriber {
+	targets := info[addr]
+	accountTargets := hub.subMan.GetAccountSubscribeInfo()[addr]
+	if len(accountTargets) == 0 {
+		return targets
+	}
+	if len(targets) == 0 {
+		return accountTargets
+	}
+	res := make([]Subscriber, 0, len(targets)+len(accountTargets))
+	pushed := make(map[Subscriber]struct{}, len(targets))
+	for _, target := range targets {
+		pushed[target] = struct{}{}
+		res = append(res, target)
+	}
+	for _, target := range accountTargets {
+		if _, ok := pushed[target]; !ok {
+			res = append(res, target)
+		}
+	}
+	return res
+}
+
+// The data of one message type in the full information of the account topic
+type accountData struct {
+	typeKey string
+	data    []json.RawMessage
+}
+
+func queryAccountAndPush(hub *Hub, c Subscriber, account string, count int) error {
+	createData, fillData, cancelData, err := queryOrdersByTag(hub, account, count)
+	if err != nil {
+		return err
+	}
+	groups := []accountData{
+		{CreateOrderKey, createData},
+		{FillOrderKey, fillData},
+		{CancelOrderKey, cancelData},
+	}
+	for _, q := range []struct {
+		typeKey string
+		qf      queryFunc
+	}{
+		{IncomeKey, hub.QueryIncome},
+		{TxKey, hub.QueryTx},
+		{LockedKey, hub.QueryLocked},
+		{UnlockKey, hub.QueryUnlock},
+		{UnbondingKey, hub.QueryUnbonding},
+		{RedelegationKey, hub.QueryRedelegation},
+		{BancorTradeKey, hub.QueryBancorTrade},
+	} {
+		data, _ := q.qf(account, hub.latestRange(count))
+		groups = append(groups, accountData{q.typeKey, data})
+	}
+	return c.WriteMsg(encodeAccountFull(groups))
+}
+
+func encodeAccountFull(groups []accountData) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(fmt.Sprintf("{\"type\":\"%s\", \"payload\":{", AccountFull))
+	for i, g := range groups {
+		if i != 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(fmt.Sprintf("\"%s\":[", g.typeKey))
+		for j, v := range g.data {
+			if j != 0 {
+				buf.WriteByte(',')
+			}
+			buf.Write(v)
+		}
+		buf.WriteByte(']')
+	}
+	buf.WriteString("}}")
+	return buf.Bytes()
+}
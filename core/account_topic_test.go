@@ -0,0 +1,49 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	dbm "github.com/tendermint/tm-db"
+)
+
+func TestAccountTopicPush(t *testing.T) {
+	both := &PlainSubscriber{ID: 1}
+	incomeOnly := &PlainSubscriber{ID: 2}
+	accountOnly := &PlainSubscriber{ID: 3}
+	subMan := &MocSubscribeManager{
+		IncomeSubscribeInfo:  map[string][]Subscriber{"bob": {both, incomeOnly}},
+		AccountSubscribeInfo: map[string][]Subscriber{"bob": {both, accountOnly}, "alice": {accountOnly}},
+	}
+	hub := NewHub(dbm.NewMemDB(), subMan, 99999, 0, 0, 0, "", 0)
+
+	// the subscriber of both the topics gets the message only once
+	hub.PushIncomeMsg("bob", []byte("1"))
+	require.EqualValues(t, []pushInfo{
+		{Target: both, Payload: "1"},
+		{Target: incomeOnly, Payload: "1"},
+		{Target: accountOnly, Payload: "1"},
+	}, subMan.PushList)
+
+	subMan.PushList = nil
+	hub.PushTxMsg("alice", []byte("2"))
+	require.EqualValues(t, []pushInfo{{Target: accountOnly, Payload: "2"}}, subMan.PushList)
+}
+
+func TestAccountTopicFullInfo(t *testing.T) {
+	hub := NewHub(dbm.NewMemDB(), &MocSubscribeManager{}, 99999, 0, 0, 0, "", 0)
+	wsManager := NewWebSocketManager()
+	wif := &MockWif{}
+	conn := wsManager.AddWsConn(wif)
+	require.Nil(t, wsManager.AddSubscribeConn(conn, AccountKey, []string{"bob"}))
+	require.EqualValues(t, 1, len(wsManager.GetAccountSubscribeInfo()["bob"]))
+
+	require.Nil(t, wsManager.PushFullInfo(hub, conn, AccountKey, []string{"bob"}, 10))
+	time.Sleep(10 * time.Millisecond)
+	records := wif.GetRecords()
+	require.EqualValues(t, 1, len(records))
+	require.EqualValues(t, `{"type":"account_full", "payload":{"create_order":[],"fill_order":[],"cancel_order":[],`+
+		`"income":[],"txs":[],"send_lock_coins":[],"unlock":[],"unbonding":[],"redelegation":[],"bancor-trade":[]}}`,
+		string(records[0]))
+}
@@ -22,7 +22,7 @@ func (hub *Hub) GetAllOrderInfo() AllOrderInfo {
 	var vCreate CreateOrderInfo
 	var vFill FillOrderInfo
 	var vCancel CancelOrderInfo
-	data, tags, timesid := hub.query(false, OrderByte, []byte{}, math.MaxInt64, 0, MaxCount, nil)
+	data, tags, timesid := hub.query(false, OrderByte, []byte{}, QueryRange{Time: math.MaxInt64, Count: MaxCount}, nil)
 	for len(data) < MaxCount {
 		for i, tag := range tags {
 			if tag == CreateOrderEndByte {
@@ -40,7 +40,7 @@ func (hub *Hub) GetAllOrderInfo() AllOrderInfo {
 		}
 		lastSid := timesid[len(timesid)-1]
 		lastTime := timesid[len(timesid)-2]
-		data, tags, timesid = hub.query(false, OrderByte, []byte{}, lastTime, lastSid, MaxCount, nil)
+		data, tags, timesid = hub.query(false, OrderByte, []byte{}, QueryRange{Time: lastTime, Sid: lastSid, Count: MaxCount}, nil)
 	}
 	return res
 }
@@ -0,0 +1,191 @@
+package core
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/tendermint/tendermint/crypto/secp256k1"
+	"github.com/tendermint/tendermint/libs/bech32"
+)
+
+// How long a challenge issued by the server can be signed
+const AuthChallengeTTL = 5 * time.Minute
+
+// At most this number of challenges are kept, and the oldest one is dropped when a new challenge is issued
+const maxLiveChallenges = 10000
+
+// How many challenges can be issued to a client in AuthChallengeWindow
+const (
+	MaxChallengesPerClient = 30
+	AuthChallengeWindow    = time.Minute
+)
+
+// The bech32 prefix of the account addresses derived from public keys
+var Bech32PrefixAccAddr = "coinex"
+
+var (
+	ErrAuthRequired      = errors.New("authentication required")
+	ErrInvalidChallenge  = errors.New("invalid or expired challenge")
+	ErrInvalidPubKey     = errors.New("invalid public key")
+	ErrInvalidSignature  = errors.New("invalid signature")
+	ErrTooManyChallenges = errors.New("too many challenges, please retry later")
+)
+
+// Authenticator issues challenges and verifies the secp256k1 signatures of them.
+// When Required is true, the address-scoped topics and REST endpoints need a valid signature of the address
+type Authenticator struct {
+	Required bool
+
+	mtx sync.Mutex
+	// the challenges are all alive for AuthChallengeTTL, so the list is ordered by their expiry time
+	challenges map[string]*list.Element
+	expiries   *list.List
+	// the number of challenges issued to each client in the current window
+	clientCounts map[string]int
+	windowStart  time.Time
+}
+
+type challengeExpiry struct {
+	challenge string
+	expiry    time.Time
+}
+
+func NewAuthenticator(required bool) *Authenticator {
+	return &Authenticator{
+		Required:     required,
+		challenges:   make(map[string]*list.Element),
+		expiries:     list.New(),
+		clientCounts: make(map[string]int),
+	}
+}
+
+func newChallenge() string {
+	bz := make([]byte, 16)
+	if _, err := rand.Read(bz); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(bz)
+}
+
+// Issues a challenge to a client, such as an IP address, which can be signed once to access the REST endpoints
+// before it expires. Returns ErrTooManyChallenges if the client asks for too many challenges
+func (a *Authenticator) NewChallenge(client string) (string, error) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	now := time.Now()
+	if now.Sub(a.windowStart) >= AuthChallengeWindow {
+		a.windowStart = now
+		a.clientCounts = make(map[string]int)
+	}
+	if a.clientCounts[client] >= MaxChallengesPerClient {
+		return "", ErrTooManyChallenges
+	}
+	a.clientCounts[client]++
+	for e := a.expiries.Front(); e != nil; e = a.expiries.Front() {
+		if now.Before(e.Value.(challengeExpiry).expiry) && a.expiries.Len() < maxLiveChallenges {
+			break
+		}
+		a.remove(e)
+	}
+	challenge := newChallenge()
+	a.challenges[challenge] = a.expiries.PushBack(challengeExpiry{challenge: challenge, expiry: now.Add(AuthChallengeTTL)})
+	return challenge, nil
+}
+
+func (a *Authenticator) remove(e *list.Element) {
+	delete(a.challenges, e.Value.(challengeExpiry).challenge)
+	a.expiries.Remove(e)
+}
+
+// Verifies that the challenge is issued by this authenticator and signed by the owner of the account.
+// The challenge can not be used any more after it is verified
+func (a *Authenticator) VerifyAccount(account, challenge, pubKey, signature string) error {
+	addr, err := a.verifySignature(challenge, pubKey, signature)
+	if err != nil {
+		return err
+	}
+	if !isAddressOf(account, addr) {
+		return ErrAuthRequired
+	}
+	return a.consume(challenge)
+}
+
+// Returns the address which signs the challenge issued by this authenticator, and the challenge is used up
+func (a *Authenticator) verify(challenge, pubKey, signature string) ([]byte, error) {
+	addr, err := a.verifySignature(challenge, pubKey, signature)
+	if err != nil {
+		return nil, err
+	}
+	return addr, a.consume(challenge)
+}
+
+func (a *Authenticator) verifySignature(challenge, pubKey, signature string) ([]byte, error) {
+	a.mtx.Lock()
+	e, ok := a.challenges[challenge]
+	a.mtx.Unlock()
+	if !ok || time.Now().After(e.Value.(challengeExpiry).expiry) {
+		return nil, ErrInvalidChallenge
+	}
+	return VerifySignature(challenge, pubKey, signature)
+}
+
+// Removes a verified challenge, which fails if the challenge has been used by a concurrent request
+func (a *Authenticator) consume(challenge string) error {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	e, ok := a.challenges[challenge]
+	if !ok {
+		return ErrInvalidChallenge
+	}
+	a.remove(e)
+	return nil
+}
+
+// Verifies the signature of msg, and returns the address of the public key.
+// The public key is the base64 encoded 33-byte compressed secp256k1 key, and the signature is the base64 encoded R||S
+func VerifySignature(msg, pubKey, signature string) ([]byte, error) {
+	pubKeyBz, err := base64.StdEncoding.DecodeString(pubKey)
+	if err != nil || len(pubKeyBz) != secp256k1.PubKeySecp256k1Size {
+		return nil, ErrInvalidPubKey
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	var key secp256k1.PubKeySecp256k1
+	copy(key[:], pubKeyBz)
+	if !key.VerifyBytes([]byte(msg), sig) {
+		return nil, ErrInvalidSignature
+	}
+	return key.Address().Bytes(), nil
+}
+
+func getBech32Address(addr []byte) string {
+	res, err := bech32.ConvertAndEncode(Bech32PrefixAccAddr, addr)
+	if err != nil {
+		return ""
+	}
+	return res
+}
+
+// Whether the bech32 address belongs to addr, the validator operator addresses are also accepted
+func isAddressOf(bech32Addr string, addr []byte) bool {
+	_, bz, err := bech32.DecodeAndConvert(bech32Addr)
+	return err == nil && bytes.Equal(bz, addr)
+}
+
+// The topics whose param is an address
+func isAddressTopic(topic string) bool {
+	switch topic {
+	case OrderKey, IncomeKey, TxKey, LockedKey, UnlockKey, UnbondingKey, RedelegationKey, BancorTradeKey,
+		DelegationRewardsKey, ValidatorCommissionKey, OpenOrdersKey, AccountKey, AlertKey, RebateKey:
+		return true
+	}
+	return false
+}
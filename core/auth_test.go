@@ -0,0 +1,109 @@
+package core
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tendermint/crypto/secp256k1"
+)
+
+func signChallenge(t *testing.T, key secp256k1.PrivKeySecp256k1, challenge string) (string, string) {
+	sig, err := key.Sign([]byte(challenge))
+	require.Nil(t, err)
+	pubKey := key.PubKey().(secp256k1.PubKeySecp256k1)
+	return base64.StdEncoding.EncodeToString(pubKey[:]), base64.StdEncoding.EncodeToString(sig)
+}
+
+func TestConnAuthenticate(t *testing.T) {
+	key := secp256k1.GenPrivKey()
+	addr := getBech32Address(key.PubKey().Address())
+	conn := NewConn(&MockWif{})
+	_, err := conn.Authenticate(signChallenge(t, key, "abc"))
+	require.Equal(t, ErrInvalidChallenge, err)
+
+	challenge := conn.NewChallenge()
+	_, err = conn.Authenticate(signChallenge(t, secp256k1.GenPrivKey(), "abc"))
+	require.Equal(t, ErrInvalidSignature, err)
+	_, err = conn.Authenticate("abc", "abc")
+	require.Equal(t, ErrInvalidPubKey, err)
+	pubKey, sig := signChallenge(t, key, challenge)
+	res, err := conn.Authenticate(pubKey, sig)
+	require.Nil(t, err)
+	require.EqualValues(t, addr, res)
+	require.True(t, conn.IsAuthenticated(addr))
+	require.False(t, conn.IsAuthenticated(getBech32Address(secp256k1.GenPrivKey().PubKey().Address())))
+	// a challenge can only be signed once
+	_, err = conn.Authenticate(pubKey, sig)
+	require.Equal(t, ErrInvalidChallenge, err)
+}
+
+func TestSubscribeWithAuth(t *testing.T) {
+	key := secp256k1.GenPrivKey()
+	addr := getBech32Address(key.PubKey().Address())
+	wsManager := NewWebSocketManager()
+	conn := wsManager.AddWsConn(&MockWif{})
+	require.Nil(t, wsManager.AddSubscribeConn(conn, OrderKey, []string{addr}))
+
+	wsManager.SetAuthenticator(NewAuthenticator(true))
+	require.Equal(t, ErrAuthRequired, wsManager.AddSubscribeConn(conn, IncomeKey, []string{addr}))
+	require.Equal(t, ErrAuthRequired, wsManager.PushFullInfo(nil, conn, IncomeKey, []string{addr}, 10))
+	_, err := wsManager.ResumeSubscribeConn(nil, conn, []Subscription{{IncomeKey, []string{addr}}}, 1, 0)
+	require.Equal(t, ErrAuthRequired, err)
+	require.Nil(t, wsManager.AddSubscribeConn(conn, DealKey, []string{"abc/cet"}))
+
+	_, err = conn.Authenticate(signChallenge(t, key, conn.NewChallenge()))
+	require.Nil(t, err)
+	require.Nil(t, wsManager.AddSubscribeConn(conn, IncomeKey, []string{addr}))
+	require.EqualValues(t, 1, len(wsManager.GetIncomeSubscribeInfo()[addr]))
+}
+
+func TestAuthenticatorVerifyAccount(t *testing.T) {
+	key := secp256k1.GenPrivKey()
+	addr := getBech32Address(key.PubKey().Address())
+	auth := NewAuthenticator(true)
+	challenge, err := auth.NewChallenge("client")
+	require.Nil(t, err)
+	pubKey, sig := signChallenge(t, key, challenge)
+	other := getBech32Address(secp256k1.GenPrivKey().PubKey().Address())
+	require.Equal(t, ErrAuthRequired, auth.VerifyAccount(other, challenge, pubKey, sig))
+	require.Nil(t, auth.VerifyAccount(addr, challenge, pubKey, sig))
+	// the challenge can not be replayed
+	require.Equal(t, ErrInvalidChallenge, auth.VerifyAccount(addr, challenge, pubKey, sig))
+	pubKey2, sig2 := signChallenge(t, key, "abc")
+	require.Equal(t, ErrInvalidChallenge, auth.VerifyAccount(addr, "abc", pubKey2, sig2))
+
+	challenge, err = auth.NewChallenge("client")
+	require.Nil(t, err)
+	pubKey, sig = signChallenge(t, key, challenge)
+	auth.challenges[challenge].Value = challengeExpiry{challenge: challenge, expiry: time.Now().Add(-time.Second)}
+	require.Equal(t, ErrInvalidChallenge, auth.VerifyAccount(addr, challenge, pubKey, sig))
+}
+
+func TestAuthenticatorNewChallenge(t *testing.T) {
+	auth := NewAuthenticator(true)
+	for i := 0; i < MaxChallengesPerClient; i++ {
+		_, err := auth.NewChallenge("client")
+		require.Nil(t, err)
+	}
+	_, err := auth.NewChallenge("client")
+	require.Equal(t, ErrTooManyChallenges, err)
+	_, err = auth.NewChallenge("other")
+	require.Nil(t, err)
+	auth.windowStart = time.Now().Add(-AuthChallengeWindow)
+	_, err = auth.NewChallenge("client")
+	require.Nil(t, err)
+
+	// the oldest challenges are dropped when too many challenges are alive
+	oldest := auth.expiries.Front().Value.(challengeExpiry).challenge
+	for i := auth.expiries.Len(); i < maxLiveChallenges+1; i++ {
+		_, err = auth.NewChallenge(fmt.Sprintf("client%d", i))
+		require.Nil(t, err)
+	}
+	require.EqualValues(t, maxLiveChallenges, len(auth.challenges))
+	require.EqualValues(t, maxLiveChallenges, auth.expiries.Len())
+	_, ok := auth.challenges[oldest]
+	require.False(t, ok)
+}
@@ -11,6 +11,8 @@ type baseCandleStick struct {
 	HighPrice  sdk.Dec `json:"high"`
 	LowPrice   sdk.Dec `json:"low"`
 	TotalDeal  sdk.Int `json:"total"`
+	TotalMoney sdk.Int `json:"total_money"` // the quote volume
+	TradeCount int64   `json:"trade_count"`
 }
 
 func newBaseCandleStick() baseCandleStick {
@@ -20,6 +22,7 @@ func newBaseCandleStick() baseCandleStick {
 		HighPrice:  sdk.ZeroDec(),
 		LowPrice:   sdk.ZeroDec(),
 		TotalDeal:  sdk.ZeroInt(),
+		TotalMoney: sdk.ZeroInt(),
 	}
 }
 
@@ -28,7 +31,7 @@ func (cs *baseCandleStick) hasDeal() bool {
 }
 
 // When new deal comes, update candle stick accordingly
-func (cs *baseCandleStick) update(price sdk.Dec, amount int64) {
+func (cs *baseCandleStick) update(price sdk.Dec, amount, money int64) {
 	if !cs.hasDeal() {
 		cs.OpenPrice = price
 		cs.HighPrice = price
@@ -43,4 +46,13 @@ func (cs *baseCandleStick) update(price sdk.Dec, amount int64) {
 	}
 	cs.ClosePrice = price
 	cs.TotalDeal = cs.TotalDeal.AddRaw(amount)
+	cs.TotalMoney = cs.TotalMoney.AddRaw(money)
+	cs.TradeCount++
+}
+
+// The candle sticks dumped by older versions have no quote volume
+func (cs *baseCandleStick) fillMissingFields() {
+	if cs.TotalMoney == (sdk.Int{}) {
+		cs.TotalMoney = sdk.ZeroInt()
+	}
 }
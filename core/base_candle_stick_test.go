@@ -17,12 +17,14 @@ func assertDealZeroCandleStick(t *testing.T) {
 	singleCandleStick := newBaseCandleStick()
 	require.False(t, singleCandleStick.hasDeal())
 
-	singleCandleStick.update(sdk.NewDec(100), 99)
+	singleCandleStick.update(sdk.NewDec(100), 99, 9900)
 	require.EqualValues(t, sdk.NewDec(100), singleCandleStick.LowPrice)
 	require.EqualValues(t, sdk.NewDec(100), singleCandleStick.HighPrice)
 	require.EqualValues(t, sdk.NewDec(100), singleCandleStick.ClosePrice)
 	require.EqualValues(t, sdk.NewDec(100), singleCandleStick.OpenPrice)
 	require.EqualValues(t, 99, singleCandleStick.TotalDeal.Int64())
+	require.EqualValues(t, 9900, singleCandleStick.TotalMoney.Int64())
+	require.EqualValues(t, 1, singleCandleStick.TradeCount)
 }
 
 func assertHaveDealCandleStick(t *testing.T) {
@@ -31,7 +33,7 @@ func assertHaveDealCandleStick(t *testing.T) {
 	singleCandleStick.LowPrice = sdk.NewDec(110)
 	singleCandleStick.HighPrice = sdk.NewDec(210)
 
-	singleCandleStick.update(sdk.NewDec(100), 99)
+	singleCandleStick.update(sdk.NewDec(100), 99, 9900)
 	require.EqualValues(t, sdk.NewDec(100), singleCandleStick.LowPrice)
 	require.EqualValues(t, sdk.NewDec(210), singleCandleStick.HighPrice)
 	require.EqualValues(t, sdk.NewDec(100), singleCandleStick.ClosePrice)
@@ -27,14 +27,55 @@ func (manager *CandleStickManager) NewBlock(currBlockTime time.Time) []*CandleSt
 	isNewDay := currBlockTime.UTC().Day() != manager.LastBlockTime.UTC().Day() || currBlockTime.UTC().Unix()-manager.LastBlockTime.UTC().Unix() > 60*60*24
 	isNewHour := currBlockTime.UTC().Hour() != manager.LastBlockTime.UTC().Hour() || currBlockTime.UTC().Unix()-manager.LastBlockTime.UTC().Unix() > 60*60
 	isNewMinute := currBlockTime.UTC().Minute() != manager.LastBlockTime.UTC().Minute() || currBlockTime.UTC().Unix()-manager.LastBlockTime.UTC().Unix() > 60
+	isNewSpan := map[byte]bool{
+		Minute:   isNewMinute,
+		Minute5:  isNewPeriod(manager.LastBlockTime, currBlockTime, 5*60),
+		Minute15: isNewPeriod(manager.LastBlockTime, currBlockTime, 15*60),
+		Minute30: isNewPeriod(manager.LastBlockTime, currBlockTime, 30*60),
+		Hour:     isNewHour,
+		Hour4:    isNewPeriod(manager.LastBlockTime, currBlockTime, 4*60*60),
+		Day:      isNewDay,
+		Week:     isNewWeek(manager.LastBlockTime, currBlockTime),
+		Month:    isNewMonth(manager.LastBlockTime, currBlockTime),
+	}
 	for _, csr := range manager.CsrMap {
-		csSlice := csr.newBlock(isNewDay, isNewHour, isNewMinute, manager.LastBlockTime)
+		csSlice := csr.newBlock(isNewSpan, manager.LastBlockTime)
 		res = append(res, csSlice...)
 	}
 	manager.LastBlockTime = currBlockTime
 	return res
 }
 
+// The records loaded from the dumps of older versions need initialization
+func (manager *CandleStickManager) fillMissingFields() {
+	for _, csr := range manager.CsrMap {
+		csr.fillMissingFields()
+	}
+}
+
+// The periods are aligned to the unix epoch, so 'seconds' must divide one day
+func isNewPeriod(lastTime, currTime time.Time, seconds int64) bool {
+	return floorDiv(currTime.Unix(), seconds) != floorDiv(lastTime.Unix(), seconds)
+}
+
+// Weeks start from Monday 00:00 UTC
+func isNewWeek(lastTime, currTime time.Time) bool {
+	const thursday = 3 * 24 * 60 * 60 // 1970-01-01 is a Thursday
+	const week = 7 * 24 * 60 * 60
+	return floorDiv(currTime.Unix()+thursday, week) != floorDiv(lastTime.Unix()+thursday, week)
+}
+
+func isNewMonth(lastTime, currTime time.Time) bool {
+	return currTime.UTC().Year() != lastTime.UTC().Year() || currTime.UTC().Month() != lastTime.UTC().Month()
+}
+
+func floorDiv(a, b int64) int64 {
+	if a < 0 && a%b != 0 {
+		return a/b - 1
+	}
+	return a / b
+}
+
 func (manager *CandleStickManager) GetRecord(Market string) *CandleStickRecord {
 	csr, ok := manager.CsrMap[Market]
 	if ok {
@@ -19,8 +19,9 @@ By comparing LastMinuteCSTime and LastUpdateTime, we'll know whether there is an
 */
 
 // Record the candle sticks within one day
-// It can provide three granularities: minute, hour and day.
-// If the client side wants other granularities, we need to merge several candle sticks into one.
+// It can provide three basic granularities: minute, hour and day.
+// The other granularities (5min, 15min, 30min, 4hour, week and month) are generated by merging several
+// basic candle sticks into one.
 type CandleStickRecord struct {
 	MinuteCS       [60]baseCandleStick `json:"minute_cs"`
 	HourCS         [24]baseCandleStick `json:"hour_cs"`
@@ -35,6 +36,12 @@ type CandleStickRecord struct {
 	LastHourPrice   sdk.Dec `json:"last_hour_price"`
 	LastDayPrice    sdk.Dec `json:"last_day_price"`
 	Market          string  `json:"Market"`
+	//the day-candle-sticks of the current week/month are merged into WeekCS/MonthCS
+	WeekCS  baseCandleStick `json:"week_cs"`
+	MonthCS baseCandleStick `json:"month_cs"`
+	//the last time and the last price of a non-empty merged candle stick, the keys are the timespans' names
+	LastMergedCSTime map[string]int64   `json:"last_merged_cs_time"`
+	LastMergedPrice  map[string]sdk.Dec `json:"last_merged_price"`
 }
 
 func NewCandleStickRecord(market string) *CandleStickRecord {
@@ -48,24 +55,75 @@ func NewCandleStickRecord(market string) *CandleStickRecord {
 	res.LastMinutePrice = sdk.ZeroDec()
 	res.LastHourPrice = sdk.ZeroDec()
 	res.LastDayPrice = sdk.ZeroDec()
+	res.fillMissingFields()
 	return res
 }
 
+// The records dumped by older versions have no merged candle sticks and no quote volume
+func (csr *CandleStickRecord) fillMissingFields() {
+	for i := range csr.MinuteCS {
+		csr.MinuteCS[i].fillMissingFields()
+	}
+	for i := range csr.HourCS {
+		csr.HourCS[i].fillMissingFields()
+	}
+	if csr.WeekCS.OpenPrice.IsNil() {
+		csr.WeekCS = newBaseCandleStick()
+	}
+	if csr.MonthCS.OpenPrice.IsNil() {
+		csr.MonthCS = newBaseCandleStick()
+	}
+	csr.WeekCS.fillMissingFields()
+	csr.MonthCS.fillMissingFields()
+	if csr.LastMergedCSTime == nil {
+		csr.LastMergedCSTime = make(map[string]int64)
+	}
+	if csr.LastMergedPrice == nil {
+		csr.LastMergedPrice = make(map[string]sdk.Dec)
+	}
+}
+
 // When a new block comes, gets the k-line data before the block time
-func (csr *CandleStickRecord) newBlock(isNewDay, isNewHour, isNewMinute bool, lastBlockTime time.Time) []*CandleStick {
-	res := make([]*CandleStick, 0, 3)
+// isNewSpan tells whether the new block is in a new minute/hour/day/... compared to the last block
+func (csr *CandleStickRecord) newBlock(isNewSpan map[byte]bool, lastBlockTime time.Time) []*CandleStick {
+	res := make([]*CandleStick, 0, 9)
 	lastBlockUnixTime := lastBlockTime.UTC().Unix()
-	if mcs := csr.getMinuteCandleStick(isNewMinute, lastBlockUnixTime); mcs != nil {
+	if mcs := csr.getMinuteCandleStick(isNewSpan[Minute], lastBlockUnixTime); mcs != nil {
 		res = append(res, mcs)
 	}
-	if hcs := csr.getHourCandleStick(isNewHour, lastBlockUnixTime); hcs != nil {
+	if hcs := csr.getHourCandleStick(isNewSpan[Hour], lastBlockUnixTime); hcs != nil {
 		res = append(res, hcs)
 	}
-	if dcs := csr.getDayCandleStick(isNewDay, lastBlockUnixTime); dcs != nil {
+	if dcs := csr.getDayCandleStick(isNewSpan[Day], lastBlockUnixTime); dcs != nil {
 		res = append(res, dcs)
 	}
 
-	csr.clearRecords(isNewHour, isNewDay)
+	minute := csr.LastUpdateTime.UTC().Minute()
+	for _, span := range []byte{Minute5, Minute15, Minute30} {
+		size := getMinutesInSpan(span)
+		start := minute / size * size
+		if cs := csr.getMergedCandleStick(isNewSpan[span], lastBlockUnixTime, span, csr.MinuteCS[start:start+size]); cs != nil {
+			res = append(res, cs)
+		}
+	}
+	// HourCS has been updated by getHourCandleStick
+	start := csr.LastUpdateTime.UTC().Hour() / 4 * 4
+	if cs := csr.getMergedCandleStick(isNewSpan[Hour4], lastBlockUnixTime, Hour4, csr.HourCS[start:start+4]); cs != nil {
+		res = append(res, cs)
+	}
+	if isNewSpan[Day] {
+		dayCS := merge(csr.HourCS[:])
+		csr.WeekCS = merge([]baseCandleStick{csr.WeekCS, dayCS})
+		csr.MonthCS = merge([]baseCandleStick{csr.MonthCS, dayCS})
+	}
+	if cs := csr.getMergedCandleStick(isNewSpan[Week], lastBlockUnixTime, Week, []baseCandleStick{csr.WeekCS}); cs != nil {
+		res = append(res, cs)
+	}
+	if cs := csr.getMergedCandleStick(isNewSpan[Month], lastBlockUnixTime, Month, []baseCandleStick{csr.MonthCS}); cs != nil {
+		res = append(res, cs)
+	}
+
+	csr.clearRecords(isNewSpan)
 	return res
 }
 
@@ -88,6 +146,7 @@ func (csr *CandleStickRecord) getMinuteCandleStick(isNewMinute bool, lastBlockTi
 			HighPrice:      csr.LastMinutePrice,
 			LowPrice:       csr.LastMinutePrice,
 			TotalDeal:      sdk.ZeroInt(),
+			TotalMoney:     sdk.ZeroInt(),
 			EndingUnixTime: lastBlockTime,
 			TimeSpan:       MinuteStr,
 			Market:         csr.Market,
@@ -118,6 +177,7 @@ func (csr *CandleStickRecord) getHourCandleStick(isNewHour bool, lastBlockTime i
 			HighPrice:      csr.LastHourPrice,
 			LowPrice:       csr.LastHourPrice,
 			TotalDeal:      sdk.ZeroInt(),
+			TotalMoney:     sdk.ZeroInt(),
 			EndingUnixTime: lastBlockTime,
 			TimeSpan:       HourStr,
 			Market:         csr.Market,
@@ -146,6 +206,7 @@ func (csr *CandleStickRecord) getDayCandleStick(isNewDay bool, lastBlockTime int
 			HighPrice:      csr.LastDayPrice,
 			LowPrice:       csr.LastDayPrice,
 			TotalDeal:      sdk.ZeroInt(),
+			TotalMoney:     sdk.ZeroInt(),
 			EndingUnixTime: lastBlockTime,
 			TimeSpan:       DayStr,
 			Market:         csr.Market,
@@ -154,7 +215,47 @@ func (csr *CandleStickRecord) getDayCandleStick(isNewDay bool, lastBlockTime int
 	return nil
 }
 
-func (csr *CandleStickRecord) clearRecords(isNewHour, isNewDay bool) {
+// Merge the sub candle sticks into one candle stick of 'span'
+func (csr *CandleStickRecord) getMergedCandleStick(isNewSpan bool, lastBlockTime int64, span byte, subList []baseCandleStick) *CandleStick {
+	lastUpdateTime := csr.LastUpdateTime.UTC().Unix()
+	if !isNewSpan || lastUpdateTime == 0 {
+		return nil
+	}
+	spanStr := getSpanStrFromSpan(span)
+	if lastUpdateTime != csr.LastMergedCSTime[spanStr] { //Has new updates after last candle stick of this span
+		cs := csr.newCandleStick(merge(subList), lastUpdateTime, span)
+		if !cs.TotalDeal.IsZero() {
+			csr.LastMergedCSTime[spanStr] = cs.EndingUnixTime
+			csr.LastMergedPrice[spanStr] = cs.ClosePrice
+			return cs
+		}
+	}
+	// When the volume is 0, fill the last price
+	lastPrice, ok := csr.LastMergedPrice[spanStr]
+	if !ok {
+		lastPrice = sdk.ZeroDec()
+	}
+	return &CandleStick{
+		OpenPrice:      lastPrice,
+		ClosePrice:     lastPrice,
+		HighPrice:      lastPrice,
+		LowPrice:       lastPrice,
+		TotalDeal:      sdk.ZeroInt(),
+		TotalMoney:     sdk.ZeroInt(),
+		EndingUnixTime: lastBlockTime,
+		TimeSpan:       spanStr,
+		Market:         csr.Market,
+	}
+}
+
+func (csr *CandleStickRecord) clearRecords(isNewSpan map[byte]bool) {
+	isNewHour, isNewDay := isNewSpan[Hour], isNewSpan[Day]
+	if isNewSpan[Week] {
+		csr.WeekCS = newBaseCandleStick()
+	}
+	if isNewSpan[Month] {
+		csr.MonthCS = newBaseCandleStick()
+	}
 	if isNewDay {
 		for i := 0; i < 24; i++ {
 			// clear the hour-candle-sticks of last day
@@ -176,14 +277,16 @@ func (csr *CandleStickRecord) newCandleStick(updateCS baseCandleStick, updateTim
 		HighPrice:      updateCS.HighPrice,
 		LowPrice:       updateCS.LowPrice,
 		TotalDeal:      updateCS.TotalDeal,
+		TotalMoney:     updateCS.TotalMoney,
+		TradeCount:     updateCS.TradeCount,
 		EndingUnixTime: updateTime,
 		TimeSpan:       getSpanStrFromSpan(span),
 		Market:         csr.Market,
 	}
 }
 
-// Update when there is a new deal
-func (csr *CandleStickRecord) Update(t time.Time, price sdk.Dec, amount int64) {
-	csr.MinuteCS[t.UTC().Minute()].update(price, amount)
+// Update when there is a new deal, 'amount' is the stock volume and 'money' is the quote volume
+func (csr *CandleStickRecord) Update(t time.Time, price sdk.Dec, amount, money int64) {
+	csr.MinuteCS[t.UTC().Minute()].update(price, amount, money)
 	csr.LastUpdateTime = t
 }
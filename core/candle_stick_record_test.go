@@ -1,6 +1,7 @@
 package core
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -12,7 +13,7 @@ import (
 func TestCandleStickRecord_Update(t *testing.T) {
 	record := NewCandleStickRecord("abc/cet")
 	now := time.Now()
-	record.Update(now, sdk.NewDec(125), 526)
+	record.Update(now, sdk.NewDec(125), 526, 65750)
 	require.EqualValues(t, now, record.LastUpdateTime)
 
 	baseCandlerStick := record.MinuteCS[now.UTC().Minute()]
@@ -61,6 +62,8 @@ func setMinuteRecord() *CandleStickRecord {
 		HighPrice:  sdk.NewDec(96),
 		LowPrice:   sdk.NewDec(69),
 		TotalDeal:  sdk.NewInt(9600),
+		TotalMoney: sdk.NewInt(800000),
+		TradeCount: 3,
 	}
 	return record
 }
@@ -94,6 +97,8 @@ func setHourRecord() *CandleStickRecord {
 			HighPrice:  sdk.NewDec(i * 2),
 			LowPrice:   sdk.NewDec(i * 2),
 			TotalDeal:  sdk.NewInt(i * 100),
+			TotalMoney: sdk.NewInt(i * 2 * i * 100),
+			TradeCount: i,
 		}
 	}
 	record.LastUpdateTime = now
@@ -131,6 +136,8 @@ func setDayRecord() *CandleStickRecord {
 			HighPrice:  sdk.NewDec(i * 2),
 			LowPrice:   sdk.NewDec(i * 2),
 			TotalDeal:  sdk.NewInt(i * 100),
+			TotalMoney: sdk.NewInt(i * 2 * i * 100),
+			TradeCount: i,
 		}
 	}
 	record.LastUpdateTime = now
@@ -138,3 +145,118 @@ func setDayRecord() *CandleStickRecord {
 	record.LastDayPrice = sdk.NewDec(999)
 	return record
 }
+
+func getCandleStickOfSpan(csSlice []*CandleStick, market, span string) *CandleStick {
+	for _, cs := range csSlice {
+		if cs.Market == market && cs.TimeSpan == span {
+			return cs
+		}
+	}
+	return nil
+}
+
+func TestMergedCandleSticks(t *testing.T) {
+	market := "abc/cet"
+	csrMan := NewCandleStickManager([]string{market})
+	csrMan.NewBlock(T("2019-07-28T23:50:10Z")) // Sunday
+	csrMan.GetRecord(market).Update(lastTime, sdk.NewDec(50), 2, 100)
+
+	// a new 5min span, but not a new 15min span
+	csSlice := csrMan.NewBlock(T("2019-07-28T23:55:10Z"))
+	require.Equal(t, sdk.NewDec(50), getCandleStickOfSpan(csSlice, market, Minute5Str).ClosePrice)
+	require.Nil(t, getCandleStickOfSpan(csSlice, market, Minute15Str))
+	csrMan.GetRecord(market).Update(lastTime, sdk.NewDec(70), 3, 210)
+	csrMan.NewBlock(T("2019-07-28T23:56:10Z"))
+	csrMan.GetRecord(market).Update(lastTime, sdk.NewDec(30), 1, 30)
+	lastTimeOld := lastTime
+
+	// 2019-07-29 is a Monday, so a new week begins; a new month does not begin
+	csSlice = csrMan.NewBlock(T("2019-07-29T00:00:10Z"))
+	require.Nil(t, getCandleStickOfSpan(csSlice, market, MonthStr))
+	require.Equal(t, CandleStick{
+		OpenPrice:      sdk.NewDec(70),
+		ClosePrice:     sdk.NewDec(30),
+		HighPrice:      sdk.NewDec(70),
+		LowPrice:       sdk.NewDec(30),
+		TotalDeal:      sdk.NewInt(4),
+		TotalMoney:     sdk.NewInt(240),
+		TradeCount:     2,
+		EndingUnixTime: lastTimeOld.Unix(),
+		TimeSpan:       Minute5Str,
+		Market:         market,
+	}, *getCandleStickOfSpan(csSlice, market, Minute5Str))
+	for _, span := range []string{Minute15Str, Minute30Str, Hour4Str, WeekStr} {
+		require.Equal(t, CandleStick{
+			OpenPrice:      sdk.NewDec(50),
+			ClosePrice:     sdk.NewDec(30),
+			HighPrice:      sdk.NewDec(70),
+			LowPrice:       sdk.NewDec(30),
+			TotalDeal:      sdk.NewInt(6),
+			TotalMoney:     sdk.NewInt(340),
+			TradeCount:     3,
+			EndingUnixTime: lastTimeOld.Unix(),
+			TimeSpan:       span,
+			Market:         market,
+		}, *getCandleStickOfSpan(csSlice, market, span))
+	}
+	csrMan.GetRecord(market).Update(lastTime, sdk.NewDec(20), 5, 100)
+	lastTimeOld = lastTime
+
+	// the deals of several days are merged into the month-candle-stick
+	csSlice = csrMan.NewBlock(T("2019-08-01T00:00:10Z"))
+	require.Nil(t, getCandleStickOfSpan(csSlice, market, WeekStr))
+	require.Equal(t, CandleStick{
+		OpenPrice:      sdk.NewDec(50),
+		ClosePrice:     sdk.NewDec(20),
+		HighPrice:      sdk.NewDec(70),
+		LowPrice:       sdk.NewDec(20),
+		TotalDeal:      sdk.NewInt(11),
+		TotalMoney:     sdk.NewInt(440),
+		TradeCount:     4,
+		EndingUnixTime: lastTimeOld.Unix(),
+		TimeSpan:       MonthStr,
+		Market:         market,
+	}, *getCandleStickOfSpan(csSlice, market, MonthStr))
+
+	// no deals in this month, fill the last price
+	csSlice = csrMan.NewBlock(T("2019-09-01T00:00:10Z"))
+	cs := getCandleStickOfSpan(csSlice, market, MonthStr)
+	require.Equal(t, sdk.NewDec(20), cs.OpenPrice)
+	require.Equal(t, sdk.NewDec(20), cs.ClosePrice)
+	require.True(t, cs.TotalDeal.IsZero())
+	require.Equal(t, T("2019-08-01T00:00:10Z").Unix(), cs.EndingUnixTime)
+}
+
+func TestFillMissingFields(t *testing.T) {
+	// a record dumped by older versions
+	old := NewCandleStickRecord("abc/cet")
+	old.Update(T("2019-07-15T08:07:10Z"), sdk.NewDec(10), 5, 50)
+	bz, err := json.Marshal(old)
+	require.Nil(t, err)
+	oldJSON := make(map[string]interface{})
+	require.Nil(t, json.Unmarshal(bz, &oldJSON))
+	delete(oldJSON, "week_cs")
+	delete(oldJSON, "month_cs")
+	delete(oldJSON, "last_merged_cs_time")
+	delete(oldJSON, "last_merged_price")
+	for _, cs := range oldJSON["minute_cs"].([]interface{}) {
+		delete(cs.(map[string]interface{}), "total_money")
+		delete(cs.(map[string]interface{}), "trade_count")
+	}
+	bz, err = json.Marshal(oldJSON)
+	require.Nil(t, err)
+
+	var record CandleStickRecord
+	require.Nil(t, json.Unmarshal(bz, &record))
+	record.fillMissingFields()
+	require.False(t, record.WeekCS.hasDeal())
+	require.False(t, record.MonthCS.hasDeal())
+	require.NotNil(t, record.LastMergedCSTime)
+	require.NotNil(t, record.LastMergedPrice)
+
+	record.Update(T("2019-07-15T08:07:20Z"), sdk.NewDec(20), 5, 100)
+	cs := record.MinuteCS[7]
+	require.Equal(t, sdk.NewInt(10), cs.TotalDeal)
+	require.Equal(t, sdk.NewInt(100), cs.TotalMoney)
+	require.EqualValues(t, 1, cs.TradeCount)
+}
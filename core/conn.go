@@ -4,6 +4,7 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/websocket"
 	log "github.com/sirupsen/logrus"
@@ -12,11 +13,21 @@ import (
 type WsInterface interface {
 	Close() error
 	WriteMessage(msgType int, data []byte) error
+	// can be called concurrently with WriteMessage
+	WriteControl(msgType int, data []byte, deadline time.Time) error
 	ReadMessage() (messageType int, p []byte, err error)
 	SetPingHandler(func(string) error)
 	PingHandler() func(string) error
 }
 
+// EventWriter is implemented by the transports which label the pushed messages with their seqs,
+// such as Server-Sent Events, whose clients resume from the last event id
+type EventWriter interface {
+	WriteEvent(seq int64, data []byte) error
+}
+
+var lastConnID int64
+
 // Conn models the connection to a client through websocket
 type Conn struct {
 	WsIfc           WsInterface
@@ -24,18 +35,31 @@ type Conn struct {
 	allTopics       map[string]struct{}            // all the topics (with or without params)
 	topicWithParams map[string]map[string]struct{} // topic --> params
 
+	id        int64
 	lastError atomic.Value
-	msgChan   chan []byte
-	closed    bool
+	queue     *sendQueue
+	encoding  atomic.Value // string
+	// non-zero if the pushed messages are labeled with the subscriptions they match, used by the relays
+	labeled int32
+
+	// the challenge to be signed, and the addresses authenticated by signing challenges
+	challenge string
+	authAddrs [][]byte
 }
 
 func NewConn(c WsInterface) *Conn {
+	return newConnWithQueue(c, DefaultSendQueueConfig())
+}
+
+func newConnWithQueue(c WsInterface, cfg SendQueueConfig) *Conn {
 	conn := &Conn{
 		WsIfc:           c,
-		msgChan:         make(chan []byte),
+		id:              atomic.AddInt64(&lastConnID, 1),
+		queue:           newSendQueue(cfg),
 		allTopics:       make(map[string]struct{}),
 		topicWithParams: make(map[string]map[string]struct{}),
 	}
+	conn.encoding.Store(EncodingJSON)
 	c.SetPingHandler(func(appData string) error {
 		return conn.WriteMsg([]byte(appData))
 	})
@@ -45,18 +69,62 @@ func NewConn(c WsInterface) *Conn {
 
 // Since WsIfc.WriteMessage is blocking, we'd like to wrap it into a goroutine
 func (c *Conn) sendMsg() {
+	encoding := EncodingJSON
 	for {
-		msg, ok := <-c.msgChan
+		msgs, ok := c.queue.popAll()
 		if !ok {
 			break
 		}
-
-		if c.lastError.Load() == nil {
-			if err := c.WsIfc.WriteMessage(websocket.TextMessage, msg); err != nil {
+		sent := 0
+		for _, msg := range msgs {
+			if c.lastError.Load() != nil {
+				break
+			}
+			// the new encoding is applied by this goroutine, since the compression can not be changed during writing
+			if e := c.GetEncoding(); e != encoding {
+				if w, ok := c.WsIfc.(Compressor); ok {
+					w.EnableWriteCompression(e == EncodingDeflate)
+				}
+				encoding = e
+			}
+			if err := c.writeQueuedMsg(msg, encoding); err != nil {
 				c.lastError.Store(err)
+				break
 			}
+			sent++
+		}
+		c.queue.addSent(sent)
+	}
+}
+
+func (c *Conn) writeQueuedMsg(msg *queuedMsg, encoding string) error {
+	if w, ok := c.WsIfc.(EventWriter); ok && msg.push != nil {
+		return w.WriteEvent(msg.push.seq, msg.encode())
+	}
+	msgType, bz := msg.encodeAs(encoding)
+	return c.WsIfc.WriteMessage(msgType, bz)
+}
+
+// The messages which have not been written are sent in the new encoding
+func (c *Conn) SetEncoding(encoding string) error {
+	if !IsValidEncoding(encoding) {
+		return ErrUnknownEncoding
+	}
+	if encoding == EncodingDeflate {
+		if w, ok := c.WsIfc.(Compressor); !ok || !w.CompressionNegotiated() {
+			return ErrDeflateNotNegotiated
 		}
 	}
+	c.encoding.Store(encoding)
+	return nil
+}
+
+func (c *Conn) GetEncoding() string {
+	return c.encoding.Load().(string)
+}
+
+func (c *Conn) IsLabeled() bool {
+	return atomic.LoadInt32(&c.labeled) != 0
 }
 
 // Since WsIfc.ReadMessage is blocking, this function is only used in the handleConn goroutine
@@ -68,17 +136,109 @@ func (c *Conn) ReadMsg(manager *WebsocketManager) (message []byte, err error) {
 	return
 }
 
+// The messages written by WriteMsg, such as the responses to commands, are never dropped.
+// The connection is closed if too many of them are waiting in the send queue
 func (c *Conn) WriteMsg(v []byte) error {
 	if val := c.lastError.Load(); val != nil {
 		return val.(error)
 	}
-	if c.closed { // Why? Should we return an error when writing to a closed Conn?
-		return nil
+	if err := c.queue.pushRaw(v); err != nil {
+		c.lastError.Store(err)
+		c.closeSlow()
+		return err
 	}
-	c.msgChan <- v
 	return nil
 }
 
+// Queues a pushed message, which may be dropped or coalesced when the send queue is full.
+// Returns ErrSlowConsumer if this connection should be closed because of its full send queue
+func (c *Conn) writePushMsg(msg *pushMsg) error {
+	if val := c.lastError.Load(); val != nil {
+		return val.(error)
+	}
+	return c.queue.push(msg)
+}
+
+// Queues the replayed messages, which keep their seqs for the EventWriter and are never dropped.
+// Returns false if the send queue has no room for all of them
+func (c *Conn) writeReplayMsgs(msgs []*pushMsg) (bool, error) {
+	if val := c.lastError.Load(); val != nil {
+		return false, val.(error)
+	}
+	return c.queue.pushReplays(msgs), nil
+}
+
+// The close message may be blocked by the slow consumer, so it is sent in another goroutine.
+// The manager removes this connection after its reading fails
+func (c *Conn) closeSlow() {
+	go func() {
+		c.writeCloseMsg(CloseSlowConsumer, "slow consumer")
+		if err := c.Close(); err != nil {
+			log.WithError(err).Error("close connection failed")
+		}
+	}()
+}
+
+// Tells the client why this connection is going to be closed
+func (c *Conn) writeCloseMsg(code int, reason string) {
+	msg := websocket.FormatCloseMessage(code, reason)
+	if err := c.WsIfc.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second)); err != nil {
+		log.WithError(err).Error("write close message failed")
+	}
+}
+
+// Issues a new challenge for this connection, the older one can not be signed any more
+func (c *Conn) NewChallenge() string {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.challenge = newChallenge()
+	return c.challenge
+}
+
+// Verifies the signature of the challenge, and returns the authenticated address.
+// Each challenge can only be signed once
+func (c *Conn) Authenticate(pubKey, signature string) (string, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if len(c.challenge) == 0 {
+		return "", ErrInvalidChallenge
+	}
+	addr, err := VerifySignature(c.challenge, pubKey, signature)
+	if err != nil {
+		return "", err
+	}
+	c.challenge = ""
+	c.authAddrs = append(c.authAddrs, addr)
+	return getBech32Address(addr), nil
+}
+
+func (c *Conn) addAuthAddr(addr []byte) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.authAddrs = append(c.authAddrs, addr)
+}
+
+// Whether the address has been authenticated by this connection
+func (c *Conn) IsAuthenticated(bech32Addr string) bool {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	for _, addr := range c.authAddrs {
+		if isAddressOf(bech32Addr, addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Conn) GetStats() SendQueueStats {
+	stats := c.queue.getStats()
+	stats.ConnID = c.id
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	stats.Topics = len(c.allTopics)
+	return stats
+}
+
 func (c *Conn) PingHandler() func(string) error {
 	return c.WsIfc.PingHandler()
 }
@@ -112,13 +272,35 @@ func (c *Conn) removeTopicAndParams(topic string, params []string) {
 	}
 }
 
+func (c *Conn) hasTopicAndParams(topic string, params []string) bool {
+	if _, ok := c.allTopics[topic]; !ok {
+		return false
+	}
+	if len(params) == 0 {
+		return true
+	}
+	_, ok := c.topicWithParams[topic][strings.Join(params, SeparateArgu)]
+	return ok
+}
+
+func (c *Conn) countWildcards() int {
+	count := 0
+	for _, params := range c.topicWithParams {
+		for p := range params {
+			if isWildcardMarket(p) {
+				count++
+			}
+		}
+	}
+	return count
+}
+
 // if the param set of this topic is empty
 func (c *Conn) topicHasEmptyParamSet(topic string) bool {
 	return len(c.topicWithParams[topic]) == 0
 }
 
 func (c *Conn) Close() error {
-	c.closed = true
-	close(c.msgChan)
+	c.queue.close()
 	return c.WsIfc.Close()
 }
@@ -10,8 +10,9 @@ import (
 )
 
 type MockWif struct {
-	mtx    sync.Mutex
-	recode [][]byte
+	mtx     sync.Mutex
+	recode  [][]byte
+	control [][]byte
 }
 
 func (m *MockWif) Close() error {
@@ -25,6 +26,13 @@ func (m *MockWif) WriteMessage(msgType int, data []byte) error {
 	return nil
 }
 
+func (m *MockWif) WriteControl(msgType int, data []byte, deadline time.Time) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.control = append(m.control, data)
+	return nil
+}
+
 func (m *MockWif) ReadMessage() (messageType int, p []byte, err error) {
 	panic("implement me")
 }
@@ -0,0 +1,157 @@
+package core
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The depth of a market is saved as a full snapshot every 'blocksInterval' blocks, and as the changed
+// price points of every block in between, so the order book can be rebuilt at any retained height.
+// The amounts in a delta record are the new amounts of the changed price points, zero for the removed ones
+type depthRecord struct {
+	Height int64         `json:"height"`
+	Sell   []*PricePoint `json:"sell"`
+	Buy    []*PricePoint `json:"buy"`
+}
+
+func sortedPricePoints(pps map[string]*PricePoint) []*PricePoint {
+	keys := make([]string, 0, len(pps))
+	for key := range pps {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	res := make([]*PricePoint, 0, len(keys))
+	for _, key := range keys {
+		res = append(res, pps[key])
+	}
+	return res
+}
+
+// The keys of snapshots and deltas are ordered by the block time and then the height,
+// and they are pruned by the block time like the other records
+func getDepthHistoryKey(firstByte byte, market string, unixTime, height int64) []byte {
+	res := make([]byte, 0, 1+1+len(market)+1+16+1)
+	res = append(res, firstByte)
+	res = append(res, byte(len(market)))
+	res = append(res, []byte(market)...)
+	res = append(res, byte(0))
+	res = append(res, Int64ToBigEndianBytes(unixTime)...)
+	res = append(res, Int64ToBigEndianBytes(height)...)
+	res = append(res, byte(0))
+	return res
+}
+
+func (hub *Hub) saveDepthRecord(firstByte byte, market string, record *depthRecord) {
+	bz, err := json.Marshal(record)
+	if err != nil {
+		hub.Log(fmt.Sprintf("Error in Marshal depth record: %v", err))
+		return
+	}
+	hub.batch.Set(getDepthHistoryKey(firstByte, market, hub.currBlockTime.Unix(), record.Height), bz)
+}
+
+// Saves the price points changed in the current block
+func (hub *Hub) saveDepthDelta(market string, deltaBuy, deltaSell map[string]*PricePoint) {
+	hub.saveDepthRecord(DepthDeltaByte, market, &depthRecord{
+		Height: hub.currBlockHeight,
+		Sell:   sortedPricePoints(deltaSell),
+		Buy:    sortedPricePoints(deltaBuy),
+	})
+}
+
+// Saves the full depth of every market, at the same interval as pushing the full depth
+func (hub *Hub) saveDepthSnapshots() {
+	if hub.currBlockHeight%hub.blocksInterval != 0 {
+		return
+	}
+	for market, triman := range hub.managersMap {
+		if strings.HasPrefix(market, "B:") {
+			continue
+		}
+		hub.saveDepthRecord(DepthSnapshotByte, market, &depthRecord{
+			Height: hub.currBlockHeight,
+			Sell:   triman.sell.DumpPricePoints(),
+			Buy:    triman.buy.DumpPricePoints(),
+		})
+	}
+}
+
+func (hub *Hub) queryBlockTimeAtHeight(height int64) (int64, bool) {
+	bz := hub.db.Get(append([]byte{BlockHeightByte}, Int64ToBigEndianBytes(height)...))
+	if len(bz) != 8 {
+		return 0, false
+	}
+	return int64(binary.LittleEndian.Uint64(bz)), true
+}
+
+// Rebuilds the depth of a market at height from the latest snapshot before it and the following deltas.
+// Returns false if the depth at height is not retained
+func (hub *Hub) QueryDepthAtHeight(market string, height int64, count int) (sell []*PricePoint, buy []*PricePoint, ok bool) {
+	count = limitCount(count)
+	hub.dbMutex.RLock()
+	defer hub.dbMutex.RUnlock()
+	unixTime, ok := hub.queryBlockTimeAtHeight(height)
+	if !ok {
+		return nil, nil, false
+	}
+	start := getDepthHistoryKey(DepthSnapshotByte, market, 0, 0)
+	end := getDepthHistoryKey(DepthSnapshotByte, market, unixTime, height+1)
+	iter := hub.db.ReverseIterator(start, end)
+	if !iter.Valid() {
+		iter.Close()
+		return nil, nil, false
+	}
+	key := iter.Key()
+	var snapshot depthRecord
+	err := json.Unmarshal(iter.Value(), &snapshot)
+	iter.Close()
+	if err != nil {
+		hub.Log(fmt.Sprintf("Error in Unmarshal depth snapshot: %v", err))
+		return nil, nil, false
+	}
+	sellMap, buyMap := make(map[string]*PricePoint), make(map[string]*PricePoint)
+	applyPricePoints(sellMap, snapshot.Sell)
+	applyPricePoints(buyMap, snapshot.Buy)
+
+	snapshotTime := BigEndianBytesToInt64(key[len(key)-17 : len(key)-9])
+	start = getDepthHistoryKey(DepthDeltaByte, market, snapshotTime, snapshot.Height+1)
+	iter = hub.db.Iterator(start, getDepthHistoryKey(DepthDeltaByte, market, unixTime, height+1))
+	defer iter.Close()
+	for ; iter.Valid(); iter.Next() {
+		var delta depthRecord
+		if err := json.Unmarshal(iter.Value(), &delta); err != nil {
+			hub.Log(fmt.Sprintf("Error in Unmarshal depth delta: %v", err))
+			return nil, nil, false
+		}
+		applyPricePoints(sellMap, delta.Sell)
+		applyPricePoints(buyMap, delta.Buy)
+	}
+
+	sell = sortedPricePoints(sellMap)
+	if len(sell) > count {
+		sell = sell[:count]
+	}
+	buy = sortedPricePoints(buyMap)
+	for i, j := 0, len(buy)-1; i < j; i, j = i+1, j-1 {
+		buy[i], buy[j] = buy[j], buy[i]
+	}
+	if len(buy) > count {
+		buy = buy[:count]
+	}
+	return sell, buy, true
+}
+
+// The price points with zero amounts are removed
+func applyPricePoints(pps map[string]*PricePoint, changes []*PricePoint) {
+	for _, pp := range changes {
+		key := string(decToBigEndianBytes(pp.Price))
+		if pp.Amount.IsZero() {
+			delete(pps, key)
+		} else {
+			pps[key] = pp
+		}
+	}
+}
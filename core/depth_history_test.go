@@ -0,0 +1,92 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/require"
+	dbm "github.com/tendermint/tm-db"
+)
+
+func TestQueryDepthAtHeight(t *testing.T) {
+	acc, _ := simpleAddr("00001")
+	addr := acc.String()
+	// the full depth is saved every 2 blocks
+	hub := NewHub(dbm.NewMemDB(), &MocSubscribeManager{}, 2, 0, 0, 0, "", 0)
+	hub.currBlockHeight = 999
+	seq := 0
+	createOrder := func(side byte, price int64, quantity int64) {
+		seq++
+		bz, _ := json.Marshal(&CreateOrderInfo{
+			OrderID:     fmt.Sprintf("%s-%d", addr, seq),
+			Sender:      addr,
+			TradingPair: "abc/cet",
+			OrderType:   LIMIT,
+			Price:       sdk.NewDec(price),
+			Quantity:    quantity,
+			Side:        side,
+			TimeInForce: GTE,
+			Height:      hub.currBlockHeight,
+		})
+		hub.ConsumeMessage("create_order_info", bz)
+	}
+	cancelOrder := func(id int, side byte, price int64, left int64) {
+		bz, _ := json.Marshal(&CancelOrderInfo{
+			OrderID:     fmt.Sprintf("%s-%d", addr, id),
+			TradingPair: "abc/cet",
+			Side:        side,
+			Price:       sdk.NewDec(price),
+			LeftStock:   left,
+			Height:      hub.currBlockHeight,
+		})
+		hub.ConsumeMessage("del_order_info", bz)
+	}
+	newBlock := func(height int64, actions func()) {
+		bz, _ := json.Marshal(&NewHeightInfo{Height: height, TimeStamp: 1000 + height, ChainID: "coinex-test"})
+		hub.ConsumeMessage("height_info", bz)
+		actions()
+		hub.ConsumeMessage("commit", nil)
+	}
+	newBlock(1000, func() {
+		createOrder(SELL, 12, 100)
+		createOrder(BUY, 10, 200)
+	})
+	newBlock(1001, func() {
+		createOrder(SELL, 13, 300)
+		createOrder(BUY, 9, 400)
+	})
+	newBlock(1002, func() {
+		cancelOrder(1, SELL, 12, 100)
+	})
+	newBlock(1003, func() {
+		createOrder(BUY, 11, 500)
+	})
+
+	checkDepth := func(height int64, sell, buy []*PricePoint) {
+		s, b, ok := hub.QueryDepthAtHeight("abc/cet", height, 10)
+		require.True(t, ok)
+		require.EqualValues(t, sell, s, height)
+		require.EqualValues(t, buy, b, height)
+	}
+	pp := func(price, amount int64) *PricePoint {
+		return &PricePoint{Price: sdk.NewDec(price), Amount: sdk.NewInt(amount)}
+	}
+	checkDepth(1000, []*PricePoint{pp(12, 100)}, []*PricePoint{pp(10, 200)})
+	checkDepth(1001, []*PricePoint{pp(12, 100), pp(13, 300)}, []*PricePoint{pp(10, 200), pp(9, 400)})
+	checkDepth(1002, []*PricePoint{pp(13, 300)}, []*PricePoint{pp(10, 200), pp(9, 400)})
+	checkDepth(1003, []*PricePoint{pp(13, 300)}, []*PricePoint{pp(11, 500), pp(10, 200), pp(9, 400)})
+	sell, buy, ok := hub.QueryDepthAtHeight("abc/cet", 1003, 1)
+	require.True(t, ok)
+	require.EqualValues(t, []*PricePoint{pp(13, 300)}, sell)
+	require.EqualValues(t, []*PricePoint{pp(11, 500)}, buy)
+
+	// no snapshot before height 1000, and no block at height 1004
+	_, _, ok = hub.QueryDepthAtHeight("abc/cet", 999, 10)
+	require.False(t, ok)
+	_, _, ok = hub.QueryDepthAtHeight("abc/cet", 1004, 10)
+	require.False(t, ok)
+	_, _, ok = hub.QueryDepthAtHeight("xyz/cet", 1003, 10)
+	require.False(t, ok)
+}
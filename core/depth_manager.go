@@ -7,6 +7,9 @@ import (
 	"github.com/emirpasic/gods/maps/treemap"
 )
 
+// The checksums of depth data are computed on the best price points of both sides
+const DepthChecksumCount = 25
+
 // Manager for the depth information of one side of the order book: sell or buy
 type DepthManager struct {
 	// ppMap keeps a depth map without any merging
@@ -151,3 +154,42 @@ func (dm *DepthManager) GetLowest(n int) []*PricePoint {
 	}
 	return res
 }
+
+// Returns the best n PricePoints merged at 'level', without merging if level is "all".
+// The buy side is sorted from the highest price and the sell side is sorted from the lowest price
+func (dm *DepthManager) GetBest(level string, n int) []*PricePoint {
+	isBuy := dm.Side != "sell"
+	if level == "all" {
+		if isBuy {
+			return dm.GetHighest(n)
+		}
+		return dm.GetLowest(n)
+	}
+	p, err := sdk.NewDecFromStr(level)
+	if err != nil || !p.IsPositive() {
+		return nil
+	}
+	mulDec := sdk.OneDec().QuoTruncate(p)
+	res := make([]*PricePoint, 0, n)
+	iter := dm.ppMap.Iterator()
+	next := iter.Next
+	if isBuy {
+		iter.End()
+		next = iter.Prev
+	} else {
+		iter.Begin()
+	}
+	for next() {
+		pp := iter.Value().(*PricePoint)
+		price := getMergedPrice(pp.Price, mulDec, isBuy)
+		if len(res) != 0 && res[len(res)-1].Price.Equal(price) {
+			res[len(res)-1].Amount = res[len(res)-1].Amount.Add(pp.Amount)
+			continue
+		}
+		if len(res) == n {
+			break
+		}
+		res = append(res, &PricePoint{Price: price, Amount: pp.Amount})
+	}
+	return res
+}
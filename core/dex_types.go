@@ -38,8 +38,8 @@ type OrderInfo struct {
 }
 
 type OrderResponse struct {
-	Data    []json.RawMessage `json:"data"`
-	Timesid []int64           `json:"timesid"`
+	Data   []json.RawMessage `json:"data"`
+	Cursor string            `json:"cursor"`
 }
 
 // someone creates an order
@@ -111,6 +111,9 @@ type DepthDetails struct {
 	TradingPair string        `json:"trading_pair"`
 	Bids        []*PricePoint `json:"bids"`
 	Asks        []*PricePoint `json:"asks"`
+	// the height when the order book was last changed, and the checksum of the order book at this height
+	Height   int64  `json:"height"`
+	Checksum uint32 `json:"checksum"`
 }
 
 // ---------------------
@@ -0,0 +1,73 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// The encodings of the messages sent to a websocket connection, the structures of the messages are the same
+const (
+	// JSON text frames, the default one
+	EncodingJSON = "json"
+	// JSON text frames compressed by the permessage-deflate extension, which must be negotiated during the handshake
+	EncodingDeflate = "deflate"
+	// gzip-compressed JSON in binary frames
+	EncodingGzip = "gzip"
+	// MessagePack in binary frames
+	EncodingMsgpack = "msgpack"
+)
+
+var (
+	ErrUnknownEncoding      = errors.New("unknown encoding")
+	ErrDeflateNotNegotiated = errors.New("permessage-deflate is not negotiated")
+)
+
+// Compressor is implemented by the websocket connections which can compress their messages with permessage-deflate
+type Compressor interface {
+	CompressionNegotiated() bool
+	// only called by the goroutine writing the messages
+	EnableWriteCompression(enable bool)
+}
+
+func IsValidEncoding(encoding string) bool {
+	switch encoding {
+	case EncodingJSON, EncodingDeflate, EncodingGzip, EncodingMsgpack:
+		return true
+	}
+	return false
+}
+
+var gzipWriterPool = sync.Pool{
+	New: func() interface{} {
+		return gzip.NewWriter(nil)
+	},
+}
+
+func gzipBytes(bz []byte) []byte {
+	var buf bytes.Buffer
+	w := gzipWriterPool.Get().(*gzip.Writer)
+	defer gzipWriterPool.Put(w)
+	w.Reset(&buf)
+	// writing to a bytes.Buffer never fails
+	_, _ = w.Write(bz)
+	_ = w.Close()
+	return buf.Bytes()
+}
+
+// Encodes a JSON message, returns the frame type and the encoded bytes.
+// A message which is not valid JSON, such as the application data of a ping, is sent as it is in MessagePack encoding
+func encodeMessage(encoding string, bz []byte) (int, []byte) {
+	switch encoding {
+	case EncodingGzip:
+		return websocket.BinaryMessage, gzipBytes(bz)
+	case EncodingMsgpack:
+		if packed, err := jsonToMsgpack(bz); err == nil {
+			return websocket.BinaryMessage, packed
+		}
+	}
+	return websocket.TextMessage, bz
+}
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONToMsgpack(t *testing.T) {
+	packed, err := jsonToMsgpack([]byte(`{"type":"deal", "seq":1, "payload":[true, null, -1, 1.5, "abc"]}`))
+	require.Nil(t, err)
+	expected := []byte{0x83, 0xa4, 't', 'y', 'p', 'e', 0xa4, 'd', 'e', 'a', 'l', 0xa3, 's', 'e', 'q', 0x01,
+		0xa7, 'p', 'a', 'y', 'l', 'o', 'a', 'd', 0x95, 0xc3, 0xc0, 0xff, 0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0, 0xa3, 'a', 'b', 'c'}
+	require.EqualValues(t, expected, packed)
+
+	for _, c := range []struct {
+		json     string
+		expected []byte
+	}{
+		{`200`, []byte{0xcc, 0xc8}},
+		{`300`, []byte{0xcd, 0x01, 0x2c}},
+		{`-33`, []byte{0xd0, 0xdf}},
+		{`-200`, []byte{0xd1, 0xff, 0x38}},
+		{`70000`, []byte{0xce, 0x00, 0x01, 0x11, 0x70}},
+		{`-70000`, []byte{0xd2, 0xff, 0xfe, 0xee, 0x90}},
+		{`5000000000`, []byte{0xcf, 0, 0, 0, 0x01, 0x2a, 0x05, 0xf2, 0}},
+		{`"` + strings.Repeat("a", 40) + `"`, append([]byte{0xd9, 40}, strings.Repeat("a", 40)...)},
+		{`[` + strings.Repeat("0,", 15) + `0]`, append([]byte{0xdc, 0, 16}, make([]byte, 16)...)},
+		{`{}`, []byte{0x80}},
+	} {
+		packed, err := jsonToMsgpack([]byte(c.json))
+		require.Nil(t, err)
+		require.EqualValues(t, c.expected, packed, c.json)
+	}
+
+	_, err = jsonToMsgpack([]byte(`{"a":1} 2`))
+	require.NotNil(t, err)
+	_, err = jsonToMsgpack([]byte(`pong`))
+	require.NotNil(t, err)
+}
+
+// compressWif records the frame types, and permessage-deflate is negotiated
+type compressWif struct {
+	MockWif
+	types    []int
+	compress bool
+}
+
+func (m *compressWif) WriteMessage(msgType int, data []byte) error {
+	m.mtx.Lock()
+	m.types = append(m.types, msgType)
+	m.mtx.Unlock()
+	return m.MockWif.WriteMessage(msgType, data)
+}
+
+func (m *compressWif) CompressionNegotiated() bool {
+	return true
+}
+
+func (m *compressWif) EnableWriteCompression(enable bool) {
+	m.compress = enable
+}
+
+func gunzip(t *testing.T, bz []byte) string {
+	r, err := gzip.NewReader(bytes.NewReader(bz))
+	require.Nil(t, err)
+	res, err := ioutil.ReadAll(r)
+	require.Nil(t, err)
+	return string(res)
+}
+
+func TestConnEncoding(t *testing.T) {
+	conn := NewConn(&MockWif{})
+	require.EqualValues(t, EncodingJSON, conn.GetEncoding())
+	require.Equal(t, ErrUnknownEncoding, conn.SetEncoding("xml"))
+	require.Equal(t, ErrDeflateNotNegotiated, conn.SetEncoding(EncodingDeflate))
+
+	wif := &compressWif{}
+	conn = NewConn(wif)
+	msg := newPushMsg(DealKey, 3, 10, []byte(`{"price":"1.5"}`))
+	require.Nil(t, conn.writePushMsg(msg))
+	time.Sleep(10 * time.Millisecond)
+	require.Nil(t, conn.SetEncoding(EncodingGzip))
+	require.Nil(t, conn.writePushMsg(msg))
+	require.Nil(t, conn.WriteMsg([]byte(`{"type":"pong"}`)))
+	time.Sleep(10 * time.Millisecond)
+	require.Nil(t, conn.SetEncoding(EncodingMsgpack))
+	require.Nil(t, conn.writePushMsg(msg))
+	require.Nil(t, conn.WriteMsg([]byte("ping data")))
+	time.Sleep(10 * time.Millisecond)
+	require.Nil(t, conn.SetEncoding(EncodingDeflate))
+	require.Nil(t, conn.writePushMsg(msg))
+	time.Sleep(10 * time.Millisecond)
+
+	records := wif.GetRecords()
+	require.EqualValues(t, []int{websocket.TextMessage, websocket.BinaryMessage, websocket.BinaryMessage,
+		websocket.BinaryMessage, websocket.TextMessage, websocket.TextMessage}, wif.types)
+	encoded := `{"type":"deal", "seq":3, "height":10, "payload":{"price":"1.5"}}`
+	require.EqualValues(t, encoded, string(records[0]))
+	require.EqualValues(t, encoded, gunzip(t, records[1]))
+	require.EqualValues(t, `{"type":"pong"}`, gunzip(t, records[2]))
+	packed, _ := jsonToMsgpack([]byte(encoded))
+	require.EqualValues(t, packed, records[3])
+	// the message which is not JSON is sent as it is
+	require.EqualValues(t, "ping data", string(records[4]))
+	require.EqualValues(t, encoded, string(records[5]))
+	require.True(t, wif.compress)
+
+	// the encoded bytes are shared by the subscribers
+	_, bz1 := msg.encodeAs(EncodingGzip)
+	_, bz2 := msg.encodeAs(EncodingGzip)
+	require.True(t, &bz1[0] == &bz2[0])
+	// the pooled gzip writers are large, collect them before the timing-sensitive tests
+	runtime.GC()
+}
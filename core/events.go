@@ -4,6 +4,7 @@ const (
 	BlockInfoKey           = "blockinfo"
 	SlashKey               = "slash"
 	TickerKey              = "ticker"
+	XTickerKey             = "xticker"
 	KlineKey               = "kline"
 	DepthKey               = "depth"
 	OptionKey              = "option"
@@ -25,6 +26,18 @@ const (
 	LockedKey              = "send_lock_coins"
 	DelegationRewardsKey   = "delegation_rewards"
 	ValidatorCommissionKey = "validator_commission"
+	OpenOrdersKey          = "open_orders"
+	OpenOrdersFull         = "open_orders_full"
+	DepthL3Key             = "depth_l3"
+	DepthL3Full            = "depth_l3_full"
+	AccountKey             = "account"
+	AccountFull            = "account_full"
+	AlertKey               = "alert"
+	AlertFull              = "alert_full"
+	RebateKey              = "rebate"
+	RebateFull             = "rebate_full"
+	GapKey                 = "gap"
+	DroppedKey             = "dropped"
 )
 
 const (
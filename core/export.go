@@ -0,0 +1,76 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// The kinds of the records which can be exported
+const (
+	ExportDeals        = "deals"
+	ExportOrders       = "orders"
+	ExportTxs          = "txs"
+	ExportIncomes      = "incomes"
+	ExportCandleSticks = "candle-sticks"
+)
+
+// The records of an export. Key is the market of deals and candle sticks, or the account of the others
+type ExportRequest struct {
+	Kind     string
+	Key      string
+	Token    string // only for orders, txs and incomes
+	Tag      string // only for orders
+	Timespan byte   // only for candle sticks
+	Range    QueryRange
+}
+
+// A page of the exported records. TimeSid contains the time and sid of each record,
+// and Tags contains the tag of each order
+type ExportPage struct {
+	Data    []json.RawMessage
+	Tags    []byte
+	TimeSid []int64
+}
+
+func (hub *Hub) queryExportPage(req *ExportRequest, qr QueryRange) (page ExportPage, err error) {
+	switch req.Kind {
+	case ExportDeals:
+		page.Data, page.TimeSid = hub.QueryDeal(req.Key, qr)
+	case ExportOrders:
+		page.Data, page.Tags, page.TimeSid = hub.QueryOrderAboutToken(req.Tag, req.Token, req.Key, qr)
+	case ExportTxs:
+		page.Data, page.TimeSid = hub.QueryTxAboutToken(req.Token, req.Key, qr)
+	case ExportIncomes:
+		page.Data, page.TimeSid = hub.QueryIncomeAboutToken(req.Token, req.Key, qr)
+	case ExportCandleSticks:
+		page.Data, _, page.TimeSid = hub.query(false, CandleStickByte, getCandleStickBytes(req.Key, req.Timespan), qr, nil)
+	default:
+		err = fmt.Errorf("unknown export %s", req.Kind)
+	}
+	return
+}
+
+// ExportRecords reads the records in the range page by page, and calls fn with every page until all the records
+// are read or fn returns an error. DB is only locked while a page is read, so fn can write the page to a slow client.
+// The next page starts from the last record of the previous one, so the records saved or pruned during the export
+// do not shift the pages
+func (hub *Hub) ExportRecords(req ExportRequest, fn func(page ExportPage) error) error {
+	qr := req.Range
+	qr.Count = MaxCount
+	for {
+		page, err := hub.queryExportPage(&req, qr)
+		if err != nil {
+			return err
+		}
+		if len(page.TimeSid) == 0 {
+			return nil
+		}
+		if err := fn(page); err != nil {
+			return err
+		}
+		if len(page.TimeSid)/2 < MaxCount {
+			return nil
+		}
+		qr.Time, qr.Sid = page.TimeSid[len(page.TimeSid)-2], page.TimeSid[len(page.TimeSid)-1]
+	}
+}
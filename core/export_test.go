@@ -0,0 +1,59 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	dbm "github.com/tendermint/tm-db"
+)
+
+func TestExportRecords(t *testing.T) {
+	db := dbm.NewMemDB()
+	hub := NewHub(db, &MocSubscribeManager{}, 99999, 0, 0, 0, "", 0)
+	// more deals than a page, one deal every second
+	total := MaxCount + 2
+	for sid := int64(1); sid <= int64(total); sid++ {
+		key := append(getEndKeyFromBytes(DealByte, []byte("abc/cet"), 1000+sid, sid), 0)
+		db.Set(key, []byte(fmt.Sprintf("%d", sid)))
+	}
+
+	export := func(qr QueryRange) (sizes []int, first, last string) {
+		err := hub.ExportRecords(ExportRequest{Kind: ExportDeals, Key: "abc/cet", Range: qr}, func(page ExportPage) error {
+			if len(sizes) == 0 {
+				first = string(page.Data[0])
+			}
+			sizes = append(sizes, len(page.Data))
+			last = string(page.Data[len(page.Data)-1])
+			return nil
+		})
+		require.Nil(t, err)
+		return
+	}
+	sizes, first, last := export(QueryRange{Asc: true})
+	require.EqualValues(t, []int{MaxCount, 2}, sizes)
+	require.Equal(t, "1", first)
+	require.Equal(t, fmt.Sprintf("%d", total), last)
+	sizes, first, last = export(QueryRange{})
+	require.EqualValues(t, []int{MaxCount, 2}, sizes)
+	require.Equal(t, fmt.Sprintf("%d", total), first)
+	require.Equal(t, "1", last)
+	sizes, first, last = export(QueryRange{From: 1011, To: 1020, Asc: true})
+	require.EqualValues(t, []int{10}, sizes)
+	require.Equal(t, "11", first)
+	require.Equal(t, "20", last)
+	sizes, _, _ = export(QueryRange{From: 5000})
+	require.EqualValues(t, 0, len(sizes))
+
+	// the export stops at the first error
+	errStop := errors.New("stop")
+	pages := 0
+	err := hub.ExportRecords(ExportRequest{Kind: ExportDeals, Key: "abc/cet"}, func(page ExportPage) error {
+		pages++
+		return errStop
+	})
+	require.Equal(t, errStop, err)
+	require.Equal(t, 1, pages)
+	require.NotNil(t, hub.ExportRecords(ExportRequest{Kind: "blocks"}, func(page ExportPage) error { return nil }))
+}
@@ -35,6 +35,12 @@ func pushFullInformation(topic string, params []string, count int, c Subscriber,
 			market = strings.Join(params, SeparateArgu)
 		}
 		err = queryTickerAndPush(hub, c, market)
+	case XTickerKey:
+		market := params[0]
+		if len(params) == 2 {
+			market = strings.Join(params, SeparateArgu)
+		}
+		err = queryXTickerAndPush(hub, c, market)
 	case TxKey:
 		err = queryAndPushFunc(hub, c, TxKey, params[0], count, hub.QueryTx)
 	case LockedKey:
@@ -57,6 +63,26 @@ func pushFullInformation(topic string, params []string, count int, c Subscriber,
 		err = queryAndPushFunc(hub, c, UnbondingKey, params[0], count, hub.QueryUnbonding)
 	case CommentKey:
 		err = queryAndPushFunc(hub, c, CommentKey, params[0], count, hub.QueryComment)
+	case DepthL3Key:
+		err = queryDepthL3AndPush(hub, c, params[0], count)
+	case OpenOrdersKey:
+		err = queryOpenOrdersAndPush(hub, c, params[0])
+	case AlertKey:
+		err = queryAlertsAndPush(hub, c, params[0])
+	case RebateKey:
+		err = queryRebatesAndPush(hub, c, params[0], count)
+	case CreateMarketInfoKey:
+		token := ""
+		if len(params) == 1 {
+			token = params[0]
+		}
+		err = queryAndPushFunc(hub, c, CreateMarketInfoKey, token, count, hub.QueryMarkets)
+	case DelegationRewardsKey:
+		err = queryAndPushFunc(hub, c, DelegationRewardsKey, params[0], count, hub.QueryDelegatorRewards)
+	case ValidatorCommissionKey:
+		err = queryAndPushFunc(hub, c, ValidatorCommissionKey, params[0], count, hub.QueryValidatorCommission)
+	case AccountKey:
+		err = queryAccountAndPush(hub, c, params[0], count)
 	}
 	return err
 }
@@ -70,7 +96,7 @@ func getCount(count int) int {
 }
 
 func querySlashAndPush(hub *Hub, c Subscriber, count int) error {
-	data, _ := hub.QuerySlash(hub.currBlockTime.Unix(), hub.sid, count)
+	data, _ := hub.QuerySlash(hub.latestRange(count))
 	bz := groupOfDataPacket(SlashKey, data)
 	err := c.WriteMsg(bz)
 	if err != nil {
@@ -84,7 +110,7 @@ func queryKlineAndpush(hub *Hub, c Subscriber, params []string, count int) error
 	if len(params) == 3 {
 		tradingPair = strings.Join(params[:2], SeparateArgu)
 	}
-	candleBz := hub.QueryCandleStick(tradingPair, GetSpanFromSpanStr(params[1]), hub.currBlockTime.Unix(), hub.sid, count)
+	candleBz := hub.QueryCandleStick(tradingPair, GetSpanFromSpanStr(params[1]), hub.latestRange(count))
 	bz := groupOfDataPacket(KlineKey, candleBz)
 	err := c.WriteMsg(bz)
 	if err != nil {
@@ -105,14 +131,60 @@ func queryDepthAndPush(hub *Hub, c Subscriber, market string, level string, coun
 	return hub.AddLevel(market, level)
 }
 
-func queryOrderAndPush(hub *Hub, c Subscriber, account string, count int) error {
-	data, tags, _ := hub.QueryOrder(account, hub.currBlockTime.Unix(), hub.sid, count)
+func queryDepthL3AndPush(hub *Hub, c Subscriber, market string, count int) error {
+	snapshot := hub.QueryOrderBookL3(market, count)
+	if snapshot == nil {
+		snapshot = &L3Snapshot{TradingPair: market, Bids: []*L3Order{}, Asks: []*L3Order{}}
+	}
+	bz, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	msg := []byte(fmt.Sprintf("{\"type\":\"%s\", \"payload\":%s}", DepthL3Full, string(bz)))
+	return c.WriteMsg(msg)
+}
+
+func queryOpenOrdersAndPush(hub *Hub, c Subscriber, account string) error {
+	bz, err := json.Marshal(hub.QueryOpenOrders(account, ""))
+	if err != nil {
+		return err
+	}
+	msg := []byte(fmt.Sprintf("{\"type\":\"%s\", \"payload\":%s}", OpenOrdersFull, string(bz)))
+	return c.WriteMsg(msg)
+}
+
+func queryAlertsAndPush(hub *Hub, c Subscriber, account string) error {
+	bz, err := json.Marshal(hub.QueryPriceAlerts(account))
+	if err != nil {
+		return err
+	}
+	msg := []byte(fmt.Sprintf("{\"type\":\"%s\", \"payload\":%s}", AlertFull, string(bz)))
+	return c.WriteMsg(msg)
+}
+
+// The rebates of the latest 'count' records are pushed when 'rebate:<referee>' is subscribed
+func queryRebatesAndPush(hub *Hub, c Subscriber, referee string, count int) error {
+	data := hub.QueryRebates(referee, 0, 0)
+	if len(data) > count {
+		data = data[len(data)-count:]
+	}
+	bz, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	msg := []byte(fmt.Sprintf("{\"type\":\"%s\", \"payload\":%s}", RebateFull, string(bz)))
+	return c.WriteMsg(msg)
+}
+
+// Returns the created, filled and cancelled orders of an account
+func queryOrdersByTag(hub *Hub, account string, count int) (createData, fillData, cancelData []json.RawMessage, err error) {
+	data, tags, _ := hub.QueryOrder(account, hub.latestRange(count))
 	if len(data) != len(tags) {
-		return errors.Errorf("The number of orders and tags is not equal")
+		return nil, nil, nil, errors.Errorf("The number of orders and tags is not equal")
 	}
-	createData := make([]json.RawMessage, 0, len(data)/2)
-	fillData := make([]json.RawMessage, 0, len(data)/2)
-	cancelData := make([]json.RawMessage, 0, len(data)/2)
+	createData = make([]json.RawMessage, 0, len(data)/2)
+	fillData = make([]json.RawMessage, 0, len(data)/2)
+	cancelData = make([]json.RawMessage, 0, len(data)/2)
 	for i := len(data) - 1; i >= 0; i-- {
 		if tags[i] == CreateOrderEndByte {
 			createData = append(createData, data[i])
@@ -122,6 +194,14 @@ func queryOrderAndPush(hub *Hub, c Subscriber, account string, count int) error
 			cancelData = append(cancelData, data[i])
 		}
 	}
+	return
+}
+
+func queryOrderAndPush(hub *Hub, c Subscriber, account string, count int) error {
+	createData, fillData, cancelData, err := queryOrdersByTag(hub, account, count)
+	if err != nil {
+		return err
+	}
 	bz := groupOfDataPacket(CreateOrderKey, createData)
 	if err := c.WriteMsg(bz); err != nil {
 		return err
@@ -131,7 +211,7 @@ func queryOrderAndPush(hub *Hub, c Subscriber, account string, count int) error
 		return err
 	}
 	bz = groupOfDataPacket(CancelOrderKey, cancelData)
-	err := c.WriteMsg(bz)
+	err = c.WriteMsg(bz)
 	return err
 }
 
@@ -146,10 +226,26 @@ func queryTickerAndPush(hub *Hub, c Subscriber, market string) error {
 	return err
 }
 
-type queryFunc func(string, int64, int64, int) ([]json.RawMessage, []int64)
+func queryXTickerAndPush(hub *Hub, c Subscriber, market string) error {
+	xtickers := hub.QueryXTickers([]string{market})
+	baseData, err := json.Marshal(xtickers)
+	if err != nil {
+		return err
+	}
+	err = c.WriteMsg([]byte(fmt.Sprintf("{\"type\":\"%s\","+
+		" \"payload\":%s}", XTickerKey, string(baseData))))
+	return err
+}
+
+type queryFunc func(string, QueryRange) ([]json.RawMessage, []int64)
+
+// The range of the latest records, which are pushed as the full information of a topic
+func (hub *Hub) latestRange(count int) QueryRange {
+	return QueryRange{Time: hub.currBlockTime.Unix(), Sid: hub.sid, Count: count}
+}
 
 func queryAndPushFunc(hub *Hub, c Subscriber, typeKey string, param string, count int, qf queryFunc) error {
-	data, _ := qf(param, hub.currBlockTime.Unix(), hub.sid, count)
+	data, _ := qf(param, hub.latestRange(count))
 	bz := groupOfDataPacket(typeKey, data)
 	err := c.WriteMsg(bz)
 	if err != nil {
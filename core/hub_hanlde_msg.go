@@ -24,20 +24,14 @@ type msgEntry struct {
 	bz      []byte
 }
 
-// A message to be sent to websocket subscriber through hub.msgsChannel
+// A message to be sent to websocket subscriber through hub.msgsChannel.
+// Every message except OptionKey takes one sequence number when it is pushed
 type MsgToPush struct {
 	topic string
 	extra interface{}
 	bz    []byte
 }
 
-type TimeAndSidWithAddr struct {
-	currTime int64
-	lastTime int64
-	sid      int64
-	addr     string
-}
-
 type Hub struct {
 	// the serial ID for a KV pair in KVStore
 	sid int64
@@ -52,11 +46,26 @@ type Hub struct {
 
 	// Updating logic and query logic share these variables
 	managersMap map[string]*TripleManager
-	tickerMap   map[string]*Ticker // it caches the tickers from managersMap[*].tkm
+	tickerMap   map[string]*Ticker  // it caches the tickers from managersMap[*].tkm
+	xtickerMap  map[string]*XTicker // it caches the xtickers from managersMap[*].xtkm, protected by tickerMapMutex
+	// the open orders of every address
+	openOrderMan *OpenOrderManager
+	// the price alerts of every address
+	alertMan *AlertManager
+	// the stats of the accounts updated in the current block, nil for the ones counted before restart
+	accountStats map[string]*accountStatsRecord
+	// the rebates of the referees updated in the current block, nil for the ones counted before restart
+	rebates map[string]*rebateRecord
 
 	// interface to the subscribe functions
 	subMan      SubscribeManager
 	msgsChannel chan MsgToPush
+	// the pushed messages kept for resuming subscriptions, and the mutex held while pushing one entry
+	pushStreams *PushStreams
+	pushMutex   sync.Mutex
+	// the sequence number of the last message sent to msgsChannel, which only depends on the consumed messages,
+	// such that the pushed messages get the same sequence numbers when they are consumed again after restart
+	queuedSeq int64
 
 	// buffers the messages from kafka to execute them in batch
 	msgEntryList []msgEntry
@@ -110,6 +119,11 @@ func NewHub(db dbm.DB, subMan SubscribeManager, interval int64, monitorInterval
 		currBlockTime:   time.Unix(0, 0),
 		lastBlockTime:   time.Unix(0, 0),
 		tickerMap:       make(map[string]*Ticker),
+		xtickerMap:      make(map[string]*XTicker),
+		openOrderMan:    NewOpenOrderManager(nil),
+		alertMan:        NewAlertManager(),
+		accountStats:    make(map[string]*accountStatsRecord),
+		rebates:         make(map[string]*rebateRecord),
 		slashSlice:      make([]*NotificationSlash, 0, 10),
 		partition:       0,
 		offset:          0,
@@ -121,10 +135,13 @@ func NewHub(db dbm.DB, subMan SubscribeManager, interval int64, monitorInterval
 		blocksInterval:  interval,
 		keepRecent:      keepRecent,
 		msgsChannel:     make(chan MsgToPush, 10000),
+		pushStreams:     NewPushStreams(MaxPushRecords),
 		currBlockHeight: initChainHeight,
 		oldChainID:      oldChainID,
 		upgradeHeight:   upgradeHeight,
 	}
+	hub.migrateCreateMarketKeys()
+	hub.loadPriceAlerts()
 
 	go hub.pushMsgToWebsocket()
 	if monitorInterval <= 0 {
@@ -163,6 +180,31 @@ func (hub *Hub) StoreLeastHeight() {
 	hub.db.Set([]byte{LatestHeightByte}, heightBytes)
 }
 
+// The create_market keys used to carry the market name, which is removed from them such that
+// all the new markets are queried by one prefix. The time, sid and the last byte are kept
+// Not safe for concurrency; Only be used in init
+func (hub *Hub) migrateCreateMarketKeys() {
+	iter := hub.db.Iterator([]byte{CreateMarketByte, 1}, []byte{CreateMarketByte + 1})
+	batch := hub.db.NewBatch()
+	defer batch.Close()
+	count := 0
+	for ; iter.Valid(); iter.Next() {
+		oldKey := iter.Key()
+		newKey := make([]byte, 0, 1+1+1+16+1)
+		newKey = append(newKey, CreateMarketByte, 0, 0)
+		newKey = append(newKey, oldKey[1+1+int(oldKey[1])+1:]...)
+		batch.Set(newKey, append([]byte{}, iter.Value()...))
+		batch.Delete(append([]byte{}, oldKey...))
+		count++
+	}
+	iter.Close()
+	if count == 0 {
+		return
+	}
+	batch.WriteSync()
+	log.Info(fmt.Sprintf("Migrated %d create_market keys", count))
+}
+
 func (hub *Hub) Log(s string) {
 	log.Error(s)
 }
@@ -280,9 +322,9 @@ func (hub *Hub) handleMsg() {
 		case "send_lock_coins":
 			hub.handleLockedCoinsMsg(entry.bz)
 		case "delegator_rewards":
-			//hub.handleDelegatorRewards(entry.bz)
+			hub.handleDelegatorRewards(entry.bz)
 		case "validator_commission":
-			//hub.handleValidatorCommission(entry.bz)
+			hub.handleValidatorCommission(entry.bz)
 		case "commit":
 			hub.commit()
 		default:
@@ -310,13 +352,13 @@ func (hub *Hub) handleNewHeightInfo(bz []byte) {
 	latestHeight := hub.QueryLatestHeight()
 	// If extra==false, then this is an invalid or out-of-date Height
 	if latestHeight >= v.Height {
-		hub.msgsChannel <- MsgToPush{topic: OptionKey, extra: true}
+		hub.enqueuePush(MsgToPush{topic: OptionKey, extra: true})
 		hub.Log(fmt.Sprintf("Skipping Height websocket %d<%d\n", latestHeight, v.Height))
 	} else if latestHeight+1 == v.Height || latestHeight < 0 {
-		hub.msgsChannel <- MsgToPush{topic: OptionKey, extra: false}
+		hub.enqueuePush(MsgToPush{topic: OptionKey, extra: false})
 		log.Info("push height info, height : ", v.Height)
 	} else {
-		hub.msgsChannel <- MsgToPush{topic: OptionKey, extra: true}
+		hub.enqueuePush(MsgToPush{topic: OptionKey, extra: true})
 		hub.Log(fmt.Sprintf("Invalid Height! websocket %d+1!=%d\n", latestHeight, v.Height))
 	}
 
@@ -326,7 +368,7 @@ func (hub *Hub) handleNewHeightInfo(bz []byte) {
 	key := append([]byte{BlockHeightByte}, heightBytes...)
 	hub.batch.Set(key, b)
 	hub.batch.Set([]byte{LatestHeightByte}, heightBytes)
-	hub.msgsChannel <- MsgToPush{topic: BlockInfoKey, bz: bz}
+	hub.enqueuePush(MsgToPush{topic: BlockInfoKey, bz: bz, extra: v.Height})
 	hub.lastBlockTime = hub.currBlockTime
 	hub.currBlockTime = time.Unix(v.TimeStamp, 0)
 	hub.beginForCandleSticks()
@@ -355,7 +397,7 @@ func (hub *Hub) beginForCandleSticks() {
 			continue
 		}
 		extra := []string{cs.Market, cs.TimeSpan}
-		hub.msgsChannel <- MsgToPush{topic: KlineKey, bz: bz, extra: extra}
+		hub.enqueuePush(MsgToPush{topic: KlineKey, bz: bz, extra: extra})
 		// Save candle sticks to KVStore
 		key := hub.getCandleStickKey(cs.Market, GetSpanFromSpanStr(cs.TimeSpan))
 		if len(bz) == 0 {
@@ -376,6 +418,8 @@ func (hub *Hub) updateTicker(candleStick *CandleStick) bool {
 	csMinute := t.UTC().Hour()*60 + t.UTC().Minute() // minute of the data
 	if candleStick.TimeSpan == MinuteStr {
 		tripleManager.tkm.UpdateNewestPrice(candleStick.ClosePrice, csMinute)
+		// the minute-candle-stick carries all the deals of this minute from fill orders and bancor trades
+		tripleManager.xtkm.UpdateNewestPrice(candleStick.ClosePrice, csMinute, candleStick.TotalDeal)
 	}
 	return true
 }
@@ -401,7 +445,7 @@ func (hub *Hub) handleLockedCoinsMsg(bz []byte) {
 	key := hub.getLockedKey(v.ToAddress)
 	hub.batch.Set(key, bz)
 	hub.sid++
-	hub.msgsChannel <- MsgToPush{topic: LockedKey, bz: bz, extra: v.ToAddress}
+	hub.enqueuePush(MsgToPush{topic: LockedKey, bz: bz, extra: v.ToAddress})
 }
 
 func (hub *Hub) handleDelegatorRewards(bz []byte) {
@@ -426,7 +470,7 @@ func (hub *Hub) commonAction(pushKey string, storeKey []byte, bz []byte, extra i
 	bz = appendHashID(bz, hub.currTxHashID)
 	hub.batch.Set(storeKey, bz)
 	hub.sid++
-	hub.msgsChannel <- MsgToPush{topic: pushKey, bz: bz, extra: extra}
+	hub.enqueuePush(MsgToPush{topic: pushKey, bz: bz, extra: extra})
 }
 
 func (hub *Hub) handleNotificationTx(bz []byte) {
@@ -468,7 +512,7 @@ func (hub *Hub) handleNotificationTx(bz []byte) {
 		k := hub.getIncomeKey(recipient)
 		hub.batch.Set(k, []byte("|"+tokenName+"|"+v.Hash))
 		hub.sid++
-		hub.msgsChannel <- MsgToPush{topic: IncomeKey, bz: bz, extra: recipient}
+		hub.enqueuePush(MsgToPush{topic: IncomeKey, bz: bz, extra: recipient})
 	}
 
 	tokensAndHash := make([]byte, 1, 100)
@@ -485,7 +529,7 @@ func (hub *Hub) handleNotificationTx(bz []byte) {
 		k := hub.getTxKey(signer)
 		hub.batch.Set(k, tokensAndHash)
 		hub.sid++
-		hub.msgsChannel <- MsgToPush{topic: TxKey, bz: bz, extra: signer}
+		hub.enqueuePush(MsgToPush{topic: TxKey, bz: bz, extra: signer})
 	}
 	if len(v.ExtraInfo) == 0 {
 		hub.analyzeMessages(v.MsgTypes, v.TxJSON)
@@ -607,15 +651,7 @@ func (hub *Hub) handleNotificationCompleteRedelegation(bz []byte) {
 		hub.Log("Error in Unmarshal NotificationCompleteRedelegation")
 		return
 	}
-	hub.msgsChannel <- MsgToPush{
-		topic: RedelegationKey,
-		extra: TimeAndSidWithAddr{
-			addr:     v.Delegator,
-			sid:      hub.sid,
-			currTime: hub.currBlockTime.Unix(),
-			lastTime: hub.lastBlockTime.Unix(),
-		},
-	}
+	hub.enqueueCompletedEvents(RedelegationKey, RedelegationByte, v.Delegator)
 }
 
 func (hub *Hub) handleNotificationCompleteUnbonding(bz []byte) {
@@ -625,8 +661,27 @@ func (hub *Hub) handleNotificationCompleteUnbonding(bz []byte) {
 		hub.Log("Error in Unmarshal NotificationCompleteUnbonding")
 		return
 	}
-	hub.msgsChannel <- MsgToPush{topic: UnbondingKey, bz: bz, extra: TimeAndSidWithAddr{addr: v.Delegator, sid: hub.sid,
-		currTime: hub.currBlockTime.Unix(), lastTime: hub.lastBlockTime.Unix()}}
+	hub.enqueueCompletedEvents(UnbondingKey, UnbondingByte, v.Delegator)
+}
+
+// Queries the redelegations or the unbondings whose completion time is between the last block and the current
+// block, and pushes them one by one. They are queried here instead of the push goroutine, such that their
+// sequence numbers are assigned when they are sent to msgsChannel
+func (hub *Hub) enqueueCompletedEvents(topic string, firstByte byte, addr string) {
+	end := hub.getEventKeyWithSidAndTime(firstByte, addr, hub.currBlockTime.Unix(), hub.sid)
+	start := hub.getEventKeyWithSidAndTime(firstByte, addr, hub.lastBlockTime.Unix()-1, hub.sid)
+	events := make([][]byte, 0, 4)
+	hub.dbMutex.RLock()
+	iter := hub.db.ReverseIterator(start, end)
+	for ; iter.Valid(); iter.Next() {
+		events = append(events, append([]byte{}, iter.Value()...))
+	}
+	iter.Close()
+	hub.dbMutex.RUnlock()
+	// the push goroutine may wait for dbMutex, so the events are sent after it is released
+	for _, bz := range events {
+		hub.enqueuePush(MsgToPush{topic: topic, bz: bz, extra: addr})
+	}
 }
 
 func (hub *Hub) handleNotificationUnlock(bz []byte) {
@@ -640,7 +695,7 @@ func (hub *Hub) handleNotificationUnlock(bz []byte) {
 	key := hub.getUnlockEventKey(addr)
 	hub.batch.Set(key, bz)
 	hub.sid++
-	hub.msgsChannel <- MsgToPush{topic: UnlockKey, bz: bz, extra: addr}
+	hub.enqueuePush(MsgToPush{topic: UnlockKey, bz: bz, extra: addr})
 }
 
 func (hub *Hub) handleTokenComment(bz []byte) {
@@ -654,7 +709,7 @@ func (hub *Hub) handleTokenComment(bz []byte) {
 	key := hub.getCommentKey(v.Token)
 	hub.batch.Set(key, bz)
 	hub.sid++
-	hub.msgsChannel <- MsgToPush{topic: CommentKey, bz: bz, extra: v.Token}
+	hub.enqueuePush(MsgToPush{topic: CommentKey, bz: bz, extra: v.Token})
 }
 
 func (hub *Hub) handleCreatMarketInfo(bz []byte) {
@@ -665,10 +720,10 @@ func (hub *Hub) handleCreatMarketInfo(bz []byte) {
 		return
 	}
 	bz = appendHashID(bz, hub.currTxHashID)
-	key := hub.getCreateMarketKey(getMarketName(v))
+	key := hub.getCreateMarketKey()
 	hub.batch.Set(key, bz)
 	hub.sid++
-	hub.msgsChannel <- MsgToPush{topic: CreateMarketInfoKey, bz: bz, extra: getMarketName(v)}
+	hub.enqueuePush(MsgToPush{topic: CreateMarketInfoKey, bz: bz, extra: []string{v.Stock, v.Money}})
 }
 
 func (hub *Hub) handleCreateOrderInfo(bz []byte) {
@@ -686,9 +741,13 @@ func (hub *Hub) handleCreateOrderInfo(bz []byte) {
 	//Save to KVStore
 	key := hub.getCreateOrderKey(v.Sender)
 	hub.batch.Set(key, bz)
+	hub.setOrderIDIndex(v.OrderID, key)
 	hub.sid++
 	//Push to subscribers
-	hub.msgsChannel <- MsgToPush{topic: CreateOrderKey, bz: bz, extra: v.Sender}
+	hub.enqueuePush(MsgToPush{topic: CreateOrderKey, bz: bz, extra: v.Sender})
+	//Update open orders
+	v.TxHash = hub.currTxHashID
+	hub.pushOpenOrder(hub.openOrderMan.Add(&v))
 	//Update depth info
 	triman, ok := hub.managersMap[v.TradingPair]
 	if !ok {
@@ -697,6 +756,7 @@ func (hub *Hub) handleCreateOrderInfo(bz []byte) {
 	amount := sdk.NewInt(v.Quantity)
 
 	triman.AddDeltaChange(v.Side == SELL, v.Price, amount)
+	triman.l3.Add(&v, hub.sid)
 }
 
 func (hub *Hub) handleFillOrderInfo(bz []byte) {
@@ -720,6 +780,7 @@ func (hub *Hub) handleFillOrderInfo(bz []byte) {
 	}
 	key := hub.getFillOrderKey(accAndSeq[0])
 	hub.batch.Set(key, bz)
+	hub.setOrderIDIndex(v.OrderID, key)
 	hub.sid++
 	if v.Side == SELL {
 		key = hub.getDealKey(v.TradingPair)
@@ -727,15 +788,21 @@ func (hub *Hub) handleFillOrderInfo(bz []byte) {
 		hub.sid++
 	}
 	//Push to subscribers
-	hub.msgsChannel <- MsgToPush{topic: FillOrderKey, bz: bz, extra: accAndSeq[0]}
+	hub.enqueuePush(MsgToPush{topic: FillOrderKey, bz: bz, extra: accAndSeq[0]})
 	if v.Side == SELL {
-		hub.msgsChannel <- MsgToPush{topic: DealKey, bz: bz, extra: v.TradingPair}
+		hub.enqueuePush(MsgToPush{topic: DealKey, bz: bz, extra: v.TradingPair})
+	}
+	hub.updateAccountStatsForTrade(accAndSeq[0], v.TradingPair, v.Side, v.CurrStock, v.CurrMoney)
+	//Update open orders
+	if order, ok := hub.openOrderMan.Fill(&v); ok {
+		hub.pushOpenOrder(order)
 	}
 	//Update candle sticks
 	if v.Side == SELL {
+		hub.setAlertPrice(v.TradingPair, v.FillPrice)
 		csRec := hub.csMan.GetRecord(v.TradingPair)
 		if csRec != nil {
-			csRec.Update(hub.currBlockTime, v.FillPrice, v.CurrStock)
+			csRec.Update(hub.currBlockTime, v.FillPrice, v.CurrStock, v.CurrMoney)
 		}
 	}
 	//Update depth info
@@ -745,6 +812,7 @@ func (hub *Hub) handleFillOrderInfo(bz []byte) {
 	}
 	negStock := sdk.NewInt(-v.CurrStock)
 	triman.AddDeltaChange(v.Side == SELL, v.Price, negStock)
+	triman.l3.Fill(&v)
 }
 
 func (hub *Hub) handleCancelOrderInfo(bz []byte) {
@@ -768,7 +836,15 @@ func (hub *Hub) handleCancelOrderInfo(bz []byte) {
 	}
 	key := hub.getCancelOrderKey(accAndSeq[0])
 	hub.batch.Set(key, bz)
+	hub.setOrderIDIndex(v.OrderID, key)
 	hub.sid++
+	hub.updateAccountStatsForFee(accAndSeq[0], v.TradingPair, v.UsedCommission, v.UsedFeatureFee)
+	hub.handleRebate(NotificationRebate{Referee: v.RebateRefereeAddr, Trader: accAndSeq[0], Market: v.TradingPair,
+		Amount: v.RebateAmount, OrderID: v.OrderID})
+	//Update open orders
+	if order, ok := hub.openOrderMan.Cancel(&v); ok {
+		hub.pushOpenOrder(order)
+	}
 	//Update depth info
 	triman, ok := hub.managersMap[v.TradingPair]
 	if !ok {
@@ -776,8 +852,25 @@ func (hub *Hub) handleCancelOrderInfo(bz []byte) {
 	}
 	negStock := sdk.NewInt(-v.LeftStock)
 	triman.AddDeltaChange(v.Side == SELL, v.Price, negStock)
+	triman.l3.Cancel(&v)
 	//Push to subscribers
-	hub.msgsChannel <- MsgToPush{topic: CancelOrderKey, bz: bz, extra: accAndSeq[0]}
+	hub.enqueuePush(MsgToPush{topic: CancelOrderKey, bz: bz, extra: accAndSeq[0]})
+}
+
+func (hub *Hub) pushOpenOrder(order OpenOrder) {
+	bz, err := json.Marshal(order)
+	if err != nil {
+		hub.Log(fmt.Sprintf("Error in Marshal OpenOrder: %v", err))
+		return
+	}
+	hub.enqueuePush(MsgToPush{topic: OpenOrdersKey, bz: bz, extra: order.Sender})
+}
+
+// The index's value is the key of the order's create/fill/cancel record,
+// and the index shares the same sid and last byte with this record
+func (hub *Hub) setOrderIDIndex(orderID string, orderKey []byte) {
+	key := hub.getOrderIDKey(orderID, orderKey[len(orderKey)-1])
+	hub.batch.Set(key, orderKey)
 }
 
 func (hub *Hub) handleMsgBancorTradeInfoForKafka(bz []byte) {
@@ -801,14 +894,21 @@ func (hub *Hub) handleMsgBancorTradeInfoForKafka(bz []byte) {
 	key = hub.getBancorDealKey(marketName)
 	hub.batch.Set(key, bz)
 	hub.sid++
+	// the bancor trades are counted in the stats of the same market as the orders
+	money := v.TxPrice.MulInt64(v.Amount).RoundInt64()
+	hub.updateAccountStatsForTrade(addr, v.Stock+"/"+v.Money, v.Side, v.Amount, money)
+	hub.updateAccountStatsForFee(addr, v.Stock+"/"+v.Money, v.UsedCommission, 0)
+	hub.handleRebate(NotificationRebate{Referee: v.RebateRefereeAddr, Trader: addr, Market: v.Stock + "/" + v.Money,
+		Amount: v.RebateAmount, TxHash: hub.currTxHashID})
 	//Update candle sticks
+	hub.setAlertPrice(marketName, v.TxPrice)
 	csRec := hub.csMan.GetRecord(marketName)
 	if csRec != nil {
-		csRec.Update(hub.currBlockTime, v.TxPrice, v.Amount)
+		csRec.Update(hub.currBlockTime, v.TxPrice, v.Amount, money)
 	}
 	//Push to subscribers
-	hub.msgsChannel <- MsgToPush{topic: BancorTradeKey, bz: bz, extra: addr}
-	hub.msgsChannel <- MsgToPush{topic: BancorDealKey, bz: bz, extra: v.Stock + "/" + v.Money}
+	hub.enqueuePush(MsgToPush{topic: BancorTradeKey, bz: bz, extra: addr})
+	hub.enqueuePush(MsgToPush{topic: BancorDealKey, bz: bz, extra: v.Stock + "/" + v.Money})
 }
 
 func (hub *Hub) handleMsgBancorInfoForKafka(bz []byte) {
@@ -831,7 +931,7 @@ func (hub *Hub) handleMsgBancorInfoForKafka(bz []byte) {
 	hub.batch.Set(key, bz)
 	hub.sid++
 	//Push to subscribers
-	hub.msgsChannel <- MsgToPush{topic: BancorKey, bz: bz, extra: v.Stock + "/" + v.Money}
+	hub.enqueuePush(MsgToPush{topic: BancorKey, bz: bz, extra: v.Stock + "/" + v.Money})
 }
 
 func (hub *Hub) commit() {
@@ -840,8 +940,13 @@ func (hub *Hub) commit() {
 	}
 	hub.commitForSlash()
 	hub.commitForTicker()
+	hub.commitForAlerts()
+	hub.commitForAccountStats()
+	hub.commitForRebates()
 	hub.commitForDepth()
+	hub.commitForL3()
 	hub.pushDepthFull()
+	hub.saveDepthSnapshots()
 	hub.dumpHubState()
 	hub.refreshDB()
 }
@@ -865,7 +970,7 @@ func (hub *Hub) commitForSlash() {
 	}
 	for _, slash := range newSlice {
 		bz, _ := json.Marshal(slash)
-		hub.msgsChannel <- MsgToPush{topic: SlashKey, bz: bz}
+		hub.enqueuePush(MsgToPush{topic: SlashKey, bz: bz})
 		key := hub.getKeyFromBytes(SlashByte, []byte{}, 0)
 		hub.batch.Set(key, bz)
 		hub.sid++
@@ -884,18 +989,26 @@ func (hub *Hub) commitForTicker() {
 	if currMinute < 0 {
 		currMinute = MinuteNumInDay - 1
 	}
+	xtkMap := make(map[string]*XTicker)
 	for _, triman := range hub.managersMap {
 		if ticker := triman.tkm.GetTicker(currMinute); ticker != nil {
 			tkMap[ticker.Market] = ticker
 		}
+		if xticker := triman.xtkm.GetXTicker(currMinute); xticker != nil {
+			xtkMap[xticker.Market] = xticker
+		}
 	}
-	hub.msgsChannel <- MsgToPush{topic: TickerKey, extra: tkMap}
+	hub.enqueuePush(MsgToPush{topic: TickerKey, extra: tkMap})
+	hub.enqueuePush(MsgToPush{topic: XTickerKey, extra: xtkMap})
 	hub.tickerMapMutex.Lock()
 	defer hub.tickerMapMutex.Unlock()
 	atomic.AddInt64(&hub.tickerMapLockCount, 1)
 	for market, ticker := range tkMap {
 		hub.tickerMap[market] = ticker
 	}
+	for market, xticker := range xtkMap {
+		hub.xtickerMap[market] = xticker
+	}
 }
 
 func (hub *Hub) commitForDepth() {
@@ -904,7 +1017,7 @@ func (hub *Hub) commitForDepth() {
 			continue
 		}
 
-		triman.Update(&hub.trimanLockCount) // consumes the recorded delta changes
+		triman.Update(&hub.trimanLockCount, hub.currBlockHeight) // consumes the recorded delta changes
 
 		depthDeltaSell, mergeDeltaSell := triman.sell.EndBlock()
 		depthDeltaBuy, mergeDeltaBuy := triman.buy.EndBlock()
@@ -922,11 +1035,36 @@ func (hub *Hub) commitForDepth() {
 			}
 		}
 
-		levelsData := encodeDepthLevels(market, mergeDeltaBuy, mergeDeltaSell)
-		if bz, err := encodeDepthLevel(market, depthDeltaBuy, depthDeltaSell); err == nil {
+		// the checksums let the subscribers verify their local order books after applying these changes
+		checksums := make(map[string]uint32, len(mergeDeltaBuy))
+		for level := range mergeDeltaBuy {
+			checksums[level] = triman.getChecksum(level)
+		}
+		hub.saveDepthDelta(market, depthDeltaBuy, depthDeltaSell)
+		levelsData := encodeDepthLevels(market, hub.currBlockHeight, checksums, mergeDeltaBuy, mergeDeltaSell)
+		if bz, err := encodeDepthLevel(market, hub.currBlockHeight, triman.getChecksum("all"),
+			depthDeltaBuy, depthDeltaSell); err == nil {
 			levelsData["all"] = bz
 		}
-		hub.msgsChannel <- MsgToPush{topic: DepthKey, bz: []byte(market), extra: levelsData}
+		hub.enqueuePush(MsgToPush{topic: DepthKey, bz: []byte(market), extra: levelsData})
+	}
+}
+
+func (hub *Hub) commitForL3() {
+	for market, triman := range hub.managersMap {
+		if strings.HasPrefix(market, "B:") {
+			continue
+		}
+		events := triman.l3.EndBlock(hub.currBlockHeight)
+		if len(events) == 0 {
+			continue
+		}
+		bz, err := json.Marshal(&L3Events{TradingPair: market, Height: hub.currBlockHeight, Events: events})
+		if err != nil {
+			hub.Log(fmt.Sprintf("Error in Marshal L3Events: %v", err))
+			continue
+		}
+		hub.enqueuePush(MsgToPush{topic: DepthL3Key, bz: bz, extra: market})
 	}
 }
 
@@ -939,8 +1077,15 @@ func (hub *Hub) pushDepthFull() {
 		if strings.HasPrefix(market, "B:") {
 			continue
 		}
-		hub.msgsChannel <- MsgToPush{topic: DepthFull, extra: market}
+		hub.enqueuePush(MsgToPush{topic: DepthFull, extra: market})
+	}
+}
+
+func (hub *Hub) enqueuePush(entry MsgToPush) {
+	if entry.topic != OptionKey {
+		hub.queuedSeq++
 	}
+	hub.msgsChannel <- entry
 }
 
 func (hub *Hub) dumpHubState() {
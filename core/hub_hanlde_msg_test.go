@@ -48,6 +48,26 @@ func TestUnmarshalHub(t *testing.T) {
 	require.Equal(t, *hub4j, *tmpHub4j)
 }
 
+func TestLoadHubWithoutXTicker(t *testing.T) {
+	db := dbm.NewMemDB()
+	subMan := GetDepthSubscribeManeger()
+	hub := NewHub(db, subMan, 99999, 0, 0, 0, "", 0)
+	hub.AddMarket("abc/cet")
+	hub4j := &HubForJSON{}
+	hub.Dump(hub4j)
+	// the dumps of older versions have no xtickers
+	hub4j.XTickerMap = nil
+	for _, info := range hub4j.Markets {
+		info.XTkMan = nil
+	}
+
+	newHub := NewHub(dbm.NewMemDB(), subMan, 99999, 0, 0, 0, "", 0)
+	require.Nil(t, newHub.Load(hub4j))
+	require.NotNil(t, newHub.xtickerMap)
+	require.NotNil(t, newHub.managersMap["abc/cet"].xtkm)
+	require.Equal(t, 0, len(newHub.QueryXTickers([]string{"abc/cet"})))
+}
+
 func TestDepthLevel(t *testing.T) {
 	acc1, _ := simpleAddr("00001")
 	addr1 := acc1.String()
@@ -136,8 +156,8 @@ func TestDepthLevel(t *testing.T) {
 	hub.ConsumeMessage("fill_order_info", bytes)
 
 	correct := `
-8: {"trading_pair":"abc/cet","bids":[{"p":"15.000000000000000000","a":"300"},{"p":"3.000000000000000000","a":"300"}],"asks":[{"p":"12.000000000000000000","a":"300"}]}
-9: {"trading_pair":"abc/cet","bids":[{"p":"15.000000000000000000","a":"300"},{"p":"3.000000000000000000","a":"300"}],"asks":[{"p":"12.000000000000000000","a":"300"}]}
+8: {"trading_pair":"abc/cet","bids":[{"p":"15.000000000000000000","a":"300"},{"p":"3.000000000000000000","a":"300"}],"asks":[{"p":"12.000000000000000000","a":"300"}],"height":1000,"checksum":892870789}
+9: {"trading_pair":"abc/cet","bids":[{"p":"15.000000000000000000","a":"300"},{"p":"3.000000000000000000","a":"300"}],"asks":[{"p":"12.000000000000000000","a":"300"}],"height":1000,"checksum":892870789}
 `
 	hub.ConsumeMessage("commit", nil)
 	time.Sleep(time.Millisecond)
@@ -171,8 +191,8 @@ func TestDepthLevel(t *testing.T) {
 	bytes, _ = json.Marshal(fillOrderInfo)
 	hub.ConsumeMessage("fill_order_info", bytes)
 	correct = `
-8: {"trading_pair":"abc/cet","bids":[],"asks":[{"p":"12.000000000000000000","a":"171"}]}
-9: {"trading_pair":"abc/cet","bids":[],"asks":[{"p":"12.000000000000000000","a":"171"}]}
+8: {"trading_pair":"abc/cet","bids":[],"asks":[{"p":"12.000000000000000000","a":"171"}],"height":1001,"checksum":4082713759}
+9: {"trading_pair":"abc/cet","bids":[],"asks":[{"p":"12.000000000000000000","a":"171"}],"height":1001,"checksum":4082713759}
 `
 	hub.ConsumeMessage("commit", nil)
 	time.Sleep(time.Millisecond)
@@ -195,8 +215,8 @@ func TestDepthLevel(t *testing.T) {
 	bytes, _ = json.Marshal(cancelOrderInfo)
 	hub.ConsumeMessage("del_order_info", bytes)
 	correct = `
-8: {"trading_pair":"abc/cet","bids":[{"p":"3.000000000000000000","a":"0"}],"asks":[]}
-9: {"trading_pair":"abc/cet","bids":[{"p":"3.000000000000000000","a":"0"}],"asks":[]}
+8: {"trading_pair":"abc/cet","bids":[{"p":"3.000000000000000000","a":"0"}],"asks":[],"height":1001,"checksum":2187500641}
+9: {"trading_pair":"abc/cet","bids":[{"p":"3.000000000000000000","a":"0"}],"asks":[],"height":1001,"checksum":2187500641}
 `
 	hub.ConsumeMessage("commit", nil)
 	time.Sleep(time.Millisecond)
@@ -217,8 +237,8 @@ func TestDepthLevel(t *testing.T) {
 	time.Sleep(time.Millisecond)
 
 	correct = `
-8: {"trading_pair":"abc/cet","bids":[{"p":"15.000000000000000000","a":"300"}],"asks":[{"p":"12.000000000000000000","a":"171"}]}
-9: {"trading_pair":"abc/cet","bids":[{"p":"15.000000000000000000","a":"300"}],"asks":[{"p":"12.000000000000000000","a":"171"}]}
+8: {"trading_pair":"abc/cet","bids":[{"p":"15.000000000000000000","a":"300"}],"asks":[{"p":"12.000000000000000000","a":"171"}],"height":1001,"checksum":2187500641}
+9: {"trading_pair":"abc/cet","bids":[{"p":"15.000000000000000000","a":"300"}],"asks":[{"p":"12.000000000000000000","a":"171"}],"height":1001,"checksum":2187500641}
 `
 	//depthSub := subMan.DepthSubscribeInfo["abc/cet"][0].(*DepthSubscriber)
 	//depthSub.CompareRet(t, []string{str})
@@ -376,8 +396,8 @@ func Test1(t *testing.T) {
 16: {"order_id":"cosmos1qy352eufqy352eufqy352eufqy35qqqptw34ca-2","sender":"cosmos1qy352eufqy352eufqy352eufqy35qqqptw34ca","trading_pair":"abc/cet","order_type":2,"price":"100.000000000000000000","quantity":300,"side":1,"time_in_force":3,"feature_fee":1,"height":1001,"frozen_fee":1,"freeze":10}
 0: {"validator":"Val1","power":"30%","reason":"double_sign","jailed":true}
 1: {"validator":"Val1","power":"30%","reason":"double_sign","jailed":true}
-8: {"trading_pair":"abc/cet","bids":[{"p":"100.000000000000000000","a":"300"}],"asks":[{"p":"100.000000000000000000","a":"300"}]}
-9: {"trading_pair":"abc/cet","bids":[{"p":"100.000000000000000000","a":"300"}],"asks":[{"p":"100.000000000000000000","a":"300"}]}
+8: {"trading_pair":"abc/cet","bids":[{"p":"100.000000000000000000","a":"300"}],"asks":[{"p":"100.000000000000000000","a":"300"}],"height":1000,"checksum":3493223406}
+9: {"trading_pair":"abc/cet","bids":[{"p":"100.000000000000000000","a":"300"}],"asks":[{"p":"100.000000000000000000","a":"300"}],"height":1000,"checksum":3493223406}
 `
 	subMan.CompareResult(t, correct)
 	subMan.ClearPushList()
@@ -390,7 +410,7 @@ func Test1(t *testing.T) {
 	hub4jo := &HubForJSON{}
 	err = json.Unmarshal(bz, hub4jo)
 	assert.Equal(t, nil, err)
-	hub.Load(hub4jo)
+	assert.Nil(t, hub.Load(hub4jo))
 	hub.upgradeHeight = 900
 	hub.oldChainID = "coinex-old"
 
@@ -492,8 +512,8 @@ func Test1(t *testing.T) {
 16: {"order_id":"cosmos1qy352eufqy352eufqy352eufqy35qqqptw34ca-1","trading_pair":"abc/cet","height":1001,"side":2,"price":"100.000000000000000000","left_stock":0,"freeze":0,"deal_stock":100,"deal_money":10,"curr_stock":100,"curr_money":10,"fill_price":"0.100000000000000000"}
 15: {"order_id":"cosmos1qy352eufqy352eufqy352eufqy35qqqptw34ca-1","trading_pair":"abc/cet","height":1001,"side":1,"price":"100.000000000000000000","del_reason":"Manually cancel the order","used_commission":0,"left_stock":50,"remain_amount":0,"deal_stock":100,"deal_money":10}
 16: {"order_id":"cosmos1qy352eufqy352eufqy352eufqy35qqqptw34ca-1","trading_pair":"abc/cet","height":1001,"side":1,"price":"100.000000000000000000","del_reason":"Manually cancel the order","used_commission":0,"left_stock":50,"remain_amount":0,"deal_stock":100,"deal_money":10}
-8: {"trading_pair":"abc/cet","bids":[{"p":"100.000000000000000000","a":"250"}],"asks":[{"p":"100.000000000000000000","a":"200"}]}
-9: {"trading_pair":"abc/cet","bids":[{"p":"100.000000000000000000","a":"250"}],"asks":[{"p":"100.000000000000000000","a":"200"}]}
+8: {"trading_pair":"abc/cet","bids":[{"p":"100.000000000000000000","a":"250"}],"asks":[{"p":"100.000000000000000000","a":"200"}],"height":1001,"checksum":4227783220}
+9: {"trading_pair":"abc/cet","bids":[{"p":"100.000000000000000000","a":"250"}],"asks":[{"p":"100.000000000000000000","a":"200"}],"height":1001,"checksum":4227783220}
 `
 	subMan.CompareResult(t, correct)
 	subMan.ClearPushList()
@@ -548,7 +568,7 @@ func Test1(t *testing.T) {
 	correct = `
 3: {"height":1002,"timestamp":1563179350,"last_block_hash":"3233343536373839303132333435363738393031"}
 4: {"height":1002,"timestamp":1563179350,"last_block_hash":"3233343536373839303132333435363738393031"}
-6: {"open":"0.100000000000000000","close":"0.100000000000000000","high":"0.100000000000000000","low":"0.100000000000000000","total":"100","unix_time":1563178750,"time_span":"1min","market":"abc/cet"}
+6: {"open":"0.100000000000000000","close":"0.100000000000000000","high":"0.100000000000000000","low":"0.100000000000000000","total":"100","total_money":"10","trade_count":1,"unix_time":1563178750,"time_span":"1min","market":"abc/cet"}
 12: {"owner":"cosmos1qy352eufqy352eufqy352eufqy35qqqptw34ca","stock":"xyz","money":"cet","init_price":"10","max_supply":"10000","max_price":"100","current_price":"20","stock_in_pool":"50","money_in_pool":"5000","earliest_cancel_time":0}
 17: {"sender":"cosmos1qy352eufqy352eufqy352eufqy35qqqz9ayrkz","stock":"xyz","money":"cet","amount":1,"side":2,"money_limit":10,"transaction_price":"2.000000000000000000","block_height":1001}
 18: {"sender":"cosmos1qy352eufqy352eufqy352eufqy35qqqz9ayrkz","stock":"xyz","money":"cet","amount":1,"side":2,"money_limit":10,"transaction_price":"2.000000000000000000","block_height":1001}
@@ -562,12 +582,12 @@ func Test1(t *testing.T) {
 	assert.Equal(t, correct, string(bytes))
 
 	//subMan.showResult()
-	correct = `{"open":"0.100000000000000000","close":"0.100000000000000000","high":"0.100000000000000000","low":"0.100000000000000000","total":"100","unix_time":1563178750,"time_span":"1min","market":"abc/cet"}`
+	correct = `{"open":"0.100000000000000000","close":"0.100000000000000000","high":"0.100000000000000000","low":"0.100000000000000000","total":"100","total_money":"10","trade_count":1,"unix_time":1563178750,"time_span":"1min","market":"abc/cet"}`
 	unixTime := T("2019-07-15T08:39:10Z").Unix()
-	data := hub.QueryCandleStick("abc/cet", Minute, unixTime, 0, 20)
+	data := hub.QueryCandleStick("abc/cet", Minute, QueryRange{Time: unixTime, Count: 20})
 	assert.Equal(t, correct, toStr(data))
 
-	data, tags, timesid := hub.QueryOrder(addr1, unixTime, 0, 20)
+	data, tags, timesid := hub.QueryOrder(addr1, QueryRange{Time: unixTime, Count: 20})
 	correct = `{"order_id":"cosmos1qy352eufqy352eufqy352eufqy35qqqptw34ca-1","trading_pair":"abc/cet","height":1001,"side":1,"price":"100.000000000000000000","del_reason":"Manually cancel the order","used_commission":0,"left_stock":50,"remain_amount":0,"deal_stock":100,"deal_money":10}
 {"order_id":"cosmos1qy352eufqy352eufqy352eufqy35qqqptw34ca-1","trading_pair":"abc/cet","height":1001,"side":2,"price":"100.000000000000000000","left_stock":0,"freeze":0,"deal_stock":100,"deal_money":10,"curr_stock":100,"curr_money":10,"fill_price":"0.100000000000000000"}
 {"order_id":"cosmos1qy352eufqy352eufqy352eufqy35qqqptw34ca-2","sender":"cosmos1qy352eufqy352eufqy352eufqy35qqqptw34ca","trading_pair":"abc/cet","order_type":2,"price":"100.000000000000000000","quantity":300,"side":1,"time_in_force":3,"feature_fee":1,"height":1001,"frozen_fee":1,"freeze":10}
@@ -577,17 +597,17 @@ func Test1(t *testing.T) {
 	bytes, _ = json.Marshal(timesid)
 	assert.Equal(t, "[1563178750,13,1563178750,11,1563178030,8,1563178030,7]", string(bytes))
 
-	data, tags, _ = hub.QueryOrderAboutToken("", "cet", addr1, unixTime, 0, 20)
+	data, tags, _ = hub.QueryOrderAboutToken("", "cet", addr1, QueryRange{Time: unixTime, Count: 20})
 	assert.Equal(t, correct, toStr(data))
 	assert.Equal(t, "dfcc", string(tags))
-	data, tags, _ = hub.QueryOrderAboutToken("", "abc", addr1, unixTime, 0, 20)
+	data, tags, _ = hub.QueryOrderAboutToken("", "abc", addr1, QueryRange{Time: unixTime, Count: 20})
 	assert.Equal(t, correct, toStr(data))
 	assert.Equal(t, "dfcc", string(tags))
-	data, tags, _ = hub.QueryOrderAboutToken("", "xyz", addr1, unixTime, 0, 20)
+	data, tags, _ = hub.QueryOrderAboutToken("", "xyz", addr1, QueryRange{Time: unixTime, Count: 20})
 	assert.Equal(t, 0, len(data))
 	assert.Equal(t, 0, len(tags))
 
-	data, tags, timesid = hub.QueryOrder(addr1, 1563178750, 10, 20)
+	data, tags, timesid = hub.QueryOrder(addr1, QueryRange{Time: 1563178750, Sid: 10, Count: 20})
 	correct = `{"order_id":"cosmos1qy352eufqy352eufqy352eufqy35qqqptw34ca-2","sender":"cosmos1qy352eufqy352eufqy352eufqy35qqqptw34ca","trading_pair":"abc/cet","order_type":2,"price":"100.000000000000000000","quantity":300,"side":1,"time_in_force":3,"feature_fee":1,"height":1001,"frozen_fee":1,"freeze":10}
 {"order_id":"cosmos1qy352eufqy352eufqy352eufqy35qqqptw34ca-1","sender":"cosmos1qy352eufqy352eufqy352eufqy35qqqptw34ca","trading_pair":"abc/cet","order_type":2,"price":"100.000000000000000000","quantity":300,"side":2,"time_in_force":3,"feature_fee":1,"height":1001,"frozen_fee":1,"freeze":10}`
 	assert.Equal(t, correct, toStr(data))
@@ -595,127 +615,127 @@ func Test1(t *testing.T) {
 	bytes, _ = json.Marshal(timesid)
 	assert.Equal(t, "[1563178030,8,1563178030,7]", string(bytes))
 
-	data, tags, timesid = hub.QueryOrderAboutToken("", "cet", addr1, 1563178750, 10, 20)
+	data, tags, timesid = hub.QueryOrderAboutToken("", "cet", addr1, QueryRange{Time: 1563178750, Sid: 10, Count: 20})
 	assert.Equal(t, correct, toStr(data))
 	assert.Equal(t, "cc", string(tags))
 	bytes, _ = json.Marshal(timesid)
 	assert.Equal(t, "[1563178030,8,1563178030,7]", string(bytes))
 
-	data, timesid = hub.QueryLocked(addr1, unixTime, 0, 20)
+	data, timesid = hub.QueryLocked(addr1, QueryRange{Time: unixTime, Count: 20})
 	correct = `{"from_address":"cosmos1qy352eufqy352eufqy352eufqy35qqqz9ayrkz","to_address":"cosmos1qy352eufqy352eufqy352eufqy35qqqptw34ca","amount":[{"denom":"xyz","amount":"15888"}],"unlock_time":1563179350}`
 	assert.Equal(t, correct, toStr(data))
 	bytes, _ = json.Marshal(timesid)
 	assert.Equal(t, "[1563178030,6]", string(bytes))
 
-	data, timesid = hub.QueryLockedAboutToken("xyz", addr1, unixTime, 0, 20)
+	data, timesid = hub.QueryLockedAboutToken("xyz", addr1, QueryRange{Time: unixTime, Count: 20})
 	correct = `{"from_address":"cosmos1qy352eufqy352eufqy352eufqy35qqqz9ayrkz","to_address":"cosmos1qy352eufqy352eufqy352eufqy35qqqptw34ca","amount":[{"denom":"xyz","amount":"15888"}],"unlock_time":1563179350}`
 	assert.Equal(t, correct, toStr(data))
 	bytes, _ = json.Marshal(timesid)
 	assert.Equal(t, "[1563178030,6]", string(bytes))
 
-	data, timesid = hub.QueryLockedAboutToken("zbc", addr1, unixTime, 0, 20)
+	data, timesid = hub.QueryLockedAboutToken("zbc", addr1, QueryRange{Time: unixTime, Count: 20})
 	assert.Equal(t, 0, len(data))
 	assert.Equal(t, 0, len(timesid))
 
-	data, timesid = hub.QueryDeal("abc/cet", unixTime, 0, 20)
+	data, timesid = hub.QueryDeal("abc/cet", QueryRange{Time: unixTime, Count: 20})
 	correct = `{"order_id":"cosmos1qy352eufqy352eufqy352eufqy35qqqptw34ca-1","trading_pair":"abc/cet","height":1001,"side":2,"price":"100.000000000000000000","left_stock":0,"freeze":0,"deal_stock":100,"deal_money":10,"curr_stock":100,"curr_money":10,"fill_price":"0.100000000000000000"}`
 	assert.Equal(t, correct, toStr(data))
 	bytes, _ = json.Marshal(timesid)
 	assert.Equal(t, "[1563178750,12]", string(bytes))
 
-	data, timesid = hub.QueryBancorInfo("xyz/cet", unixTime, 0, 20)
+	data, timesid = hub.QueryBancorInfo("xyz/cet", QueryRange{Time: unixTime, Count: 20})
 	correct = `{"owner":"cosmos1qy352eufqy352eufqy352eufqy35qqqptw34ca","stock":"xyz","money":"cet","init_price":"10","max_supply":"10000","max_price":"100","current_price":"20","stock_in_pool":"50","money_in_pool":"5000","earliest_cancel_time":0}`
 	assert.Equal(t, correct, toStr(data))
 	bytes, _ = json.Marshal(timesid)
-	assert.Equal(t, "[1563179350,15]", string(bytes))
+	assert.Equal(t, "[1563179350,16]", string(bytes))
 
-	data, timesid = hub.QueryBancorTrade(addr2, unixTime, 0, 20)
+	data, timesid = hub.QueryBancorTrade(addr2, QueryRange{Time: unixTime, Count: 20})
 	correct = `{"sender":"cosmos1qy352eufqy352eufqy352eufqy35qqqz9ayrkz","stock":"xyz","money":"cet","amount":1,"side":2,"money_limit":10,"transaction_price":"2.000000000000000000","block_height":1001}`
 	assert.Equal(t, correct, toStr(data))
 	bytes, _ = json.Marshal(timesid)
-	assert.Equal(t, "[1563179350,16]", string(bytes))
+	assert.Equal(t, "[1563179350,17]", string(bytes))
 
-	data, timesid = hub.QueryBancorTradeAboutToken("xyz", addr2, unixTime, 0, 20)
+	data, timesid = hub.QueryBancorTradeAboutToken("xyz", addr2, QueryRange{Time: unixTime, Count: 20})
 	correct = `{"sender":"cosmos1qy352eufqy352eufqy352eufqy35qqqz9ayrkz","stock":"xyz","money":"cet","amount":1,"side":2,"money_limit":10,"transaction_price":"2.000000000000000000","block_height":1001}`
 	assert.Equal(t, correct, toStr(data))
 	bytes, _ = json.Marshal(timesid)
-	assert.Equal(t, "[1563179350,16]", string(bytes))
+	assert.Equal(t, "[1563179350,17]", string(bytes))
 
-	data, timesid = hub.QueryBancorTradeAboutToken("cet", addr2, unixTime, 0, 20)
+	data, timesid = hub.QueryBancorTradeAboutToken("cet", addr2, QueryRange{Time: unixTime, Count: 20})
 	correct = `{"sender":"cosmos1qy352eufqy352eufqy352eufqy35qqqz9ayrkz","stock":"xyz","money":"cet","amount":1,"side":2,"money_limit":10,"transaction_price":"2.000000000000000000","block_height":1001}`
 	assert.Equal(t, correct, toStr(data))
 	bytes, _ = json.Marshal(timesid)
-	assert.Equal(t, "[1563179350,16]", string(bytes))
+	assert.Equal(t, "[1563179350,17]", string(bytes))
 
-	data, timesid = hub.QueryBancorTradeAboutToken("abc", addr2, unixTime, 0, 20)
+	data, timesid = hub.QueryBancorTradeAboutToken("abc", addr2, QueryRange{Time: unixTime, Count: 20})
 	assert.Equal(t, 0, len(data))
 	assert.Equal(t, 0, len(timesid))
 
-	data, timesid = hub.QueryRedelegation(addr2, unixTime, 0, 20)
+	data, timesid = hub.QueryRedelegation(addr2, QueryRange{Time: unixTime, Count: 20})
 	correct = `{"delegator":"cosmos1qy352eufqy352eufqy352eufqy35qqqz9ayrkz","src":"Val1","dst":"Val2","amount":"500","completion_time":1563178690}`
 	assert.Equal(t, correct, toStr(data))
 	bytes, _ = json.Marshal(timesid)
 	assert.Equal(t, "[1563178690,4]", string(bytes))
 
-	data, timesid = hub.QueryUnbonding(addr1, unixTime, 0, 20)
+	data, timesid = hub.QueryUnbonding(addr1, QueryRange{Time: unixTime, Count: 20})
 	correct = `{"delegator":"cosmos1qy352eufqy352eufqy352eufqy35qqqptw34ca","validator":"Val1","amount":"300","completion_time":1563178690}`
 	assert.Equal(t, correct, toStr(data))
 	bytes, _ = json.Marshal(timesid)
 	assert.Equal(t, "[1563178690,5]", string(bytes))
 
-	data, timesid = hub.QueryUnlock(addr2, unixTime, 0, 20)
+	data, timesid = hub.QueryUnlock(addr2, QueryRange{Time: unixTime, Count: 20})
 	correct = `{"address":"cosmos1qy352eufqy352eufqy352eufqy35qqqz9ayrkz","unlocked":[{"denom":"abc","amount":"15000"}],"locked_coins":[{"coin":{"denom":"cet","amount":"5000"},"unlock_time":1563178690}],"frozen_coins":[],"coins":[],"height":1001}`
 	assert.Equal(t, correct, toStr(data))
 	bytes, _ = json.Marshal(timesid)
 	assert.Equal(t, "[1563178030,3]", string(bytes))
 
-	data, timesid = hub.QueryUnlockAboutToken("abc", addr2, unixTime, 0, 20)
+	data, timesid = hub.QueryUnlockAboutToken("abc", addr2, QueryRange{Time: unixTime, Count: 20})
 	correct = `{"address":"cosmos1qy352eufqy352eufqy352eufqy35qqqz9ayrkz","unlocked":[{"denom":"abc","amount":"15000"}],"locked_coins":[{"coin":{"denom":"cet","amount":"5000"},"unlock_time":1563178690}],"frozen_coins":[],"coins":[],"height":1001}`
 	assert.Equal(t, correct, toStr(data))
 	bytes, _ = json.Marshal(timesid)
 	assert.Equal(t, "[1563178030,3]", string(bytes))
 
-	data, timesid = hub.QueryUnlockAboutToken("xyz", addr2, unixTime, 0, 20)
+	data, timesid = hub.QueryUnlockAboutToken("xyz", addr2, QueryRange{Time: unixTime, Count: 20})
 	assert.Equal(t, 0, len(data))
 	assert.Equal(t, 0, len(timesid))
 
-	data, timesid = hub.QueryIncome(addr2, unixTime, 0, 20)
+	data, timesid = hub.QueryIncome(addr2, QueryRange{Time: unixTime, Count: 20})
 	correct = `{"signers":["cosmos1qy352eufqy352eufqy352eufqy35qqqptw34ca"],"transfers":[{"sender":"cosmos1qy352eufqy352eufqy352eufqy35qqqptw34ca","recipient":"cosmos1qy352eufqy352eufqy352eufqy35qqqz9ayrkz","amount":"1cet"}],"serial_number":20000,"msg_types":["MsgType1"],"tx_json":"","height":1001,"hash":""}`
 	assert.Equal(t, correct, toStr(data))
 	bytes, _ = json.Marshal(timesid)
 	assert.Equal(t, "[1563178030,1]", string(bytes))
 
-	data, timesid = hub.QueryIncomeAboutToken("cet", addr2, unixTime, 0, 20)
+	data, timesid = hub.QueryIncomeAboutToken("cet", addr2, QueryRange{Time: unixTime, Count: 20})
 	assert.Equal(t, correct, toStr(data))
 	bytes, _ = json.Marshal(timesid)
 	assert.Equal(t, "[1563178030,1]", string(bytes))
 
-	data, timesid = hub.QueryIncomeAboutToken("xyz", addr2, unixTime, 0, 20)
+	data, timesid = hub.QueryIncomeAboutToken("xyz", addr2, QueryRange{Time: unixTime, Count: 20})
 	assert.Equal(t, 0, len(data))
 	assert.Equal(t, 0, len(timesid))
 
-	data, timesid = hub.QueryTx(addr1, unixTime, 0, 20)
+	data, timesid = hub.QueryTx(addr1, QueryRange{Time: unixTime, Count: 20})
 	correct = `{"signers":["cosmos1qy352eufqy352eufqy352eufqy35qqqptw34ca"],"transfers":[{"sender":"cosmos1qy352eufqy352eufqy352eufqy35qqqptw34ca","recipient":"cosmos1qy352eufqy352eufqy352eufqy35qqqz9ayrkz","amount":"1cet"}],"serial_number":20000,"msg_types":["MsgType1"],"tx_json":"","height":1001,"hash":""}`
 	assert.Equal(t, correct, toStr(data))
 	bytes, _ = json.Marshal(timesid)
 	assert.Equal(t, "[1563178030,2]", string(bytes))
 
-	data, timesid = hub.QueryTxAboutToken("cet", addr1, unixTime, 0, 20)
+	data, timesid = hub.QueryTxAboutToken("cet", addr1, QueryRange{Time: unixTime, Count: 20})
 	assert.Equal(t, correct, toStr(data))
 	bytes, _ = json.Marshal(timesid)
 	assert.Equal(t, "[1563178030,2]", string(bytes))
 
-	data, timesid = hub.QueryTxAboutToken("xyz", addr1, unixTime, 0, 20)
+	data, timesid = hub.QueryTxAboutToken("xyz", addr1, QueryRange{Time: unixTime, Count: 20})
 	assert.Equal(t, 0, len(data))
 	assert.Equal(t, 0, len(timesid))
 
-	data, timesid = hub.QueryComment("cet", unixTime, 0, 20)
+	data, timesid = hub.QueryComment("cet", QueryRange{Time: unixTime, Count: 20})
 	correct = `{"id":181,"height":1001,"sender":"cosmos1qy352eufqy352eufqy352eufqy35qqqz9ayrkz","token":"cet","donation":0,"title":"I love CET","content":"I love CET so much.","content_type":3,"references":[{"id":180,"reward_target":"cosmos1qy352eufqy352eufqy352eufqy35qqqptw34ca","reward_token":"cet","reward_amount":500000,"attitudes":[]}]}`
 	assert.Equal(t, correct, toStr(data))
 	bytes, _ = json.Marshal(timesid)
 	assert.Equal(t, "[1563178750,10]", string(bytes))
 
-	data, timesid = hub.QuerySlash(unixTime, 0, 20)
+	data, timesid = hub.QuerySlash(QueryRange{Time: unixTime, Count: 20})
 	correct = `{"validator":"Val1","power":"30%","reason":"double_sign","jailed":true}`
 	assert.Equal(t, correct, toStr(data))
 	bytes, _ = json.Marshal(timesid)
@@ -753,12 +773,12 @@ func Test1(t *testing.T) {
 	correct = `
 3: {"height":1003,"timestamp":1563179470,"last_block_hash":"3233343536373839303132333435363738393031"}
 4: {"height":1003,"timestamp":1563179470,"last_block_hash":"3233343536373839303132333435363738393031"}
-6: {"open":"0.100000000000000000","close":"0.100000000000000000","high":"0.100000000000000000","low":"0.100000000000000000","total":"0","unix_time":1563179350,"time_span":"1min","market":"abc/cet"}
-28: {"open":"2.000000000000000000","close":"2.000000000000000000","high":"2.000000000000000000","low":"2.000000000000000000","total":"1","unix_time":1563179350,"time_span":"1min","market":"B:xyz/cet"}
+6: {"open":"0.100000000000000000","close":"0.100000000000000000","high":"0.100000000000000000","low":"0.100000000000000000","total":"0","total_money":"0","trade_count":0,"unix_time":1563179350,"time_span":"1min","market":"abc/cet"}
+28: {"open":"2.000000000000000000","close":"2.000000000000000000","high":"2.000000000000000000","low":"2.000000000000000000","total":"1","total_money":"2","trade_count":1,"unix_time":1563179350,"time_span":"1min","market":"B:xyz/cet"}
 15: {"order_id":"cosmos1qy352eufqy352eufqy352eufqy35qqqptw34ca-1","trading_pair":"abc/cet","height":1003,"side":2,"price":"100.000000000000000000","left_stock":0,"freeze":0,"deal_stock":200,"deal_money":25,"curr_stock":200,"curr_money":25,"fill_price":"0.125000000000000000"}
 16: {"order_id":"cosmos1qy352eufqy352eufqy352eufqy35qqqptw34ca-1","trading_pair":"abc/cet","height":1003,"side":2,"price":"100.000000000000000000","left_stock":0,"freeze":0,"deal_stock":200,"deal_money":25,"curr_stock":200,"curr_money":25,"fill_price":"0.125000000000000000"}
-8: {"trading_pair":"abc/cet","bids":[],"asks":[{"p":"100.000000000000000000","a":"0"}]}
-9: {"trading_pair":"abc/cet","bids":[],"asks":[{"p":"100.000000000000000000","a":"0"}]}
+8: {"trading_pair":"abc/cet","bids":[],"asks":[{"p":"100.000000000000000000","a":"0"}],"height":1003,"checksum":2028515874}
+9: {"trading_pair":"abc/cet","bids":[],"asks":[{"p":"100.000000000000000000","a":"0"}],"height":1003,"checksum":2028515874}
 `
 	subMan.CompareResult(t, correct)
 	subMan.ClearPushList()
@@ -794,31 +814,31 @@ func Test1(t *testing.T) {
 	correct = `
 3: {"height":1004,"timestamp":1563235270,"last_block_hash":"3233343536373839303132333435363738393031"}
 4: {"height":1004,"timestamp":1563235270,"last_block_hash":"3233343536373839303132333435363738393031"}
-6: {"open":"0.125000000000000000","close":"0.125000000000000000","high":"0.125000000000000000","low":"0.125000000000000000","total":"200","unix_time":1563179470,"time_span":"1min","market":"abc/cet"}
-7: {"open":"0.100000000000000000","close":"0.125000000000000000","high":"0.125000000000000000","low":"0.100000000000000000","total":"300","unix_time":1563179470,"time_span":"1hour","market":"abc/cet"}
-5: {"open":"0.100000000000000000","close":"0.125000000000000000","high":"0.125000000000000000","low":"0.100000000000000000","total":"300","unix_time":1563179470,"time_span":"1day","market":"abc/cet"}
-28: {"open":"2.000000000000000000","close":"2.000000000000000000","high":"2.000000000000000000","low":"2.000000000000000000","total":"0","unix_time":1563179470,"time_span":"1min","market":"B:xyz/cet"}
-29: {"open":"2.000000000000000000","close":"2.000000000000000000","high":"2.000000000000000000","low":"2.000000000000000000","total":"1","unix_time":1563179350,"time_span":"1hour","market":"B:xyz/cet"}
-30: {"open":"2.000000000000000000","close":"2.000000000000000000","high":"2.000000000000000000","low":"2.000000000000000000","total":"1","unix_time":1563179350,"time_span":"1day","market":"B:xyz/cet"}
+6: {"open":"0.125000000000000000","close":"0.125000000000000000","high":"0.125000000000000000","low":"0.125000000000000000","total":"200","total_money":"25","trade_count":1,"unix_time":1563179470,"time_span":"1min","market":"abc/cet"}
+7: {"open":"0.100000000000000000","close":"0.125000000000000000","high":"0.125000000000000000","low":"0.100000000000000000","total":"300","total_money":"35","trade_count":2,"unix_time":1563179470,"time_span":"1hour","market":"abc/cet"}
+5: {"open":"0.100000000000000000","close":"0.125000000000000000","high":"0.125000000000000000","low":"0.100000000000000000","total":"300","total_money":"35","trade_count":2,"unix_time":1563179470,"time_span":"1day","market":"abc/cet"}
+28: {"open":"2.000000000000000000","close":"2.000000000000000000","high":"2.000000000000000000","low":"2.000000000000000000","total":"0","total_money":"0","trade_count":0,"unix_time":1563179470,"time_span":"1min","market":"B:xyz/cet"}
+29: {"open":"2.000000000000000000","close":"2.000000000000000000","high":"2.000000000000000000","low":"2.000000000000000000","total":"1","total_money":"2","trade_count":1,"unix_time":1563179350,"time_span":"1hour","market":"B:xyz/cet"}
+30: {"open":"2.000000000000000000","close":"2.000000000000000000","high":"2.000000000000000000","low":"2.000000000000000000","total":"1","total_money":"2","trade_count":1,"unix_time":1563179350,"time_span":"1day","market":"B:xyz/cet"}
 15: {"order_id":"cosmos1qy352eufqy352eufqy352eufqy35qqqptw34ca-1","trading_pair":"abc/cet","height":1003,"side":2,"price":"110.000000000000000000","left_stock":0,"freeze":0,"deal_stock":200,"deal_money":25,"curr_stock":200,"curr_money":25,"fill_price":"0.125000000000000000"}
 16: {"order_id":"cosmos1qy352eufqy352eufqy352eufqy35qqqptw34ca-1","trading_pair":"abc/cet","height":1003,"side":2,"price":"110.000000000000000000","left_stock":0,"freeze":0,"deal_stock":200,"deal_money":25,"curr_stock":200,"curr_money":25,"fill_price":"0.125000000000000000"}
-8: {"trading_pair":"abc/cet","bids":[],"asks":[{"p":"110.000000000000000000","a":"-200"}]}
-9: {"trading_pair":"abc/cet","bids":[],"asks":[{"p":"110.000000000000000000","a":"-200"}]}
+8: {"trading_pair":"abc/cet","bids":[],"asks":[{"p":"110.000000000000000000","a":"-200"}],"height":1004,"checksum":1387707637}
+9: {"trading_pair":"abc/cet","bids":[],"asks":[{"p":"110.000000000000000000","a":"-200"}],"height":1004,"checksum":1387707637}
 `
 	subMan.CompareResult(t, correct)
 	subMan.ClearPushList()
 
 	unixTime = T("2019-07-25T08:39:10Z").Unix()
-	data = hub.QueryCandleStick("B:xyz/cet", Hour, unixTime, 0, 20)
-	correct = `{"open":"2.000000000000000000","close":"2.000000000000000000","high":"2.000000000000000000","low":"2.000000000000000000","total":"1","unix_time":1563179350,"time_span":"1hour","market":"B:xyz/cet"}`
+	data = hub.QueryCandleStick("B:xyz/cet", Hour, QueryRange{Time: unixTime, Count: 20})
+	correct = `{"open":"2.000000000000000000","close":"2.000000000000000000","high":"2.000000000000000000","low":"2.000000000000000000","total":"1","total_money":"2","trade_count":1,"unix_time":1563179350,"time_span":"1hour","market":"B:xyz/cet"}`
 	assert.Equal(t, correct, toStr(data))
 
-	data = hub.QueryCandleStick("abc/cet", Hour, unixTime, 0, 20)
-	correct = `{"open":"0.100000000000000000","close":"0.125000000000000000","high":"0.125000000000000000","low":"0.100000000000000000","total":"300","unix_time":1563179470,"time_span":"1hour","market":"abc/cet"}`
+	data = hub.QueryCandleStick("abc/cet", Hour, QueryRange{Time: unixTime, Count: 20})
+	correct = `{"open":"0.100000000000000000","close":"0.125000000000000000","high":"0.125000000000000000","low":"0.100000000000000000","total":"300","total_money":"35","trade_count":2,"unix_time":1563179470,"time_span":"1hour","market":"abc/cet"}`
 	assert.Equal(t, correct, toStr(data))
 
-	data = hub.QueryCandleStick("abc/cet", Day, unixTime, 0, 20)
-	correct = `{"open":"0.100000000000000000","close":"0.125000000000000000","high":"0.125000000000000000","low":"0.100000000000000000","total":"300","unix_time":1563179470,"time_span":"1day","market":"abc/cet"}`
+	data = hub.QueryCandleStick("abc/cet", Day, QueryRange{Time: unixTime, Count: 20})
+	correct = `{"open":"0.100000000000000000","close":"0.125000000000000000","high":"0.125000000000000000","low":"0.100000000000000000","total":"300","total_money":"35","trade_count":2,"unix_time":1563179470,"time_span":"1day","market":"abc/cet"}`
 	assert.Equal(t, correct, toStr(data))
 
 	T("2019-07-15T08:37:10Z")
@@ -834,12 +854,12 @@ func Test1(t *testing.T) {
 	correct = `
 3: {"height":1005,"timestamp":1563179830,"last_block_hash":"3031323334353637383930313233343536373839"}
 4: {"height":1005,"timestamp":1563179830,"last_block_hash":"3031323334353637383930313233343536373839"}
-7: {"open":"0.125000000000000000","close":"0.125000000000000000","high":"0.125000000000000000","low":"0.125000000000000000","total":"200","unix_time":1563235270,"time_span":"1hour","market":"abc/cet"}
-6: {"open":"0.125000000000000000","close":"0.125000000000000000","high":"0.125000000000000000","low":"0.125000000000000000","total":"200","unix_time":1563235270,"time_span":"1min","market":"abc/cet"}
-5: {"open":"0.125000000000000000","close":"0.125000000000000000","high":"0.125000000000000000","low":"0.125000000000000000","total":"200","unix_time":1563235270,"time_span":"1day","market":"abc/cet"}
-28: {"open":"2.000000000000000000","close":"2.000000000000000000","high":"2.000000000000000000","low":"2.000000000000000000","total":"0","unix_time":1563235270,"time_span":"1min","market":"B:xyz/cet"}
-29: {"open":"2.000000000000000000","close":"2.000000000000000000","high":"2.000000000000000000","low":"2.000000000000000000","total":"0","unix_time":1563235270,"time_span":"1hour","market":"B:xyz/cet"}
-30: {"open":"2.000000000000000000","close":"2.000000000000000000","high":"2.000000000000000000","low":"2.000000000000000000","total":"0","unix_time":1563235270,"time_span":"1day","market":"B:xyz/cet"}
+7: {"open":"0.125000000000000000","close":"0.125000000000000000","high":"0.125000000000000000","low":"0.125000000000000000","total":"200","total_money":"25","trade_count":1,"unix_time":1563235270,"time_span":"1hour","market":"abc/cet"}
+6: {"open":"0.125000000000000000","close":"0.125000000000000000","high":"0.125000000000000000","low":"0.125000000000000000","total":"200","total_money":"25","trade_count":1,"unix_time":1563235270,"time_span":"1min","market":"abc/cet"}
+5: {"open":"0.125000000000000000","close":"0.125000000000000000","high":"0.125000000000000000","low":"0.125000000000000000","total":"200","total_money":"25","trade_count":1,"unix_time":1563235270,"time_span":"1day","market":"abc/cet"}
+28: {"open":"2.000000000000000000","close":"2.000000000000000000","high":"2.000000000000000000","low":"2.000000000000000000","total":"0","total_money":"0","trade_count":0,"unix_time":1563235270,"time_span":"1min","market":"B:xyz/cet"}
+29: {"open":"2.000000000000000000","close":"2.000000000000000000","high":"2.000000000000000000","low":"2.000000000000000000","total":"0","total_money":"0","trade_count":0,"unix_time":1563235270,"time_span":"1hour","market":"B:xyz/cet"}
+30: {"open":"2.000000000000000000","close":"2.000000000000000000","high":"2.000000000000000000","low":"2.000000000000000000","total":"0","total_money":"0","trade_count":0,"unix_time":1563235270,"time_span":"1day","market":"B:xyz/cet"}
 
 `
 	subMan.CompareResult(t, correct)
@@ -858,8 +878,8 @@ func Test1(t *testing.T) {
 	correct = `
 3: {"height":1006,"timestamp":1563179890,"last_block_hash":"3031323334353637383930313233343536373839"}
 4: {"height":1006,"timestamp":1563179890,"last_block_hash":"3031323334353637383930313233343536373839"}
-6: {"open":"0.125000000000000000","close":"0.125000000000000000","high":"0.125000000000000000","low":"0.125000000000000000","total":"0","unix_time":1563179830,"time_span":"1min","market":"abc/cet"}
-28: {"open":"2.000000000000000000","close":"2.000000000000000000","high":"2.000000000000000000","low":"2.000000000000000000","total":"0","unix_time":1563179830,"time_span":"1min","market":"B:xyz/cet"}
+6: {"open":"0.125000000000000000","close":"0.125000000000000000","high":"0.125000000000000000","low":"0.125000000000000000","total":"0","total_money":"0","trade_count":0,"unix_time":1563179830,"time_span":"1min","market":"abc/cet"}
+28: {"open":"2.000000000000000000","close":"2.000000000000000000","high":"2.000000000000000000","low":"2.000000000000000000","total":"0","total_money":"0","trade_count":0,"unix_time":1563179830,"time_span":"1min","market":"B:xyz/cet"}
 `
 	subMan.CompareResult(t, correct)
 	subMan.ClearPushList()
@@ -929,8 +949,8 @@ func Test1(t *testing.T) {
 4: {"height":1007,"timestamp":1563179950,"last_block_hash":"3031323334353637383930313233343536373839"}
 17: {"sender":"cosmos1qy352eufqy352eufqy352eufqy35qqqz9ayrkz","stock":"xyz","money":"cet","amount":2,"side":2,"money_limit":20,"transaction_price":"3.000000000000000000","block_height":1007}
 18: {"sender":"cosmos1qy352eufqy352eufqy352eufqy35qqqz9ayrkz","stock":"xyz","money":"cet","amount":2,"side":2,"money_limit":20,"transaction_price":"3.000000000000000000","block_height":1007}
-28: {"open":"2.000000000000000000","close":"2.000000000000000000","high":"2.000000000000000000","low":"2.000000000000000000","total":"0","unix_time":1563179890,"time_span":"1min","market":"B:xyz/cet"}
-6: {"open":"0.125000000000000000","close":"0.125000000000000000","high":"0.125000000000000000","low":"0.125000000000000000","total":"0","unix_time":1563179890,"time_span":"1min","market":"abc/cet"}
+28: {"open":"2.000000000000000000","close":"2.000000000000000000","high":"2.000000000000000000","low":"2.000000000000000000","total":"0","total_money":"0","trade_count":0,"unix_time":1563179890,"time_span":"1min","market":"B:xyz/cet"}
+6: {"open":"0.125000000000000000","close":"0.125000000000000000","high":"0.125000000000000000","low":"0.125000000000000000","total":"0","total_money":"0","trade_count":0,"unix_time":1563179890,"time_span":"1min","market":"abc/cet"}
 `
 	subMan.CompareResult(t, correct)
 	subMan.ClearPushList()
@@ -949,8 +969,8 @@ func Test1(t *testing.T) {
 3: {"height":1008,"timestamp":1563180010,"last_block_hash":"3031323334353637383930313233343536373839"}
 4: {"height":1008,"timestamp":1563180010,"last_block_hash":"3031323334353637383930313233343536373839"}
 27: [{"market":"B:xyz/cet","new":"3.000000000000000000","old":"2.000000000000000000","minute_in_day":519}]
-28: {"open":"3.000000000000000000","close":"3.000000000000000000","high":"3.000000000000000000","low":"3.000000000000000000","total":"2","unix_time":1563179950,"time_span":"1min","market":"B:xyz/cet"}
-6: {"open":"0.125000000000000000","close":"0.125000000000000000","high":"0.125000000000000000","low":"0.125000000000000000","total":"0","unix_time":1563179950,"time_span":"1min","market":"abc/cet"}
+28: {"open":"3.000000000000000000","close":"3.000000000000000000","high":"3.000000000000000000","low":"3.000000000000000000","total":"2","total_money":"6","trade_count":1,"unix_time":1563179950,"time_span":"1min","market":"B:xyz/cet"}
+6: {"open":"0.125000000000000000","close":"0.125000000000000000","high":"0.125000000000000000","low":"0.125000000000000000","total":"0","total_money":"0","trade_count":0,"unix_time":1563179950,"time_span":"1min","market":"abc/cet"}
 `
 	subMan.CompareResult(t, correct)
 	subMan.ClearPushList()
@@ -966,17 +986,68 @@ func Test1(t *testing.T) {
 	tickers := hub.QueryTickers([]string{"abc/cet", "B:xyz/cet"})
 	assert.Equal(t, correctTickers, tickers)
 
-	data, timesid = hub.QueryDonation(unixTime, 0, 20)
+	data, timesid = hub.QueryDonation(QueryRange{Time: unixTime, Count: 20})
 	correct = `{"sender":"coinex10dxnwwzht8x2qt3tv8wkgqdlxkm4ks9fn97xxa","amount":"1000000000"}
 {"sender":"coinex1celqkm3yfkgg6nz9s5yfpnkzdsd0n3jhux4p65","amount":"200000000"}`
 	assert.Equal(t, correct, toStr(data))
 	bytes, _ = json.Marshal(timesid)
-	assert.Equal(t, "[1563179950,47,1563179950,43]", string(bytes))
+	assert.Equal(t, "[1563179950,70,1563179950,66]", string(bytes))
 
 	height = hub.QueryLatestHeight()
 	require.EqualValues(t, 1008, height)
 }
 
+func TestLoadHubWithoutL3(t *testing.T) {
+	subMan := GetDepthSubscribeManeger()
+	hub := NewHub(dbm.NewMemDB(), subMan, 99999, 0, 0, 0, "", 0)
+	hub.AddMarket("abc/cet")
+	hub4j := &HubForJSON{}
+	hub.Dump(hub4j)
+	// the dumps of older versions have no L3 orders, which are rebuilt from the open orders
+	hub4j.Markets[0].SellPricePoints = []*PricePoint{{Price: sdk.NewDec(10), Amount: sdk.NewInt(100)}}
+	hub4j.OpenOrders = map[string]map[string]*OpenOrder{
+		"a": {"a-1": {OrderID: "a-1", TradingPair: "abc/cet", Side: SELL, Price: sdk.NewDec(10), LeftStock: 60, Height: 2}},
+		"b": {"b-1": {OrderID: "b-1", TradingPair: "abc/cet", Side: SELL, Price: sdk.NewDec(10), LeftStock: 40, Height: 1}},
+	}
+	newHub := NewHub(dbm.NewMemDB(), subMan, 99999, 0, 0, 0, "", 0)
+	require.Nil(t, newHub.Load(hub4j))
+	_, asks, _ := newHub.managersMap["abc/cet"].l3.GetOrders(10)
+	require.Equal(t, []string{"b-1", "a-1"}, []string{asks[0].OrderID, asks[1].OrderID})
+	require.EqualValues(t, []int64{-2, -1}, []int64{asks[0].Seq, asks[1].Seq})
+
+	// the open orders must match the depth
+	hub4j.OpenOrders["a"]["a-1"].LeftStock = 50
+	newHub = NewHub(dbm.NewMemDB(), subMan, 99999, 0, 0, 0, "", 0)
+	require.NotNil(t, newHub.Load(hub4j))
+	hub4j.OpenOrders = nil
+	newHub = NewHub(dbm.NewMemDB(), subMan, 99999, 0, 0, 0, "", 0)
+	require.NotNil(t, newHub.Load(hub4j))
+}
+
+func TestLoadDepthHeight(t *testing.T) {
+	subMan := GetDepthSubscribeManeger()
+	hub := NewHub(dbm.NewMemDB(), subMan, 99999, 0, 0, 0, "", 0)
+	hub.AddMarket("abc/cet")
+	hub.currBlockHeight = 1000
+	hub.managersMap["abc/cet"].height = 990
+	hub4j := &HubForJSON{}
+	hub.Dump(hub4j)
+
+	// the depth data keep their height and checksums after restart
+	newHub := NewHub(dbm.NewMemDB(), subMan, 99999, 0, 0, 0, "", 0)
+	require.Nil(t, newHub.Load(hub4j))
+	_, _, height, checksum := newHub.managersMap["abc/cet"].GetDepthWithChecksum("all", 20)
+	_, _, _, expected := hub.managersMap["abc/cet"].GetDepthWithChecksum("all", 20)
+	require.EqualValues(t, 990, height)
+	require.EqualValues(t, expected, checksum)
+
+	// the dumps of older versions have no height
+	hub4j.Markets[0].Height = 0
+	newHub = NewHub(dbm.NewMemDB(), subMan, 99999, 0, 0, 0, "", 0)
+	require.Nil(t, newHub.Load(hub4j))
+	require.EqualValues(t, 1000, newHub.managersMap["abc/cet"].height)
+}
+
 func TestDumpOffset(t *testing.T) {
 	acc1, _ := simpleAddr("00001")
 	acc2, _ := simpleAddr("00002")
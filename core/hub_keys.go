@@ -41,9 +41,15 @@ const (
 	CancelOrderOnlyByte = byte(0x44)
 
 	// Later add key
-	CreateMarketByte        = byte(0x46)
+	CreateMarketByte        = byte(0x46) //-, []byte{}, 0, currBlockTime, hub.sid, lastByte=0
 	ValidatorCommissionByte = byte(0x48)
 	DelegatorRewardsByte    = byte(0x50)
+	OrderIDByte             = byte(0x52) //-, []byte(orderID), 0, currBlockTime, hub.sid, lastByte=CreateOrderEndByte/FillOrderEndByte/CancelOrderEndByte
+	AlertByte               = byte(0x54) //-, []byte(addr), 0, []byte(alertID)
+	DepthSnapshotByte       = byte(0x56) //-, []byte(market), 0, currBlockTime, height, lastByte=0
+	DepthDeltaByte          = byte(0x58) //-, []byte(market), 0, currBlockTime, height, lastByte=0
+	AccountStatsByte        = byte(0x5A) //-, []byte(addr), 0, []byte(market), 0, the start time of the day
+	RebateByte              = byte(0x5C) //-, []byte(referee), 0, the start time of the day, []byte(market)
 )
 
 func (hub *Hub) getCandleStickKey(market string, timespan byte) []byte {
@@ -65,8 +71,11 @@ func (hub *Hub) getBancorDealKey(market string) []byte {
 func (hub *Hub) getCommentKey(token string) []byte {
 	return hub.getKeyFromBytes(CommentByte, []byte(token), 0)
 }
-func (hub *Hub) getCreateMarketKey(market string) []byte {
-	return hub.getKeyFromBytes(CreateMarketByte, []byte(market), 0)
+func (hub *Hub) getCreateMarketKey() []byte {
+	return hub.getKeyFromBytes(CreateMarketByte, []byte{}, 0)
+}
+func (hub *Hub) getOrderIDKey(orderID string, lastByte byte) []byte {
+	return hub.getKeyFromBytes(OrderIDByte, []byte(orderID), lastByte)
 }
 func (hub *Hub) getCreateOrderKey(addr string) []byte {
 	return hub.getKeyFromBytes(OrderByte, []byte(addr), CreateOrderEndByte)
@@ -132,3 +141,39 @@ func (hub *Hub) getEventKeyWithSidAndTime(firstByte byte, addr string, time int6
 	res = append(res, 0)
 	return res
 }
+
+// The price alerts are kept until they are removed, so their keys have no time
+func getAlertKey(addr, id string) []byte {
+	res := make([]byte, 0, 1+1+len(addr)+1+len(id))
+	res = append(res, AlertByte)
+	res = append(res, byte(len(addr)))
+	res = append(res, []byte(addr)...)
+	res = append(res, byte(0))
+	res = append(res, []byte(id)...)
+	return res
+}
+
+// The stats of an account are aggregated by day and kept forever, so their keys have no sid
+func getAccountStatsKey(addr, market string, dayTime int64) []byte {
+	res := make([]byte, 0, 1+1+len(addr)+1+len(market)+1+8)
+	res = append(res, AccountStatsByte)
+	res = append(res, byte(len(addr)))
+	res = append(res, []byte(addr)...)
+	res = append(res, byte(0))
+	res = append(res, []byte(market)...)
+	res = append(res, byte(0))
+	res = append(res, Int64ToBigEndianBytes(dayTime)...)
+	return res
+}
+
+// The rebates of a referee are ordered by the day and then the market
+func getRebateKey(referee string, dayTime int64, market string) []byte {
+	res := make([]byte, 0, 1+1+len(referee)+1+8+len(market))
+	res = append(res, RebateByte)
+	res = append(res, byte(len(referee)))
+	res = append(res, []byte(referee)...)
+	res = append(res, byte(0))
+	res = append(res, Int64ToBigEndianBytes(dayTime)...)
+	res = append(res, []byte(market)...)
+	return res
+}
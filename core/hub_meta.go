@@ -2,9 +2,13 @@ package core
 
 import (
 	"encoding/binary"
+	"fmt"
+	"sort"
 	"strings"
 	"sync/atomic"
 	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
 )
 
 // for serialization and deserialization of Hub
@@ -14,31 +18,71 @@ type HubForJSON struct {
 	Sid             int64                `json:"sid"`
 	CSMan           CandleStickManager   `json:"csman"`
 	TickerMap       map[string]*Ticker   `json:"ticker_map"`
+	XTickerMap      map[string]*XTicker  `json:"xticker_map"`
 	CurrBlockHeight int64                `json:"curr_block_height"`
 	CurrBlockTime   int64                `json:"curr_block_time"`
 	LastBlockTime   int64                `json:"last_block_time"`
 	Markets         []*MarketInfoForJSON `json:"markets"`
+
+	OpenOrders map[string]map[string]*OpenOrder `json:"open_orders"`
+	// the sequence number of the last pushed message
+	PushSeq int64 `json:"push_seq"`
 }
 
 type MarketInfoForJSON struct {
-	TkMan           *TickerManager `json:"tkman"`
-	SellPricePoints []*PricePoint  `json:"sells"`
-	BuyPricePoints  []*PricePoint  `json:"buys"`
+	TkMan           *TickerManager  `json:"tkman"`
+	XTkMan          *XTickerManager `json:"xtkman,omitempty"`
+	SellPricePoints []*PricePoint   `json:"sells"`
+	BuyPricePoints  []*PricePoint   `json:"buys"`
+	L3Orders        []*L3Order      `json:"l3_orders,omitempty"`
+	// the height when the depth data were last changed
+	Height int64 `json:"height,omitempty"`
 }
 
-func (hub *Hub) Load(hub4j *HubForJSON) {
+// Returns an error if the dump can not be loaded without losing some state
+func (hub *Hub) Load(hub4j *HubForJSON) error {
 	hub.sid = hub4j.Sid
 	hub.csMan = hub4j.CSMan
+	hub.csMan.fillMissingFields()
 	hub.tickerMap = hub4j.TickerMap
+	hub.xtickerMap = hub4j.XTickerMap
+	if hub.xtickerMap == nil {
+		hub.xtickerMap = make(map[string]*XTicker)
+	}
 	hub.currBlockHeight = hub4j.CurrBlockHeight
 	hub.currBlockTime = time.Unix(0, hub4j.CurrBlockTime)
 	hub.lastBlockTime = time.Unix(0, hub4j.LastBlockTime)
+	hub.openOrderMan = NewOpenOrderManager(hub4j.OpenOrders)
+	hub.pushStreams.Reset(hub4j.PushSeq)
+	hub.queuedSeq = hub4j.PushSeq
 
 	for _, info := range hub4j.Markets {
 		triman := &TripleManager{
-			sell: NewDepthManager("sell"),
-			buy:  NewDepthManager("buy"),
-			tkm:  info.TkMan,
+			sell:   NewDepthManager("sell"),
+			buy:    NewDepthManager("buy"),
+			tkm:    info.TkMan,
+			xtkm:   info.XTkMan,
+			height: info.Height,
+		}
+		if triman.height == 0 {
+			// the dumps of older versions have no height of the depth data, which changed no later than this block
+			triman.height = hub4j.CurrBlockHeight
+		}
+		if triman.xtkm == nil {
+			// the dumps of older versions have no xticker
+			triman.xtkm = NewXTickerManager(info.TkMan.Market)
+		}
+		if !strings.HasPrefix(info.TkMan.Market, "B:") {
+			orders := info.L3Orders
+			if len(orders) == 0 && len(info.SellPricePoints)+len(info.BuyPricePoints) != 0 {
+				// the dumps of older versions have no L3 orders
+				var err error
+				if orders, err = rebuildL3Orders(info, hub4j.OpenOrders); err != nil {
+					return err
+				}
+			}
+			triman.l3 = NewOrderBookL3(orders)
+			triman.l3.height = hub4j.CurrBlockHeight
 		}
 		for _, pp := range info.SellPricePoints {
 			triman.sell.DeltaChange(pp.Price, pp.Amount)
@@ -48,22 +92,92 @@ func (hub *Hub) Load(hub4j *HubForJSON) {
 		}
 		hub.managersMap[info.TkMan.Market] = triman
 	}
+	return nil
+}
+
+// Rebuilds the L3 orders of a market from the open orders, which must match the depth of this market.
+// The rebuilt orders are queued before the new ones, in the order of their heights
+func rebuildL3Orders(info *MarketInfoForJSON, openOrders map[string]map[string]*OpenOrder) ([]*L3Order, error) {
+	market := info.TkMan.Market
+	orders := make([]*L3Order, 0, 64)
+	amounts := make(map[string]sdk.Int)
+	for _, addrOrders := range openOrders {
+		for _, order := range addrOrders {
+			if order.TradingPair != market || order.LeftStock <= 0 {
+				continue
+			}
+			orders = append(orders, &L3Order{
+				OrderID: order.OrderID,
+				Side:    order.Side,
+				Price:   order.Price,
+				Amount:  order.LeftStock,
+				Height:  order.Height,
+			})
+			key := fmt.Sprintf("%d:%s", order.Side, order.Price)
+			if amount, ok := amounts[key]; ok {
+				amounts[key] = amount.AddRaw(order.LeftStock)
+			} else {
+				amounts[key] = sdk.NewInt(order.LeftStock)
+			}
+		}
+	}
+	depth := make(map[string]sdk.Int)
+	for side, points := range map[byte][]*PricePoint{SELL: info.SellPricePoints, BUY: info.BuyPricePoints} {
+		for _, pp := range points {
+			if pp.Amount.IsPositive() {
+				depth[fmt.Sprintf("%d:%s", side, pp.Price)] = pp.Amount
+			}
+		}
+	}
+	if !isSameAmounts(amounts, depth) {
+		return nil, fmt.Errorf("the dump has no L3 orders of %s, which can not be rebuilt from the open orders "+
+			"since they do not match the depth", market)
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		if orders[i].Height != orders[j].Height {
+			return orders[i].Height < orders[j].Height
+		}
+		return orders[i].OrderID < orders[j].OrderID
+	})
+	for i, order := range orders {
+		order.Seq = int64(i - len(orders))
+	}
+	return orders, nil
+}
+
+func isSameAmounts(a, b map[string]sdk.Int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, amount := range a {
+		if other, ok := b[key]; !ok || !other.Equal(amount) {
+			return false
+		}
+	}
+	return true
 }
 
 func (hub *Hub) Dump(hub4j *HubForJSON) {
 	hub4j.Sid = hub.sid
 	hub4j.CSMan = hub.csMan
 	hub4j.TickerMap = hub.tickerMap
+	hub4j.XTickerMap = hub.xtickerMap
 	hub4j.CurrBlockHeight = hub.currBlockHeight
 	hub4j.CurrBlockTime = hub.currBlockTime.UnixNano()
 	hub4j.LastBlockTime = hub.lastBlockTime.UnixNano()
+	hub4j.OpenOrders = hub.openOrderMan.Dump()
+	// the messages which are not pushed yet are counted, since they are not consumed again after restart
+	hub4j.PushSeq = hub.queuedSeq
 
 	hub4j.Markets = make([]*MarketInfoForJSON, 0, len(hub.managersMap))
 	for _, triman := range hub.managersMap {
 		hub4j.Markets = append(hub4j.Markets, &MarketInfoForJSON{
 			TkMan:           triman.tkm,
+			XTkMan:          triman.xtkm,
 			SellPricePoints: triman.sell.DumpPricePoints(),
 			BuyPricePoints:  triman.buy.DumpPricePoints(),
+			L3Orders:        triman.l3.DumpOrders(),
+			Height:          triman.height,
 		})
 	}
 }
@@ -151,7 +265,8 @@ func (hub *Hub) AddMarket(market string) {
 	if strings.HasPrefix(market, "B:") {
 		// A bancor market has no depth information
 		hub.managersMap[market] = &TripleManager{
-			tkm: NewTickerManager(market),
+			tkm:  NewTickerManager(market),
+			xtkm: NewXTickerManager(market),
 		}
 	} else {
 		// A normal market
@@ -159,6 +274,8 @@ func (hub *Hub) AddMarket(market string) {
 			sell: NewDepthManager("sell"),
 			buy:  NewDepthManager("buy"),
 			tkm:  NewTickerManager(market),
+			xtkm: NewXTickerManager(market),
+			l3:   NewOrderBookL3(nil),
 		}
 	}
 	hub.csMan.AddMarket(market)
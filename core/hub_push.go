@@ -5,61 +5,89 @@ package core
 func (hub *Hub) pushMsgToWebsocket() {
 	for {
 		entry := <-hub.msgsChannel
-		switch entry.topic {
-		case BlockInfoKey:
-			hub.PushHeightInfoMsg(entry.bz)
-		case KlineKey:
-			vals := entry.extra.([]string)
-			hub.PushCandleMsg( /*market*/ vals[0], entry.bz /*timespan*/, vals[1])
-		case LockedKey:
-			hub.PushLockedCoinsMsg( /*addr*/ entry.extra.(string), entry.bz)
-		case IncomeKey:
-			hub.PushIncomeMsg( /*receiver*/ entry.extra.(string), entry.bz)
-		case TxKey:
-			hub.PushTxMsg( /*addr*/ entry.extra.(string), entry.bz)
-		case RedelegationKey:
-			hub.PushRedelegationMsg(entry.extra.(TimeAndSidWithAddr))
-		case UnbondingKey:
-			hub.PushUnbondingMsg(entry.extra.(TimeAndSidWithAddr))
-		case UnlockKey:
-			hub.PushUnlockMsg( /*addr*/ entry.extra.(string), entry.bz)
-		case CommentKey:
-			hub.PushCommentMsg( /*token*/ entry.extra.(string), entry.bz)
-		case CreateMarketInfoKey:
-			hub.PushMarketInfoMsg( /*market*/ entry.extra.(string), entry.bz)
-		case CreateOrderKey:
-			hub.PushCreateOrderInfoMsg( /*addr*/ entry.extra.(string), entry.bz)
-		case FillOrderKey:
-			hub.PushFillOrderInfoMsg( /*addr*/ entry.extra.(string), entry.bz)
-		case DealKey:
-			hub.PushDealInfoMsg( /*market*/ entry.extra.(string), entry.bz)
-		case CancelOrderKey:
-			hub.PushCancelOrderMsg( /*addr*/ entry.extra.(string), entry.bz)
-		case BancorTradeKey:
-			hub.PushBancorTradeInfoMsg( /*addr*/ entry.extra.(string), entry.bz)
-		case BancorDealKey:
-			hub.PushBancorDealMsg( /*market*/ entry.extra.(string), entry.bz)
-		case BancorKey:
-			hub.PushBancorMsg( /*market*/ entry.extra.(string), entry.bz)
-		case SlashKey:
-			hub.PushSlashMsg(entry.bz)
-		case TickerKey:
-			hub.PushTickerMsg(entry.extra) // TODO. will modify param type
-		case DepthFull:
-			hub.PushDepthFullMsg( /*market*/ entry.extra.(string))
-		case DepthKey:
-			hub.PushDepthMsg( /*market*/ entry.bz, entry.extra.(map[string][]byte))
-		case OptionKey:
-			hub.subMan.SetSkipOption(entry.extra.(bool))
-		case ValidatorCommissionKey:
-			hub.PushValidatorCommissionMsg( /*addr*/ entry.extra.(string), entry.bz)
-		case DelegationRewardsKey:
-			hub.PushDelegationRewardsMsg( /*addr*/ entry.extra.(string), entry.bz)
-		}
+		hub.pushEntry(entry)
+	}
+}
+
+// The push mutex is held while pushing one entry, such that a resumed subscription will not miss any message
+func (hub *Hub) pushEntry(entry MsgToPush) {
+	hub.pushMutex.Lock()
+	defer hub.pushMutex.Unlock()
+	defer hub.subMan.Flush()
+	switch entry.topic {
+	case BlockInfoKey:
+		hub.pushStreams.SetHeight(entry.extra.(int64))
+		hub.PushHeightInfoMsg(entry.bz)
+	case KlineKey:
+		vals := entry.extra.([]string)
+		hub.PushCandleMsg( /*market*/ vals[0], entry.bz /*timespan*/, vals[1])
+	case LockedKey:
+		hub.PushLockedCoinsMsg( /*addr*/ entry.extra.(string), entry.bz)
+	case IncomeKey:
+		hub.PushIncomeMsg( /*receiver*/ entry.extra.(string), entry.bz)
+	case TxKey:
+		hub.PushTxMsg( /*addr*/ entry.extra.(string), entry.bz)
+	case RedelegationKey:
+		hub.PushRedelegationMsg( /*addr*/ entry.extra.(string), entry.bz)
+	case UnbondingKey:
+		hub.PushUnbondingMsg( /*addr*/ entry.extra.(string), entry.bz)
+	case UnlockKey:
+		hub.PushUnlockMsg( /*addr*/ entry.extra.(string), entry.bz)
+	case CommentKey:
+		hub.PushCommentMsg( /*token*/ entry.extra.(string), entry.bz)
+	case CreateMarketInfoKey:
+		vals := entry.extra.([]string)
+		hub.PushMarketInfoMsg( /*stock*/ vals[0] /*money*/, vals[1], entry.bz)
+	case CreateOrderKey:
+		hub.PushCreateOrderInfoMsg( /*addr*/ entry.extra.(string), entry.bz)
+	case FillOrderKey:
+		hub.PushFillOrderInfoMsg( /*addr*/ entry.extra.(string), entry.bz)
+	case DealKey:
+		hub.PushDealInfoMsg( /*market*/ entry.extra.(string), entry.bz)
+	case CancelOrderKey:
+		hub.PushCancelOrderMsg( /*addr*/ entry.extra.(string), entry.bz)
+	case BancorTradeKey:
+		hub.PushBancorTradeInfoMsg( /*addr*/ entry.extra.(string), entry.bz)
+	case BancorDealKey:
+		hub.PushBancorDealMsg( /*market*/ entry.extra.(string), entry.bz)
+	case BancorKey:
+		hub.PushBancorMsg( /*market*/ entry.extra.(string), entry.bz)
+	case SlashKey:
+		hub.PushSlashMsg(entry.bz)
+	case TickerKey:
+		hub.PushTickerMsg(entry.extra) // TODO. will modify param type
+	case XTickerKey:
+		hub.PushXTickerMsg(entry.extra)
+	case DepthFull:
+		hub.PushDepthFullMsg( /*market*/ entry.extra.(string))
+	case DepthKey:
+		hub.PushDepthMsg( /*market*/ entry.bz, entry.extra.(map[string][]byte))
+	case OptionKey:
+		hub.subMan.SetSkipOption(entry.extra.(bool))
+	case ValidatorCommissionKey:
+		hub.PushValidatorCommissionMsg( /*addr*/ entry.extra.(string), entry.bz)
+	case DelegationRewardsKey:
+		hub.PushDelegationRewardsMsg( /*addr*/ entry.extra.(string), entry.bz)
+	case DepthL3Key:
+		hub.PushDepthL3Msg( /*market*/ entry.extra.(string), entry.bz)
+	case OpenOrdersKey:
+		hub.PushOpenOrderMsg( /*addr*/ entry.extra.(string), entry.bz)
+	case AlertKey:
+		hub.PushAlertMsg( /*addr*/ entry.extra.(string), entry.bz)
+	case RebateKey:
+		hub.PushRebateMsg( /*referee*/ entry.extra.(string), entry.bz)
 	}
 }
 
+// Assigns the next sequence number to a message and keeps it for the resumed subscriptions of 'streams'.
+// The following pushes carry this sequence number and the current height
+func (hub *Hub) beginPush(typeKey string, bz []byte, streams ...string) {
+	seq, height := hub.pushStreams.Add(typeKey, bz, streams...)
+	hub.subMan.SetPushMeta(seq, height)
+}
+
 func (hub *Hub) PushHeightInfoMsg(bz []byte) {
+	hub.beginPush(BlockInfoKey, bz, BlockInfoKey)
 	infos := hub.subMan.GetHeightSubscribeInfo()
 	for _, ss := range infos {
 		hub.subMan.PushHeight(ss, bz)
@@ -68,11 +96,9 @@ func (hub *Hub) PushHeightInfoMsg(bz []byte) {
 
 // TODO. Add test
 func (hub *Hub) PushCandleMsg(market string, bz []byte, timeSpan string) {
-	var targets []Subscriber
+	hub.beginPush(KlineKey, bz, getStreamName(KlineKey, market, timeSpan))
 	info := hub.subMan.GetCandleStickSubscribeInfo()
-	if info != nil {
-		targets = info[market]
-	}
+	targets := getMarketSubscribers(info, market)
 	for _, target := range targets {
 		ts, ok := target.Detail().(string)
 		if !ok || ts != timeSpan {
@@ -83,87 +109,55 @@ func (hub *Hub) PushCandleMsg(market string, bz []byte, timeSpan string) {
 }
 
 func (hub *Hub) PushLockedCoinsMsg(addr string, bz []byte) {
+	hub.beginPush(LockedKey, bz, getStreamName(LockedKey, addr), getStreamName(AccountKey, addr))
 	infos := hub.subMan.GetLockedSubscribeInfo()
-	if conns, ok := infos[addr]; ok {
-		for _, c := range conns {
-			hub.subMan.PushLockedSendMsg(c, bz)
-		}
+	for _, c := range hub.getAccountSubscribers(infos, addr) {
+		hub.subMan.PushLockedSendMsg(c, bz)
 	}
 }
 
 func (hub *Hub) PushIncomeMsg(receiver string, bz []byte) {
+	hub.beginPush(IncomeKey, bz, getStreamName(IncomeKey, receiver), getStreamName(AccountKey, receiver))
 	info := hub.subMan.GetIncomeSubscribeInfo()
-	targets, ok := info[receiver]
-	if ok {
-		for _, target := range targets {
-			hub.subMan.PushIncome(target, bz)
-		}
+	for _, target := range hub.getAccountSubscribers(info, receiver) {
+		hub.subMan.PushIncome(target, bz)
 	}
 }
 
 func (hub *Hub) PushTxMsg(addr string, bz []byte) {
+	hub.beginPush(TxKey, bz, getStreamName(TxKey, addr), getStreamName(AccountKey, addr))
 	info := hub.subMan.GetTxSubscribeInfo()
-	targets, ok := info[addr]
-	if ok {
-		for _, target := range targets {
-			hub.subMan.PushTx(target, bz)
-		}
+	for _, target := range hub.getAccountSubscribers(info, addr) {
+		hub.subMan.PushTx(target, bz)
 	}
 }
 
-func (hub *Hub) PushRedelegationMsg(param TimeAndSidWithAddr) {
+func (hub *Hub) PushRedelegationMsg(addr string, bz []byte) {
+	hub.beginPush(RedelegationKey, bz, getStreamName(RedelegationKey, addr), getStreamName(AccountKey, addr))
 	info := hub.subMan.GetRedelegationSubscribeInfo()
-	targets, ok := info[param.addr]
-	if ok {
-		// query the redelegations whose completion time is between current block and last block
-		end := hub.getEventKeyWithSidAndTime(RedelegationByte, param.addr, param.currTime, param.sid)
-		start := hub.getEventKeyWithSidAndTime(RedelegationByte, param.addr, param.lastTime-1, param.sid)
-		hub.dbMutex.RLock()
-		iter := hub.db.ReverseIterator(start, end)
-		defer func() {
-			iter.Close()
-			hub.dbMutex.RUnlock()
-		}()
-		for ; iter.Valid(); iter.Next() {
-			for _, target := range targets {
-				hub.subMan.PushRedelegation(target, iter.Value())
-			}
-		}
+	for _, target := range hub.getAccountSubscribers(info, addr) {
+		hub.subMan.PushRedelegation(target, bz)
 	}
 }
 
-func (hub *Hub) PushUnbondingMsg(param TimeAndSidWithAddr) {
+func (hub *Hub) PushUnbondingMsg(addr string, bz []byte) {
+	hub.beginPush(UnbondingKey, bz, getStreamName(UnbondingKey, addr), getStreamName(AccountKey, addr))
 	info := hub.subMan.GetUnbondingSubscribeInfo()
-	targets, ok := info[param.addr]
-	if ok {
-		// query the unbondings whose completion time is between current block and last block
-		end := hub.getEventKeyWithSidAndTime(UnbondingByte, param.addr, param.currTime, param.sid)
-		start := hub.getEventKeyWithSidAndTime(UnbondingByte, param.addr, param.lastTime-1, param.sid)
-		hub.dbMutex.RLock()
-		iter := hub.db.ReverseIterator(start, end)
-		defer func() {
-			iter.Close()
-			hub.dbMutex.RUnlock()
-		}()
-		for ; iter.Valid(); iter.Next() {
-			for _, target := range targets {
-				hub.subMan.PushUnbonding(target, iter.Value())
-			}
-		}
+	for _, target := range hub.getAccountSubscribers(info, addr) {
+		hub.subMan.PushUnbonding(target, bz)
 	}
 }
 
 func (hub *Hub) PushUnlockMsg(addr string, bz []byte) {
+	hub.beginPush(UnlockKey, bz, getStreamName(UnlockKey, addr), getStreamName(AccountKey, addr))
 	info := hub.subMan.GetUnlockSubscribeInfo()
-	targets, ok := info[addr]
-	if ok {
-		for _, target := range targets {
-			hub.subMan.PushUnlock(target, bz)
-		}
+	for _, target := range hub.getAccountSubscribers(info, addr) {
+		hub.subMan.PushUnlock(target, bz)
 	}
 }
 
 func (hub *Hub) PushCommentMsg(token string, bz []byte) {
+	hub.beginPush(CommentKey, bz, getStreamName(CommentKey, token))
 	info := hub.subMan.GetCommentSubscribeInfo()
 	targets, ok := info[token]
 	if ok {
@@ -173,69 +167,68 @@ func (hub *Hub) PushCommentMsg(token string, bz []byte) {
 	}
 }
 
-func (hub *Hub) PushMarketInfoMsg(market string, bz []byte) {
+// The new market is pushed to the subscribers of its stock, its money and
+// the subscribers without any token (whose key is an empty string)
+func (hub *Hub) PushMarketInfoMsg(stock, money string, bz []byte) {
+	hub.beginPush(CreateMarketInfoKey, bz, CreateMarketInfoKey,
+		getStreamName(CreateMarketInfoKey, stock), getStreamName(CreateMarketInfoKey, money))
 	info := hub.subMan.GetMarketSubscribeInfo()
-	targets, ok := info[market]
-	if ok {
-		for _, target := range targets {
+	pushed := make(map[Subscriber]struct{})
+	for _, token := range []string{"", stock, money} {
+		for _, target := range info[token] {
+			if _, ok := pushed[target]; ok {
+				continue
+			}
+			pushed[target] = struct{}{}
 			hub.subMan.PushCreateMarket(target, bz)
 		}
 	}
 }
 
 func (hub *Hub) PushCreateOrderInfoMsg(addr string, bz []byte) {
+	hub.beginPush(CreateOrderKey, bz, getStreamName(OrderKey, addr), getStreamName(AccountKey, addr))
 	//Push to subscribers
 	info := hub.subMan.GetOrderSubscribeInfo()
-	targets, ok := info[addr]
-	if ok {
-		for _, target := range targets {
-			hub.subMan.PushCreateOrder(target, bz)
-		}
+	for _, target := range hub.getAccountSubscribers(info, addr) {
+		hub.subMan.PushCreateOrder(target, bz)
 	}
 }
 
 func (hub *Hub) PushFillOrderInfoMsg(addr string, bz []byte) {
+	hub.beginPush(FillOrderKey, bz, getStreamName(OrderKey, addr), getStreamName(AccountKey, addr))
 	//Push to subscribers
 	info := hub.subMan.GetOrderSubscribeInfo()
-	targets, ok := info[addr]
-	if ok {
-		for _, target := range targets {
-			hub.subMan.PushFillOrder(target, bz)
-		}
+	for _, target := range hub.getAccountSubscribers(info, addr) {
+		hub.subMan.PushFillOrder(target, bz)
 	}
 }
 
 func (hub *Hub) PushDealInfoMsg(market string, bz []byte) {
+	hub.beginPush(DealKey, bz, getStreamName(DealKey, market))
 	info := hub.subMan.GetDealSubscribeInfo()
-	targets, ok := info[market]
-	if ok {
-		for _, target := range targets {
-			hub.subMan.PushDeal(target, bz)
-		}
+	for _, target := range getMarketSubscribers(info, market) {
+		hub.subMan.PushDeal(target, bz)
 	}
 }
 
 func (hub *Hub) PushCancelOrderMsg(addr string, bz []byte) {
+	hub.beginPush(CancelOrderKey, bz, getStreamName(OrderKey, addr), getStreamName(AccountKey, addr))
 	info := hub.subMan.GetOrderSubscribeInfo()
-	targets, ok := info[addr]
-	if ok {
-		for _, target := range targets {
-			hub.subMan.PushCancelOrder(target, bz)
-		}
+	for _, target := range hub.getAccountSubscribers(info, addr) {
+		hub.subMan.PushCancelOrder(target, bz)
 	}
 }
 
 func (hub *Hub) PushBancorTradeInfoMsg(addr string, bz []byte) {
+	hub.beginPush(BancorTradeKey, bz, getStreamName(BancorTradeKey, addr), getStreamName(AccountKey, addr))
 	info := hub.subMan.GetBancorTradeSubscribeInfo()
-	targets, ok := info[addr]
-	if ok {
-		for _, target := range targets {
-			hub.subMan.PushBancorTrade(target, bz)
-		}
+	for _, target := range hub.getAccountSubscribers(info, addr) {
+		hub.subMan.PushBancorTrade(target, bz)
 	}
 }
 
 func (hub *Hub) PushBancorDealMsg(market string, bz []byte) {
+	hub.beginPush(BancorDealKey, bz, getStreamName(BancorDealKey, market))
 	info := hub.subMan.GetBancorDealSubscribeInfo()
 	targets, ok := info[market]
 	if ok {
@@ -246,6 +239,7 @@ func (hub *Hub) PushBancorDealMsg(market string, bz []byte) {
 }
 
 func (hub *Hub) PushBancorMsg(market string, bz []byte) {
+	hub.beginPush(BancorKey, bz, getStreamName(BancorKey, market))
 	info := hub.subMan.GetBancorInfoSubscribeInfo()
 	targets, ok := info[market]
 	if ok {
@@ -256,6 +250,7 @@ func (hub *Hub) PushBancorMsg(market string, bz []byte) {
 }
 
 func (hub *Hub) PushSlashMsg(bz []byte) {
+	hub.beginPush(SlashKey, bz, SlashKey)
 	infos := hub.subMan.GetSlashSubscribeInfo()
 	for _, ss := range infos {
 		hub.subMan.PushSlash(ss, bz)
@@ -263,13 +258,14 @@ func (hub *Hub) PushSlashMsg(bz []byte) {
 }
 
 func (hub *Hub) PushTickerMsg(msg interface{}) {
+	hub.beginPush(TickerKey, nil)
 	tkMap := msg.(map[string]*Ticker)
 	infos := hub.subMan.GetTickerSubscribeInfo()
 	for _, subscriber := range infos {
 		marketList := subscriber.Detail().(map[string]struct{})
 		tickerList := make([]*Ticker, 0, len(marketList))
-		for market := range marketList {
-			if ticker, ok := tkMap[market]; ok {
+		for market, ticker := range tkMap {
+			if subscribesMarket(marketList, market) {
 				tickerList = append(tickerList, ticker)
 			}
 		}
@@ -279,14 +275,29 @@ func (hub *Hub) PushTickerMsg(msg interface{}) {
 	}
 }
 
+func (hub *Hub) PushXTickerMsg(msg interface{}) {
+	hub.beginPush(XTickerKey, nil)
+	xtkMap := msg.(map[string]*XTicker)
+	infos := hub.subMan.GetXTickerSubscribeInfo()
+	for _, subscriber := range infos {
+		marketList := subscriber.Detail().(map[string]struct{})
+		xtickerList := make([]*XTicker, 0, len(marketList))
+		for market, xticker := range xtkMap {
+			if subscribesMarket(marketList, market) {
+				xtickerList = append(xtickerList, xticker)
+			}
+		}
+		if len(xtickerList) != 0 {
+			hub.subMan.PushXTicker(subscriber, xtickerList)
+		}
+	}
+}
+
 // TODO, will check topic
 func (hub *Hub) PushDepthFullMsg(market string) {
+	hub.beginPush(DepthFull, nil)
 	info := hub.subMan.GetDepthSubscribeInfo()
-	targets, ok := info[market]
-	if !ok {
-		return
-	}
-	for _, target := range targets {
+	for _, target := range getMarketSubscribers(info, market) {
 		level := target.Detail().(string)
 		if bz, err := hub.getDepthFullData(market, level, MaxCount); err == nil {
 			hub.subMan.PushDepthFullMsg(target, bz)
@@ -301,38 +312,40 @@ func (hub *Hub) getDepthFullData(market string, level string, count int) ([]byte
 		bz  []byte
 		err error
 	)
-	sell, buy := hub.QueryDepth(market, count)
+	if !hub.HasMarket(market) {
+		return encodeDepthData(market, 0, 0, nil, nil)
+	}
+	sell, buy, height, checksum := hub.managersMap[market].GetDepthWithChecksum(level, limitCount(count))
 	if level == "all" {
-		bz, err = encodeDepthData(market, buy, sell)
+		bz, err = encodeDepthData(market, height, checksum, buy, sell)
 		return bz, err
 	}
 
 	buyLevel := mergePrice(buy, level, true)
 	sellLevel := mergePrice(sell, level, false)
-	bz, err = encodeDepthLevel(market, buyLevel, sellLevel)
+	bz, err = encodeDepthLevel(market, height, checksum, buyLevel, sellLevel)
 	return bz, err
 }
 
 // TODO, will replace string with interface
 func (hub *Hub) PushDepthMsg(data []byte, levelsData map[string][]byte) {
+	hub.beginPush(DepthKey, nil)
 	market := string(data)
 	info := hub.subMan.GetDepthSubscribeInfo()
-	targets, ok := info[market]
-	if ok {
-		for _, target := range targets {
-			level := target.Detail().(string)
-			if len(levelsData[level]) != 0 {
-				if level == "all" {
-					hub.subMan.PushDepthWithChange(target, levelsData[level])
-				} else {
-					hub.subMan.PushDepthWithDelta(target, levelsData[level])
-				}
+	for _, target := range getMarketSubscribers(info, market) {
+		level := target.Detail().(string)
+		if len(levelsData[level]) != 0 {
+			if level == "all" {
+				hub.subMan.PushDepthWithChange(target, levelsData[level])
+			} else {
+				hub.subMan.PushDepthWithDelta(target, levelsData[level])
 			}
 		}
 	}
 }
 
 func (hub *Hub) PushValidatorCommissionMsg(addr string, data []byte) {
+	hub.beginPush(ValidatorCommissionKey, data, getStreamName(ValidatorCommissionKey, addr))
 	info := hub.subMan.GetValidatorCommissionInfo()
 	targets, ok := info[addr]
 	if !ok {
@@ -345,6 +358,7 @@ func (hub *Hub) PushValidatorCommissionMsg(addr string, data []byte) {
 }
 
 func (hub *Hub) PushDelegationRewardsMsg(addr string, data []byte) {
+	hub.beginPush(DelegationRewardsKey, data, getStreamName(DelegationRewardsKey, addr))
 	info := hub.subMan.GetDelegationRewards()
 	targets, ok := info[addr]
 	if !ok {
@@ -355,3 +369,45 @@ func (hub *Hub) PushDelegationRewardsMsg(addr string, data []byte) {
 		hub.subMan.PushDelegationRewards(target, data)
 	}
 }
+
+func (hub *Hub) PushOpenOrderMsg(addr string, data []byte) {
+	hub.beginPush(OpenOrdersKey, data, getStreamName(OpenOrdersKey, addr))
+	info := hub.subMan.GetOpenOrdersSubscribeInfo()
+	targets, ok := info[addr]
+	if !ok {
+		return
+	}
+
+	for _, target := range targets {
+		hub.subMan.PushOpenOrder(target, data)
+	}
+}
+
+func (hub *Hub) PushDepthL3Msg(market string, data []byte) {
+	hub.beginPush(DepthL3Key, data, getStreamName(DepthL3Key, market))
+	info := hub.subMan.GetDepthL3SubscribeInfo()
+	targets, ok := info[market]
+	if !ok {
+		return
+	}
+
+	for _, target := range targets {
+		hub.subMan.PushDepthL3(target, data)
+	}
+}
+
+func (hub *Hub) PushAlertMsg(addr string, data []byte) {
+	hub.beginPush(AlertKey, data, getStreamName(AlertKey, addr))
+	info := hub.subMan.GetAlertSubscribeInfo()
+	for _, target := range info[addr] {
+		hub.subMan.PushAlert(target, data)
+	}
+}
+
+func (hub *Hub) PushRebateMsg(referee string, bz []byte) {
+	hub.beginPush(RebateKey, bz, getStreamName(RebateKey, referee))
+	info := hub.subMan.GetRebateSubscribeInfo()
+	for _, target := range info[referee] {
+		hub.subMan.PushRebate(target, bz)
+	}
+}
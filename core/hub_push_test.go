@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/stretchr/testify/require"
 	dbm "github.com/tendermint/tm-db"
 )
@@ -95,16 +96,40 @@ func TestHub_PushBancorDealMsg(t *testing.T) {
 }
 
 func TestHub_PushMarketInfoMsg(t *testing.T) {
+	// the subscribers are registered before the hub pushes, as the map is read by the push goroutine.
+	// The subscriber without token receives all the new markets, and only once if it also subscribes a token
+	all, abc, xyz := &PlainSubscriber{1}, &PlainSubscriber{2}, &PlainSubscriber{3}
 	subMan := &MocSubscribeManager{}
-	subMan.MarketSubscribeInfo = make(map[string][]Subscriber)
-	subMan.MarketSubscribeInfo["abc/cet"] = make([]Subscriber, 1)
-	subMan.MarketSubscribeInfo["abc/cet"][0] = &PlainSubscriber{1}
+	subMan.MarketSubscribeInfo = map[string][]Subscriber{
+		"":    {all},
+		"abc": {abc, all},
+		"xyz": {xyz},
+	}
 	hub := getHub(t, subMan)
 	defer os.RemoveAll("tmp")
 
 	key := "create_market_info"
 	val := `{"stock":"abc","money":"cet","creator":"cettest1kwzuytmjmkd7x045s5r5k92hwrfgn2vhfn77wx","price_precision":3,"order_precision":0}`
-	consumeMsgAndCompareRet(t, hub, subMan, key, val)
+	hub.ConsumeMessage(key, []byte(val))
+	fillCommitInfo(hub)
+	time.Sleep(time.Millisecond)
+	subMan.CompareResult(t, fmt.Sprintf("1: %s\n2: %s", val, val))
+	subMan.ClearPushList()
+
+	val2 := `{"stock":"xyz","money":"cet","creator":"cettest1kwzuytmjmkd7x045s5r5k92hwrfgn2vhfn77wx","price_precision":3,"order_precision":0}`
+	hub.ConsumeMessage(key, []byte(val2))
+	fillCommitInfo(hub)
+	time.Sleep(time.Millisecond)
+	subMan.CompareResult(t, fmt.Sprintf("1: %s\n3: %s", val2, val2))
+
+	data, _ := hub.QueryMarkets("", QueryRange{Time: hub.currBlockTime.Unix() + 1, Sid: math.MaxInt64, Count: 10})
+	require.Equal(t, val2+"\n"+val, toStr(data))
+	data, _ = hub.QueryMarkets("abc", QueryRange{Time: hub.currBlockTime.Unix() + 1, Sid: math.MaxInt64, Count: 10})
+	require.Equal(t, val, toStr(data))
+	data, _ = hub.QueryMarkets("cet", QueryRange{Time: hub.currBlockTime.Unix() + 1, Sid: math.MaxInt64, Count: 10})
+	require.Equal(t, 2, len(data))
+	data, _ = hub.QueryMarkets("btc", QueryRange{Time: hub.currBlockTime.Unix() + 1, Sid: math.MaxInt64, Count: 10})
+	require.Equal(t, 0, len(data))
 }
 
 func TestHub_PushCreateOrderInfoMsg(t *testing.T) {
@@ -278,3 +303,134 @@ func TestHub_PushCommentMsg(t *testing.T) {
 	val := `{"id":0,"height":10,"sender":"coinex1py9lss4nr0lm6ep4uwk3tclacw42a5nx0ra92r","token":"cet","donation":200000000,"title":"I-Love-CET","content":"cet-to-the-moon","content_type":3,"references":null}`
 	consumeMsgAndCompareRet(t, hub, subMan, key, val)
 }
+
+func TestHub_PushDelegationRewardsMsg(t *testing.T) {
+	addr := "coinexvaloper1yj66ancalgk7dz3383s6cyvdd0nd93q0sekwpv"
+	subMan := &MocSubscribeManager{}
+	subMan.RewardsSubscribeInfo = make(map[string][]Subscriber)
+	subMan.RewardsSubscribeInfo[addr] = make([]Subscriber, 1)
+	subMan.RewardsSubscribeInfo[addr][0] = &PlainSubscriber{1}
+	hub := getHub(t, subMan)
+	defer os.RemoveAll("tmp")
+
+	key := "delegator_rewards"
+	val := `{"validator":"coinexvaloper1yj66ancalgk7dz3383s6cyvdd0nd93q0sekwpv","rewards":"1000000cet"}`
+	consumeMsgAndCompareRet(t, hub, subMan, key, val)
+
+	data, timesid := hub.QueryDelegatorRewards(addr, QueryRange{Time: hub.currBlockTime.Unix() + 1, Sid: math.MaxInt64, Count: 10})
+	require.Equal(t, 1, len(data))
+	require.Equal(t, 2, len(timesid))
+	require.Equal(t, val, string(data[0]))
+}
+
+func TestHub_PushValidatorCommissionMsg(t *testing.T) {
+	addr := "coinexvaloper1yj66ancalgk7dz3383s6cyvdd0nd93q0sekwpv"
+	subMan := &MocSubscribeManager{}
+	subMan.CommissionSubscribeInfo = make(map[string][]Subscriber)
+	subMan.CommissionSubscribeInfo[addr] = make([]Subscriber, 1)
+	subMan.CommissionSubscribeInfo[addr][0] = &PlainSubscriber{1}
+	hub := getHub(t, subMan)
+	defer os.RemoveAll("tmp")
+
+	key := "validator_commission"
+	val := `{"validator":"coinexvaloper1yj66ancalgk7dz3383s6cyvdd0nd93q0sekwpv","commission":"1000000cet"}`
+	consumeMsgAndCompareRet(t, hub, subMan, key, val)
+
+	data, timesid := hub.QueryValidatorCommission(addr, QueryRange{Time: hub.currBlockTime.Unix() + 1, Sid: math.MaxInt64, Count: 10})
+	require.Equal(t, 1, len(data))
+	require.Equal(t, 2, len(timesid))
+	require.Equal(t, val, string(data[0]))
+}
+
+func TestHub_PushOpenOrderMsg(t *testing.T) {
+	addr := "coinex1ughhs0eyames355v4tzq5nx2g806p55rna0d2x"
+	subMan := &MocSubscribeManager{}
+	subMan.OpenOrdersSubscribeInfo = make(map[string][]Subscriber)
+	subMan.OpenOrdersSubscribeInfo[addr] = make([]Subscriber, 1)
+	subMan.OpenOrdersSubscribeInfo[addr][0] = &PlainSubscriber{1}
+	hub := getHub(t, subMan)
+	defer os.RemoveAll("tmp")
+
+	val := `{"order_id":"coinex1ughhs0eyames355v4tzq5nx2g806p55rna0d2x-9","sender":"coinex1ughhs0eyames355v4tzq5nx2g806p55rna0d2x","trading_pair":"abc/cet","order_type":2,"price":"1.000000000000000000","quantity":100,"side":1,"time_in_force":3,"height":10,"freeze":100}`
+	hub.ConsumeMessage("create_order_info", []byte(val))
+	fillCommitInfo(hub)
+	time.Sleep(time.Millisecond)
+	expected := `{"order_id":"coinex1ughhs0eyames355v4tzq5nx2g806p55rna0d2x-9","sender":"coinex1ughhs0eyames355v4tzq5nx2g806p55rna0d2x","trading_pair":"abc/cet","order_type":2,"price":"1.000000000000000000","quantity":100,"side":1,"time_in_force":3,"height":10,"freeze":100,"left_stock":100,"deal_stock":0,"deal_money":0,"status":"open"}`
+	subMan.CompareResult(t, fmt.Sprintf("1: %s", expected))
+	subMan.ClearPushList()
+
+	val = `{"order_id":"coinex1ughhs0eyames355v4tzq5nx2g806p55rna0d2x-9","trading_pair":"abc/cet","height":11,"side":1,"price":"1.000000000000000000","left_stock":0,"freeze":0,"deal_stock":100,"deal_money":100,"curr_stock":100,"curr_money":100,"fill_price":"1.000000000000000000"}`
+	hub.ConsumeMessage("fill_order_info", []byte(val))
+	fillCommitInfo(hub)
+	time.Sleep(time.Millisecond)
+	expected = `{"order_id":"coinex1ughhs0eyames355v4tzq5nx2g806p55rna0d2x-9","sender":"coinex1ughhs0eyames355v4tzq5nx2g806p55rna0d2x","trading_pair":"abc/cet","order_type":2,"price":"1.000000000000000000","quantity":100,"side":1,"time_in_force":3,"height":10,"freeze":0,"left_stock":0,"deal_stock":100,"deal_money":100,"status":"filled"}`
+	subMan.CompareResult(t, fmt.Sprintf("1: %s", expected))
+	require.Equal(t, 0, len(hub.QueryOpenOrders(addr, "")))
+}
+
+func TestHub_PushDepthL3Msg(t *testing.T) {
+	subMan := &MocSubscribeManager{}
+	subMan.DepthL3SubscribeInfo = make(map[string][]Subscriber)
+	subMan.DepthL3SubscribeInfo["abc/cet"] = make([]Subscriber, 1)
+	subMan.DepthL3SubscribeInfo["abc/cet"][0] = &PlainSubscriber{1}
+	hub := getHub(t, subMan)
+	defer os.RemoveAll("tmp")
+
+	val := `{"order_id":"coinex1ughhs0eyames355v4tzq5nx2g806p55rna0d2x-9","sender":"coinex1ughhs0eyames355v4tzq5nx2g806p55rna0d2x","trading_pair":"abc/cet","order_type":2,"price":"1.000000000000000000","quantity":100,"side":1,"time_in_force":3,"height":10,"freeze":100}`
+	hub.ConsumeMessage("create_order_info", []byte(val))
+	fillCommitInfo(hub)
+	time.Sleep(time.Millisecond)
+	expected := `{"trading_pair":"abc/cet","height":5,"events":[{"action":"add","order_id":"coinex1ughhs0eyames355v4tzq5nx2g806p55rna0d2x-9","side":1,"price":"1.000000000000000000","amount":100}]}`
+	subMan.CompareResult(t, fmt.Sprintf("1: %s", expected))
+
+	snapshot := hub.QueryOrderBookL3("abc/cet", 10)
+	require.Equal(t, 1, len(snapshot.Bids))
+	require.Equal(t, 0, len(snapshot.Asks))
+	require.EqualValues(t, 5, snapshot.Height)
+	require.Nil(t, hub.QueryOrderBookL3("xyz/cet", 10))
+
+	// the snapshot queried in the middle of a block is the book of the last committed block
+	hub.currBlockHeight = 6
+	hub.handleFillOrderInfo([]byte(`{"order_id":"coinex1ughhs0eyames355v4tzq5nx2g806p55rna0d2x-9","trading_pair":"abc/cet","height":6,"side":1,"price":"1.000000000000000000","left_stock":40,"freeze":40,"deal_stock":60,"deal_money":60,"curr_stock":60,"curr_money":60,"fill_price":"1.000000000000000000"}`))
+	snapshot = hub.QueryOrderBookL3("abc/cet", 10)
+	require.EqualValues(t, 5, snapshot.Height)
+	require.EqualValues(t, 100, snapshot.Bids[0].Amount)
+	hub.commitForL3()
+	snapshot = hub.QueryOrderBookL3("abc/cet", 10)
+	require.EqualValues(t, 6, snapshot.Height)
+	require.EqualValues(t, 40, snapshot.Bids[0].Amount)
+}
+
+func TestHub_PushXTickerMsg(t *testing.T) {
+	subMan := &MocSubscribeManager{}
+	subMan.XTickerSubscribeInfo = make([]Subscriber, 1)
+	subMan.XTickerSubscribeInfo[0] = NewTickerSubscriber(1, map[string]struct{}{"abc/cet": {}})
+	hub := getHub(t, subMan)
+	defer os.RemoveAll("tmp")
+	hub.AddMarket("abc/cet")
+
+	fill := `{"order_id":"coinex1ughhs0eyames355v4tzq5nx2g806p55rna0d2x-9","trading_pair":"abc/cet","height":6,"side":2,"price":"1.000000000000000000","left_stock":0,"freeze":0,"deal_stock":%d,"deal_money":%d,"curr_stock":%d,"curr_money":%d,"fill_price":"%s"}`
+	hub.ConsumeMessage("height_info", []byte(`{"chain_id":"coinex-test","height":6,"timestamp":1563178030}`))
+	hub.ConsumeMessage("fill_order_info", []byte(fmt.Sprintf(fill, 100, 200, 100, 200, "2.000000000000000000")))
+	hub.ConsumeMessage("fill_order_info", []byte(fmt.Sprintf(fill, 150, 300, 50, 100, "2.000000000000000000")))
+	fillCommitInfo(hub)
+	hub.ConsumeMessage("height_info", []byte(`{"chain_id":"coinex-test","height":7,"timestamp":1563178090}`))
+	hub.ConsumeMessage("fill_order_info", []byte(fmt.Sprintf(fill, 10, 30, 10, 30, "3.000000000000000000")))
+	fillCommitInfo(hub)
+	hub.ConsumeMessage("height_info", []byte(`{"chain_id":"coinex-test","height":8,"timestamp":1563178150}`))
+	fillCommitInfo(hub)
+	time.Sleep(10 * time.Millisecond) // three blocks are pushed
+
+	expected1 := `[{"market":"abc/cet","new":"2.000000000000000000","old":"0","minute_in_day":487,"high":"2.000000000000000000","low":"2.000000000000000000","volume":"150"}]`
+	expected2 := `[{"market":"abc/cet","new":"3.000000000000000000","old":"2.000000000000000000","minute_in_day":488,"high":"3.000000000000000000","low":"2.000000000000000000","volume":"160"}]`
+	require.Equal(t, []*XTicker{{
+		Market:            "abc/cet",
+		NewPrice:          sdk.NewDec(3),
+		OldPriceOneDayAgo: sdk.NewDec(2),
+		MinuteInDay:       488,
+		HighPrice:         sdk.NewDec(3),
+		LowPrice:          sdk.NewDec(2),
+		TotalDeal:         sdk.NewInt(160),
+	}}, hub.QueryXTickers([]string{"abc/cet", "xyz/cet"}))
+	subMan.CompareResult(t, fmt.Sprintf("1: %s\n1: %s", expected1, expected2))
+}
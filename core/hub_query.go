@@ -1,12 +1,15 @@
 package core
 
 import (
+	"bytes"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"math"
 	"strings"
 	"sync/atomic"
+
+	dbm "github.com/tendermint/tm-db"
 )
 
 //============================================================
@@ -26,6 +29,20 @@ func (hub *Hub) QueryTickers(marketList []string) []*Ticker {
 	return tickerList
 }
 
+func (hub *Hub) QueryXTickers(marketList []string) []*XTicker {
+	xtickerList := make([]*XTicker, 0, len(marketList))
+	hub.tickerMapMutex.RLock()
+	atomic.AddInt64(&hub.tickerMapLockCount, 1)
+	for _, market := range marketList {
+		xticker, ok := hub.xtickerMap[market]
+		if ok {
+			xtickerList = append(xtickerList, xticker)
+		}
+	}
+	hub.tickerMapMutex.RUnlock()
+	return xtickerList
+}
+
 func (hub *Hub) QueryLatestHeight() int64 {
 	bz := hub.db.Get([]byte{LatestHeightByte})
 	if len(bz) == 0 {
@@ -88,13 +105,31 @@ func (hub *Hub) QueryDepth(market string, count int) (sell []*PricePoint, buy []
 	return
 }
 
-func (hub *Hub) QueryCandleStick(market string, timespan byte, time int64, sid int64, count int) []json.RawMessage {
+// Returns nil if the market does not exist or it is a bancor market.
+// The snapshot is at the last committed block, whose changes are pushed with its height
+func (hub *Hub) QueryOrderBookL3(market string, count int) *L3Snapshot {
 	count = limitCount(count)
+	if !hub.HasMarket(market) {
+		return nil
+	}
+	book := hub.managersMap[market].l3
+	if book == nil {
+		return nil
+	}
+	bids, asks, height := book.GetOrders(count)
+	return &L3Snapshot{
+		TradingPair: market,
+		Height:      height,
+		Bids:        bids,
+		Asks:        asks,
+	}
+}
+
+func (hub *Hub) QueryCandleStick(market string, timespan byte, qr QueryRange) []json.RawMessage {
+	count := limitCount(qr.Count)
 	data := make([]json.RawMessage, 0, count)
-	end := getCandleStickEndKey(market, timespan, time, sid)
-	start := getCandleStickStartKey(market, timespan)
 	hub.dbMutex.RLock()
-	iter := hub.db.ReverseIterator(start, end)
+	iter := qr.iterator(hub.db, CandleStickByte, getCandleStickBytes(market, timespan))
 	defer func() {
 		iter.Close()
 		hub.dbMutex.RUnlock()
@@ -127,87 +162,182 @@ func (hub *Hub) QueryTxByHashID(hexHashID string) json.RawMessage {
 
 //=========
 
-func (hub *Hub) QueryOrder(account string, time int64, sid int64, count int) (
+func (hub *Hub) QueryOrder(account string, qr QueryRange) (
 	data []json.RawMessage, tags []byte, timesid []int64) {
-	return hub.query(false, OrderByte, []byte(account), time, sid, count, nil)
+	return hub.query(false, OrderByte, []byte(account), qr, nil)
+}
+
+// Get the create/fill/cancel records of an order through the order_id index, nil is returned if nothing is found
+func (hub *Hub) QueryOrderLifecycle(orderID string) *OrderLifecycle {
+	res := &OrderLifecycle{
+		OrderID:    orderID,
+		Status:     OrderStatusOpen,
+		TxHashes:   make([]string, 0, 2),
+		FillOrders: make([]json.RawMessage, 0, 4),
+	}
+	start := getStartKeyFromBytes(OrderIDByte, []byte(orderID))
+	end := getEndKeyFromBytes(OrderIDByte, []byte(orderID), math.MaxInt64, math.MaxInt64)
+	hub.dbMutex.RLock()
+	iter := hub.db.Iterator(start, end)
+	defer func() {
+		iter.Close()
+		hub.dbMutex.RUnlock()
+	}()
+	found := false
+	for ; iter.Valid(); iter.Next() {
+		entry := hub.db.Get(iter.Value())
+		if len(entry) == 0 { // the record has been pruned
+			continue
+		}
+		found = true
+		iKey := iter.Key()
+		switch iKey[len(iKey)-1] {
+		case CreateOrderEndByte:
+			var v CreateOrderInfo
+			if err := unmarshalAndLogErr(entry, &v); err != nil {
+				continue
+			}
+			res.CreateOrder = entry
+			if len(v.TxHash) != 0 {
+				res.TxHashes = append(res.TxHashes, v.TxHash)
+			}
+		case FillOrderEndByte:
+			var v FillOrderInfo
+			if err := unmarshalAndLogErr(entry, &v); err != nil {
+				continue
+			}
+			res.FillOrders = append(res.FillOrders, entry)
+			res.DealStock, res.DealMoney = v.DealStock, v.DealMoney
+			res.Status = OrderStatusPartiallyFilled
+			if v.LeftStock == 0 {
+				res.Status = OrderStatusFilled
+			}
+		case CancelOrderEndByte:
+			var v CancelOrderInfo
+			if err := unmarshalAndLogErr(entry, &v); err != nil {
+				continue
+			}
+			res.CancelOrder = entry
+			res.DealStock, res.DealMoney = v.DealStock, v.DealMoney
+			res.Status = OrderStatusCancelled
+			if v.LeftStock == 0 {
+				res.Status = OrderStatusFilled
+			}
+			if len(v.TxHash) != 0 {
+				res.TxHashes = append(res.TxHashes, v.TxHash)
+			}
+		}
+	}
+	if !found {
+		return nil
+	}
+	return res
 }
 
-func (hub *Hub) QueryBancorDeal(market string, time int64, sid int64, count int) (data []json.RawMessage, timesid []int64) {
-	data, _, timesid = hub.query(false, BancorDealByte, []byte(market), time, sid, count, nil)
+func (hub *Hub) QueryOpenOrders(account, market string) []*OpenOrder {
+	return hub.openOrderMan.GetOpenOrders(account, market)
+}
+
+func (hub *Hub) QueryBancorDeal(market string, qr QueryRange) (data []json.RawMessage, timesid []int64) {
+	data, _, timesid = hub.query(false, BancorDealByte, []byte(market), qr, nil)
 	return
 }
 
-func (hub *Hub) QueryDeal(market string, time int64, sid int64, count int) (data []json.RawMessage, timesid []int64) {
-	data, _, timesid = hub.query(false, DealByte, []byte(market), time, sid, count, nil)
+func (hub *Hub) QueryDeal(market string, qr QueryRange) (data []json.RawMessage, timesid []int64) {
+	data, _, timesid = hub.query(false, DealByte, []byte(market), qr, nil)
 	return
 }
 
-func (hub *Hub) QueryBancorInfo(market string, time int64, sid int64, count int) (data []json.RawMessage, timesid []int64) {
-	data, _, timesid = hub.query(false, BancorInfoByte, []byte(market), time, sid, count, nil)
+func (hub *Hub) QueryBancorInfo(market string, qr QueryRange) (data []json.RawMessage, timesid []int64) {
+	data, _, timesid = hub.query(false, BancorInfoByte, []byte(market), qr, nil)
 	return
 }
 
-func (hub *Hub) QueryBancorTrade(account string, time int64, sid int64, count int) (data []json.RawMessage, timesid []int64) {
-	data, _, timesid = hub.query(false, BancorTradeByte, []byte(account), time, sid, count, nil)
+func (hub *Hub) QueryBancorTrade(account string, qr QueryRange) (data []json.RawMessage, timesid []int64) {
+	data, _, timesid = hub.query(false, BancorTradeByte, []byte(account), qr, nil)
+	return
+}
+
+func (hub *Hub) QueryRedelegation(account string, qr QueryRange) (data []json.RawMessage, timesid []int64) {
+	data, _, timesid = hub.query(false, RedelegationByte, []byte(account), qr, nil)
+	return
+}
+func (hub *Hub) QueryUnbonding(account string, qr QueryRange) (data []json.RawMessage, timesid []int64) {
+	data, _, timesid = hub.query(false, UnbondingByte, []byte(account), qr, nil)
+	return
+}
+func (hub *Hub) QueryUnlock(account string, qr QueryRange) (data []json.RawMessage, timesid []int64) {
+	data, _, timesid = hub.query(false, UnlockByte, []byte(account), qr, nil)
 	return
 }
 
-func (hub *Hub) QueryRedelegation(account string, time int64, sid int64, count int) (data []json.RawMessage, timesid []int64) {
-	data, _, timesid = hub.query(false, RedelegationByte, []byte(account), time, sid, count, nil)
+func (hub *Hub) QueryIncome(account string, qr QueryRange) (data []json.RawMessage, timesid []int64) {
+	data, _, timesid = hub.query(true, IncomeByte, []byte(account), qr, nil)
 	return
 }
-func (hub *Hub) QueryUnbonding(account string, time int64, sid int64, count int) (data []json.RawMessage, timesid []int64) {
-	data, _, timesid = hub.query(false, UnbondingByte, []byte(account), time, sid, count, nil)
+
+func (hub *Hub) QueryTx(account string, qr QueryRange) (data []json.RawMessage, timesid []int64) {
+	data, _, timesid = hub.query(true, TxByte, []byte(account), qr, nil)
 	return
 }
-func (hub *Hub) QueryUnlock(account string, time int64, sid int64, count int) (data []json.RawMessage, timesid []int64) {
-	data, _, timesid = hub.query(false, UnlockByte, []byte(account), time, sid, count, nil)
+
+func (hub *Hub) QueryLocked(account string, qr QueryRange) (data []json.RawMessage, timesid []int64) {
+	data, _, timesid = hub.query(false, LockedByte, []byte(account), qr, nil)
 	return
 }
 
-func (hub *Hub) QueryIncome(account string, time int64, sid int64, count int) (data []json.RawMessage, timesid []int64) {
-	data, _, timesid = hub.query(true, IncomeByte, []byte(account), time, sid, count, nil)
+func (hub *Hub) QueryComment(token string, qr QueryRange) (data []json.RawMessage, timesid []int64) {
+	data, _, timesid = hub.query(false, CommentByte, []byte(token), qr, nil)
 	return
 }
 
-func (hub *Hub) QueryTx(account string, time int64, sid int64, count int) (data []json.RawMessage, timesid []int64) {
-	data, _, timesid = hub.query(true, TxByte, []byte(account), time, sid, count, nil)
+func (hub *Hub) QuerySlash(qr QueryRange) (data []json.RawMessage, timesid []int64) {
+	data, _, timesid = hub.query(false, SlashByte, []byte{}, qr, nil)
 	return
 }
 
-func (hub *Hub) QueryLocked(account string, time int64, sid int64, count int) (data []json.RawMessage, timesid []int64) {
-	data, _, timesid = hub.query(false, LockedByte, []byte(account), time, sid, count, nil)
+func (hub *Hub) QueryDonation(qr QueryRange) (data []json.RawMessage, timesid []int64) {
+	data, _, timesid = hub.query(false, DonationByte, []byte{}, qr, nil)
 	return
 }
 
-func (hub *Hub) QueryComment(token string, time int64, sid int64, count int) (data []json.RawMessage, timesid []int64) {
-	data, _, timesid = hub.query(false, CommentByte, []byte(token), time, sid, count, nil)
+func (hub *Hub) QueryDelist(market string, qr QueryRange) (data []json.RawMessage, timesid []int64) {
+	data, _, timesid = hub.query(false, DelistByte, []byte(market), qr, nil)
 	return
 }
 
-func (hub *Hub) QuerySlash(time int64, sid int64, count int) (data []json.RawMessage, timesid []int64) {
-	data, _, timesid = hub.query(false, SlashByte, []byte{}, time, sid, count, nil)
+func (hub *Hub) QueryMarkets(token string, qr QueryRange) (data []json.RawMessage, timesid []int64) {
+	if token == "" { // no token-based-filtering
+		data, _, timesid = hub.query(false, CreateMarketByte, []byte{}, qr, nil)
+		return
+	}
+	data, _, timesid = hub.query(false, CreateMarketByte, []byte{},
+		qr, func(tag byte, entry []byte) bool {
+			s1 := fmt.Sprintf("\"stock\":\"%s\"", token)
+			s2 := fmt.Sprintf("\"money\":\"%s\"", token)
+			return strings.Index(string(entry), s1) > 0 || strings.Index(string(entry), s2) > 0
+		})
 	return
 }
 
-func (hub *Hub) QueryDonation(time int64, sid int64, count int) (data []json.RawMessage, timesid []int64) {
-	data, _, timesid = hub.query(false, DonationByte, []byte{}, time, sid, count, nil)
+func (hub *Hub) QueryDelegatorRewards(account string, qr QueryRange) (data []json.RawMessage, timesid []int64) {
+	data, _, timesid = hub.query(false, DelegatorRewardsByte, []byte(account), qr, nil)
 	return
 }
 
-func (hub *Hub) QueryDelist(market string, time int64, sid int64, count int) (data []json.RawMessage, timesid []int64) {
-	data, _, timesid = hub.query(false, DelistByte, []byte(market), time, sid, count, nil)
+func (hub *Hub) QueryValidatorCommission(account string, qr QueryRange) (data []json.RawMessage, timesid []int64) {
+	data, _, timesid = hub.query(false, ValidatorCommissionByte, []byte(account), qr, nil)
 	return
 }
 
-func (hub *Hub) QueryDelists(time int64, sid int64, count int) (data []json.RawMessage, timesid []int64) {
-	data, _, timesid = hub.query(false, DelistsByte, []byte{}, time, sid, count, nil)
+func (hub *Hub) QueryDelists(qr QueryRange) (data []json.RawMessage, timesid []int64) {
+	data, _, timesid = hub.query(false, DelistsByte, []byte{}, qr, nil)
 	return
 }
 
 // --------------
 
-func (hub *Hub) QueryOrderAboutToken(tag, token, account string, time int64, sid int64, count int) (
+func (hub *Hub) QueryOrderAboutToken(tag, token, account string, qr QueryRange) (
 	data []json.RawMessage, tags []byte, timesid []int64) {
 	firstByte := OrderByte
 	if tag == CreateOrderStr {
@@ -218,9 +348,9 @@ func (hub *Hub) QueryOrderAboutToken(tag, token, account string, time int64, sid
 		firstByte = CancelOrderOnlyByte
 	}
 	if token == "" { // no token-based-filtering
-		return hub.query(false, firstByte, []byte(account), time, sid, count, nil)
+		return hub.query(false, firstByte, []byte(account), qr, nil)
 	}
-	return hub.query(false, firstByte, []byte(account), time, sid, count, func(tag byte, entry []byte) bool {
+	return hub.query(false, firstByte, []byte(account), qr, func(tag byte, entry []byte) bool {
 		s1 := fmt.Sprintf("/%s\",\"height\":", token)
 		s2 := fmt.Sprintf("\"trading_pair\":\"%s/", token)
 		if tag == CreateOrderEndByte {
@@ -231,14 +361,14 @@ func (hub *Hub) QueryOrderAboutToken(tag, token, account string, time int64, sid
 
 }
 
-func (hub *Hub) QueryLockedAboutToken(token, account string, time int64, sid int64, count int) (
+func (hub *Hub) QueryLockedAboutToken(token, account string, qr QueryRange) (
 	data []json.RawMessage, timesid []int64) {
 	if token == "" { // no token-based-filtering
-		data, _, timesid = hub.query(false, LockedByte, []byte(account), time, sid, count, nil)
+		data, _, timesid = hub.query(false, LockedByte, []byte(account), qr, nil)
 		return
 	}
 	data, _, timesid = hub.query(false, LockedByte, []byte(account),
-		time, sid, count, func(tag byte, entry []byte) bool {
+		qr, func(tag byte, entry []byte) bool {
 			s := fmt.Sprintf("\"denom\":\"%s\",\"amount\":", token)
 			return strings.Index(string(entry), s) > 0
 		})
@@ -246,14 +376,14 @@ func (hub *Hub) QueryLockedAboutToken(token, account string, time int64, sid int
 
 }
 
-func (hub *Hub) QueryBancorTradeAboutToken(token, account string, time int64, sid int64, count int) (
+func (hub *Hub) QueryBancorTradeAboutToken(token, account string, qr QueryRange) (
 	data []json.RawMessage, timesid []int64) {
 	if token == "" { // no token-based-filtering
-		data, _, timesid = hub.query(false, BancorTradeByte, []byte(account), time, sid, count, nil)
+		data, _, timesid = hub.query(false, BancorTradeByte, []byte(account), qr, nil)
 		return
 	}
-	data, _, timesid = hub.query(false, BancorTradeByte, []byte(account), time, sid,
-		count, func(tag byte, entry []byte) bool {
+	data, _, timesid = hub.query(false, BancorTradeByte, []byte(account),
+		qr, func(tag byte, entry []byte) bool {
 			s1 := fmt.Sprintf("\"money\":\"%s\"", token)
 			s2 := fmt.Sprintf("\"stock\":\"%s\"", token)
 			return strings.Index(string(entry), s1) > 0 || strings.Index(string(entry), s2) > 0
@@ -262,13 +392,13 @@ func (hub *Hub) QueryBancorTradeAboutToken(token, account string, time int64, si
 
 }
 
-func (hub *Hub) QueryUnlockAboutToken(token, account string, time int64, sid int64, count int) (
+func (hub *Hub) QueryUnlockAboutToken(token, account string, qr QueryRange) (
 	data []json.RawMessage, timesid []int64) {
 	if token == "" { // no token-based-filtering
-		data, _, timesid = hub.query(false, UnlockByte, []byte(account), time, sid, count, nil)
+		data, _, timesid = hub.query(false, UnlockByte, []byte(account), qr, nil)
 		return
 	}
-	data, _, timesid = hub.query(false, UnlockByte, []byte(account), time, sid, count,
+	data, _, timesid = hub.query(false, UnlockByte, []byte(account), qr,
 		func(tag byte, entry []byte) bool {
 			entryStr := string(entry)
 			ending := strings.Index(entryStr, "\"locked_coins\":")
@@ -282,13 +412,13 @@ func (hub *Hub) QueryUnlockAboutToken(token, account string, time int64, sid int
 
 }
 
-func (hub *Hub) QueryIncomeAboutToken(token, account string, time int64, sid int64, count int) (
+func (hub *Hub) QueryIncomeAboutToken(token, account string, qr QueryRange) (
 	data []json.RawMessage, timesid []int64) {
 	if token == "" { // no token-based-filtering
-		data, _, timesid = hub.query(true, IncomeByte, []byte(account), time, sid, count, nil)
+		data, _, timesid = hub.query(true, IncomeByte, []byte(account), qr, nil)
 		return
 	}
-	data, _, timesid = hub.query(true, IncomeByte, []byte(account), time, sid, count,
+	data, _, timesid = hub.query(true, IncomeByte, []byte(account), qr,
 		func(tag byte, entry []byte) bool {
 			return strings.Contains(string(entry), "|"+token+"|")
 		})
@@ -296,23 +426,69 @@ func (hub *Hub) QueryIncomeAboutToken(token, account string, time int64, sid int
 
 }
 
-func (hub *Hub) QueryTxAboutToken(token, account string, time int64, sid int64, count int) (
+func (hub *Hub) QueryTxAboutToken(token, account string, qr QueryRange) (
 	data []json.RawMessage, timesid []int64) {
 	if token == "" { // no token-based-filtering
-		data, _, timesid = hub.query(true, TxByte, []byte(account), time, sid, count, nil)
+		data, _, timesid = hub.query(true, TxByte, []byte(account), qr, nil)
 		return
 	}
-	data, _, timesid = hub.query(true, TxByte, []byte(account), time, sid, count,
+	data, _, timesid = hub.query(true, TxByte, []byte(account), qr,
 		func(tag byte, entry []byte) bool {
 			return strings.Contains(string(entry), "|"+token+"|")
 		})
 	return
 }
 
+// The range of a query on the records whose keys end with (time, sid), such as the keys returned by getEndKeyFromBytes.
+// The records after the cursor (Time, Sid) are returned, the older ones first by default and the newer ones first if Asc.
+// A zero Time means no cursor, and a zero From or To means the time is not bounded on that side
+type QueryRange struct {
+	Time  int64
+	Sid   int64
+	From  int64 // the inclusive bounds of the time
+	To    int64
+	Asc   bool
+	Count int
+}
+
+func (qr QueryRange) iterator(db dbm.DB, firstByte byte, bz []byte) dbm.Iterator {
+	start := getStartKeyFromBytes(firstByte, bz)
+	end := getEndKeyFromBytes(firstByte, bz, math.MaxInt64, math.MaxInt64)
+	if qr.From > 0 {
+		start = getEndKeyFromBytes(firstByte, bz, qr.From, 0)
+	}
+	if qr.To > 0 && qr.To < math.MaxInt64 {
+		end = getEndKeyFromBytes(firstByte, bz, qr.To+1, 0)
+	}
+	if qr.Time > 0 && !qr.Asc {
+		if cursor := getEndKeyFromBytes(firstByte, bz, qr.Time, qr.Sid); bytes.Compare(cursor, end) < 0 {
+			end = cursor
+		}
+	}
+	if qr.Time > 0 && qr.Asc && qr.Sid < math.MaxInt64 {
+		// the record at the cursor is skipped
+		if cursor := getEndKeyFromBytes(firstByte, bz, qr.Time, qr.Sid+1); bytes.Compare(cursor, start) > 0 {
+			start = cursor
+		}
+	}
+	if bytes.Compare(start, end) > 0 {
+		end = start
+	}
+	if qr.Asc {
+		return db.Iterator(start, end)
+	}
+	return db.ReverseIterator(start, end)
+}
+
+// Whether the time is in the bounds, for the queries whose keys do not end with (time, sid)
+func (qr QueryRange) inTimeBounds(time int64) bool {
+	return (qr.From <= 0 || time >= qr.From) && (qr.To <= 0 || time <= qr.To)
+}
+
 type filterFunc func(tag byte, entry []byte) bool
 
-func (hub *Hub) query(fetchTxDetail bool, firstByteIn byte, bz []byte, time int64, sid int64,
-	count int, filter filterFunc) (data []json.RawMessage, tags []byte, timesid []int64) {
+func (hub *Hub) query(fetchTxDetail bool, firstByteIn byte, bz []byte, qr QueryRange,
+	filter filterFunc) (data []json.RawMessage, tags []byte, timesid []int64) {
 	firstByte := firstByteIn
 	if firstByteIn == CreateOrderOnlyByte || firstByteIn == FillOrderOnlyByte || firstByteIn == CancelOrderOnlyByte {
 		firstByte = OrderByte
@@ -321,17 +497,25 @@ func (hub *Hub) query(fetchTxDetail bool, firstByteIn byte, bz []byte, time int6
 	} else {
 		firstByteIn = 0
 	}
-	count = limitCount(count)
+	count := limitCount(qr.Count)
 	data = make([]json.RawMessage, 0, count)
 	tags = make([]byte, 0, count)
 	timesid = make([]int64, 0, 2*count)
-	start := getStartKeyFromBytes(firstByte, bz)
-	end := getEndKeyFromBytes(firstByte, bz, time, sid)
+	hub.dbMutex.RLock()
+	var iter dbm.Iterator
 	if firstByteIn == DelistsByte {
-		end = []byte{DelistsByte} // To a different 'firstByte'
+		// To a different 'firstByte', the delists of all the markets are ordered by market, so the direction
+		// applies to the markets, and only the time bounds are checked for each record. They can not be
+		// paged by the time and the sid, which are ignored
+		start, end := getStartKeyFromBytes(firstByte, bz), []byte{DelistsByte}
+		if qr.Asc {
+			iter = hub.db.Iterator(start, end)
+		} else {
+			iter = hub.db.ReverseIterator(start, end)
+		}
+	} else {
+		iter = qr.iterator(hub.db, firstByte, bz)
 	}
-	hub.dbMutex.RLock()
-	iter := hub.db.ReverseIterator(start, end)
 	defer func() {
 		iter.Close()
 		hub.dbMutex.RUnlock()
@@ -345,6 +529,9 @@ func (hub *Hub) query(fetchTxDetail bool, firstByteIn byte, bz []byte, time int6
 		timeBytes := iKey[idx-8 : idx]
 		timeNum := binary.BigEndian.Uint64(timeBytes)
 		entry := json.RawMessage(iter.Value())
+		if firstByteIn == DelistsByte && !qr.inTimeBounds(int64(timeNum)) {
+			continue
+		}
 		if filter != nil && !filter(tag, entry) {
 			continue
 		}
@@ -3,9 +3,11 @@ package core
 import (
 	"encoding/binary"
 	"encoding/json"
+	"fmt"
 	"os"
 	"testing"
 
+	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/stretchr/testify/require"
 	dbm "github.com/tendermint/tm-db"
 )
@@ -81,3 +83,187 @@ func TestQueryBlockTime(t *testing.T) {
 	correct := `[1995,1994,1993,1992,1991,1990]`
 	require.EqualValues(t, correct, string(bytes))
 }
+
+func TestQueryOrderLifecycle(t *testing.T) {
+	acc1, _ := simpleAddr("00001")
+	addr1 := acc1.String()
+	db := dbm.NewMemDB()
+	hub := NewHub(db, &MocSubscribeManager{}, 99999, 0, 0, 0, "", 0)
+	hub.currBlockHeight = 999
+	T("2019-07-15T08:07:10Z")
+	newHeightInfo := &NewHeightInfo{
+		Height:        1000,
+		TimeStamp:     lastTime.Unix(),
+		LastBlockHash: []byte("01234567890123456789"),
+		ChainID:       "coinex-test",
+	}
+	bytes, _ := json.Marshal(newHeightInfo)
+	hub.ConsumeMessage("height_info", bytes)
+
+	hub.currTxHashID = "AB01"
+	for i, side := range []byte{SELL, BUY} {
+		createOrderInfo := &CreateOrderInfo{
+			OrderID:     fmt.Sprintf("%s-%d", addr1, i+1),
+			Sender:      addr1,
+			TradingPair: "abc/cet",
+			OrderType:   LIMIT,
+			Price:       sdk.NewDec(12),
+			Quantity:    300,
+			Side:        side,
+			TimeInForce: GTE,
+			Height:      1000,
+		}
+		bytes, _ = json.Marshal(createOrderInfo)
+		hub.ConsumeMessage("create_order_info", bytes)
+	}
+	fillOrderInfo := &FillOrderInfo{
+		OrderID:     addr1 + "-1",
+		TradingPair: "abc/cet",
+		Height:      1000,
+		Side:        SELL,
+		Price:       sdk.NewDec(12),
+		LeftStock:   200,
+		DealStock:   100,
+		DealMoney:   1200,
+		CurrStock:   100,
+		CurrMoney:   1200,
+		FillPrice:   sdk.NewDec(12),
+	}
+	bytes, _ = json.Marshal(fillOrderInfo)
+	hub.ConsumeMessage("fill_order_info", bytes)
+	hub.ConsumeMessage("commit", nil)
+
+	info := hub.QueryOrderLifecycle(addr1 + "-1")
+	require.NotNil(t, info)
+	require.Equal(t, OrderStatusPartiallyFilled, info.Status)
+	require.EqualValues(t, 100, info.DealStock)
+	require.EqualValues(t, 1200, info.DealMoney)
+	require.Equal(t, []string{"AB01"}, info.TxHashes)
+	require.Equal(t, 1, len(info.FillOrders))
+	require.Nil(t, info.CancelOrder)
+
+	info = hub.QueryOrderLifecycle(addr1 + "-2")
+	require.NotNil(t, info)
+	require.Equal(t, OrderStatusOpen, info.Status)
+	require.Equal(t, 0, len(info.FillOrders))
+
+	T("2019-07-15T08:08:10Z")
+	newHeightInfo.Height = 1001
+	newHeightInfo.TimeStamp = lastTime.Unix()
+	bytes, _ = json.Marshal(newHeightInfo)
+	hub.ConsumeMessage("height_info", bytes)
+
+	fillOrderInfo.Height = 1001
+	fillOrderInfo.LeftStock = 0
+	fillOrderInfo.DealStock = 300
+	fillOrderInfo.DealMoney = 3600
+	fillOrderInfo.CurrStock = 200
+	fillOrderInfo.CurrMoney = 2400
+	bytes, _ = json.Marshal(fillOrderInfo)
+	hub.ConsumeMessage("fill_order_info", bytes)
+
+	hub.currTxHashID = "CD02"
+	cancelOrderInfo := &CancelOrderInfo{
+		OrderID:     addr1 + "-2",
+		TradingPair: "abc/cet",
+		Height:      1001,
+		Side:        BUY,
+		Price:       sdk.NewDec(12),
+		DelReason:   "Manually cancel the order",
+		LeftStock:   300,
+	}
+	bytes, _ = json.Marshal(cancelOrderInfo)
+	hub.ConsumeMessage("del_order_info", bytes)
+	hub.ConsumeMessage("commit", nil)
+
+	info = hub.QueryOrderLifecycle(addr1 + "-1")
+	require.Equal(t, OrderStatusFilled, info.Status)
+	require.EqualValues(t, 300, info.DealStock)
+	require.EqualValues(t, 3600, info.DealMoney)
+	require.Equal(t, 2, len(info.FillOrders))
+
+	info = hub.QueryOrderLifecycle(addr1 + "-2")
+	require.Equal(t, OrderStatusCancelled, info.Status)
+	require.Equal(t, []string{"AB01", "CD02"}, info.TxHashes)
+	require.NotNil(t, info.CancelOrder)
+
+	require.Nil(t, hub.QueryOrderLifecycle(addr1+"-3"))
+}
+
+func TestQueryRange(t *testing.T) {
+	db := dbm.NewMemDB()
+	hub := NewHub(db, &MocSubscribeManager{}, 99999, 0, 0, 0, "", 0)
+	// two deals at each of the times 100, 101 and 102, and a deal of another market
+	sid := int64(0)
+	for _, tm := range []int64{100, 100, 101, 101, 102, 102} {
+		sid++
+		key := append(getEndKeyFromBytes(DealByte, []byte("abc/cet"), tm, sid), 0)
+		db.Set(key, []byte(fmt.Sprintf("%d", sid)))
+	}
+	db.Set(append(getEndKeyFromBytes(DealByte, []byte("xyz/cet"), 101, 7), 0), []byte("7"))
+
+	checkQuery := func(qr QueryRange, expected []string, expectedTimeSid []int64) {
+		data, timesid := hub.QueryDeal("abc/cet", qr)
+		res := make([]string, 0, len(data))
+		for _, d := range data {
+			res = append(res, string(d))
+		}
+		require.EqualValues(t, expected, res, qr)
+		if expectedTimeSid != nil {
+			require.EqualValues(t, expectedTimeSid, timesid, qr)
+		}
+	}
+	checkQuery(QueryRange{Count: 10}, []string{"6", "5", "4", "3", "2", "1"}, nil)
+	checkQuery(QueryRange{Count: 2}, []string{"6", "5"}, []int64{102, 6, 102, 5})
+	checkQuery(QueryRange{Time: 102, Sid: 5, Count: 2}, []string{"4", "3"}, []int64{101, 4, 101, 3})
+	checkQuery(QueryRange{Asc: true, Count: 2}, []string{"1", "2"}, []int64{100, 1, 100, 2})
+	checkQuery(QueryRange{Time: 100, Sid: 2, Asc: true, Count: 2}, []string{"3", "4"}, nil)
+	checkQuery(QueryRange{From: 101, Count: 10}, []string{"6", "5", "4", "3"}, nil)
+	checkQuery(QueryRange{To: 101, Count: 10}, []string{"4", "3", "2", "1"}, nil)
+	checkQuery(QueryRange{From: 101, To: 101, Asc: true, Count: 10}, []string{"3", "4"}, nil)
+	// the cursor is bounded by the time range
+	checkQuery(QueryRange{Time: 102, Sid: 6, From: 101, To: 101, Count: 10}, []string{"4", "3"}, nil)
+	checkQuery(QueryRange{Time: 101, Sid: 4, From: 101, To: 101, Asc: true, Count: 10}, []string{}, nil)
+	checkQuery(QueryRange{From: 102, To: 101, Count: 10}, []string{}, nil)
+}
+
+func TestQueryDelists(t *testing.T) {
+	db := dbm.NewMemDB()
+	hub := NewHub(db, &MocSubscribeManager{}, 99999, 0, 0, 0, "", 0)
+	// the delists of all the markets are ordered by market
+	for i, market := range []string{"abc/cet", "def/cet", "xyz/cet"} {
+		key := append(getEndKeyFromBytes(DelistByte, []byte(market), int64(100+i), int64(i+1)), 0)
+		db.Set(key, Int64ToBigEndianBytes(int64(1000+i)))
+	}
+	checkQuery := func(qr QueryRange, expected []string) {
+		data, _ := hub.QueryDelists(qr)
+		res := make([]string, 0, len(data)/2)
+		for i := 0; i < len(data); i += 2 {
+			res = append(res, string(data[i]))
+		}
+		require.EqualValues(t, expected, res, qr)
+	}
+	checkQuery(QueryRange{Count: 10}, []string{"xyz/cet", "def/cet", "abc/cet"})
+	checkQuery(QueryRange{Asc: true, Count: 10}, []string{"abc/cet", "def/cet", "xyz/cet"})
+	checkQuery(QueryRange{From: 101, Count: 10}, []string{"xyz/cet", "def/cet"})
+	checkQuery(QueryRange{To: 101, Asc: true, Count: 10}, []string{"abc/cet", "def/cet"})
+	checkQuery(QueryRange{From: 101, To: 101, Count: 10}, []string{"def/cet"})
+	// the time and the sid are ignored
+	checkQuery(QueryRange{Time: 101, Sid: 2, From: 101, Asc: true, Count: 10}, []string{"def/cet", "xyz/cet"})
+}
+
+func TestMigrateCreateMarketKeys(t *testing.T) {
+	db := dbm.NewMemDB()
+	// the keys written before the market name is removed from them
+	db.Set(append(getEndKeyFromBytes(CreateMarketByte, []byte("abc/cet"), 100, 1), 0), []byte("1"))
+	db.Set(append(getEndKeyFromBytes(CreateMarketByte, []byte("xyz/cet"), 101, 2), 0), []byte("2"))
+	db.Set(append(getEndKeyFromBytes(CreateMarketByte, []byte{}, 102, 3), 0), []byte("3"))
+	hub := NewHub(db, &MocSubscribeManager{}, 99999, 0, 0, 0, "", 0)
+
+	data, timesid := hub.QueryMarkets("", QueryRange{Count: 10})
+	require.Equal(t, "3\n2\n1", toStr(data))
+	require.EqualValues(t, []int64{102, 3, 101, 2, 100, 1}, timesid)
+	iter := db.Iterator([]byte{CreateMarketByte, 1}, []byte{CreateMarketByte + 1})
+	require.False(t, iter.Valid())
+	iter.Close()
+}
@@ -126,6 +126,7 @@ type MocSubscribeManager struct {
 	SlashSubscribeInfo        []Subscriber
 	HeightSubscribeInfo       []Subscriber
 	TickerSubscribeInfo       []Subscriber
+	XTickerSubscribeInfo      []Subscriber
 	CandleStickSubscribeInfo  map[string][]Subscriber
 	DepthSubscribeInfo        map[string][]Subscriber
 	DealSubscribeInfo         map[string][]Subscriber
@@ -141,17 +142,44 @@ type MocSubscribeManager struct {
 	LockedSubcribeInfo        map[string][]Subscriber
 	BancorDealSubscribeInfo   map[string][]Subscriber
 	MarketSubscribeInfo       map[string][]Subscriber
+	CommissionSubscribeInfo   map[string][]Subscriber
+	RewardsSubscribeInfo      map[string][]Subscriber
+	OpenOrdersSubscribeInfo   map[string][]Subscriber
+	DepthL3SubscribeInfo      map[string][]Subscriber
+	AccountSubscribeInfo      map[string][]Subscriber
+	AlertSubscribeInfo        map[string][]Subscriber
+	RebateSubscribeInfo       map[string][]Subscriber
 
 	sync.Mutex
 	PushList []pushInfo
 }
 
 func (sm *MocSubscribeManager) GetValidatorCommissionInfo() map[string][]Subscriber {
-	panic("implement me")
+	return sm.CommissionSubscribeInfo
 }
 
 func (sm *MocSubscribeManager) GetDelegationRewards() map[string][]Subscriber {
-	panic("implement me")
+	return sm.RewardsSubscribeInfo
+}
+
+func (sm *MocSubscribeManager) GetOpenOrdersSubscribeInfo() map[string][]Subscriber {
+	return sm.OpenOrdersSubscribeInfo
+}
+
+func (sm *MocSubscribeManager) GetDepthL3SubscribeInfo() map[string][]Subscriber {
+	return sm.DepthL3SubscribeInfo
+}
+
+func (sm *MocSubscribeManager) GetAccountSubscribeInfo() map[string][]Subscriber {
+	return sm.AccountSubscribeInfo
+}
+
+func (sm *MocSubscribeManager) GetAlertSubscribeInfo() map[string][]Subscriber {
+	return sm.AlertSubscribeInfo
+}
+
+func (sm *MocSubscribeManager) GetRebateSubscribeInfo() map[string][]Subscriber {
+	return sm.RebateSubscribeInfo
 }
 
 func (sm *MocSubscribeManager) PushCreateMarket(subscriber Subscriber, info []byte) {
@@ -161,11 +189,39 @@ func (sm *MocSubscribeManager) PushCreateMarket(subscriber Subscriber, info []by
 }
 
 func (sm *MocSubscribeManager) PushValidatorCommissionInfo(subscriber Subscriber, info []byte) {
-	panic("implement me")
+	sm.Lock()
+	defer sm.Unlock()
+	sm.PushList = append(sm.PushList, pushInfo{subscriber, string(info)})
 }
 
 func (sm *MocSubscribeManager) PushDelegationRewards(subscriber Subscriber, info []byte) {
-	panic("implement me")
+	sm.Lock()
+	defer sm.Unlock()
+	sm.PushList = append(sm.PushList, pushInfo{subscriber, string(info)})
+}
+
+func (sm *MocSubscribeManager) PushOpenOrder(subscriber Subscriber, info []byte) {
+	sm.Lock()
+	defer sm.Unlock()
+	sm.PushList = append(sm.PushList, pushInfo{subscriber, string(info)})
+}
+
+func (sm *MocSubscribeManager) PushAlert(subscriber Subscriber, info []byte) {
+	sm.Lock()
+	defer sm.Unlock()
+	sm.PushList = append(sm.PushList, pushInfo{subscriber, string(info)})
+}
+
+func (sm *MocSubscribeManager) PushRebate(subscriber Subscriber, info []byte) {
+	sm.Lock()
+	defer sm.Unlock()
+	sm.PushList = append(sm.PushList, pushInfo{subscriber, string(info)})
+}
+
+func (sm *MocSubscribeManager) PushDepthL3(subscriber Subscriber, info []byte) {
+	sm.Lock()
+	defer sm.Unlock()
+	sm.PushList = append(sm.PushList, pushInfo{subscriber, string(info)})
 }
 
 func (sm *MocSubscribeManager) GetMarketSubscribeInfo() map[string][]Subscriber {
@@ -307,6 +363,12 @@ func GetSubscribeManager(addr1, addr2 string) *MocSubscribeManager {
 func (sm *MocSubscribeManager) SetSkipOption(isSkip bool) {
 }
 
+func (sm *MocSubscribeManager) SetPushMeta(seq int64, height int64) {
+}
+
+func (sm *MocSubscribeManager) Flush() {
+}
+
 func (sm *MocSubscribeManager) GetSlashSubscribeInfo() []Subscriber {
 	return sm.SlashSubscribeInfo
 }
@@ -316,6 +378,9 @@ func (sm *MocSubscribeManager) GetHeightSubscribeInfo() []Subscriber {
 func (sm *MocSubscribeManager) GetTickerSubscribeInfo() []Subscriber {
 	return sm.TickerSubscribeInfo
 }
+func (sm *MocSubscribeManager) GetXTickerSubscribeInfo() []Subscriber {
+	return sm.XTickerSubscribeInfo
+}
 func (sm *MocSubscribeManager) GetCandleStickSubscribeInfo() map[string][]Subscriber {
 	return sm.CandleStickSubscribeInfo
 }
@@ -379,6 +444,12 @@ func (sm *MocSubscribeManager) PushTicker(subscriber Subscriber, t []*Ticker) {
 	info, _ := json.Marshal(t)
 	sm.PushList = append(sm.PushList, pushInfo{Target: subscriber, Payload: string(info)})
 }
+func (sm *MocSubscribeManager) PushXTicker(subscriber Subscriber, t []*XTicker) {
+	sm.Lock()
+	defer sm.Unlock()
+	info, _ := json.Marshal(t)
+	sm.PushList = append(sm.PushList, pushInfo{Target: subscriber, Payload: string(info)})
+}
 func (sm *MocSubscribeManager) PushDepthWithChange(subscriber Subscriber, info []byte) {
 	sm.Lock()
 	defer sm.Unlock()
@@ -1,5 +1,7 @@
 package core
 
+import "time"
+
 type mockWsConn struct {
 	num int
 }
@@ -12,6 +14,10 @@ func (m *mockWsConn) WriteMessage(msgType int, data []byte) error {
 	panic("implement me")
 }
 
+func (m *mockWsConn) WriteControl(msgType int, data []byte, deadline time.Time) error {
+	return nil
+}
+
 func (m *mockWsConn) ReadMessage() (messageType int, p []byte, err error) {
 	panic("implement me")
 }
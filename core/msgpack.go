@@ -0,0 +1,160 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Transcodes a JSON document into MessagePack with the same structure, the keys of the maps keep their order.
+// The integers are encoded in the smallest MessagePack formats, and the other numbers are encoded as float64
+func jsonToMsgpack(bz []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(bz))
+	dec.UseNumber()
+	var buf bytes.Buffer
+	if err := transcodeValue(dec, &buf); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid data after the top-level value")
+	}
+	return buf.Bytes(), nil
+}
+
+func transcodeValue(dec *json.Decoder, buf *bytes.Buffer) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch v := token.(type) {
+	case json.Delim:
+		// the elements are encoded before the header, which contains their count
+		var (
+			body bytes.Buffer
+			n    int
+		)
+		for ; dec.More(); n++ {
+			if v == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				writeMsgpackString(&body, key.(string))
+			}
+			if err := transcodeValue(dec, &body); err != nil {
+				return err
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+		if v == '{' {
+			writeMsgpackHeader(buf, n, 0x80, 0xde, 0xdf)
+		} else {
+			writeMsgpackHeader(buf, n, 0x90, 0xdc, 0xdd)
+		}
+		buf.Write(body.Bytes())
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		return writeMsgpackNumber(buf, v)
+	case string:
+		writeMsgpackString(buf, v)
+	}
+	return nil
+}
+
+// The header of a map or an array, whose fix format holds no more than 15 elements
+func writeMsgpackHeader(buf *bytes.Buffer, n int, fix, code16, code32 byte) {
+	switch {
+	case n < 16:
+		buf.WriteByte(fix | byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(code16)
+		writeUint(buf, uint64(n), 2)
+	default:
+		buf.WriteByte(code32)
+		writeUint(buf, uint64(n), 4)
+	}
+}
+
+func writeMsgpackString(buf *bytes.Buffer, s string) {
+	n := len(s)
+	switch {
+	case n < 32:
+		buf.WriteByte(0xa0 | byte(n))
+	case n <= math.MaxUint8:
+		buf.WriteByte(0xd9)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(0xda)
+		writeUint(buf, uint64(n), 2)
+	default:
+		buf.WriteByte(0xdb)
+		writeUint(buf, uint64(n), 4)
+	}
+	buf.WriteString(s)
+}
+
+func writeMsgpackNumber(buf *bytes.Buffer, num json.Number) error {
+	if i, err := num.Int64(); err == nil {
+		writeMsgpackInt(buf, i)
+		return nil
+	}
+	f, err := num.Float64()
+	if err != nil {
+		return err
+	}
+	buf.WriteByte(0xcb)
+	writeUint(buf, math.Float64bits(f), 8)
+	return nil
+}
+
+func writeMsgpackInt(buf *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0 && i <= math.MaxInt8:
+		buf.WriteByte(byte(i))
+	case i < 0 && i >= -32:
+		buf.WriteByte(byte(int8(i)))
+	case i > 0 && i <= math.MaxUint8:
+		buf.WriteByte(0xcc)
+		buf.WriteByte(byte(i))
+	case i > 0 && i <= math.MaxUint16:
+		buf.WriteByte(0xcd)
+		writeUint(buf, uint64(i), 2)
+	case i > 0 && i <= math.MaxUint32:
+		buf.WriteByte(0xce)
+		writeUint(buf, uint64(i), 4)
+	case i > 0:
+		buf.WriteByte(0xcf)
+		writeUint(buf, uint64(i), 8)
+	case i >= math.MinInt8:
+		buf.WriteByte(0xd0)
+		buf.WriteByte(byte(int8(i)))
+	case i >= math.MinInt16:
+		buf.WriteByte(0xd1)
+		writeUint(buf, uint64(uint16(int16(i))), 2)
+	case i >= math.MinInt32:
+		buf.WriteByte(0xd2)
+		writeUint(buf, uint64(uint32(int32(i))), 4)
+	default:
+		buf.WriteByte(0xd3)
+		writeUint(buf, uint64(i), 8)
+	}
+}
+
+// Writes the last n bytes of v in big endian
+func writeUint(buf *bytes.Buffer, v uint64, n int) {
+	var bz [8]byte
+	binary.BigEndian.PutUint64(bz[:], v)
+	buf.Write(bz[8-n:])
+}
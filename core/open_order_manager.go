@@ -0,0 +1,162 @@
+package core
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// An order which has been created, but has not been fully filled or cancelled
+type OpenOrder struct {
+	OrderID     string  `json:"order_id"`
+	Sender      string  `json:"sender"`
+	TradingPair string  `json:"trading_pair"`
+	OrderType   byte    `json:"order_type"`
+	Price       sdk.Dec `json:"price"`
+	Quantity    int64   `json:"quantity"`
+	Side        byte    `json:"side"`
+	TimeInForce int     `json:"time_in_force"`
+	Height      int64   `json:"height"`
+	Freeze      int64   `json:"freeze"`
+	LeftStock   int64   `json:"left_stock"`
+	DealStock   int64   `json:"deal_stock"`
+	DealMoney   int64   `json:"deal_money"`
+	Status      string  `json:"status"`
+	TxHash      string  `json:"tx_hash,omitempty"`
+}
+
+// Keeps the open orders of every address, which are updated by the create/fill/cancel records
+type OpenOrderManager struct {
+	// the first key is the sender's address and the second key is the order id
+	orders map[string]map[string]*OpenOrder
+	// the open orders are updated by the consumer and queried by the rest and websocket services
+	mutex sync.RWMutex
+}
+
+func NewOpenOrderManager(orders map[string]map[string]*OpenOrder) *OpenOrderManager {
+	if orders == nil {
+		orders = make(map[string]map[string]*OpenOrder)
+	}
+	return &OpenOrderManager{orders: orders}
+}
+
+// Returns a copy of the newly created order
+func (oom *OpenOrderManager) Add(v *CreateOrderInfo) OpenOrder {
+	oom.mutex.Lock()
+	defer oom.mutex.Unlock()
+	order := &OpenOrder{
+		OrderID:     v.OrderID,
+		Sender:      v.Sender,
+		TradingPair: v.TradingPair,
+		OrderType:   v.OrderType,
+		Price:       v.Price,
+		Quantity:    v.Quantity,
+		Side:        v.Side,
+		TimeInForce: v.TimeInForce,
+		Height:      v.Height,
+		Freeze:      v.Freeze,
+		LeftStock:   v.Quantity,
+		Status:      OrderStatusOpen,
+		TxHash:      v.TxHash,
+	}
+	if len(oom.orders[v.Sender]) == 0 {
+		oom.orders[v.Sender] = make(map[string]*OpenOrder)
+	}
+	oom.orders[v.Sender][v.OrderID] = order
+	return *order
+}
+
+// Returns a copy of the filled order, and false if the order is unknown
+// A fully filled order is removed from the open orders
+func (oom *OpenOrderManager) Fill(v *FillOrderInfo) (OpenOrder, bool) {
+	oom.mutex.Lock()
+	defer oom.mutex.Unlock()
+	order, ok := oom.get(v.OrderID)
+	if !ok {
+		return OpenOrder{}, false
+	}
+	order.Freeze = v.Freeze
+	order.LeftStock = v.LeftStock
+	order.DealStock = v.DealStock
+	order.DealMoney = v.DealMoney
+	order.Status = OrderStatusPartiallyFilled
+	if v.LeftStock == 0 {
+		order.Status = OrderStatusFilled
+		oom.remove(order)
+	}
+	return *order, true
+}
+
+// Returns a copy of the cancelled order, and false if the order is unknown
+func (oom *OpenOrderManager) Cancel(v *CancelOrderInfo) (OpenOrder, bool) {
+	oom.mutex.Lock()
+	defer oom.mutex.Unlock()
+	order, ok := oom.get(v.OrderID)
+	if !ok {
+		return OpenOrder{}, false
+	}
+	order.LeftStock = v.LeftStock
+	order.DealStock = v.DealStock
+	order.DealMoney = v.DealMoney
+	order.Status = OrderStatusCancelled
+	if v.LeftStock == 0 {
+		order.Status = OrderStatusFilled
+	}
+	oom.remove(order)
+	return *order, true
+}
+
+// Get the open orders of an address, sorted by height and order id.
+// If market is not empty, only the orders of this market are returned
+func (oom *OpenOrderManager) GetOpenOrders(addr, market string) []*OpenOrder {
+	oom.mutex.RLock()
+	defer oom.mutex.RUnlock()
+	res := make([]*OpenOrder, 0, len(oom.orders[addr]))
+	for _, order := range oom.orders[addr] {
+		if len(market) != 0 && order.TradingPair != market {
+			continue
+		}
+		orderCopy := *order
+		res = append(res, &orderCopy)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Height != res[j].Height {
+			return res[i].Height < res[j].Height
+		}
+		return res[i].OrderID < res[j].OrderID
+	})
+	return res
+}
+
+// Returns all the open orders, used to dump hub's state
+func (oom *OpenOrderManager) Dump() map[string]map[string]*OpenOrder {
+	oom.mutex.RLock()
+	defer oom.mutex.RUnlock()
+	res := make(map[string]map[string]*OpenOrder, len(oom.orders))
+	for addr, orders := range oom.orders {
+		res[addr] = make(map[string]*OpenOrder, len(orders))
+		for id, order := range orders {
+			orderCopy := *order
+			res[addr][id] = &orderCopy
+		}
+	}
+	return res
+}
+
+func (oom *OpenOrderManager) get(orderID string) (*OpenOrder, bool) {
+	accAndSeq := strings.Split(orderID, "-")
+	if len(accAndSeq) != 2 {
+		return nil, false
+	}
+	order, ok := oom.orders[accAndSeq[0]][orderID]
+	return order, ok
+}
+
+func (oom *OpenOrderManager) remove(order *OpenOrder) {
+	delete(oom.orders[order.Sender], order.OrderID)
+	if len(oom.orders[order.Sender]) == 0 {
+		delete(oom.orders, order.Sender)
+	}
+}
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+func TestOpenOrderManager(t *testing.T) {
+	addr := "coinex1ughhs0eyames355v4tzq5nx2g806p55rna0d2x"
+	oom := NewOpenOrderManager(nil)
+	for i, market := range []string{"abc/cet", "xyz/cet", "abc/cet"} {
+		oom.Add(&CreateOrderInfo{
+			OrderID:     fmt.Sprintf("%s-%d", addr, i+1),
+			Sender:      addr,
+			TradingPair: market,
+			Price:       sdk.NewDec(10),
+			Quantity:    100,
+			Side:        BUY,
+			Height:      int64(10 + i),
+		})
+	}
+	orders := oom.GetOpenOrders(addr, "")
+	require.Equal(t, 3, len(orders))
+	require.Equal(t, addr+"-1", orders[0].OrderID)
+	require.Equal(t, addr+"-3", orders[2].OrderID)
+	require.Equal(t, 2, len(oom.GetOpenOrders(addr, "abc/cet")))
+	require.Equal(t, 0, len(oom.GetOpenOrders("coinex1other", "")))
+
+	// partially filled
+	order, ok := oom.Fill(&FillOrderInfo{OrderID: addr + "-1", LeftStock: 60, DealStock: 40, DealMoney: 400})
+	require.True(t, ok)
+	require.Equal(t, OrderStatusPartiallyFilled, order.Status)
+	require.EqualValues(t, 60, oom.GetOpenOrders(addr, "abc/cet")[0].LeftStock)
+
+	// fully filled
+	order, ok = oom.Fill(&FillOrderInfo{OrderID: addr + "-1", LeftStock: 0, DealStock: 100, DealMoney: 1000})
+	require.True(t, ok)
+	require.Equal(t, OrderStatusFilled, order.Status)
+	require.Equal(t, 1, len(oom.GetOpenOrders(addr, "abc/cet")))
+
+	// cancelled
+	order, ok = oom.Cancel(&CancelOrderInfo{OrderID: addr + "-2", LeftStock: 100})
+	require.True(t, ok)
+	require.Equal(t, OrderStatusCancelled, order.Status)
+	require.Equal(t, 0, len(oom.GetOpenOrders(addr, "xyz/cet")))
+
+	// unknown orders
+	_, ok = oom.Fill(&FillOrderInfo{OrderID: addr + "-2", LeftStock: 0})
+	require.False(t, ok)
+	_, ok = oom.Cancel(&CancelOrderInfo{OrderID: "invalid-order-id"})
+	require.False(t, ok)
+
+	// dump and load
+	bz, err := json.Marshal(oom.Dump())
+	require.Nil(t, err)
+	var orderMap map[string]map[string]*OpenOrder
+	require.Nil(t, json.Unmarshal(bz, &orderMap))
+	require.Equal(t, oom.GetOpenOrders(addr, ""), NewOpenOrderManager(orderMap).GetOpenOrders(addr, ""))
+}
@@ -0,0 +1,193 @@
+package core
+
+import (
+	"sort"
+	"sync"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+const (
+	L3ActionAdd    = "add"
+	L3ActionModify = "modify"
+	L3ActionRemove = "remove"
+)
+
+// One resting order in the level-3 order book
+type L3Order struct {
+	OrderID string  `json:"order_id"`
+	Side    byte    `json:"side"`
+	Price   sdk.Dec `json:"price"`
+	Amount  int64   `json:"amount"` // the stock amount left in the order book
+	Height  int64   `json:"height"`
+	// the hub's sid when the order was created, orders at the same price are queued in the order of Seq
+	Seq int64 `json:"seq"`
+}
+
+// One change to the level-3 order book
+type L3Event struct {
+	Action  string  `json:"action"`
+	OrderID string  `json:"order_id"`
+	Side    byte    `json:"side"`
+	Price   sdk.Dec `json:"price"`
+	Amount  int64   `json:"amount"`
+}
+
+// The changes to the level-3 order book of a market during one block
+type L3Events struct {
+	TradingPair string     `json:"trading_pair"`
+	Height      int64      `json:"height"`
+	Events      []*L3Event `json:"events"`
+}
+
+type L3Snapshot struct {
+	TradingPair string     `json:"trading_pair"`
+	Height      int64      `json:"height"`
+	Bids        []*L3Order `json:"bids"`
+	Asks        []*L3Order `json:"asks"`
+}
+
+// A change to the order book during the current block. order is the new order for L3ActionAdd,
+// and only its OrderID and Amount are used by the other actions
+type l3Change struct {
+	action string
+	order  *L3Order
+}
+
+// The per-order book of one market, which is maintained alongside the aggregated depth in TripleManager.
+// Like the depth, the changes of a block are applied as a whole by EndBlock, so the book can not be
+// queried in the middle of a block
+type OrderBookL3 struct {
+	orders map[string]*L3Order
+	// the height of the last block applied to the book
+	height int64
+	// the changes during the current block, which are only used by the hub's goroutine
+	changes []l3Change
+	mutex   sync.RWMutex
+}
+
+func NewOrderBookL3(orders []*L3Order) *OrderBookL3 {
+	book := &OrderBookL3{
+		orders:  make(map[string]*L3Order, len(orders)),
+		changes: make([]l3Change, 0, 10),
+	}
+	for _, order := range orders {
+		book.orders[order.OrderID] = order
+	}
+	return book
+}
+
+func (book *OrderBookL3) Add(v *CreateOrderInfo, seq int64) {
+	book.changes = append(book.changes, l3Change{action: L3ActionAdd, order: &L3Order{
+		OrderID: v.OrderID,
+		Side:    v.Side,
+		Price:   v.Price,
+		Amount:  v.Quantity,
+		Height:  v.Height,
+		Seq:     seq,
+	}})
+}
+
+// The order's amount is changed to the left stock, and the order is removed if nothing is left
+func (book *OrderBookL3) Fill(v *FillOrderInfo) {
+	book.changes = append(book.changes, l3Change{action: L3ActionModify,
+		order: &L3Order{OrderID: v.OrderID, Amount: v.LeftStock}})
+}
+
+func (book *OrderBookL3) Cancel(v *CancelOrderInfo) {
+	book.changes = append(book.changes, l3Change{action: L3ActionRemove, order: &L3Order{OrderID: v.OrderID}})
+}
+
+// Applies the changes during the block at height, and returns them as the events
+func (book *OrderBookL3) EndBlock(height int64) []*L3Event {
+	book.mutex.Lock()
+	defer book.mutex.Unlock()
+	book.height = height
+	events := make([]*L3Event, 0, len(book.changes))
+	for _, c := range book.changes {
+		if c.action == L3ActionAdd {
+			book.orders[c.order.OrderID] = c.order
+			events = append(events, newL3Event(L3ActionAdd, c.order))
+			continue
+		}
+		order, ok := book.orders[c.order.OrderID]
+		if !ok {
+			continue
+		}
+		order.Amount = c.order.Amount
+		if order.Amount <= 0 {
+			delete(book.orders, order.OrderID)
+			events = append(events, newL3Event(L3ActionRemove, order))
+			continue
+		}
+		events = append(events, newL3Event(L3ActionModify, order))
+	}
+	book.changes = book.changes[:0]
+	return events
+}
+
+func newL3Event(action string, order *L3Order) *L3Event {
+	return &L3Event{
+		Action:  action,
+		OrderID: order.OrderID,
+		Side:    order.Side,
+		Price:   order.Price,
+		Amount:  order.Amount,
+	}
+}
+
+// Returns no more than 'count' orders for each side, and the height of the last block applied to the book.
+// The bids are sorted from the highest price and the asks are sorted from the lowest price,
+// and the orders at the same price are sorted by their queue positions
+func (book *OrderBookL3) GetOrders(count int) (bids []*L3Order, asks []*L3Order, height int64) {
+	book.mutex.RLock()
+	height = book.height
+	bids = make([]*L3Order, 0, len(book.orders))
+	asks = make([]*L3Order, 0, len(book.orders))
+	for _, order := range book.orders {
+		orderCopy := *order
+		if order.Side == SELL {
+			asks = append(asks, &orderCopy)
+		} else {
+			bids = append(bids, &orderCopy)
+		}
+	}
+	book.mutex.RUnlock()
+	sort.Slice(bids, func(i, j int) bool {
+		if !bids[i].Price.Equal(bids[j].Price) {
+			return bids[i].Price.GT(bids[j].Price)
+		}
+		return bids[i].Seq < bids[j].Seq
+	})
+	sort.Slice(asks, func(i, j int) bool {
+		if !asks[i].Price.Equal(asks[j].Price) {
+			return asks[i].Price.LT(asks[j].Price)
+		}
+		return asks[i].Seq < asks[j].Seq
+	})
+	if len(bids) > count {
+		bids = bids[:count]
+	}
+	if len(asks) > count {
+		asks = asks[:count]
+	}
+	return
+}
+
+// dump all the orders for serialization
+func (book *OrderBookL3) DumpOrders() []*L3Order {
+	if book == nil {
+		return nil
+	}
+	book.mutex.RLock()
+	defer book.mutex.RUnlock()
+	orders := make([]*L3Order, 0, len(book.orders))
+	for _, order := range book.orders {
+		orderCopy := *order
+		orders = append(orders, &orderCopy)
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].Seq < orders[j].Seq
+	})
+	return orders
+}
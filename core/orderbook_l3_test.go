@@ -0,0 +1,63 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+func TestOrderBookL3(t *testing.T) {
+	book := NewOrderBookL3(nil)
+	book.Add(&CreateOrderInfo{OrderID: "a-1", Side: BUY, Price: sdk.NewDec(10), Quantity: 100, Height: 1}, 1)
+	book.Add(&CreateOrderInfo{OrderID: "a-2", Side: BUY, Price: sdk.NewDec(11), Quantity: 100, Height: 1}, 2)
+	book.Add(&CreateOrderInfo{OrderID: "b-1", Side: BUY, Price: sdk.NewDec(10), Quantity: 50, Height: 2}, 3)
+	book.Add(&CreateOrderInfo{OrderID: "b-2", Side: SELL, Price: sdk.NewDec(13), Quantity: 70, Height: 2}, 4)
+	book.Add(&CreateOrderInfo{OrderID: "c-1", Side: SELL, Price: sdk.NewDec(12), Quantity: 30, Height: 2}, 5)
+	// the changes are not applied in the middle of a block
+	bids, asks, height := book.GetOrders(10)
+	require.Equal(t, 0, len(bids)+len(asks))
+	require.EqualValues(t, 0, height)
+	events := book.EndBlock(2)
+	require.Equal(t, 5, len(events))
+	require.Equal(t, L3ActionAdd, events[0].Action)
+	require.Equal(t, 0, len(book.EndBlock(3)))
+
+	// bids from the highest price, asks from the lowest price, the same price in the queue order
+	bids, asks, height = book.GetOrders(10)
+	require.EqualValues(t, 3, height)
+	require.Equal(t, []string{"a-2", "a-1", "b-1"}, []string{bids[0].OrderID, bids[1].OrderID, bids[2].OrderID})
+	require.Equal(t, []string{"c-1", "b-2"}, []string{asks[0].OrderID, asks[1].OrderID})
+	bids, asks, _ = book.GetOrders(1)
+	require.Equal(t, 1, len(bids))
+	require.Equal(t, 1, len(asks))
+
+	book.Fill(&FillOrderInfo{OrderID: "a-1", LeftStock: 40})
+	book.Fill(&FillOrderInfo{OrderID: "c-1", LeftStock: 0})
+	book.Cancel(&CancelOrderInfo{OrderID: "b-2", LeftStock: 70})
+	book.Cancel(&CancelOrderInfo{OrderID: "unknown-1"})
+	bids, _, _ = book.GetOrders(10)
+	require.EqualValues(t, 100, bids[1].Amount)
+	events = book.EndBlock(4)
+	require.Equal(t, 3, len(events))
+	require.Equal(t, L3ActionModify, events[0].Action)
+	require.EqualValues(t, 40, events[0].Amount)
+	require.Equal(t, L3ActionRemove, events[1].Action)
+	require.Equal(t, L3ActionRemove, events[2].Action)
+	require.Equal(t, "b-2", events[2].OrderID)
+
+	bids, asks, _ = book.GetOrders(10)
+	require.Equal(t, 3, len(bids))
+	require.EqualValues(t, 40, bids[1].Amount)
+	require.Equal(t, 0, len(asks))
+
+	// dump and load
+	bz, err := json.Marshal(book.DumpOrders())
+	require.Nil(t, err)
+	var orders []*L3Order
+	require.Nil(t, json.Unmarshal(bz, &orders))
+	loadedBids, _, _ := NewOrderBookL3(orders).GetOrders(10)
+	require.Equal(t, bids, loadedBids)
+}
@@ -0,0 +1,338 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+const (
+	AlertPriceAbove = "price_above"
+	AlertPriceBelow = "price_below"
+	AlertPctChange  = "pct_change"
+)
+
+// The number of price alerts which can be registered by one address
+const MaxAlertsPerAddress = 32
+
+var (
+	ErrTooManyAlerts = errors.New("too many alerts")
+	ErrAlertNotFound = errors.New("alert not found")
+	ErrHubStopped    = errors.New("hub is stopped")
+)
+
+// A condition on the deal price of a market, registered by an address.
+// 'price_above' and 'price_below' fire when the price crosses Value, and 'pct_change' fires when the price
+// moves more than Value percent from the price Window minutes ago. An alert fires again only after
+// its condition stops holding, and it is kept until it is removed
+type PriceAlert struct {
+	ID        string  `json:"id"`
+	Address   string  `json:"address"`
+	Market    string  `json:"market"`
+	Condition string  `json:"condition"`
+	Value     sdk.Dec `json:"value"`
+	Window    int     `json:"window,omitempty"`
+
+	// whether the alert has been evaluated, and whether its condition held at the last evaluation
+	evaluated bool
+	triggered bool
+}
+
+// The payload of the 'alert' message pushed when an alert fires
+type FiredAlert struct {
+	PriceAlert
+	Price sdk.Dec `json:"price"`
+	// the price Window minutes ago, only for 'pct_change'
+	BasePrice *sdk.Dec `json:"base_price,omitempty"`
+	Height    int64    `json:"height"`
+	Timestamp int64    `json:"timestamp"`
+}
+
+func (a *PriceAlert) validate() error {
+	if len(a.Address) == 0 || len(a.Market) == 0 {
+		return errors.New("the address and the market of the alert are required")
+	}
+	switch a.Condition {
+	case AlertPriceAbove, AlertPriceBelow:
+		a.Window = 0
+	case AlertPctChange:
+		if a.Window <= 0 || a.Window >= MinuteNumInDay {
+			return fmt.Errorf("the window of %s should be between 1 and %d minutes", AlertPctChange, MinuteNumInDay-1)
+		}
+	default:
+		return fmt.Errorf("unknown alert condition %s", a.Condition)
+	}
+	if a.Value.Int == nil || !a.Value.IsPositive() {
+		return errors.New("the value of the alert should be positive")
+	}
+	return nil
+}
+
+// Whether the condition holds at price, basePrice is only used by 'pct_change'
+func (a *PriceAlert) holds(price, basePrice sdk.Dec) bool {
+	switch a.Condition {
+	case AlertPriceAbove:
+		return price.GT(a.Value)
+	case AlertPriceBelow:
+		return price.LT(a.Value)
+	case AlertPctChange:
+		return basePrice.IsPositive() && price.Sub(basePrice).Abs().MulInt64(100).GTE(a.Value.Mul(basePrice))
+	}
+	return false
+}
+
+// Keeps the price alerts of every address, which are registered by the websocket connections
+// and evaluated by the hub when a block is committed
+type AlertManager struct {
+	mtx     sync.Mutex
+	alerts  map[string][]*PriceAlert // address --> its alerts, in the order of registration
+	markets map[string][]*PriceAlert // market --> the alerts on it
+	lastID  int64
+
+	// the last deal prices of the markets in the current block and before it, only used by the hub's goroutine
+	blockPrices map[string]sdk.Dec
+	lastPrices  map[string]sdk.Dec
+}
+
+func NewAlertManager() *AlertManager {
+	return &AlertManager{
+		alerts:      make(map[string][]*PriceAlert),
+		markets:     make(map[string][]*PriceAlert),
+		blockPrices: make(map[string]sdk.Dec),
+		lastPrices:  make(map[string]sdk.Dec),
+	}
+}
+
+// The IDs are increasing numbers, such that the alerts loaded from DB keep the order of registration
+func (am *AlertManager) newID() string {
+	id := time.Now().UnixNano()
+	if id <= am.lastID {
+		id = am.lastID + 1
+	}
+	am.lastID = id
+	return strconv.FormatInt(id, 10)
+}
+
+// Assigns an ID to the alert, and adds it after it is saved
+func (am *AlertManager) add(alert *PriceAlert, save func(*PriceAlert) error) error {
+	am.mtx.Lock()
+	defer am.mtx.Unlock()
+	if len(am.alerts[alert.Address]) >= MaxAlertsPerAddress {
+		return ErrTooManyAlerts
+	}
+	alert.ID = am.newID()
+	if err := save(alert); err != nil {
+		return err
+	}
+	am.insert(alert)
+	return nil
+}
+
+func (am *AlertManager) insert(alert *PriceAlert) {
+	am.alerts[alert.Address] = append(am.alerts[alert.Address], alert)
+	am.markets[alert.Market] = append(am.markets[alert.Market], alert)
+	if id, err := strconv.ParseInt(alert.ID, 10, 64); err == nil && id > am.lastID {
+		am.lastID = id
+	}
+}
+
+// Removes the alert after it is deleted
+func (am *AlertManager) remove(addr, id string, del func(*PriceAlert) error) error {
+	am.mtx.Lock()
+	defer am.mtx.Unlock()
+	var alert *PriceAlert
+	for _, a := range am.alerts[addr] {
+		if a.ID == id {
+			alert = a
+		}
+	}
+	if alert == nil {
+		return ErrAlertNotFound
+	}
+	if err := del(alert); err != nil {
+		return err
+	}
+	am.alerts[addr] = removeAlert(am.alerts[addr], alert)
+	if len(am.alerts[addr]) == 0 {
+		delete(am.alerts, addr)
+	}
+	am.markets[alert.Market] = removeAlert(am.markets[alert.Market], alert)
+	if len(am.markets[alert.Market]) == 0 {
+		delete(am.markets, alert.Market)
+	}
+	return nil
+}
+
+func removeAlert(alerts []*PriceAlert, alert *PriceAlert) []*PriceAlert {
+	res := make([]*PriceAlert, 0, len(alerts))
+	for _, a := range alerts {
+		if a != alert {
+			res = append(res, a)
+		}
+	}
+	return res
+}
+
+// Returns the copies of the alerts of addr
+func (am *AlertManager) get(addr string) []PriceAlert {
+	am.mtx.Lock()
+	defer am.mtx.Unlock()
+	res := make([]PriceAlert, 0, len(am.alerts[addr]))
+	for _, a := range am.alerts[addr] {
+		res = append(res, *a)
+	}
+	return res
+}
+
+// Sets the deal price of the market in the current block. Before the first price of a market is evaluated,
+// getLastPrice is used to get its deal price before the current block
+func (am *AlertManager) setPrice(market string, price sdk.Dec, getLastPrice func() (sdk.Dec, bool)) {
+	_, hasBlock := am.blockPrices[market]
+	if _, hasLast := am.lastPrices[market]; !hasBlock && !hasLast {
+		if p, ok := getLastPrice(); ok {
+			am.lastPrices[market] = p
+		}
+	}
+	am.blockPrices[market] = price
+}
+
+// Evaluates the alerts of the markets dealt in the current block, and returns the fired ones.
+// getPrice returns the price of a market some minutes ago
+func (am *AlertManager) evaluate(getPrice func(market string, minutes int) (sdk.Dec, bool)) []FiredAlert {
+	am.mtx.Lock()
+	defer am.mtx.Unlock()
+	var fired []FiredAlert
+	for market, price := range am.blockPrices {
+		lastPrice, hasLast := am.lastPrices[market]
+		for _, alert := range am.markets[market] {
+			var basePrice *sdk.Dec
+			var holds bool
+			if alert.Condition == AlertPctChange {
+				if p, ok := getPrice(market, alert.Window); ok {
+					basePrice = &p
+					holds = alert.holds(price, p)
+				}
+			} else {
+				if !alert.evaluated && hasLast {
+					// a new alert fires only when the price crosses its value
+					alert.triggered = alert.holds(lastPrice, sdk.Dec{})
+				}
+				holds = alert.holds(price, sdk.Dec{})
+			}
+			if holds && !alert.triggered {
+				fired = append(fired, FiredAlert{PriceAlert: *alert, Price: price, BasePrice: basePrice})
+			}
+			alert.evaluated = true
+			alert.triggered = holds
+		}
+		am.lastPrices[market] = price
+	}
+	am.blockPrices = make(map[string]sdk.Dec)
+	return fired
+}
+
+// Registers a price alert for its address, and returns it with its ID
+func (hub *Hub) AddPriceAlert(alert PriceAlert) (PriceAlert, error) {
+	if err := alert.validate(); err != nil {
+		return alert, err
+	}
+	if !hub.HasMarket(alert.Market) {
+		return alert, fmt.Errorf("market %s not found", alert.Market)
+	}
+	err := hub.alertMan.add(&alert, hub.saveAlert)
+	return alert, err
+}
+
+func (hub *Hub) RemovePriceAlert(addr, id string) error {
+	return hub.alertMan.remove(addr, id, hub.deleteAlert)
+}
+
+func (hub *Hub) QueryPriceAlerts(addr string) []PriceAlert {
+	return hub.alertMan.get(addr)
+}
+
+func (hub *Hub) saveAlert(alert *PriceAlert) error {
+	bz, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	hub.dbMutex.Lock()
+	defer hub.dbMutex.Unlock()
+	if hub.stopped {
+		return ErrHubStopped
+	}
+	hub.db.SetSync(getAlertKey(alert.Address, alert.ID), bz)
+	return nil
+}
+
+func (hub *Hub) deleteAlert(alert *PriceAlert) error {
+	hub.dbMutex.Lock()
+	defer hub.dbMutex.Unlock()
+	if hub.stopped {
+		return ErrHubStopped
+	}
+	hub.db.DeleteSync(getAlertKey(alert.Address, alert.ID))
+	return nil
+}
+
+// Loads the alerts saved in DB, which are evaluated again from the next block
+func (hub *Hub) loadPriceAlerts() {
+	hub.dbMutex.RLock()
+	iter := hub.db.Iterator([]byte{AlertByte}, []byte{AlertByte + 1})
+	defer func() {
+		iter.Close()
+		hub.dbMutex.RUnlock()
+	}()
+	for ; iter.Valid(); iter.Next() {
+		var alert PriceAlert
+		if err := json.Unmarshal(iter.Value(), &alert); err != nil {
+			hub.Log(fmt.Sprintf("Error in Unmarshal PriceAlert: %v", err))
+			continue
+		}
+		hub.alertMan.insert(&alert)
+	}
+}
+
+// Must be called before the deal is updated into the candle sticks
+func (hub *Hub) setAlertPrice(market string, price sdk.Dec) {
+	hub.alertMan.setPrice(market, price, func() (sdk.Dec, bool) {
+		// the deals of the current minute are not in the ticker until the minute is flushed
+		if csRec := hub.csMan.GetRecord(market); csRec != nil {
+			if cs := csRec.MinuteCS[hub.currBlockTime.UTC().Minute()]; cs.hasDeal() {
+				return cs.ClosePrice, true
+			}
+		}
+		triman, ok := hub.managersMap[market]
+		if !ok {
+			return sdk.Dec{}, false
+		}
+		currMinute := hub.currBlockTime.UTC().Hour()*60 + hub.currBlockTime.UTC().Minute()
+		return triman.tkm.GetPriceBefore(currMinute, 0)
+	})
+}
+
+func (hub *Hub) commitForAlerts() {
+	currMinute := hub.currBlockTime.UTC().Hour()*60 + hub.currBlockTime.UTC().Minute()
+	fired := hub.alertMan.evaluate(func(market string, minutes int) (sdk.Dec, bool) {
+		triman, ok := hub.managersMap[market]
+		if !ok {
+			return sdk.Dec{}, false
+		}
+		return triman.tkm.GetPriceBefore(currMinute, minutes)
+	})
+	for _, f := range fired {
+		f.Height = hub.currBlockHeight
+		f.Timestamp = hub.currBlockTime.Unix()
+		bz, err := json.Marshal(f)
+		if err != nil {
+			hub.Log(fmt.Sprintf("Error in Marshal FiredAlert: %v", err))
+			continue
+		}
+		hub.enqueuePush(MsgToPush{topic: AlertKey, bz: bz, extra: f.Address})
+	}
+}
@@ -0,0 +1,130 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/require"
+	dbm "github.com/tendermint/tm-db"
+)
+
+func getFiredIDs(fired []FiredAlert) []string {
+	ids := make([]string, 0, len(fired))
+	for _, f := range fired {
+		ids = append(ids, f.ID)
+	}
+	return ids
+}
+
+func TestAlertManagerEvaluate(t *testing.T) {
+	am := NewAlertManager()
+	save := func(*PriceAlert) error { return nil }
+	above := &PriceAlert{Address: "bob", Market: "abc/cet", Condition: AlertPriceAbove, Value: sdk.NewDec(10)}
+	below := &PriceAlert{Address: "bob", Market: "abc/cet", Condition: AlertPriceBelow, Value: sdk.NewDec(8)}
+	change := &PriceAlert{Address: "alice", Market: "abc/cet", Condition: AlertPctChange, Value: sdk.NewDec(25), Window: 5}
+	for _, a := range []*PriceAlert{above, below, change} {
+		require.Nil(t, a.validate())
+		require.Nil(t, am.add(a, save))
+	}
+	// the price 5 minutes ago
+	getPrice := func(market string, minutes int) (sdk.Dec, bool) {
+		if minutes == 5 {
+			return sdk.NewDec(10), true
+		}
+		return sdk.Dec{}, false
+	}
+	noLastPrice := func() (sdk.Dec, bool) { return sdk.Dec{}, false }
+
+	// the price is already above 10 before the alert is evaluated, but it does not cross 10
+	am.setPrice("abc/cet", sdk.NewDec(11), noLastPrice)
+	require.EqualValues(t, []string{above.ID}, getFiredIDs(am.evaluate(getPrice)))
+	am.setPrice("abc/cet", sdk.NewDec(12), noLastPrice)
+	require.EqualValues(t, 0, len(am.evaluate(getPrice)))
+	am.setPrice("abc/cet", sdk.NewDec(7), noLastPrice)
+	fired := am.evaluate(getPrice)
+	require.EqualValues(t, []string{below.ID, change.ID}, getFiredIDs(fired))
+	require.Nil(t, fired[0].BasePrice)
+	require.EqualValues(t, sdk.NewDec(10), *fired[1].BasePrice)
+	// no deal in this block
+	require.EqualValues(t, 0, len(am.evaluate(getPrice)))
+	// the price still moves more than 25% from 10
+	am.setPrice("abc/cet", sdk.NewDec(13), noLastPrice)
+	require.EqualValues(t, []string{above.ID}, getFiredIDs(am.evaluate(getPrice)))
+	am.setPrice("abc/cet", sdk.NewDec(10), noLastPrice)
+	require.EqualValues(t, 0, len(am.evaluate(getPrice)))
+
+	// a new alert fires only when the price crosses its value
+	newAbove := &PriceAlert{Address: "bob", Market: "abc/cet", Condition: AlertPriceAbove, Value: sdk.NewDec(9)}
+	require.Nil(t, am.add(newAbove, save))
+	am.setPrice("abc/cet", sdk.NewDec(11), noLastPrice)
+	require.EqualValues(t, []string{above.ID}, getFiredIDs(am.evaluate(getPrice)))
+
+	// the first price of a market is compared with the price before the block, which is already above 10
+	am = NewAlertManager()
+	require.Nil(t, am.add(&PriceAlert{Address: "bob", Market: "abc/cet", Condition: AlertPriceAbove, Value: sdk.NewDec(10)}, save))
+	lastPrice := func() (sdk.Dec, bool) { return sdk.NewDec(11), true }
+	am.setPrice("abc/cet", sdk.NewDec(12), lastPrice)
+	am.setPrice("abc/cet", sdk.NewDec(9), func() (sdk.Dec, bool) { return sdk.NewDec(12), true })
+	am.setPrice("abc/cet", sdk.NewDec(13), lastPrice)
+	require.EqualValues(t, 0, len(am.evaluate(getPrice)))
+}
+
+func TestPriceAlertValidate(t *testing.T) {
+	for _, a := range []PriceAlert{
+		{Address: "bob", Condition: AlertPriceAbove, Value: sdk.NewDec(1)},
+		{Address: "bob", Market: "abc/cet", Condition: "price", Value: sdk.NewDec(1)},
+		{Address: "bob", Market: "abc/cet", Condition: AlertPriceBelow},
+		{Address: "bob", Market: "abc/cet", Condition: AlertPriceBelow, Value: sdk.NewDec(-1)},
+		{Address: "bob", Market: "abc/cet", Condition: AlertPctChange, Value: sdk.NewDec(1)},
+		{Address: "bob", Market: "abc/cet", Condition: AlertPctChange, Value: sdk.NewDec(1), Window: MinuteNumInDay},
+	} {
+		require.NotNil(t, a.validate())
+	}
+}
+
+func TestHubPriceAlerts(t *testing.T) {
+	db := dbm.NewMemDB()
+	subMan := &MocSubscribeManager{}
+	hub := NewHub(db, subMan, 99999, 0, 0, 0, "", 0)
+	_, err := hub.AddPriceAlert(PriceAlert{Address: "bob", Market: "abc/cet", Condition: AlertPriceAbove, Value: sdk.NewDec(10)})
+	require.Equal(t, "market abc/cet not found", err.Error())
+	hub.AddMarket("abc/cet")
+	first, err := hub.AddPriceAlert(PriceAlert{Address: "bob", Market: "abc/cet", Condition: AlertPriceAbove, Value: sdk.NewDec(10)})
+	require.Nil(t, err)
+	second, err := hub.AddPriceAlert(PriceAlert{Address: "bob", Market: "abc/cet", Condition: AlertPriceBelow, Value: sdk.NewDec(5)})
+	require.Nil(t, err)
+	require.Equal(t, ErrAlertNotFound, hub.RemovePriceAlert("alice", first.ID))
+	for i := 2; i < MaxAlertsPerAddress; i++ {
+		_, err = hub.AddPriceAlert(PriceAlert{Address: "bob", Market: "abc/cet", Condition: AlertPriceAbove, Value: sdk.NewDec(20)})
+		require.Nil(t, err)
+	}
+	_, err = hub.AddPriceAlert(PriceAlert{Address: "bob", Market: "abc/cet", Condition: AlertPriceAbove, Value: sdk.NewDec(20)})
+	require.Equal(t, ErrTooManyAlerts, err)
+
+	// the alerts are loaded from DB in the order of registration
+	hub = NewHub(db, subMan, 99999, 0, 0, 0, "", 0)
+	hub.AddMarket("abc/cet")
+	alerts := hub.QueryPriceAlerts("bob")
+	require.EqualValues(t, MaxAlertsPerAddress, len(alerts))
+	require.EqualValues(t, first, alerts[0])
+	require.EqualValues(t, second, alerts[1])
+	for _, a := range alerts[1:] {
+		require.Nil(t, hub.RemovePriceAlert("bob", a.ID))
+	}
+	require.EqualValues(t, []PriceAlert{first}, NewHub(db, subMan, 99999, 0, 0, 0, "", 0).QueryPriceAlerts("bob"))
+
+	// the fired alert is pushed to the subscribers of its address
+	subscriber := &PlainSubscriber{ID: 1}
+	subMan.AlertSubscribeInfo = map[string][]Subscriber{"bob": {subscriber}}
+	hub.currBlockHeight = 100
+	hub.currBlockTime = time.Unix(1000, 0)
+	hub.setAlertPrice("abc/cet", sdk.NewDec(11))
+	hub.commitForAlerts()
+	time.Sleep(10 * time.Millisecond)
+	subMan.Lock()
+	defer subMan.Unlock()
+	require.EqualValues(t, []pushInfo{{Target: subscriber, Payload: `{"id":"` + first.ID + `","address":"bob",` +
+		`"market":"abc/cet","condition":"price_above","value":"10.000000000000000000","price":"11.000000000000000000",` +
+		`"height":100,"timestamp":1000}`}}, subMan.PushList)
+}
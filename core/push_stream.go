@@ -0,0 +1,162 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// How many pushed messages are kept in memory for resuming subscriptions
+const MaxPushRecords = 100000
+
+// A pushed message kept for the resumed subscriptions.
+// streams are the subscription topics with params which received this message, such as "order:<addr>"
+type pushRecord struct {
+	streams []string
+	typeKey string
+	seq     int64
+	height  int64
+	payload []byte
+}
+
+// PushStreams assigns sequence numbers to the pushed messages and keeps the latest ones in a ring buffer.
+// The sequence number is shared by all the topics, so it increases monotonically on every topic
+type PushStreams struct {
+	seq    int64
+	height int64
+	// the ring buffer grows until its capacity is reached, then 'head' is the index of the oldest record
+	records  []*pushRecord
+	head     int
+	capacity int
+	// the records whose seq or height is larger than these values are all in the ring buffer
+	keptSeq    int64
+	keptHeight int64
+	// updated by the push goroutine and read by the websocket goroutines
+	mutex sync.RWMutex
+}
+
+func NewPushStreams(capacity int) *PushStreams {
+	return &PushStreams{
+		records:    make([]*pushRecord, 0, 1024),
+		capacity:   capacity,
+		keptHeight: -1,
+	}
+}
+
+// Drops all the records and continues the sequence numbers from 'seq', used after loading hub's dump
+func (ps *PushStreams) Reset(seq int64) {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	ps.seq = seq
+	ps.keptSeq = seq
+	ps.keptHeight = -1
+	ps.records = make([]*pushRecord, 0, 1024)
+	ps.head = 0
+}
+
+// Called when a new block begins
+func (ps *PushStreams) SetHeight(height int64) {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	if ps.keptHeight < 0 {
+		// the messages before this block are not kept
+		ps.keptHeight = height - 1
+	}
+	ps.height = height
+}
+
+// Assigns the next sequence number to a message, and keeps this message if it belongs to some streams.
+// Returns the sequence number and the height of this message
+func (ps *PushStreams) Add(typeKey string, payload []byte, streams ...string) (int64, int64) {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	ps.seq++
+	if len(streams) == 0 || ps.capacity <= 0 {
+		return ps.seq, ps.height
+	}
+	ps.keep(&pushRecord{
+		streams: streams,
+		typeKey: typeKey,
+		seq:     ps.seq,
+		height:  ps.height,
+		// the payload may be reused by a db iterator
+		payload: append([]byte{}, payload...),
+	})
+	return ps.seq, ps.height
+}
+
+func (ps *PushStreams) keep(record *pushRecord) {
+	if len(ps.records) < ps.capacity {
+		ps.records = append(ps.records, record)
+		return
+	}
+	oldest := ps.records[ps.head]
+	ps.keptSeq = oldest.seq
+	if ps.keptHeight < oldest.height {
+		ps.keptHeight = oldest.height
+	}
+	ps.records[ps.head] = record
+	ps.head = (ps.head + 1) % ps.capacity
+}
+
+// Returns the latest sequence number and height
+func (ps *PushStreams) Latest() (int64, int64) {
+	ps.mutex.RLock()
+	defer ps.mutex.RUnlock()
+	return ps.seq, ps.height
+}
+
+// Returns the records of a stream whose seq is larger than sinceSeq, or whose height is larger than sinceHeight
+// if sinceSeq is zero. Returns false if some of these records are not kept.
+func (ps *PushStreams) Since(stream string, sinceSeq, sinceHeight int64) ([]*pushRecord, bool) {
+	ps.mutex.RLock()
+	defer ps.mutex.RUnlock()
+	if sinceSeq > 0 {
+		// a sequence number which has not been assigned yet comes from another server
+		if sinceSeq < ps.keptSeq || sinceSeq > ps.seq {
+			return nil, false
+		}
+	} else if ps.keptHeight < 0 || sinceHeight < ps.keptHeight {
+		return nil, false
+	}
+	res := make([]*pushRecord, 0, 16)
+	for i := 0; i < len(ps.records); i++ {
+		r := ps.records[(ps.head+i)%len(ps.records)]
+		if sinceSeq > 0 && r.seq <= sinceSeq {
+			continue
+		}
+		if sinceSeq == 0 && r.height <= sinceHeight {
+			continue
+		}
+		for _, s := range r.streams {
+			if s == stream {
+				res = append(res, r)
+				break
+			}
+		}
+	}
+	return res, true
+}
+
+// The stream name of a subscription topic with its params
+func getStreamName(topic string, params ...string) string {
+	return strings.Join(append([]string{topic}, params...), SeparateArgu)
+}
+
+func encodePushMsg(typeKey string, seq, height int64, info []byte) []byte {
+	if seq == 0 {
+		return []byte(fmt.Sprintf("{\"type\":\"%s\", \"payload\":%s}", typeKey, string(info)))
+	}
+	return []byte(fmt.Sprintf("{\"type\":\"%s\", \"seq\":%d, \"height\":%d, \"payload\":%s}",
+		typeKey, seq, height, string(info)))
+}
+
+func encodeLabeledPushMsg(typeKey, label string, seq, height int64, info []byte) []byte {
+	return []byte(fmt.Sprintf("{\"type\":\"%s\", \"label\":\"%s\", \"seq\":%d, \"height\":%d, \"payload\":%s}",
+		typeKey, label, seq, height, string(info)))
+}
+
+func encodeGapMsg(stream string, seq, height int64) []byte {
+	return []byte(fmt.Sprintf("{\"type\":\"%s\", \"payload\":{\"topic\":\"%s\",\"seq\":%d,\"height\":%d}}",
+		GapKey, stream, seq, height))
+}
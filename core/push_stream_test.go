@@ -0,0 +1,208 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	dbm "github.com/tendermint/tm-db"
+)
+
+func getSeqs(records []*pushRecord) []int64 {
+	seqs := make([]int64, 0, len(records))
+	for _, r := range records {
+		seqs = append(seqs, r.seq)
+	}
+	return seqs
+}
+
+// Waits for the push goroutine to push the message of seq
+func waitForPushSeq(t *testing.T, hub *Hub, seq int64) {
+	for i := 0; i < 100; i++ {
+		if pushed, _ := hub.pushStreams.Latest(); pushed >= seq {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	pushed, _ := hub.pushStreams.Latest()
+	require.EqualValues(t, seq, pushed)
+}
+
+func TestPushStreams(t *testing.T) {
+	ps := NewPushStreams(3)
+	_, ok := ps.Since("order:bob", 0, 0)
+	require.False(t, ok)
+
+	ps.SetHeight(10)
+	seq, height := ps.Add(CreateOrderKey, []byte("1"), "order:bob")
+	require.EqualValues(t, 1, seq)
+	require.EqualValues(t, 10, height)
+	ps.Add(TickerKey, nil)
+	ps.Add(CreateOrderKey, []byte("3"), "order:alice")
+	ps.SetHeight(11)
+	ps.Add(FillOrderKey, []byte("4"), "order:bob")
+
+	records, ok := ps.Since("order:bob", 1, 0)
+	require.True(t, ok)
+	require.EqualValues(t, []int64{4}, getSeqs(records))
+	require.EqualValues(t, "4", string(records[0].payload))
+	require.EqualValues(t, 11, records[0].height)
+	records, ok = ps.Since("order:bob", 0, 9)
+	require.True(t, ok)
+	require.EqualValues(t, []int64{1, 4}, getSeqs(records))
+	records, ok = ps.Since("order:bob", 4, 0)
+	require.True(t, ok)
+	require.EqualValues(t, 0, len(records))
+	_, ok = ps.Since("order:bob", 0, 8)
+	require.False(t, ok)
+	// a sequence number which has not been assigned
+	_, ok = ps.Since("order:bob", 5, 0)
+	require.False(t, ok)
+
+	// the record with seq 1 is dropped
+	ps.Add(CancelOrderKey, []byte("5"), "order:bob")
+	_, ok = ps.Since("order:bob", 0, 9)
+	require.False(t, ok)
+	records, ok = ps.Since("order:bob", 0, 10)
+	require.True(t, ok)
+	require.EqualValues(t, []int64{4, 5}, getSeqs(records))
+	records, ok = ps.Since("order:bob", 1, 0)
+	require.True(t, ok)
+	require.EqualValues(t, []int64{4, 5}, getSeqs(records))
+	ps.Add(CreateOrderKey, []byte("6"), "order:alice")
+	_, ok = ps.Since("order:bob", 1, 0)
+	require.False(t, ok)
+
+	ps.Reset(100)
+	_, ok = ps.Since("order:bob", 6, 0)
+	require.False(t, ok)
+	ps.SetHeight(20)
+	seq, _ = ps.Add(CreateOrderKey, []byte("101"), "order:bob")
+	require.EqualValues(t, 101, seq)
+	records, ok = ps.Since("order:bob", 100, 0)
+	require.True(t, ok)
+	require.EqualValues(t, []int64{101}, getSeqs(records))
+}
+
+func TestPushSeqAfterRestart(t *testing.T) {
+	db := dbm.NewMemDB()
+	hub := NewHub(db, &MocSubscribeManager{}, 99999, 0, 0, 0, "", 0)
+	hub.currBlockHeight = 999
+	newBlock := func(hub *Hub, height, timestamp int64, msgs ...string) {
+		bz, _ := json.Marshal(&NewHeightInfo{Height: height, TimeStamp: timestamp, ChainID: "coinex-test"})
+		hub.ConsumeMessage("height_info", bz)
+		for i := 0; i < len(msgs); i += 2 {
+			hub.ConsumeMessage(msgs[i], []byte(msgs[i+1]))
+		}
+		hub.ConsumeMessage("commit", nil)
+	}
+	newBlock(hub, 1000, 1000, "begin_unbonding",
+		`{"delegator":"bob","validator":"val","amount":"100","completion_time":1005}`)
+	// the messages which are not pushed yet are counted in the dump
+	hub4j := &HubForJSON{}
+	hub.Dump(hub4j)
+	// the height info, the ticker and the xticker
+	require.EqualValues(t, 3, hub4j.PushSeq)
+	block1001 := []string{
+		"send_lock_coins", `{"from_address":"alice","to_address":"bob","amount":[],"unlock_time":2000}`,
+		"complete_unbonding", `{"delegator":"bob","validator":"val"}`,
+	}
+	newBlock(hub, 1001, 1010, block1001...)
+	waitForPushSeq(t, hub, 6)
+	expected, ok := hub.pushStreams.Since(getStreamName(AccountKey, "bob"), 3, 0)
+	require.True(t, ok)
+	require.EqualValues(t, []int64{5, 6}, getSeqs(expected))
+	require.EqualValues(t, UnbondingKey, expected[1].typeKey)
+
+	// the messages consumed again get the same seqs, though the unbondings have no subscribers
+	newHub := NewHub(db, &MocSubscribeManager{}, 99999, 0, 0, 0, "", 0)
+	require.Nil(t, newHub.Load(hub4j))
+	newBlock(newHub, 1001, 1010, block1001...)
+	waitForPushSeq(t, newHub, 6)
+	records, ok := newHub.pushStreams.Since(getStreamName(AccountKey, "bob"), 3, 0)
+	require.True(t, ok)
+	require.EqualValues(t, len(expected), len(records))
+	for i, r := range records {
+		require.EqualValues(t, expected[i].seq, r.seq)
+		require.EqualValues(t, expected[i].typeKey, r.typeKey)
+		require.EqualValues(t, expected[i].payload, r.payload)
+	}
+}
+
+func TestEncodePushMsg(t *testing.T) {
+	require.EqualValues(t, `{"type":"deal", "payload":{}}`, string(encodePushMsg(DealKey, 0, 0, []byte("{}"))))
+	require.EqualValues(t, `{"type":"deal", "seq":3, "height":10, "payload":{}}`,
+		string(encodePushMsg(DealKey, 3, 10, []byte("{}"))))
+	require.EqualValues(t, `{"type":"gap", "payload":{"topic":"deal:abc/cet","seq":3,"height":10}}`,
+		string(encodeGapMsg("deal:abc/cet", 3, 10)))
+}
+
+func TestResumeSubscribeConn(t *testing.T) {
+	wsManager := NewWebSocketManager()
+	hub := NewHub(dbm.NewMemDB(), wsManager, 99999, 0, 0, 0, "", 0)
+	hub.msgsChannel <- MsgToPush{topic: BlockInfoKey, bz: []byte("{}"), extra: int64(10)}
+	hub.msgsChannel <- MsgToPush{topic: CreateOrderKey, bz: []byte(`{"id":1}`), extra: "bob"}
+	hub.msgsChannel <- MsgToPush{topic: DealKey, bz: []byte(`{"id":2}`), extra: "abc/cet"}
+	hub.msgsChannel <- MsgToPush{topic: FillOrderKey, bz: []byte(`{"id":3}`), extra: "bob"}
+	waitForPushSeq(t, hub, 4)
+
+	wsIfc := &MockWif{}
+	conn := wsManager.AddWsConn(wsIfc)
+	gaps, err := wsManager.ResumeSubscribeConn(hub, conn, []Subscription{{OrderKey, []string{"bob"}}}, 2, 0)
+	require.Nil(t, err)
+	require.EqualValues(t, 0, len(gaps))
+	ticker := Subscription{TickerKey, []string{"abc/cet"}}
+	gaps, err = wsManager.ResumeSubscribeConn(hub, conn, []Subscription{ticker}, 2, 0)
+	require.Nil(t, err)
+	require.EqualValues(t, []Subscription{ticker}, gaps)
+	hub.msgsChannel <- MsgToPush{topic: CancelOrderKey, bz: []byte(`{"id":5}`), extra: "bob"}
+	waitForPushSeq(t, hub, 5)
+
+	time.Sleep(10 * time.Millisecond)
+	records := wsIfc.GetRecords()
+	require.EqualValues(t, 3, len(records))
+	require.EqualValues(t, `{"type":"fill_order", "seq":4, "height":10, "payload":{"id":3}}`, string(records[0]))
+	require.EqualValues(t, `{"type":"gap", "payload":{"topic":"ticker:abc/cet","seq":4,"height":10}}`, string(records[1]))
+	require.EqualValues(t, `{"type":"cancel_order", "seq":5, "height":10, "payload":{"id":5}}`, string(records[2]))
+}
+
+func TestResumeSubscribeConnWithTopics(t *testing.T) {
+	wsManager := NewWebSocketManager()
+	hub := NewHub(dbm.NewMemDB(), wsManager, 99999, 0, 0, 0, "", 0)
+	hub.msgsChannel <- MsgToPush{topic: BlockInfoKey, bz: []byte("{}"), extra: int64(10)}
+	hub.msgsChannel <- MsgToPush{topic: CreateOrderKey, bz: []byte(`{"id":1}`), extra: "bob"}
+	hub.msgsChannel <- MsgToPush{topic: DealKey, bz: []byte(`{"id":2}`), extra: "abc/cet"}
+	hub.msgsChannel <- MsgToPush{topic: FillOrderKey, bz: []byte(`{"id":3}`), extra: "bob"}
+	hub.msgsChannel <- MsgToPush{topic: DealKey, bz: []byte(`{"id":4}`), extra: "abc/cet"}
+	waitForPushSeq(t, hub, 5)
+
+	// the messages of the topics are replayed in the order of seqs, before the messages pushed later
+	wsIfc := &MockWif{}
+	conn := wsManager.AddWsConn(wsIfc)
+	gaps, err := wsManager.ResumeSubscribeConn(hub, conn, []Subscription{
+		{DealKey, []string{"abc/cet"}}, {OrderKey, []string{"bob"}}}, 1, 0)
+	require.Nil(t, err)
+	require.EqualValues(t, 0, len(gaps))
+	hub.msgsChannel <- MsgToPush{topic: DealKey, bz: []byte(`{"id":6}`), extra: "abc/cet"}
+	waitForPushSeq(t, hub, 6)
+
+	time.Sleep(10 * time.Millisecond)
+	records := wsIfc.GetRecords()
+	require.EqualValues(t, 5, len(records))
+	for i, seq := range []int{2, 3, 4, 5, 6} {
+		require.Contains(t, string(records[i]), fmt.Sprintf(`"seq":%d,`, seq))
+	}
+
+	// all the subscriptions get gaps if the send queue has no room for the replayed messages
+	cfg := DefaultSendQueueConfig()
+	cfg.Size = 4
+	require.Nil(t, wsManager.SetSendQueueConfig(cfg))
+	small := wsManager.AddWsConn(&MockWif{})
+	subs := []Subscription{{DealKey, []string{"abc/cet"}}, {OrderKey, []string{"bob"}}}
+	gaps, err = wsManager.ResumeSubscribeConn(hub, small, subs, 1, 0)
+	require.Nil(t, err)
+	require.EqualValues(t, subs, gaps)
+	require.EqualValues(t, 0, len(small.allTopics))
+}
@@ -0,0 +1,99 @@
+package core
+
+import (
+	"bytes"
+	"runtime"
+	"sync"
+)
+
+// The number of goroutines which deliver the pushed messages to the connections
+var PushWorkerCount = runtime.NumCPU()
+
+// The pushes fewer than this number are delivered by the push goroutine itself
+const MinParallelPushes = 256
+
+type pushTask struct {
+	conn *Conn
+	msg  *pushMsg
+}
+
+type pushBatch struct {
+	tasks []pushTask
+	wg    *sync.WaitGroup
+}
+
+// fanOutPusher collects the pushes of one hub entry and delivers them with a pool of workers.
+// The connections are sharded among the workers, such that the messages to one connection keep their order
+type fanOutPusher struct {
+	manager *WebsocketManager
+	// the last pushed message, which is reused by its following subscribers
+	lastMsg *pushMsg
+	pending []pushTask
+
+	workerCount  int
+	workers      []chan pushBatch
+	startWorkers sync.Once
+}
+
+func newFanOutPusher(manager *WebsocketManager, workerCount int) *fanOutPusher {
+	if workerCount < 1 {
+		workerCount = 1
+	}
+	return &fanOutPusher{
+		manager:     manager,
+		workerCount: workerCount,
+	}
+}
+
+// The envelope of a message is encoded only once for all its subscribers with the same label
+func (p *fanOutPusher) add(conn *Conn, label, typeKey string, seq, height int64, payload []byte) {
+	msg := p.lastMsg
+	if msg == nil || msg.typeKey != typeKey || msg.seq != seq || msg.label != label || !bytes.Equal(msg.payload, payload) {
+		msg = newPushMsg(typeKey, seq, height, payload)
+		msg.label = label
+		p.lastMsg = msg
+	}
+	p.pending = append(p.pending, pushTask{conn: conn, msg: msg})
+}
+
+// Delivers the pending pushes and waits until they are all queued by their connections
+func (p *fanOutPusher) flush() {
+	tasks := p.pending
+	p.pending = nil
+	p.lastMsg = nil
+	if len(tasks) < MinParallelPushes || p.workerCount == 1 {
+		for _, t := range tasks {
+			p.manager.deliver(t.conn, t.msg)
+		}
+		return
+	}
+	p.startWorkers.Do(func() {
+		p.workers = make([]chan pushBatch, p.workerCount)
+		for i := range p.workers {
+			p.workers[i] = make(chan pushBatch)
+			go p.work(p.workers[i])
+		}
+	})
+	shards := make([][]pushTask, p.workerCount)
+	for _, t := range tasks {
+		i := int(t.conn.id % int64(p.workerCount))
+		shards[i] = append(shards[i], t)
+	}
+	var wg sync.WaitGroup
+	for i, shard := range shards {
+		if len(shard) != 0 {
+			wg.Add(1)
+			p.workers[i] <- pushBatch{tasks: shard, wg: &wg}
+		}
+	}
+	wg.Wait()
+}
+
+func (p *fanOutPusher) work(batches chan pushBatch) {
+	for batch := range batches {
+		for _, t := range batch.tasks {
+			p.manager.deliver(t.conn, t.msg)
+		}
+		batch.wg.Done()
+	}
+}
@@ -0,0 +1,117 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// The rebate paid to a referee for a trade of the account it refers, which is pushed on 'rebate:<referee>'.
+// OrderID is only for the orders, and the rebates of bancor trades are in the markets of their stock and money
+type NotificationRebate struct {
+	Referee   string `json:"referee"`
+	Trader    string `json:"trader"`
+	Market    string `json:"market"`
+	Amount    int64  `json:"amount"`
+	OrderID   string `json:"order_id,omitempty"`
+	TxHash    string `json:"tx_hash,omitempty"`
+	Height    int64  `json:"height"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// The rebates of a referee in a market in one day of UTC, Time is the start time of the day
+type RebateStats struct {
+	Market string  `json:"market"`
+	Time   int64   `json:"time"`
+	Amount sdk.Int `json:"amount"`
+	Count  int64   `json:"count"`
+}
+
+// Height is the last block which updates the record, as accountStatsRecord
+type rebateRecord struct {
+	Height int64 `json:"height"`
+	RebateStats
+}
+
+func (hub *Hub) handleRebate(v NotificationRebate) {
+	if v.Amount <= 0 || len(v.Referee) == 0 {
+		return
+	}
+	dayTime := hub.currBlockTime.Unix() / SecondsPerDay * SecondsPerDay
+	key := getRebateKey(v.Referee, dayTime, v.Market)
+	rec, ok := hub.rebates[string(key)]
+	if !ok {
+		rec = &rebateRecord{
+			Height:      hub.currBlockHeight,
+			RebateStats: RebateStats{Market: v.Market, Time: dayTime, Amount: sdk.ZeroInt()},
+		}
+		if bz := hub.db.Get(key); bz != nil {
+			var last rebateRecord
+			if err := json.Unmarshal(bz, &last); err != nil {
+				hub.Log(fmt.Sprintf("Error in Unmarshal RebateStats: %v", err))
+			} else if last.Height >= hub.currBlockHeight {
+				rec = nil
+			} else {
+				last.Height = hub.currBlockHeight
+				rec = &last
+			}
+		}
+		hub.rebates[string(key)] = rec
+	}
+	// the blocks consumed again after restart are neither counted nor pushed twice
+	if rec == nil {
+		return
+	}
+	rec.Amount = rec.Amount.AddRaw(v.Amount)
+	rec.Count++
+
+	v.Height, v.Timestamp = hub.currBlockHeight, hub.currBlockTime.Unix()
+	bz, err := json.Marshal(v)
+	if err != nil {
+		hub.Log(fmt.Sprintf("Error in Marshal NotificationRebate: %v", err))
+		return
+	}
+	hub.enqueuePush(MsgToPush{topic: RebateKey, bz: bz, extra: v.Referee})
+}
+
+func (hub *Hub) commitForRebates() {
+	for key, rec := range hub.rebates {
+		if rec == nil {
+			continue
+		}
+		bz, err := json.Marshal(rec)
+		if err != nil {
+			hub.Log(fmt.Sprintf("Error in Marshal RebateStats: %v", err))
+			continue
+		}
+		hub.batch.Set([]byte(key), bz)
+	}
+	hub.rebates = make(map[string]*rebateRecord)
+}
+
+// Returns the rebates of the referee in the days which overlap [from, to], ordered by the day and then the market.
+// Zero from or to means unbounded
+func (hub *Hub) QueryRebates(referee string, from, to int64) []RebateStats {
+	start := getRebateKey(referee, from/SecondsPerDay*SecondsPerDay, "")
+	end := getRebateKey(referee, to/SecondsPerDay*SecondsPerDay+SecondsPerDay, "")
+	if to == 0 {
+		end = end[:len(end)-8]
+		end[len(end)-1]++
+	}
+
+	hub.dbMutex.RLock()
+	defer hub.dbMutex.RUnlock()
+	iter := hub.db.Iterator(start, end)
+	defer iter.Close()
+	res := make([]RebateStats, 0)
+	for ; iter.Valid(); iter.Next() {
+		var rec rebateRecord
+		if err := json.Unmarshal(iter.Value(), &rec); err != nil {
+			hub.Log(fmt.Sprintf("Error in Unmarshal RebateStats: %v", err))
+			continue
+		}
+		res = append(res, rec.RebateStats)
+	}
+	return res
+}
@@ -0,0 +1,84 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/require"
+	dbm "github.com/tendermint/tm-db"
+)
+
+func TestRebates(t *testing.T) {
+	acc, _ := simpleAddr("00001")
+	addr := acc.String()
+	db := dbm.NewMemDB()
+	subMan := &MocSubscribeManager{}
+	subscriber := &PlainSubscriber{ID: 1}
+	subMan.RebateSubscribeInfo = map[string][]Subscriber{"alice": {subscriber}}
+	hub := NewHub(db, subMan, 99999, 0, 0, 0, "", 0)
+	hub.currBlockHeight = 999
+	cancel := func(seq int, market string, rebate int64, referee string) {
+		bz, _ := json.Marshal(&CancelOrderInfo{
+			OrderID:           fmt.Sprintf("%s-%d", addr, seq),
+			TradingPair:       market,
+			Height:            hub.currBlockHeight,
+			Price:             sdk.NewDec(1),
+			RebateAmount:      rebate,
+			RebateRefereeAddr: referee,
+		})
+		hub.ConsumeMessage("del_order_info", bz)
+	}
+	newBlock := func(height, timestamp int64, actions func()) {
+		bz, _ := json.Marshal(&NewHeightInfo{Height: height, TimeStamp: timestamp, ChainID: "coinex-test"})
+		hub.ConsumeMessage("height_info", bz)
+		actions()
+		hub.ConsumeMessage("commit", nil)
+	}
+	day := int64(SecondsPerDay)
+	newBlock(1000, day+10, func() {
+		cancel(1, "abc/cet", 10, "alice")
+		cancel(2, "abc/cet", 5, "alice")
+		cancel(3, "xyz/cet", 7, "alice")
+		cancel(4, "abc/cet", 3, "bob")
+		cancel(5, "abc/cet", 0, "")
+	})
+	block1001 := func() {
+		cancel(6, "abc/cet", 1, "alice")
+		bz, _ := json.Marshal(&MsgBancorTradeInfoForKafka{Sender: addr, Stock: "abc", Money: "cet", Amount: 10,
+			Side: BUY, TxPrice: sdk.NewDec(1), BlockHeight: 1001, RebateAmount: 2, RebateRefereeAddr: "alice"})
+		hub.ConsumeMessage("bancor_trade", bz)
+	}
+	newBlock(1001, 2*day+10, block1001)
+
+	stats := func(market string, dayTime, amount, count int64) RebateStats {
+		return RebateStats{Market: market, Time: dayTime, Amount: sdk.NewInt(amount), Count: count}
+	}
+	require.EqualValues(t, []RebateStats{stats("abc/cet", day, 15, 2), stats("xyz/cet", day, 7, 1),
+		stats("abc/cet", 2*day, 3, 2)}, hub.QueryRebates("alice", 0, 0))
+	require.EqualValues(t, []RebateStats{stats("abc/cet", day, 15, 2), stats("xyz/cet", day, 7, 1)},
+		hub.QueryRebates("alice", day+100, day+200))
+	require.EqualValues(t, []RebateStats{stats("abc/cet", 2*day, 3, 2)}, hub.QueryRebates("alice", 2*day-1+day, 0))
+	require.EqualValues(t, []RebateStats{stats("abc/cet", day, 3, 1)}, hub.QueryRebates("bob", 0, 0))
+	require.EqualValues(t, 0, len(hub.QueryRebates("alice", 3*day, 0)))
+
+	// the rebates are pushed to the subscribers of their referees
+	time.Sleep(10 * time.Millisecond)
+	subMan.Lock()
+	require.EqualValues(t, 5, len(subMan.PushList))
+	require.Equal(t, `{"referee":"alice","trader":"`+addr+`","market":"abc/cet","amount":2,"height":1001,"timestamp":`+
+		fmt.Sprintf("%d", 2*day+10)+`}`, subMan.PushList[4].Payload)
+	subMan.PushList = nil
+	subMan.Unlock()
+
+	// the block consumed again after restart is neither counted nor pushed twice
+	hub = NewHub(db, subMan, 99999, 0, 0, 1000, "", 0)
+	newBlock(1001, 2*day+10, block1001)
+	require.EqualValues(t, stats("abc/cet", 2*day, 3, 2), hub.QueryRebates("alice", 2*day, 0)[0])
+	time.Sleep(10 * time.Millisecond)
+	subMan.Lock()
+	defer subMan.Unlock()
+	require.EqualValues(t, 0, len(subMan.PushList))
+}
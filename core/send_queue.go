@@ -0,0 +1,487 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/gorilla/websocket"
+)
+
+// The policies applied to a pushed message when the send queue of its connection is full
+const (
+	// drop the message, and a 'dropped' message is sent before the next queued message
+	PolicyDrop = "drop"
+	// merge the message into the last queued message of its stream, drop it if there is no such message
+	PolicyCoalesce = "coalesce"
+	// close the connection with CloseSlowConsumer
+	PolicyDisconnect = "disconnect"
+)
+
+// The topic classes, each class can have its own policy
+const (
+	ClassDepth   = "depth"  // depth_change, depth_delta and depth_full
+	ClassTicker  = "ticker" // ticker and xticker
+	ClassDefault = "default"
+)
+
+// The close code sent to the connections which are closed by PolicyDisconnect
+const CloseSlowConsumer = websocket.ClosePolicyViolation
+
+var ErrSlowConsumer = errors.New("the send queue is full")
+
+type SendQueueConfig struct {
+	// the max number of pushed messages in the queue of one connection
+	Size int `json:"size"`
+	// topic class --> policy
+	Policies map[string]string `json:"policies"`
+}
+
+func DefaultSendQueueConfig() SendQueueConfig {
+	return SendQueueConfig{
+		Size: 1024,
+		Policies: map[string]string{
+			ClassDepth:   PolicyCoalesce,
+			ClassTicker:  PolicyCoalesce,
+			ClassDefault: PolicyDrop,
+		},
+	}
+}
+
+func (cfg SendQueueConfig) Validate() error {
+	if cfg.Size <= 0 {
+		return fmt.Errorf("invalid send queue size: %d", cfg.Size)
+	}
+	for class, policy := range cfg.Policies {
+		if class != ClassDepth && class != ClassTicker && class != ClassDefault {
+			return fmt.Errorf("unknown topic class: %s", class)
+		}
+		if policy != PolicyDrop && policy != PolicyCoalesce && policy != PolicyDisconnect {
+			return fmt.Errorf("unknown send queue policy: %s", policy)
+		}
+	}
+	return nil
+}
+
+func (cfg SendQueueConfig) getPolicy(class string) string {
+	if policy, ok := cfg.Policies[class]; ok {
+		return policy
+	}
+	return cfg.Policies[ClassDefault]
+}
+
+func getTopicClass(typeKey string) string {
+	switch typeKey {
+	case DepthChange, DepthDelta, DepthFull:
+		return ClassDepth
+	case TickerKey, XTickerKey:
+		return ClassTicker
+	}
+	return ClassDefault
+}
+
+// The statistics of a connection's send queue
+type SendQueueStats struct {
+	ConnID      int64 `json:"conn_id"`
+	Topics      int   `json:"topics"`
+	Length      int   `json:"length"`
+	Capacity    int   `json:"capacity"`
+	MaxLength   int   `json:"max_length"`
+	Sent        int64 `json:"sent"`
+	Dropped     int64 `json:"dropped"`
+	Coalesced   int64 `json:"coalesced"`
+	SlowClosed  bool  `json:"slow_closed"`
+	LastDropSeq int64 `json:"last_drop_seq"`
+}
+
+// A pushed message, which is shared by all its subscribers and encoded only once
+type pushMsg struct {
+	typeKey string
+	seq     int64
+	height  int64
+	payload []byte
+	// the subscription matched by this message, only for the labeled connections
+	label string
+
+	encodeOnce sync.Once
+	encoded    []byte
+	// the market of a depth message, which is decoded when needed
+	marketOnce sync.Once
+	market     string
+
+	// the binary encodings are also shared
+	gzipOnce    sync.Once
+	gzipped     []byte
+	msgpackOnce sync.Once
+	msgpackType int
+	packed      []byte
+}
+
+func newPushMsg(typeKey string, seq, height int64, payload []byte) *pushMsg {
+	return &pushMsg{
+		typeKey: typeKey,
+		seq:     seq,
+		height:  height,
+		// the payload may be reused by a db iterator
+		payload: append([]byte{}, payload...),
+	}
+}
+
+func (m *pushMsg) encode() []byte {
+	m.encodeOnce.Do(func() {
+		if len(m.label) != 0 {
+			m.encoded = encodeLabeledPushMsg(m.typeKey, m.label, m.seq, m.height, m.payload)
+		} else {
+			m.encoded = encodePushMsg(m.typeKey, m.seq, m.height, m.payload)
+		}
+	})
+	return m.encoded
+}
+
+func (m *pushMsg) encodeAs(encoding string) (int, []byte) {
+	switch encoding {
+	case EncodingGzip:
+		m.gzipOnce.Do(func() {
+			_, m.gzipped = encodeMessage(encoding, m.encode())
+		})
+		return websocket.BinaryMessage, m.gzipped
+	case EncodingMsgpack:
+		m.msgpackOnce.Do(func() {
+			m.msgpackType, m.packed = encodeMessage(encoding, m.encode())
+		})
+		return m.msgpackType, m.packed
+	}
+	return websocket.TextMessage, m.encode()
+}
+
+func (m *pushMsg) getMarket() string {
+	m.marketOnce.Do(func() {
+		var v struct {
+			TradingPair string `json:"trading_pair"`
+		}
+		if err := json.Unmarshal(m.payload, &v); err == nil {
+			m.market = v.TradingPair
+		}
+	})
+	return m.market
+}
+
+// A message in the send queue, which is either a pushed message or a raw one, such as the response to a command
+type queuedMsg struct {
+	push *pushMsg
+	raw  []byte
+}
+
+func (m *queuedMsg) encode() []byte {
+	if m.push == nil {
+		return m.raw
+	}
+	return m.push.encode()
+}
+
+// Returns the frame type and the encoded bytes
+func (m *queuedMsg) encodeAs(encoding string) (int, []byte) {
+	if m.push == nil {
+		return encodeMessage(encoding, m.raw)
+	}
+	return m.push.encodeAs(encoding)
+}
+
+// The bounded outbound queue of a connection.
+// The pushed (including the replayed) messages and the raw messages are bounded separately. The raw messages
+// are never dropped, and the connection should be closed when there are too many of them
+type sendQueue struct {
+	mtx    sync.Mutex
+	cfg    SendQueueConfig
+	msgs   []*queuedMsg
+	notify chan struct{}
+	closed bool
+	// the number of pushed and raw messages in msgs
+	pushed int
+	raw    int
+
+	// the first dropped message since the last 'dropped' message, and the number of dropped messages
+	firstDropped *pushMsg
+	dropCount    int64
+
+	stats SendQueueStats
+}
+
+func newSendQueue(cfg SendQueueConfig) *sendQueue {
+	return &sendQueue{
+		cfg:    cfg,
+		msgs:   make([]*queuedMsg, 0, 16),
+		notify: make(chan struct{}, 1),
+	}
+}
+
+func (q *sendQueue) wakeup() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Returns ErrSlowConsumer if the connection should be closed
+func (q *sendQueue) pushRaw(msg []byte) error {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	if q.closed {
+		return nil
+	}
+	if q.raw >= q.cfg.Size {
+		q.stats.SlowClosed = true
+		return ErrSlowConsumer
+	}
+	q.msgs = append(q.msgs, &queuedMsg{raw: msg})
+	q.raw++
+	q.updateMaxLength()
+	q.wakeup()
+	return nil
+}
+
+// The replayed messages are queued together only if there is room for all of them, since none of them
+// can be dropped. Returns false if they are not queued
+func (q *sendQueue) pushReplays(msgs []*pushMsg) bool {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	if q.closed {
+		return true
+	}
+	if q.pushed+len(msgs) > q.cfg.Size {
+		return false
+	}
+	for _, msg := range msgs {
+		q.msgs = append(q.msgs, &queuedMsg{push: msg})
+	}
+	q.pushed += len(msgs)
+	q.updateMaxLength()
+	q.wakeup()
+	return true
+}
+
+// Returns ErrSlowConsumer if the connection should be closed
+func (q *sendQueue) push(msg *pushMsg) error {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	if q.closed {
+		return nil
+	}
+	if q.pushed >= q.cfg.Size {
+		switch q.cfg.getPolicy(getTopicClass(msg.typeKey)) {
+		case PolicyDisconnect:
+			q.stats.SlowClosed = true
+			return ErrSlowConsumer
+		case PolicyCoalesce:
+			if q.coalesce(msg) {
+				q.stats.Coalesced++
+				return nil
+			}
+		}
+		q.drop(msg)
+		return nil
+	}
+	if q.firstDropped != nil {
+		q.msgs = append(q.msgs, &queuedMsg{raw: encodeDroppedMsg(q.firstDropped, q.dropCount)})
+		q.firstDropped = nil
+		q.dropCount = 0
+	}
+	q.msgs = append(q.msgs, &queuedMsg{push: msg})
+	q.pushed++
+	q.updateMaxLength()
+	q.wakeup()
+	return nil
+}
+
+func (q *sendQueue) drop(msg *pushMsg) {
+	if q.firstDropped == nil {
+		q.firstDropped = msg
+	}
+	q.dropCount++
+	q.stats.Dropped++
+	q.stats.LastDropSeq = msg.seq
+}
+
+// Merges msg into the last queued message of its stream, if they have the same type. The merged message is
+// moved to the tail of the queue, such that the seqs are still in order. msg is not merged if the last queued
+// message of its stream has another type, such as a depth_full after the depth_change to be merged into
+func (q *sendQueue) coalesce(msg *pushMsg) bool {
+	for i := len(q.msgs) - 1; i >= 0; i-- {
+		old := q.msgs[i].push
+		if old == nil || !isSameStream(old, msg) {
+			continue
+		}
+		if old.typeKey != msg.typeKey {
+			return false
+		}
+		merged, err := mergePayloads(msg.typeKey, old.payload, msg.payload)
+		if err != nil {
+			return false
+		}
+		// the queued message may be shared with other connections, so it is replaced instead of modified
+		copy(q.msgs[i:], q.msgs[i+1:])
+		q.msgs[len(q.msgs)-1] = &queuedMsg{push: &pushMsg{typeKey: msg.typeKey, seq: msg.seq, height: msg.height,
+			payload: merged, label: msg.label}}
+		return true
+	}
+	return false
+}
+
+// The messages of a stream match the same subscription (which includes the depth level for the labeled
+// connections), and they are the depth messages of the same market or the messages of the same type
+func isSameStream(a, b *pushMsg) bool {
+	if a.label != b.label || getTopicClass(a.typeKey) != getTopicClass(b.typeKey) {
+		return false
+	}
+	if getTopicClass(a.typeKey) == ClassDepth {
+		return a.getMarket() == b.getMarket()
+	}
+	return a.typeKey == b.typeKey
+}
+
+func (q *sendQueue) updateMaxLength() {
+	if len(q.msgs) > q.stats.MaxLength {
+		q.stats.MaxLength = len(q.msgs)
+	}
+}
+
+// Blocks until there are some messages, returns false if the queue is closed
+func (q *sendQueue) popAll() ([]*queuedMsg, bool) {
+	for {
+		q.mtx.Lock()
+		if q.closed {
+			q.mtx.Unlock()
+			return nil, false
+		}
+		if len(q.msgs) != 0 {
+			msgs := q.msgs
+			q.msgs = make([]*queuedMsg, 0, 16)
+			q.pushed = 0
+			q.raw = 0
+			q.mtx.Unlock()
+			return msgs, true
+		}
+		q.mtx.Unlock()
+		<-q.notify
+	}
+}
+
+func (q *sendQueue) addSent(n int) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	q.stats.Sent += int64(n)
+}
+
+func (q *sendQueue) close() {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	q.closed = true
+	q.wakeup()
+}
+
+func (q *sendQueue) getStats() SendQueueStats {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	stats := q.stats
+	stats.Length = len(q.msgs)
+	stats.Capacity = q.cfg.Size
+	return stats
+}
+
+func encodeDroppedMsg(first *pushMsg, count int64) []byte {
+	return []byte(fmt.Sprintf("{\"type\":\"%s\", \"payload\":{\"seq\":%d,\"height\":%d,\"count\":%d}}",
+		DroppedKey, first.seq, first.height, count))
+}
+
+// Merges two pushed payloads of the same type into one, the later one takes precedence
+func mergePayloads(typeKey string, older, newer []byte) ([]byte, error) {
+	switch typeKey {
+	case DepthChange, DepthDelta:
+		return mergeDepthPayloads(typeKey == DepthDelta, older, newer)
+	case DepthFull:
+		return newer, nil
+	case TickerKey, XTickerKey:
+		return mergeTickerPayloads(older, newer)
+	}
+	return nil, fmt.Errorf("%s can not be coalesced", typeKey)
+}
+
+// depth_delta carries the changes of amounts, which are summed up.
+// depth_change carries the new amounts, which override the older ones.
+func mergeDepthPayloads(isDelta bool, older, newer []byte) ([]byte, error) {
+	var o, n DepthDetails
+	if err := json.Unmarshal(older, &o); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(newer, &n); err != nil {
+		return nil, err
+	}
+	n.Bids = mergePricePoints(isDelta, o.Bids, n.Bids)
+	n.Asks = mergePricePoints(isDelta, o.Asks, n.Asks)
+	sort.Slice(n.Bids, func(i, j int) bool {
+		return n.Bids[i].Price.GT(n.Bids[j].Price)
+	})
+	sort.Slice(n.Asks, func(i, j int) bool {
+		return n.Asks[i].Price.LT(n.Asks[j].Price)
+	})
+	return json.Marshal(n)
+}
+
+func mergePricePoints(isDelta bool, older, newer []*PricePoint) []*PricePoint {
+	if len(older)+len(newer) == 0 {
+		return []*PricePoint{}
+	}
+	points := make(map[string]*PricePoint, len(older)+len(newer))
+	for _, pp := range older {
+		points[pp.Price.String()] = pp
+	}
+	for _, pp := range newer {
+		key := pp.Price.String()
+		if old, ok := points[key]; ok && isDelta {
+			points[key] = &PricePoint{Price: pp.Price, Amount: old.Amount.Add(pp.Amount)}
+		} else {
+			points[key] = pp
+		}
+	}
+	res := make([]*PricePoint, 0, len(points))
+	for _, pp := range points {
+		if isDelta && pp.Amount.Equal(sdk.ZeroInt()) {
+			continue
+		}
+		res = append(res, pp)
+	}
+	return res
+}
+
+// The tickers are merged by their markets
+func mergeTickerPayloads(older, newer []byte) ([]byte, error) {
+	var o, n []json.RawMessage
+	if err := json.Unmarshal(older, &o); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(newer, &n); err != nil {
+		return nil, err
+	}
+	getMarket := func(raw json.RawMessage) string {
+		var v struct {
+			Market string `json:"market"`
+		}
+		_ = json.Unmarshal(raw, &v)
+		return v.Market
+	}
+	updated := make(map[string]struct{}, len(n))
+	for _, raw := range n {
+		updated[getMarket(raw)] = struct{}{}
+	}
+	res := make([]json.RawMessage, 0, len(o)+len(n))
+	for _, raw := range o {
+		if _, ok := updated[getMarket(raw)]; !ok {
+			res = append(res, raw)
+		}
+	}
+	res = append(res, n...)
+	return json.Marshal(res)
+}
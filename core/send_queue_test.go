@@ -0,0 +1,210 @@
+package core
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func encodeQueuedMsgs(msgs []*queuedMsg) []string {
+	res := make([]string, 0, len(msgs))
+	for _, msg := range msgs {
+		res = append(res, string(msg.encode()))
+	}
+	return res
+}
+
+func TestSendQueueDrop(t *testing.T) {
+	cfg := DefaultSendQueueConfig()
+	cfg.Size = 2
+	q := newSendQueue(cfg)
+	require.Nil(t, q.push(newPushMsg(DealKey, 1, 10, []byte(`{"id":1}`))))
+	require.Nil(t, q.pushRaw([]byte("pong")))
+	require.Nil(t, q.push(newPushMsg(DealKey, 2, 10, []byte(`{"id":2}`))))
+	require.Nil(t, q.push(newPushMsg(DealKey, 3, 11, []byte(`{"id":3}`))))
+	require.Nil(t, q.push(newPushMsg(DealKey, 4, 11, []byte(`{"id":4}`))))
+	msgs, ok := q.popAll()
+	require.True(t, ok)
+	require.EqualValues(t, []string{
+		`{"type":"deal", "seq":1, "height":10, "payload":{"id":1}}`,
+		`pong`,
+		`{"type":"deal", "seq":2, "height":10, "payload":{"id":2}}`,
+	}, encodeQueuedMsgs(msgs))
+
+	require.Nil(t, q.push(newPushMsg(DealKey, 5, 12, []byte(`{"id":5}`))))
+	msgs, _ = q.popAll()
+	require.EqualValues(t, []string{
+		`{"type":"dropped", "payload":{"seq":3,"height":11,"count":2}}`,
+		`{"type":"deal", "seq":5, "height":12, "payload":{"id":5}}`,
+	}, encodeQueuedMsgs(msgs))
+
+	stats := q.getStats()
+	require.EqualValues(t, 2, stats.Dropped)
+	require.EqualValues(t, 4, stats.LastDropSeq)
+	require.EqualValues(t, 3, stats.MaxLength)
+	require.EqualValues(t, 2, stats.Capacity)
+}
+
+func TestSendQueueBounds(t *testing.T) {
+	cfg := DefaultSendQueueConfig()
+	cfg.Size = 2
+	q := newSendQueue(cfg)
+	// the replayed messages are queued only if there is room for all of them
+	require.Nil(t, q.push(newPushMsg(DealKey, 1, 10, []byte(`{"id":1}`))))
+	require.False(t, q.pushReplays([]*pushMsg{newPushMsg(DealKey, 2, 10, nil), newPushMsg(DealKey, 3, 10, nil)}))
+	require.True(t, q.pushReplays([]*pushMsg{newPushMsg(DealKey, 2, 10, []byte(`{"id":2}`))}))
+	require.Nil(t, q.pushRaw([]byte("pong")))
+	require.Nil(t, q.pushRaw([]byte("pong")))
+	require.Equal(t, ErrSlowConsumer, q.pushRaw([]byte("pong")))
+	require.True(t, q.getStats().SlowClosed)
+	msgs, _ := q.popAll()
+	require.EqualValues(t, []string{
+		`{"type":"deal", "seq":1, "height":10, "payload":{"id":1}}`,
+		`{"type":"deal", "seq":2, "height":10, "payload":{"id":2}}`,
+		`pong`,
+		`pong`,
+	}, encodeQueuedMsgs(msgs))
+	require.Nil(t, q.pushRaw([]byte("pong")))
+}
+
+func TestSendQueueCoalesce(t *testing.T) {
+	cfg := DefaultSendQueueConfig()
+	cfg.Size = 3
+	q := newSendQueue(cfg)
+	require.Nil(t, q.push(newPushMsg(DepthDelta, 1, 10, []byte(`{"trading_pair":"abc/cet","bids":[{"p":"1.000000000000000000","a":"10"}],"asks":null,"height":10,"checksum":0}`))))
+	require.Nil(t, q.push(newPushMsg(DepthDelta, 2, 10, []byte(`{"trading_pair":"xyz/cet","bids":null,"asks":[{"p":"3.000000000000000000","a":"1"}],"height":10,"checksum":0}`))))
+	require.Nil(t, q.push(newPushMsg(TickerKey, 3, 10, []byte(`[{"market":"abc/cet","new":"1.0"},{"market":"xyz/cet","new":"2.0"}]`))))
+	require.Nil(t, q.push(newPushMsg(DepthDelta, 4, 11, []byte(`{"trading_pair":"abc/cet","bids":[{"p":"1.000000000000000000","a":"5"},{"p":"2.000000000000000000","a":"-3"}],"asks":null,"height":11,"checksum":0}`))))
+	require.Nil(t, q.push(newPushMsg(TickerKey, 5, 11, []byte(`[{"market":"abc/cet","new":"1.5"}]`))))
+	require.Nil(t, q.push(newPushMsg(DealKey, 6, 11, []byte(`{}`))))
+	msgs, _ := q.popAll()
+	// the merged messages are moved to the tail, and the empty sides are still arrays
+	require.EqualValues(t, []string{
+		`{"type":"depth_delta", "seq":2, "height":10, "payload":{"trading_pair":"xyz/cet","bids":null,"asks":[{"p":"3.000000000000000000","a":"1"}],"height":10,"checksum":0}}`,
+		`{"type":"depth_delta", "seq":4, "height":11, "payload":{"trading_pair":"abc/cet","bids":[{"p":"2.000000000000000000","a":"-3"},{"p":"1.000000000000000000","a":"15"}],"asks":[],"height":11,"checksum":0}}`,
+		`{"type":"ticker", "seq":5, "height":11, "payload":[{"market":"xyz/cet","new":"2.0"},{"market":"abc/cet","new":"1.5"}]}`,
+	}, encodeQueuedMsgs(msgs))
+	stats := q.getStats()
+	require.EqualValues(t, 2, stats.Coalesced)
+	require.EqualValues(t, 1, stats.Dropped)
+
+	// depth_change is not merged across a later depth_full of the same market
+	require.Nil(t, q.push(newPushMsg(DepthChange, 7, 10, []byte(`{"trading_pair":"abc/cet","bids":[],"asks":[],"height":10,"checksum":0}`))))
+	require.Nil(t, q.push(newPushMsg(DepthFull, 8, 10, []byte(`{"trading_pair":"abc/cet","bids":[],"asks":[],"height":10,"checksum":1}`))))
+	require.Nil(t, q.push(newPushMsg(DepthChange, 9, 10, []byte(`{"trading_pair":"xyz/cet","bids":[],"asks":[],"height":10,"checksum":2}`))))
+	require.Nil(t, q.push(newPushMsg(DepthChange, 10, 11, []byte(`{"trading_pair":"abc/cet","bids":[],"asks":[],"height":11,"checksum":3}`))))
+	msgs, _ = q.popAll()
+	require.EqualValues(t, []string{
+		`{"type":"dropped", "payload":{"seq":6,"height":11,"count":1}}`,
+		`{"type":"depth_change", "seq":7, "height":10, "payload":{"trading_pair":"abc/cet","bids":[],"asks":[],"height":10,"checksum":0}}`,
+		`{"type":"depth_full", "seq":8, "height":10, "payload":{"trading_pair":"abc/cet","bids":[],"asks":[],"height":10,"checksum":1}}`,
+		`{"type":"depth_change", "seq":9, "height":10, "payload":{"trading_pair":"xyz/cet","bids":[],"asks":[],"height":10,"checksum":2}}`,
+	}, encodeQueuedMsgs(msgs))
+	require.EqualValues(t, 2, q.getStats().Dropped)
+	require.EqualValues(t, 10, q.getStats().LastDropSeq)
+
+	// the later amounts override the older ones in depth_change
+	merged, err := mergePayloads(DepthChange,
+		[]byte(`{"trading_pair":"abc/cet","bids":null,"asks":[{"p":"2.000000000000000000","a":"1"},{"p":"3.000000000000000000","a":"1"}],"height":10,"checksum":1}`),
+		[]byte(`{"trading_pair":"abc/cet","bids":null,"asks":[{"p":"1.000000000000000000","a":"5"},{"p":"2.000000000000000000","a":"0"}],"height":11,"checksum":2}`))
+	require.Nil(t, err)
+	require.EqualValues(t, `{"trading_pair":"abc/cet","bids":[],"asks":[{"p":"1.000000000000000000","a":"5"},{"p":"2.000000000000000000","a":"0"},{"p":"3.000000000000000000","a":"1"}],"height":11,"checksum":2}`, string(merged))
+	_, err = mergePayloads(DealKey, []byte(`{}`), []byte(`{}`))
+	require.NotNil(t, err)
+}
+
+func TestSendQueueCoalesceLabeled(t *testing.T) {
+	cfg := DefaultSendQueueConfig()
+	cfg.Size = 2
+	q := newSendQueue(cfg)
+	newLabeledMsg := func(label, typeKey string, seq int64, payload string) *pushMsg {
+		msg := newPushMsg(typeKey, seq, 10, []byte(payload))
+		msg.label = label
+		return msg
+	}
+	require.Nil(t, q.push(newLabeledMsg("depth:abc/cet:1", DepthChange, 1, `{"trading_pair":"abc/cet","bids":[],"asks":[],"height":10,"checksum":1}`)))
+	require.Nil(t, q.push(newLabeledMsg("depth:abc/cet:0.1", DepthChange, 2, `{"trading_pair":"abc/cet","bids":[],"asks":[],"height":10,"checksum":2}`)))
+	// the messages of the other levels are not merged
+	require.Nil(t, q.push(newLabeledMsg("depth:abc/cet:0.1", DepthChange, 3, `{"trading_pair":"abc/cet","bids":[],"asks":[],"height":10,"checksum":3}`)))
+	require.Nil(t, q.push(newLabeledMsg("depth:abc/cet:1", DepthChange, 4, `{"trading_pair":"abc/cet","bids":[],"asks":[],"height":10,"checksum":4}`)))
+	require.Nil(t, q.push(newLabeledMsg("ticker:xyz/cet", TickerKey, 5, `[{"market":"xyz/cet","new":"2.0"}]`)))
+	msgs, _ := q.popAll()
+	require.EqualValues(t, []string{
+		`{"type":"depth_change", "label":"depth:abc/cet:0.1", "seq":3, "height":10, "payload":{"trading_pair":"abc/cet","bids":[],"asks":[],"height":10,"checksum":3}}`,
+		`{"type":"depth_change", "label":"depth:abc/cet:1", "seq":4, "height":10, "payload":{"trading_pair":"abc/cet","bids":[],"asks":[],"height":10,"checksum":4}}`,
+	}, encodeQueuedMsgs(msgs))
+	stats := q.getStats()
+	require.EqualValues(t, 2, stats.Coalesced)
+	require.EqualValues(t, 1, stats.Dropped)
+}
+
+func TestSendQueueConfig(t *testing.T) {
+	cfg := DefaultSendQueueConfig()
+	require.Nil(t, cfg.Validate())
+	require.EqualValues(t, PolicyCoalesce, cfg.getPolicy(getTopicClass(DepthFull)))
+	require.EqualValues(t, PolicyCoalesce, cfg.getPolicy(getTopicClass(XTickerKey)))
+	require.EqualValues(t, PolicyDrop, cfg.getPolicy(getTopicClass(OrderKey)))
+	cfg.Policies[ClassTicker] = "block"
+	require.NotNil(t, cfg.Validate())
+	cfg = DefaultSendQueueConfig()
+	cfg.Size = 0
+	require.NotNil(t, cfg.Validate())
+}
+
+// BlockedWif blocks the writing until 'unblock' is closed
+type BlockedWif struct {
+	MockWif
+	unblock chan struct{}
+	once    sync.Once
+}
+
+func (m *BlockedWif) WriteMessage(msgType int, data []byte) error {
+	<-m.unblock
+	return m.MockWif.WriteMessage(msgType, data)
+}
+
+func (m *BlockedWif) Close() error {
+	m.once.Do(func() { close(m.unblock) })
+	return nil
+}
+
+func TestSlowConsumer(t *testing.T) {
+	wsManager := NewWebSocketManager()
+	cfg := DefaultSendQueueConfig()
+	cfg.Size = 2
+	cfg.Policies[ClassDefault] = PolicyDisconnect
+	require.Nil(t, wsManager.SetSendQueueConfig(cfg))
+
+	slowWif := &BlockedWif{unblock: make(chan struct{})}
+	slow := wsManager.AddWsConn(slowWif)
+	fastWif := &MockWif{}
+	fast := wsManager.AddWsConn(fastWif)
+	for _, conn := range []*Conn{slow, fast} {
+		require.Nil(t, wsManager.AddSubscribeConn(conn, DealKey, []string{"abc/cet"}))
+	}
+	for i := 0; i < 5; i++ {
+		for _, sub := range wsManager.GetDealSubscribeInfo()["abc/cet"] {
+			wsManager.PushDeal(sub, []byte("{}"))
+		}
+		wsManager.Flush()
+		time.Sleep(10 * time.Millisecond)
+	}
+	// the fast connection is not blocked by the slow one
+	require.EqualValues(t, 5, len(fastWif.GetRecords()))
+	require.EqualValues(t, 1, len(wsManager.GetDealSubscribeInfo()["abc/cet"]))
+
+	stats := wsManager.GetConnStats()
+	require.EqualValues(t, 1, len(stats))
+	require.EqualValues(t, fast.id, stats[0].ConnID)
+	require.EqualValues(t, 1, stats[0].Topics)
+	require.EqualValues(t, 5, stats[0].Sent)
+	require.True(t, slow.GetStats().SlowClosed)
+
+	slowWif.mtx.Lock()
+	defer slowWif.mtx.Unlock()
+	require.EqualValues(t, 1, len(slowWif.control))
+	require.EqualValues(t, []byte{0x03, 0xf0}, slowWif.control[0][:2])
+	require.EqualValues(t, "slow consumer", string(slowWif.control[0][2:]))
+}
@@ -97,3 +97,21 @@ func (tm *TickerManager) GetTicker(currMinute int) *Ticker {
 	}
 	return nil
 }
+
+// Returns the price at 'minutes' minutes before currMinute, minutes should be less than a day
+func (tm *TickerManager) GetPriceBefore(currMinute, minutes int) (sdk.Dec, bool) {
+	if !tm.Initialized || minutes < 0 || minutes >= MinuteNumInDay {
+		return sdk.Dec{}, false
+	}
+	elapsed := func(minute int) int {
+		return (currMinute - minute + MinuteNumInDay) % MinuteNumInDay
+	}
+	// the latest prices are still in the small fifo
+	if minutes <= elapsed(tm.Minute1st) {
+		return tm.Price1st, true
+	}
+	if minutes <= elapsed(tm.Minute2nd) {
+		return tm.Price2nd, true
+	}
+	return tm.PriceList[(currMinute-minutes+MinuteNumInDay)%MinuteNumInDay], true
+}
@@ -69,3 +69,19 @@ func assertGetTickerInTwoDay(t *testing.T, manager *TickerManager, queryTime int
 	require.EqualValues(t, sdk.NewDec(oldPrice), ticker.OldPriceOneDayAgo)
 	require.EqualValues(t, manager.Market, ticker.Market)
 }
+
+func TestTickerManager_GetPriceBefore(t *testing.T) {
+	tm := NewTickerManager("abc/cet")
+	_, ok := tm.GetPriceBefore(30, 0)
+	require.False(t, ok)
+	tm.UpdateNewestPrice(sdk.NewDec(10), 30)
+	tm.UpdateNewestPrice(sdk.NewDec(20), 40)
+	tm.UpdateNewestPrice(sdk.NewDec(30), 50)
+	for minutes, price := range map[int]int64{0: 30, 5: 30, 6: 20, 15: 20, 16: 10, 40: 10, MinuteNumInDay - 1: 10} {
+		p, ok := tm.GetPriceBefore(55, minutes)
+		require.True(t, ok)
+		require.EqualValues(t, sdk.NewDec(price), p, minutes)
+	}
+	_, ok = tm.GetPriceBefore(55, MinuteNumInDay)
+	require.False(t, ok)
+}
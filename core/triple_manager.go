@@ -18,11 +18,15 @@ type TripleManager struct {
 	sell *DepthManager
 	buy  *DepthManager
 	tkm  *TickerManager
+	xtkm *XTickerManager
+	l3   *OrderBookL3
 
 	// Depth data are updated in a batch mode, i.e., one block applies as a whole
 	// So these data can not be queried during the execution of a block
 	// We use this mutex to protect them from being queried if they are during updating of a block
 	mutex sync.RWMutex
+	// the height when the depth data were last changed
+	height int64
 
 	entryList []DeltaChangeEntry
 }
@@ -31,13 +35,14 @@ func (triman *TripleManager) AddDeltaChange(isSell bool, price sdk.Dec, amount s
 	triman.entryList = append(triman.entryList, DeltaChangeEntry{isSell, price, amount})
 }
 
-func (triman *TripleManager) Update(lockCount *int64) {
+func (triman *TripleManager) Update(lockCount *int64, height int64) {
 	if len(triman.entryList) == 0 {
 		return
 	}
 	triman.mutex.Lock()
 	defer triman.mutex.Unlock()
 	atomic.AddInt64(lockCount, 1)
+	triman.height = height
 	for _, e := range triman.entryList {
 		if e.isSell {
 			triman.sell.DeltaChange(e.price, e.amount)
@@ -47,3 +52,19 @@ func (triman *TripleManager) Update(lockCount *int64) {
 	}
 	triman.entryList = triman.entryList[:0]
 }
+
+// Returns the best 'count' PricePoints of both sides, the height when they were last changed,
+// and the checksum of the order book merged at 'level'
+func (triman *TripleManager) GetDepthWithChecksum(level string, count int) (sell []*PricePoint,
+	buy []*PricePoint, height int64, checksum uint32) {
+	triman.mutex.RLock()
+	defer triman.mutex.RUnlock()
+	return triman.sell.GetLowest(count), triman.buy.GetHighest(count), triman.height, triman.getChecksum(level)
+}
+
+// The caller must hold the mutex, or be the goroutine which updates the depth data
+func (triman *TripleManager) getChecksum(level string) uint32 {
+	return getDepthChecksum(triman.height,
+		triman.buy.GetBest(level, DepthChecksumCount),
+		triman.sell.GetBest(level, DepthChecksumCount))
+}
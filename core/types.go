@@ -13,12 +13,29 @@ const (
 	Minute             = byte(0x10)
 	Hour               = byte(0x20)
 	Day                = byte(0x30)
+	Minute5            = byte(0x11)
+	Minute15           = byte(0x12)
+	Minute30           = byte(0x13)
+	Hour4              = byte(0x21)
+	Week               = byte(0x40)
+	Month              = byte(0x50)
 	MinuteStr          = "1min"
 	HourStr            = "1hour"
 	DayStr             = "1day"
+	Minute5Str         = "5min"
+	Minute15Str        = "15min"
+	Minute30Str        = "30min"
+	Hour4Str           = "4hour"
+	WeekStr            = "1week"
+	MonthStr           = "1month"
 	CreateOrderStr     = "create"
 	FillOrderStr       = "fill"
 	CancelOrderStr     = "cancel"
+
+	OrderStatusOpen            = "open"
+	OrderStatusPartiallyFilled = "partially_filled"
+	OrderStatusFilled          = "filled"
+	OrderStatusCancelled       = "cancelled"
 )
 
 // push candle stick msg to ws
@@ -28,11 +45,25 @@ type CandleStick struct {
 	HighPrice      sdk.Dec `json:"high"`
 	LowPrice       sdk.Dec `json:"low"`
 	TotalDeal      sdk.Int `json:"total"`
+	TotalMoney     sdk.Int `json:"total_money"`
+	TradeCount     int64   `json:"trade_count"`
 	EndingUnixTime int64   `json:"unix_time"`
 	TimeSpan       string  `json:"time_span"`
 	Market         string  `json:"market"`
 }
 
+// the whole lifecycle of an order, found through the order_id index
+type OrderLifecycle struct {
+	OrderID     string            `json:"order_id"`
+	Status      string            `json:"status"`
+	DealStock   int64             `json:"deal_stock"`
+	DealMoney   int64             `json:"deal_money"`
+	TxHashes    []string          `json:"tx_hashes"`
+	CreateOrder json.RawMessage   `json:"create_order_info,omitempty"`
+	FillOrders  []json.RawMessage `json:"fill_order_info"`
+	CancelOrder json.RawMessage   `json:"cancel_order_info,omitempty"`
+}
+
 type Ticker struct {
 	Market            string  `json:"market"`
 	NewPrice          sdk.Dec `json:"new"`
@@ -73,12 +104,14 @@ type SubscribeManager interface {
 	//The returned subscribers have detailed information of markets
 	//one subscriber can subscribe tickers from no more than 100 markets
 	GetTickerSubscribeInfo() []Subscriber
+	GetXTickerSubscribeInfo() []Subscriber
 
 	//The returned subscribers have detailed information of timespan
 	GetCandleStickSubscribeInfo() map[string][]Subscriber
 
 	//the map keys are markets' names
 	GetDepthSubscribeInfo() map[string][]Subscriber
+	GetDepthL3SubscribeInfo() map[string][]Subscriber
 	GetDealSubscribeInfo() map[string][]Subscriber
 
 	//the map keys are bancor contracts' names
@@ -86,9 +119,9 @@ type SubscribeManager interface {
 
 	//the map keys are tokens' names
 	GetCommentSubscribeInfo() map[string][]Subscriber
+	GetMarketSubscribeInfo() map[string][]Subscriber
 
 	//the map keys are accounts' bech32 addresses
-	GetMarketSubscribeInfo() map[string][]Subscriber
 	GetOrderSubscribeInfo() map[string][]Subscriber
 	GetBancorTradeSubscribeInfo() map[string][]Subscriber
 	GetBancorDealSubscribeInfo() map[string][]Subscriber
@@ -100,11 +133,16 @@ type SubscribeManager interface {
 	GetLockedSubscribeInfo() map[string][]Subscriber
 	GetValidatorCommissionInfo() map[string][]Subscriber
 	GetDelegationRewards() map[string][]Subscriber
+	GetOpenOrdersSubscribeInfo() map[string][]Subscriber
+	GetAccountSubscribeInfo() map[string][]Subscriber
+	GetAlertSubscribeInfo() map[string][]Subscriber
+	GetRebateSubscribeInfo() map[string][]Subscriber
 
 	PushLockedSendMsg(subscriber Subscriber, info []byte)
 	PushSlash(subscriber Subscriber, info []byte)
 	PushHeight(subscriber Subscriber, info []byte)
 	PushTicker(subscriber Subscriber, t []*Ticker)
+	PushXTicker(subscriber Subscriber, t []*XTicker)
 	PushDepthFullMsg(subscriber Subscriber, info []byte)
 	PushDepthWithChange(subscriber Subscriber, info []byte)
 	PushDepthWithDelta(subscriber Subscriber, delta []byte)
@@ -125,8 +163,16 @@ type SubscribeManager interface {
 	PushComment(subscriber Subscriber, info []byte)
 	PushValidatorCommissionInfo(subscriber Subscriber, info []byte)
 	PushDelegationRewards(subscriber Subscriber, info []byte)
+	PushOpenOrder(subscriber Subscriber, info []byte)
+	PushDepthL3(subscriber Subscriber, info []byte)
+	PushAlert(subscriber Subscriber, info []byte)
+	PushRebate(subscriber Subscriber, info []byte)
 
 	SetSkipOption(isSkip bool)
+	// The following pushes carry this sequence number and height
+	SetPushMeta(seq int64, height int64)
+	// Sends the pushes since the last Flush, called after each entry is pushed
+	Flush()
 }
 
 type Consumer interface {
@@ -136,31 +182,40 @@ type Consumer interface {
 type Querier interface {
 	// All these functions can be safely called in goroutines.
 	QueryTickers(marketList []string) []*Ticker
+	QueryXTickers(marketList []string) []*XTicker
 	QueryBlockTime(height int64, count int) []int64
 	QueryDepth(market string, count int) (sell []*PricePoint, buy []*PricePoint)
-	QueryCandleStick(market string, timespan byte, time int64, sid int64, count int) []json.RawMessage
-
-	QueryLocked(account string, time int64, sid int64, count int) (data []json.RawMessage, timesid []int64)
-	QueryDeal(market string, time int64, sid int64, count int) (data []json.RawMessage, timesid []int64)
-	QueryBancorDeal(market string, time int64, sid int64, count int) (data []json.RawMessage, timesid []int64)
-	QueryBancorInfo(market string, time int64, sid int64, count int) (data []json.RawMessage, timesid []int64)
-	QueryBancorTrade(account string, time int64, sid int64, count int) (data []json.RawMessage, timesid []int64)
-	QueryRedelegation(account string, time int64, sid int64, count int) (data []json.RawMessage, timesid []int64)
-	QueryUnbonding(account string, time int64, sid int64, count int) (data []json.RawMessage, timesid []int64)
-	QueryUnlock(account string, time int64, sid int64, count int) (data []json.RawMessage, timesid []int64)
-	QueryIncome(account string, time int64, sid int64, count int) (data []json.RawMessage, timesid []int64)
-	QueryTx(account string, time int64, sid int64, count int) (data []json.RawMessage, timesid []int64)
-	QueryComment(token string, time int64, sid int64, count int) (data []json.RawMessage, timesid []int64)
-	QuerySlash(time int64, sid int64, count int) (data []json.RawMessage, timesid []int64)
-	QueryDonation(time int64, sid int64, count int) (data []json.RawMessage, timesid []int64)
-	QueryDelist(market string, time int64, sid int64, count int) (data []json.RawMessage, timesid []int64)
-
-	QueryOrderAboutToken(tag, token, account string, time int64, sid int64, count int) (data []json.RawMessage, tags []byte, timesid []int64)
-	QueryLockedAboutToken(token, account string, time int64, sid int64, count int) (data []json.RawMessage, timesid []int64)
-	QueryBancorTradeAboutToken(token, account string, time int64, sid int64, count int) (data []json.RawMessage, timesid []int64)
-	QueryUnlockAboutToken(token, account string, time int64, sid int64, count int) (data []json.RawMessage, timesid []int64)
-	QueryIncomeAboutToken(token, account string, time int64, sid int64, count int) (data []json.RawMessage, timesid []int64)
-	QueryTxAboutToken(token, account string, time int64, sid int64, count int) (data []json.RawMessage, timesid []int64)
+	QueryCandleStick(market string, timespan byte, qr QueryRange) []json.RawMessage
+
+	QueryLocked(account string, qr QueryRange) (data []json.RawMessage, timesid []int64)
+	QueryDeal(market string, qr QueryRange) (data []json.RawMessage, timesid []int64)
+	QueryBancorDeal(market string, qr QueryRange) (data []json.RawMessage, timesid []int64)
+	QueryBancorInfo(market string, qr QueryRange) (data []json.RawMessage, timesid []int64)
+	QueryBancorTrade(account string, qr QueryRange) (data []json.RawMessage, timesid []int64)
+	QueryRedelegation(account string, qr QueryRange) (data []json.RawMessage, timesid []int64)
+	QueryUnbonding(account string, qr QueryRange) (data []json.RawMessage, timesid []int64)
+	QueryUnlock(account string, qr QueryRange) (data []json.RawMessage, timesid []int64)
+	QueryIncome(account string, qr QueryRange) (data []json.RawMessage, timesid []int64)
+	QueryTx(account string, qr QueryRange) (data []json.RawMessage, timesid []int64)
+	QueryComment(token string, qr QueryRange) (data []json.RawMessage, timesid []int64)
+	QuerySlash(qr QueryRange) (data []json.RawMessage, timesid []int64)
+	QueryDonation(qr QueryRange) (data []json.RawMessage, timesid []int64)
+	QueryDelist(market string, qr QueryRange) (data []json.RawMessage, timesid []int64)
+	QueryOrderLifecycle(orderID string) *OrderLifecycle
+	QueryOpenOrders(account, market string) []*OpenOrder
+	QueryOrderBookL3(market string, count int) *L3Snapshot
+	QueryAccountStats(account, market string, from, to int64) []AccountStats
+	QueryRebates(referee string, from, to int64) []RebateStats
+	QueryMarkets(token string, qr QueryRange) (data []json.RawMessage, timesid []int64)
+	QueryDelegatorRewards(account string, qr QueryRange) (data []json.RawMessage, timesid []int64)
+	QueryValidatorCommission(account string, qr QueryRange) (data []json.RawMessage, timesid []int64)
+
+	QueryOrderAboutToken(tag, token, account string, qr QueryRange) (data []json.RawMessage, tags []byte, timesid []int64)
+	QueryLockedAboutToken(token, account string, qr QueryRange) (data []json.RawMessage, timesid []int64)
+	QueryBancorTradeAboutToken(token, account string, qr QueryRange) (data []json.RawMessage, timesid []int64)
+	QueryUnlockAboutToken(token, account string, qr QueryRange) (data []json.RawMessage, timesid []int64)
+	QueryIncomeAboutToken(token, account string, qr QueryRange) (data []json.RawMessage, timesid []int64)
+	QueryTxAboutToken(token, account string, qr QueryRange) (data []json.RawMessage, timesid []int64)
 
 	QueryTxByHashID(hexHashID string) json.RawMessage
 }
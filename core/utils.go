@@ -4,8 +4,10 @@ import (
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"hash/crc32"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 
 	sdk "github.com/cosmos/cosmos-sdk/types"
@@ -23,6 +25,18 @@ func getSpanStrFromSpan(span byte) string {
 		return HourStr
 	case Day:
 		return DayStr
+	case Minute5:
+		return Minute5Str
+	case Minute15:
+		return Minute15Str
+	case Minute30:
+		return Minute30Str
+	case Hour4:
+		return Hour4Str
+	case Week:
+		return WeekStr
+	case Month:
+		return MonthStr
 	default:
 		return ""
 	}
@@ -36,11 +50,37 @@ func GetSpanFromSpanStr(spanStr string) byte {
 		return Hour
 	case DayStr:
 		return Day
+	case Minute5Str:
+		return Minute5
+	case Minute15Str:
+		return Minute15
+	case Minute30Str:
+		return Minute30
+	case Hour4Str:
+		return Hour4
+	case WeekStr:
+		return Week
+	case MonthStr:
+		return Month
 	default:
 		return 0
 	}
 }
 
+// the number of minute-candle-sticks merged into one candle stick of 'span'
+func getMinutesInSpan(span byte) int {
+	switch span {
+	case Minute5:
+		return 5
+	case Minute15:
+		return 15
+	case Minute30:
+		return 30
+	default:
+		return 1
+	}
+}
+
 // ----------------
 // price and amount
 
@@ -67,6 +107,8 @@ func merge(subList []baseCandleStick) (cs baseCandleStick) {
 			}
 		}
 		cs.TotalDeal = cs.TotalDeal.Add(sub.TotalDeal)
+		cs.TotalMoney = cs.TotalMoney.Add(sub.TotalMoney)
+		cs.TradeCount += sub.TradeCount
 	}
 	return
 }
@@ -88,11 +130,7 @@ func mergePrice(updated []*PricePoint, level string, isBuy bool) map[string]*Pri
 
 // 'm' keeps a depth map, use 'point' to update it at the granularity defined by 'mulDec'
 func updateAmount(m map[string]*PricePoint, point *PricePoint, mulDec sdk.Dec, isBuy bool) {
-	price := point.Price.Mul(mulDec).Ceil()
-	if isBuy {
-		price = point.Price.Mul(mulDec).TruncateDec()
-	}
-	price = price.Quo(mulDec)
+	price := getMergedPrice(point.Price, mulDec, isBuy)
 	s := string(decToBigEndianBytes(price))
 	if val, ok := m[s]; ok {
 		val.Amount = val.Amount.Add(point.Amount)
@@ -104,6 +142,35 @@ func updateAmount(m map[string]*PricePoint, point *PricePoint, mulDec sdk.Dec, i
 	}
 }
 
+// The buy prices are merged downward and the sell prices are merged upward
+func getMergedPrice(price sdk.Dec, mulDec sdk.Dec, isBuy bool) sdk.Dec {
+	merged := price.Mul(mulDec).Ceil()
+	if isBuy {
+		merged = price.Mul(mulDec).TruncateDec()
+	}
+	return merged.Quo(mulDec)
+}
+
+// The checksum of the best price points on both sides, the canonical string is:
+// <height>|<bid price>:<bid amount>,<bid price>:<bid amount>...|<ask price>:<ask amount>,...
+// where the bids are sorted from the highest price and the asks are sorted from the lowest price
+func getDepthChecksum(height int64, bids, asks []*PricePoint) uint32 {
+	var sb strings.Builder
+	sb.WriteString(strconv.FormatInt(height, 10))
+	for _, side := range [][]*PricePoint{bids, asks} {
+		sb.WriteString("|")
+		for i, pp := range side {
+			if i != 0 {
+				sb.WriteString(",")
+			}
+			sb.WriteString(pp.Price.String())
+			sb.WriteString(":")
+			sb.WriteString(pp.Amount.String())
+		}
+	}
+	return crc32.ChecksumIEEE([]byte(sb.String()))
+}
+
 func decToBigEndianBytes(d sdk.Dec) []byte {
 	var result [DecByteCount]byte
 	bytes := d.Int.Bytes() //  returns the absolute value of d as a big-endian byte slice.
@@ -128,10 +195,15 @@ func GetTopicAndParams(subscriptionTopic string) (topic string, params []string,
 }
 
 func checkTopicValid(topic string, params []string) bool {
+	if !checkWildcardParams(topic, params) {
+		return false
+	}
 	switch topic {
 	case BlockInfoKey, SlashKey:
 		return len(params) == 0
-	case TickerKey: // ticker:abc/cet; ticker:B:abc/cet
+	case CreateMarketInfoKey: // create_market; create_market:<token>
+		return len(params) <= 1
+	case TickerKey, XTickerKey: // ticker:abc/cet; ticker:B:abc/cet
 		if len(params) == 1 {
 			return true
 		}
@@ -140,7 +212,8 @@ func checkTopicValid(topic string, params []string) bool {
 		}
 	case UnbondingKey, RedelegationKey, LockedKey,
 		UnlockKey, TxKey, IncomeKey, OrderKey, CommentKey,
-		BancorTradeKey, BancorKey, DealKey, BancorDealKey:
+		BancorTradeKey, BancorKey, DealKey, BancorDealKey,
+		DelegationRewardsKey, ValidatorCommissionKey, OpenOrdersKey, DepthL3Key, AccountKey, AlertKey, RebateKey:
 		return len(params) == 1
 	case KlineKey: // kline:abc/cet:1min; kline:B:abc/cet:1min
 		if len(params) != 2 && len(params) != 3 {
@@ -153,8 +226,7 @@ func checkTopicValid(topic string, params []string) bool {
 			}
 			timeSpan = params[2]
 		}
-		switch timeSpan {
-		case MinuteStr, HourStr, DayStr:
+		if GetSpanFromSpanStr(timeSpan) != 0 {
 			return true
 		}
 	case DepthKey: //depth:<trading-pair>:<level>
@@ -179,18 +251,20 @@ func checkTopicValid(topic string, params []string) bool {
 // encode data
 type LevelsPricePoint map[string]map[string]*PricePoint // [level][priceBigEndian][*PricePoint]
 
-func encodeDepthLevels(market string, buyDepths, sellDepths LevelsPricePoint) map[string][]byte {
+// 'checksums' are the checksums of the order book merged at every level
+func encodeDepthLevels(market string, height int64, checksums map[string]uint32,
+	buyDepths, sellDepths LevelsPricePoint) map[string][]byte {
 	levelsData := make(map[string][]byte)
 	if len(buyDepths) == 0 && len(sellDepths) == 0 {
 		return levelsData
 	}
 	for level, depth := range buyDepths {
 		if sell, ok := sellDepths[level]; ok {
-			if bz, err := encodeDepthLevel(market, depth, sell); err == nil {
+			if bz, err := encodeDepthLevel(market, height, checksums[level], depth, sell); err == nil {
 				levelsData[level] = bz
 			}
 		} else {
-			if bz, err := encodeDepthLevel(market, depth, nil); err == nil {
+			if bz, err := encodeDepthLevel(market, height, checksums[level], depth, nil); err == nil {
 				levelsData[level] = bz
 			}
 		}
@@ -198,7 +272,8 @@ func encodeDepthLevels(market string, buyDepths, sellDepths LevelsPricePoint) ma
 	return levelsData
 }
 
-func encodeDepthLevel(market string, buyDepth map[string]*PricePoint, sellDepth map[string]*PricePoint) ([]byte, error) {
+func encodeDepthLevel(market string, height int64, checksum uint32,
+	buyDepth map[string]*PricePoint, sellDepth map[string]*PricePoint) ([]byte, error) {
 	buyValues := make([]*PricePoint, 0, len(buyDepth))
 	sellValues := make([]*PricePoint, 0, len(sellDepth))
 	for _, p := range buyDepth {
@@ -217,15 +292,17 @@ func encodeDepthLevel(market string, buyDepth map[string]*PricePoint, sellDepth
 			return sellValues[i].Price.LT(sellValues[j].Price)
 		})
 	}
-	bz, err := encodeDepthData(market, buyValues, sellValues)
+	bz, err := encodeDepthData(market, height, checksum, buyValues, sellValues)
 	return bz, err
 }
 
-func encodeDepthData(market string, buy, sell []*PricePoint) ([]byte, error) {
+func encodeDepthData(market string, height int64, checksum uint32, buy, sell []*PricePoint) ([]byte, error) {
 	depRes := DepthDetails{
 		TradingPair: market,
 		Bids:        buy,
 		Asks:        sell,
+		Height:      height,
+		Checksum:    checksum,
 	}
 	bz, err := json.Marshal(depRes)
 	return bz, err
@@ -261,14 +338,8 @@ func BigEndianBytesToInt64(bz []byte) int64 {
 // ---------------
 // key in Hub
 
-func getCandleStickStartKey(market string, timespan byte) []byte {
-	bz := append([]byte(market), []byte{0, timespan}...)
-	return getStartKeyFromBytes(CandleStickByte, bz)
-}
-
-func getCandleStickEndKey(market string, timespan byte, endTime int64, sid int64) []byte {
-	bz := append([]byte(market), []byte{0, timespan}...)
-	return getEndKeyFromBytes(CandleStickByte, bz, endTime, sid)
+func getCandleStickBytes(market string, timespan byte) []byte {
+	return append([]byte(market), []byte{0, timespan}...)
 }
 
 func getStartKeyFromBytes(firstByte byte, bz []byte) []byte {
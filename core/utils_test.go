@@ -2,6 +2,7 @@ package core
 
 import (
 	"fmt"
+	"hash/crc32"
 	"testing"
 	"time"
 
@@ -12,31 +13,37 @@ import (
 func TestBaseCandleStick(t *testing.T) {
 	cs := newBaseCandleStick()
 	//test update
-	cs.update(sdk.NewDec(50), 2)
-	cs.update(sdk.NewDec(10), 2)
+	cs.update(sdk.NewDec(50), 2, 100)
+	cs.update(sdk.NewDec(10), 2, 20)
 	require.Equal(t, baseCandleStick{
 		OpenPrice:  sdk.NewDec(50),
 		ClosePrice: sdk.NewDec(10),
 		HighPrice:  sdk.NewDec(50),
 		LowPrice:   sdk.NewDec(10),
 		TotalDeal:  sdk.NewInt(4),
+		TotalMoney: sdk.NewInt(120),
+		TradeCount: 2,
 	}, cs)
-	cs.update(sdk.NewDec(60), 1)
-	cs.update(sdk.NewDec(20), 1)
+	cs.update(sdk.NewDec(60), 1, 60)
+	cs.update(sdk.NewDec(20), 1, 20)
 	require.Equal(t, baseCandleStick{
 		OpenPrice:  sdk.NewDec(50),
 		ClosePrice: sdk.NewDec(20),
 		HighPrice:  sdk.NewDec(60),
 		LowPrice:   sdk.NewDec(10),
 		TotalDeal:  sdk.NewInt(6),
+		TotalMoney: sdk.NewInt(200),
+		TradeCount: 4,
 	}, cs)
-	cs.update(sdk.NewDec(5), 10)
+	cs.update(sdk.NewDec(5), 10, 50)
 	require.Equal(t, baseCandleStick{
 		OpenPrice:  sdk.NewDec(50),
 		ClosePrice: sdk.NewDec(5),
 		HighPrice:  sdk.NewDec(60),
 		LowPrice:   sdk.NewDec(5),
 		TotalDeal:  sdk.NewInt(16),
+		TotalMoney: sdk.NewInt(250),
+		TradeCount: 5,
 	}, cs)
 	//test merge
 	cs1 := baseCandleStick{
@@ -45,6 +52,8 @@ func TestBaseCandleStick(t *testing.T) {
 		HighPrice:  sdk.NewDec(50),
 		LowPrice:   sdk.NewDec(10),
 		TotalDeal:  sdk.NewInt(4),
+		TotalMoney: sdk.NewInt(120),
+		TradeCount: 2,
 	}
 	cs2 := baseCandleStick{
 		OpenPrice:  sdk.NewDec(50),
@@ -52,6 +61,8 @@ func TestBaseCandleStick(t *testing.T) {
 		HighPrice:  sdk.NewDec(200),
 		LowPrice:   sdk.NewDec(5),
 		TotalDeal:  sdk.NewInt(4),
+		TotalMoney: sdk.NewInt(300),
+		TradeCount: 3,
 	}
 	cs3 := baseCandleStick{
 		OpenPrice:  sdk.NewDec(50),
@@ -59,6 +70,7 @@ func TestBaseCandleStick(t *testing.T) {
 		HighPrice:  sdk.NewDec(200),
 		LowPrice:   sdk.NewDec(5),
 		TotalDeal:  sdk.NewInt(0),
+		TotalMoney: sdk.NewInt(0),
 	}
 	cs = merge([]baseCandleStick{cs1, cs2, cs3})
 	require.Equal(t, baseCandleStick{
@@ -67,6 +79,8 @@ func TestBaseCandleStick(t *testing.T) {
 		HighPrice:  sdk.NewDec(200),
 		LowPrice:   sdk.NewDec(5),
 		TotalDeal:  sdk.NewInt(8),
+		TotalMoney: sdk.NewInt(420),
+		TradeCount: 5,
 	}, cs)
 }
 
@@ -90,11 +104,11 @@ func TestCandleStickRecord(t *testing.T) {
 
 	csSlice = csrMan.NewBlock(T("2019-07-15T08:08:10Z"))
 	require.Equal(t, 0, len(csSlice))
-	csrMan.GetRecord(market1).Update(lastTime, sdk.NewDec(50), 2)
+	csrMan.GetRecord(market1).Update(lastTime, sdk.NewDec(50), 2, 100)
 
 	csSlice = csrMan.NewBlock(T("2019-07-15T08:08:40Z"))
 	require.Equal(t, 0, len(csSlice))
-	csrMan.GetRecord(market1).Update(lastTime, sdk.NewDec(10), 2)
+	csrMan.GetRecord(market1).Update(lastTime, sdk.NewDec(10), 2, 20)
 	lastTimeOld := lastTime
 
 	csSlice = csrMan.NewBlock(T("2019-07-15T08:09:10Z"))
@@ -104,6 +118,8 @@ func TestCandleStickRecord(t *testing.T) {
 		HighPrice:      sdk.NewDec(50),
 		LowPrice:       sdk.NewDec(10),
 		TotalDeal:      sdk.NewInt(4),
+		TotalMoney:     sdk.NewInt(120),
+		TradeCount:     2,
 		EndingUnixTime: lastTimeOld.Unix(),
 		TimeSpan:       getSpanStrFromSpan(Minute),
 		Market:         market1,
@@ -111,15 +127,15 @@ func TestCandleStickRecord(t *testing.T) {
 
 	csSlice = csrMan.NewBlock(T("2019-07-15T08:09:10Z"))
 	require.Equal(t, 0, len(csSlice))
-	csrMan.GetRecord(market1).Update(lastTime, sdk.NewDec(10), 2)
+	csrMan.GetRecord(market1).Update(lastTime, sdk.NewDec(10), 2, 20)
 
 	csSlice = csrMan.NewBlock(T("2019-07-15T08:09:20Z"))
 	require.Equal(t, 0, len(csSlice))
-	csrMan.GetRecord(market1).Update(lastTime, sdk.NewDec(40), 2)
+	csrMan.GetRecord(market1).Update(lastTime, sdk.NewDec(40), 2, 80)
 
 	csSlice = csrMan.NewBlock(T("2019-07-15T08:09:40Z"))
 	require.Equal(t, 0, len(csSlice))
-	csrMan.GetRecord(market1).Update(lastTime, sdk.NewDec(20), 2)
+	csrMan.GetRecord(market1).Update(lastTime, sdk.NewDec(20), 2, 40)
 	lastTimeOld = lastTime
 
 	csSlice = csrMan.NewBlock(T("2019-07-15T08:10:40Z"))
@@ -129,6 +145,8 @@ func TestCandleStickRecord(t *testing.T) {
 		HighPrice:      sdk.NewDec(40),
 		LowPrice:       sdk.NewDec(10),
 		TotalDeal:      sdk.NewInt(6),
+		TotalMoney:     sdk.NewInt(140),
+		TradeCount:     3,
 		EndingUnixTime: lastTimeOld.Unix(),
 		TimeSpan:       getSpanStrFromSpan(Minute),
 		Market:         market1,
@@ -136,7 +154,7 @@ func TestCandleStickRecord(t *testing.T) {
 
 	csSlice = csrMan.NewBlock(T("2019-07-15T08:10:40Z"))
 	require.Equal(t, 0, len(csSlice))
-	csrMan.GetRecord(market1).Update(lastTime, sdk.NewDec(80), 10)
+	csrMan.GetRecord(market1).Update(lastTime, sdk.NewDec(80), 10, 800)
 	lastTimeOld = lastTime
 
 	csSlice = csrMan.NewBlock(T("2019-07-15T09:00:40Z"))
@@ -146,6 +164,8 @@ func TestCandleStickRecord(t *testing.T) {
 		HighPrice:      sdk.NewDec(80),
 		LowPrice:       sdk.NewDec(80),
 		TotalDeal:      sdk.NewInt(10),
+		TotalMoney:     sdk.NewInt(800),
+		TradeCount:     1,
 		EndingUnixTime: lastTimeOld.Unix(),
 		TimeSpan:       getSpanStrFromSpan(Minute),
 		Market:         market1,
@@ -157,6 +177,8 @@ func TestCandleStickRecord(t *testing.T) {
 		HighPrice:      sdk.NewDec(80),
 		LowPrice:       sdk.NewDec(10),
 		TotalDeal:      sdk.NewInt(20),
+		TotalMoney:     sdk.NewInt(1060),
+		TradeCount:     6,
 		EndingUnixTime: lastTimeOld.Unix(),
 		TimeSpan:       getSpanStrFromSpan(Hour),
 		Market:         market1,
@@ -195,6 +217,36 @@ func TestDepthManager(t *testing.T) {
 	}
 }
 
+func TestDepthManagerGetBest(t *testing.T) {
+	buy := NewDepthManager("buy")
+	sell := NewDepthManager("sell")
+	for _, p := range []int64{91, 95, 99, 101, 109, 120} {
+		buy.DeltaChange(sdk.NewDec(p), sdk.NewInt(p))
+		sell.DeltaChange(sdk.NewDec(p), sdk.NewInt(p))
+	}
+	require.Equal(t, []*PricePoint{
+		{Price: sdk.NewDec(120), Amount: sdk.NewInt(120)},
+		{Price: sdk.NewDec(100), Amount: sdk.NewInt(210)},
+	}, buy.GetBest("10", 2))
+	require.Equal(t, []*PricePoint{
+		{Price: sdk.NewDec(100), Amount: sdk.NewInt(285)},
+		{Price: sdk.NewDec(110), Amount: sdk.NewInt(210)},
+		{Price: sdk.NewDec(120), Amount: sdk.NewInt(120)},
+	}, sell.GetBest("10", 5))
+	require.Equal(t, buy.GetHighest(3), buy.GetBest("all", 3))
+	require.Equal(t, sell.GetLowest(3), sell.GetBest("all", 3))
+	require.Nil(t, sell.GetBest("abc", 3))
+}
+
+func TestDepthChecksum(t *testing.T) {
+	price, _ := sdk.NewDecFromStr("0.5")
+	bids := []*PricePoint{{Price: price, Amount: sdk.NewInt(100)}, {Price: sdk.NewDec(0), Amount: sdk.NewInt(7)}}
+	asks := []*PricePoint{{Price: sdk.NewDec(1), Amount: sdk.NewInt(20)}}
+	canonical := "1000|0.500000000000000000:100,0.000000000000000000:7|1.000000000000000000:20"
+	require.Equal(t, crc32.ChecksumIEEE([]byte(canonical)), getDepthChecksum(1000, bids, asks))
+	require.Equal(t, crc32.ChecksumIEEE([]byte("1000||")), getDepthChecksum(1000, nil, nil))
+}
+
 func TestMergePrice(t *testing.T) {
 	update := make([]*PricePoint, 2)
 	price, _ := sdk.NewDecFromStr("100.815623247626481946")
@@ -3,8 +3,10 @@ package core
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -17,6 +19,8 @@ const (
 type ImplSubscriber struct {
 	Conn  *Conn
 	value interface{}
+	// the subscription matched by the pushed messages, only for the labeled connections
+	label string
 }
 
 func (i ImplSubscriber) Detail() interface{} {
@@ -35,33 +39,154 @@ type WebsocketManager struct {
 	mtx sync.RWMutex
 
 	SkipPushed   bool // skip the messages to be pushed to subscribers, used during startup
+	pushSeq      int64
+	pushHeight   int64
+	queueConfig  SendQueueConfig
+	maxWildcards int
+	auth         *Authenticator
 	wsConn2Conn  map[WsInterface]*Conn
 	topics2Conns map[string]map[*Conn]struct{}
+
+	// topic --> key --> subscriptions, the key is a market, token or account according to the topic.
+	// It is updated when the subscriptions are added or removed
+	subIndex map[string]map[string]map[subEntry]struct{}
+	// the subscribers returned by Get*SubscribeInfo, which are built from the indexes
+	// and dropped when the subscriptions of their topic change
+	keyedViews map[string]map[string][]Subscriber
+	listViews  map[string][]Subscriber
+
+	pusher *fanOutPusher
+}
+
+// A subscription in the index, detail is the depth level or the timespan of candle sticks,
+// and label is the subscription itself, such as 'depth:abc/cet:0.1'
+type subEntry struct {
+	conn   *Conn
+	detail string
+	label  string
 }
 
 func NewWebSocketManager() *WebsocketManager {
-	return &WebsocketManager{
+	w := &WebsocketManager{
+		queueConfig:  DefaultSendQueueConfig(),
+		maxWildcards: MaxWildcardSubscriptions,
+		auth:         NewAuthenticator(false),
 		topics2Conns: make(map[string]map[*Conn]struct{}),
 		wsConn2Conn:  make(map[WsInterface]*Conn),
+		subIndex:     make(map[string]map[string]map[subEntry]struct{}),
+		keyedViews:   make(map[string]map[string][]Subscriber),
+		listViews:    make(map[string][]Subscriber),
+	}
+	w.pusher = newFanOutPusher(w, PushWorkerCount)
+	return w
+}
+
+// The config is used by the connections added later
+func (w *WebsocketManager) SetSendQueueConfig(cfg SendQueueConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
 	}
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	w.queueConfig = cfg
+	return nil
+}
+
+// Limits the wildcard subscriptions of each connection
+func (w *WebsocketManager) SetMaxWildcards(n int) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	w.maxWildcards = n
+}
+
+func (w *WebsocketManager) SetAuthenticator(auth *Authenticator) {
+	w.auth = auth
+}
+
+func (w *WebsocketManager) GetAuthenticator() *Authenticator {
+	return w.auth
+}
+
+// When authentication is required, an address-scoped topic can only be subscribed after its address is authenticated
+func (w *WebsocketManager) checkAuth(c *Conn, topic string, params []string) error {
+	if !isAddressTopic(topic) || len(params) == 0 {
+		return nil
+	}
+	return w.CheckAccountAuth(c, params[0])
+}
+
+// Authenticates a connection with a challenge issued by the authenticator instead of the connection,
+// which is used by the connections without a command channel, such as the Server-Sent Events streams
+func (w *WebsocketManager) AuthenticateConn(c *Conn, challenge, pubKey, signature string) (string, error) {
+	addr, err := w.auth.verify(challenge, pubKey, signature)
+	if err != nil {
+		return "", err
+	}
+	c.addAuthAddr(addr)
+	return getBech32Address(addr), nil
+}
+
+// Returns ErrAuthRequired if authentication is required and the account is not authenticated by the connection
+func (w *WebsocketManager) CheckAccountAuth(c *Conn, account string) error {
+	if w.auth.Required && !c.IsAuthenticated(account) {
+		return ErrAuthRequired
+	}
+	return nil
+}
+
+// Returns the send queue statistics of all the connections, sorted by their IDs
+func (w *WebsocketManager) GetConnStats() []SendQueueStats {
+	w.mtx.RLock()
+	conns := make([]*Conn, 0, len(w.wsConn2Conn))
+	for _, conn := range w.wsConn2Conn {
+		conns = append(conns, conn)
+	}
+	w.mtx.RUnlock()
+	res := make([]SendQueueStats, 0, len(conns))
+	for _, conn := range conns {
+		res = append(res, conn.GetStats())
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ConnID < res[j].ConnID
+	})
+	return res
 }
 
 func (w *WebsocketManager) SetSkipOption(isSkip bool) {
 	w.SkipPushed = isSkip
 }
 
+func (w *WebsocketManager) SetPushMeta(seq int64, height int64) {
+	w.pushSeq = seq
+	w.pushHeight = height
+}
+
 // Wrap this WsInterface with 'Conn' and return this 'Conn'
 // The map 'wsConn2Conn' ensures for the same WsInterface, we only wrap it once
 func (w *WebsocketManager) AddWsConn(c WsInterface) *Conn {
 	w.mtx.Lock()
 	defer w.mtx.Unlock()
 	if _, ok := w.wsConn2Conn[c]; !ok {
-		w.wsConn2Conn[c] = NewConn(c)
+		w.wsConn2Conn[c] = newConnWithQueue(c, w.queueConfig)
 	}
 	return w.wsConn2Conn[c]
 }
 
 func (w *WebsocketManager) CloseWsConn(c *Conn) {
+	w.removeWsConn(c)
+	if err := c.Close(); err != nil {
+		log.WithError(err).Error(fmt.Sprintf("close connection failed"))
+	}
+}
+
+// The close message may be blocked by the slow consumer, so it is sent in another goroutine
+// and the push goroutine only removes this connection
+func (w *WebsocketManager) closeSlowConn(c *Conn) {
+	w.removeWsConn(c)
+	c.closeSlow()
+}
+
+func (w *WebsocketManager) removeWsConn(c *Conn) {
 	w.mtx.Lock()
 	defer w.mtx.Unlock()
 	//for topic := range c.allTopics {
@@ -73,27 +198,128 @@ func (w *WebsocketManager) CloseWsConn(c *Conn) {
 		delete(w.topics2Conns[topic], c)
 	}
 	delete(w.wsConn2Conn, c.WsIfc)
-	if err := c.Close(); err != nil {
-		log.WithError(err).Error(fmt.Sprintf("close connection failed"))
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	for topic := range c.allTopics {
+		w.removeIndex(topic, nil, c)
+		for param := range c.topicWithParams[topic] {
+			w.removeIndex(topic, strings.Split(param, SeparateArgu), c)
+		}
 	}
 }
 
 func (w *WebsocketManager) AddSubscribeConn(c *Conn, topic string, params []string) error {
+	if err := w.checkAuth(c, topic, params); err != nil {
+		return err
+	}
 	//The modification to w and c should be atomic, so we need two locks before modification
 	w.mtx.Lock()
 	defer w.mtx.Unlock()
 	c.mtx.Lock()
 	defer c.mtx.Unlock()
+	if hasWildcardParam(params) && !c.hasTopicAndParams(topic, params) && c.countWildcards() >= w.maxWildcards {
+		return ErrTooManyWildcards
+	}
 	w.addConnWithTopic(topic, c)
 	c.addTopicAndParams(topic, params)
+	w.addIndex(topic, params, c)
 	return nil
 }
 
+// The full information of the wildcard subscriptions are not pushed, which can be queried through REST API
 func (w *WebsocketManager) PushFullInfo(hub *Hub, c *Conn, topic string, params []string, count int) error {
+	if err := w.checkAuth(c, topic, params); err != nil {
+		return err
+	}
+	if hasWildcardParam(params) {
+		return nil
+	}
 	err := pushFullInformation(topic, params, count, ImplSubscriber{Conn: c}, hub)
 	return err
 }
 
+// A subscription topic with its params
+type Subscription struct {
+	Topic  string
+	Params []string
+}
+
+// Replays the messages pushed after sinceSeq (or sinceHeight if sinceSeq is zero) and then adds the subscriptions.
+// The messages of all the subscriptions are replayed in the order of their seqs, and no message can be pushed
+// until all the subscriptions are added. If some messages of a subscription are not kept any more, a gap message
+// is sent for it and it is returned without being added, then the caller should push its full information
+// as a new subscription does. So are all the subscriptions if the send queue has no room for the replayed messages.
+func (w *WebsocketManager) ResumeSubscribeConn(hub *Hub, c *Conn, subs []Subscription,
+	sinceSeq int64, sinceHeight int64) ([]Subscription, error) {
+	for _, sub := range subs {
+		if err := w.checkAuth(c, sub.Topic, sub.Params); err != nil {
+			return nil, err
+		}
+	}
+	hub.pushMutex.Lock()
+	defer hub.pushMutex.Unlock()
+	gaps := make([]Subscription, 0, len(subs))
+	resumed := make([]Subscription, 0, len(subs))
+	replayed := make(map[int64]*pushRecord)
+	writeGap := func(sub Subscription) error {
+		seq, height := hub.pushStreams.Latest()
+		gaps = append(gaps, sub)
+		return c.WriteMsg(encodeGapMsg(getStreamName(sub.Topic, sub.Params...), seq, height))
+	}
+	for _, sub := range subs {
+		stream := getStreamName(sub.Topic, sub.Params...)
+		records, ok := hub.pushStreams.Since(stream, sinceSeq, sinceHeight)
+		// the state topics are not kept, the wildcard subscriptions are not kept as streams,
+		// and the messages are not pushed when skipped during startup
+		if !ok || !isResumableTopic(sub.Topic) || hasWildcardParam(sub.Params) || w.SkipPushed {
+			if err := writeGap(sub); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		// a message which belongs to several subscriptions is replayed once
+		for _, r := range records {
+			replayed[r.seq] = r
+		}
+		resumed = append(resumed, sub)
+	}
+	records := make([]*pushRecord, 0, len(replayed))
+	for _, r := range replayed {
+		records = append(records, r)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].seq < records[j].seq })
+	msgs := make([]*pushMsg, len(records))
+	for i, r := range records {
+		msgs[i] = newPushMsg(r.typeKey, r.seq, r.height, r.payload)
+	}
+	ok, err := c.writeReplayMsgs(msgs)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		for _, sub := range resumed {
+			if err := writeGap(sub); err != nil {
+				return nil, err
+			}
+		}
+		return gaps, nil
+	}
+	for _, sub := range resumed {
+		if err := w.AddSubscribeConn(c, sub.Topic, sub.Params); err != nil {
+			return nil, err
+		}
+	}
+	return gaps, nil
+}
+
+func isResumableTopic(topic string) bool {
+	switch topic {
+	case TickerKey, XTickerKey, DepthKey:
+		return false
+	}
+	return true
+}
+
 func (w *WebsocketManager) addConnWithTopic(topic string, conn *Conn) {
 	if len(w.topics2Conns[topic]) == 0 {
 		w.topics2Conns[topic] = make(map[*Conn]struct{})
@@ -102,13 +328,15 @@ func (w *WebsocketManager) addConnWithTopic(topic string, conn *Conn) {
 }
 
 func (w *WebsocketManager) RemoveSubscribeConn(c *Conn, topic string, params []string) error {
-	//The modification to w and c should be atomic, so we need two locks before modification
-	c.mtx.Lock()
-	defer c.mtx.Unlock()
+	//The modification to w and c should be atomic, so we need two locks before modification,
+	//and they are locked in the same order as AddSubscribeConn
 	w.mtx.Lock()
 	defer w.mtx.Unlock()
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
 	c.removeTopicAndParams(topic, params)
 	w.removeConnWithTopic(topic, c)
+	w.removeIndex(topic, params, c)
 	return nil
 }
 
@@ -125,113 +353,202 @@ func (w *WebsocketManager) removeConnWithTopic(topic string, conn *Conn) {
 	}
 }
 
-func (w *WebsocketManager) GetSlashSubscribeInfo() []Subscriber {
-	w.mtx.RLock()
-	defer w.mtx.RUnlock()
-	conns := w.topics2Conns[SlashKey]
-	res := make([]Subscriber, 0, len(conns))
-	for conn := range conns {
-		res = append(res, ImplSubscriber{Conn: conn})
+// Returns the key of a subscription in the index and its detail
+func getIndexKey(topic string, params []string) (key string, detail string) {
+	switch topic {
+	case DepthKey:
+		if len(params) == 2 {
+			return params[0], params[1]
+		}
+		return strings.Join(params, SeparateArgu), "all"
+	case KlineKey:
+		if len(params) >= 2 {
+			return strings.Join(params[:len(params)-1], SeparateArgu), params[len(params)-1]
+		}
 	}
-	return res
+	return strings.Join(params, SeparateArgu), ""
 }
 
-func (w *WebsocketManager) GetHeightSubscribeInfo() []Subscriber {
-	w.mtx.RLock()
-	defer w.mtx.RUnlock()
-	conns := w.topics2Conns[BlockInfoKey]
-	res := make([]Subscriber, 0, len(conns))
-	for conn := range conns {
-		res = append(res, ImplSubscriber{Conn: conn})
+func (w *WebsocketManager) addIndex(topic string, params []string, c *Conn) {
+	key, detail := getIndexKey(topic, params)
+	keys, ok := w.subIndex[topic]
+	if !ok {
+		keys = make(map[string]map[subEntry]struct{})
+		w.subIndex[topic] = keys
 	}
-	return res
+	if len(keys[key]) == 0 {
+		keys[key] = make(map[subEntry]struct{})
+	}
+	keys[key][subEntry{conn: c, detail: detail, label: getStreamName(topic, params...)}] = struct{}{}
+	w.dropViews(topic)
 }
 
-func (w *WebsocketManager) GetTickerSubscribeInfo() []Subscriber {
-	w.mtx.RLock()
-	defer w.mtx.RUnlock()
-	conns := w.topics2Conns[TickerKey]
-	res := make([]Subscriber, 0, len(conns))
-	for conn := range conns {
-		res = append(res, ImplSubscriber{
-			Conn:  conn,
-			value: conn.topicWithParams[TickerKey],
-		})
+func (w *WebsocketManager) removeIndex(topic string, params []string, c *Conn) {
+	key, detail := getIndexKey(topic, params)
+	keys := w.subIndex[topic]
+	delete(keys[key], subEntry{conn: c, detail: detail, label: getStreamName(topic, params...)})
+	if len(keys[key]) == 0 {
+		delete(keys, key)
 	}
-	return res
+	if len(keys) == 0 {
+		delete(w.subIndex, topic)
+	}
+	w.dropViews(topic)
 }
 
-// The key of the result map is market
-func (w *WebsocketManager) GetCandleStickSubscribeInfo() map[string][]Subscriber {
+func (w *WebsocketManager) dropViews(topic string) {
+	delete(w.keyedViews, topic)
+	delete(w.listViews, topic)
+}
+
+// Returns the subscribers of a topic grouped by their keys, which are built only after the subscriptions change
+func (w *WebsocketManager) getKeyedView(topic string) map[string][]Subscriber {
 	w.mtx.RLock()
-	defer w.mtx.RUnlock()
-	conns := w.topics2Conns[KlineKey]
-	res := make(map[string][]Subscriber)
-	for conn := range conns {
-		params := conn.topicWithParams[KlineKey]
-		for p := range params {
-			vals := strings.Split(p, SeparateArgu)
-			market := vals[0]
-			timeSpan := vals[1]
-			if len(vals) == 3 {
-				market = strings.Join(vals[:2], SeparateArgu)
-				timeSpan = vals[2]
+	view, ok := w.keyedViews[topic]
+	w.mtx.RUnlock()
+	if ok {
+		return view
+	}
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if view, ok = w.keyedViews[topic]; ok {
+		return view
+	}
+	keys := w.subIndex[topic]
+	view = make(map[string][]Subscriber, len(keys))
+	for key, entries := range keys {
+		targets := make([]Subscriber, 0, len(entries))
+		for e := range entries {
+			// the labeled connections get the messages of every subscription, which are not merged
+			var label string
+			if e.conn.IsLabeled() {
+				label = e.label
 			}
-			res[market] = append(res[vals[0]], ImplSubscriber{
-				Conn:  conn,
-				value: timeSpan,
-			})
+			// the subscribers of all the markets only get the markets of the tokens they subscribe if they do
+			if topic == CreateMarketInfoKey && len(key) == 0 && len(label) == 0 && hasTopicParams(e.conn, topic) {
+				continue
+			}
+			var value interface{}
+			if topic == DepthKey || topic == KlineKey {
+				value = e.detail
+			}
+			targets = append(targets, ImplSubscriber{Conn: e.conn, value: value, label: label})
+		}
+		if len(targets) != 0 {
+			view[key] = targets
 		}
 	}
+	w.keyedViews[topic] = view
+	return view
+}
 
-	return res
+func hasTopicParams(c *Conn, topic string) bool {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return !c.topicHasEmptyParamSet(topic)
 }
 
-// Get the Subscribers which doesn't need detail information
-func (w *WebsocketManager) getNoDetailSubscribe(topic string) map[string][]Subscriber {
+// Returns one subscriber for each connection subscribing this topic, and the details are the param sets if needed
+func (w *WebsocketManager) getListView(topic string, withParams bool) []Subscriber {
 	w.mtx.RLock()
-	defer w.mtx.RUnlock()
+	view, ok := w.listViews[topic]
+	w.mtx.RUnlock()
+	if ok {
+		return view
+	}
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if view, ok = w.listViews[topic]; ok {
+		return view
+	}
 	conns := w.topics2Conns[topic]
-	res := make(map[string][]Subscriber)
+	view = make([]Subscriber, 0, len(conns))
 	for conn := range conns {
-		for param := range conn.topicWithParams[topic] {
-			res[param] = append(res[param], ImplSubscriber{
-				Conn: conn,
-			})
+		if conn.IsLabeled() {
+			view = append(view, getLabeledSubscribers(conn, topic, withParams)...)
+			continue
 		}
+		s := ImplSubscriber{Conn: conn}
+		if withParams {
+			conn.mtx.RLock()
+			s.value = conn.topicWithParams[topic]
+			conn.mtx.RUnlock()
+		}
+		view = append(view, s)
 	}
+	w.listViews[topic] = view
+	return view
+}
 
+// A labeled connection gets one subscriber for each param set, whose detail only has this param set
+func getLabeledSubscribers(conn *Conn, topic string, withParams bool) []Subscriber {
+	if !withParams {
+		return []Subscriber{ImplSubscriber{Conn: conn, label: topic}}
+	}
+	conn.mtx.RLock()
+	defer conn.mtx.RUnlock()
+	res := make([]Subscriber, 0, len(conn.topicWithParams[topic]))
+	for param := range conn.topicWithParams[topic] {
+		res = append(res, ImplSubscriber{
+			Conn:  conn,
+			value: map[string]struct{}{param: {}},
+			label: getStreamName(topic, param),
+		})
+	}
 	return res
 }
 
-// The key of the result map is market
-func (w *WebsocketManager) GetDepthSubscribeInfo() map[string][]Subscriber {
-	w.mtx.RLock()
-	defer w.mtx.RUnlock()
-	conns := w.topics2Conns[DepthKey]
-	res := make(map[string][]Subscriber)
-	for conn := range conns {
-		params := conn.topicWithParams[DepthKey]
-		for p := range params {
-			var (
-				level  = "all"
-				vals   = strings.Split(p, SeparateArgu)
-				market = vals[0]
-			)
-			if len(vals) == 2 {
-				level = vals[1]
-			}
-			res[market] = append(res[market], ImplSubscriber{
-				Conn:  conn,
-				value: level,
-			})
-		}
+// Labels the pushed messages to a connection with the subscriptions they match, such that a relay can
+// dispatch the messages of all its subscriptions from one connection
+func (w *WebsocketManager) SetLabeled(c *Conn, labeled bool) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	var v int32
+	if labeled {
+		v = 1
+	}
+	atomic.StoreInt32(&c.labeled, v)
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	for topic := range c.allTopics {
+		w.dropViews(topic)
 	}
-	return res
 }
 
+func (w *WebsocketManager) GetSlashSubscribeInfo() []Subscriber {
+	return w.getListView(SlashKey, false)
+}
+
+func (w *WebsocketManager) GetHeightSubscribeInfo() []Subscriber {
+	return w.getListView(BlockInfoKey, false)
+}
+
+func (w *WebsocketManager) GetTickerSubscribeInfo() []Subscriber {
+	return w.getListView(TickerKey, true)
+}
+
+func (w *WebsocketManager) GetXTickerSubscribeInfo() []Subscriber {
+	return w.getListView(XTickerKey, true)
+}
+
+// The key of the result map is market, and the details are timespans
+func (w *WebsocketManager) GetCandleStickSubscribeInfo() map[string][]Subscriber {
+	return w.getKeyedView(KlineKey)
+}
+
+// Get the Subscribers which doesn't need detail information
+func (w *WebsocketManager) getNoDetailSubscribe(topic string) map[string][]Subscriber {
+	return w.getKeyedView(topic)
+}
+
+// The key of the result map is market, and the details are depth levels
+func (w *WebsocketManager) GetDepthSubscribeInfo() map[string][]Subscriber {
+	return w.getKeyedView(DepthKey)
+}
+
+// The key of the result map is token, and the key of the subscribers without token is an empty string
 func (w *WebsocketManager) GetMarketSubscribeInfo() map[string][]Subscriber {
-	return w.getNoDetailSubscribe(CreateMarketInfoKey)
+	return w.getKeyedView(CreateMarketInfoKey)
 }
 
 func (w *WebsocketManager) GetDealSubscribeInfo() map[string][]Subscriber {
@@ -276,15 +593,52 @@ func (w *WebsocketManager) GetDelegationRewards() map[string][]Subscriber {
 func (w *WebsocketManager) GetValidatorCommissionInfo() map[string][]Subscriber {
 	return w.getNoDetailSubscribe(ValidatorCommissionKey)
 }
+func (w *WebsocketManager) GetOpenOrdersSubscribeInfo() map[string][]Subscriber {
+	return w.getNoDetailSubscribe(OpenOrdersKey)
+}
+func (w *WebsocketManager) GetDepthL3SubscribeInfo() map[string][]Subscriber {
+	return w.getNoDetailSubscribe(DepthL3Key)
+}
+func (w *WebsocketManager) GetAccountSubscribeInfo() map[string][]Subscriber {
+	return w.getNoDetailSubscribe(AccountKey)
+}
+func (w *WebsocketManager) GetAlertSubscribeInfo() map[string][]Subscriber {
+	return w.getNoDetailSubscribe(AlertKey)
+}
+func (w *WebsocketManager) GetRebateSubscribeInfo() map[string][]Subscriber {
+	return w.getNoDetailSubscribe(RebateKey)
+}
 
 // Push msgs----------------------------
+// Only called by the push goroutine, the messages are sent to the connections when Flush is called
 func (w *WebsocketManager) sendEncodeMsg(subscriber Subscriber, typeKey string, info []byte) {
 	if !w.SkipPushed {
-		msg := []byte(fmt.Sprintf("{\"type\":\"%s\", \"payload\":%s}", typeKey, string(info)))
-		if err := subscriber.WriteMsg(msg); err != nil {
-			log.Errorf(err.Error())
-			w.CloseWsConn(subscriber.(ImplSubscriber).Conn)
+		s := subscriber.(ImplSubscriber)
+		w.pusher.add(s.Conn, s.label, typeKey, w.pushSeq, w.pushHeight, info)
+	}
+}
+
+func (w *WebsocketManager) Flush() {
+	w.pusher.flush()
+}
+
+// Pushes a message received from another trade-server, such as the primary of a relay, with its own seq and height.
+// It is encoded only once for all the connections, and queued as the pushed messages of this server
+func (w *WebsocketManager) RelayMsg(conns []*Conn, typeKey string, seq, height int64, payload []byte) {
+	msg := newPushMsg(typeKey, seq, height, payload)
+	for _, conn := range conns {
+		w.deliver(conn, msg)
+	}
+}
+
+func (w *WebsocketManager) deliver(conn *Conn, msg *pushMsg) {
+	if err := conn.writePushMsg(msg); err != nil {
+		log.Errorf(err.Error())
+		if err == ErrSlowConsumer {
+			w.closeSlowConn(conn)
+			return
 		}
+		w.CloseWsConn(conn)
 	}
 }
 func (w *WebsocketManager) PushLockedSendMsg(subscriber Subscriber, info []byte) {
@@ -304,6 +658,14 @@ func (w *WebsocketManager) PushTicker(subscriber Subscriber, t []*Ticker) {
 	}
 	w.sendEncodeMsg(subscriber, TickerKey, payload)
 }
+func (w *WebsocketManager) PushXTicker(subscriber Subscriber, t []*XTicker) {
+	payload, err := json.Marshal(t)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	w.sendEncodeMsg(subscriber, XTickerKey, payload)
+}
 func (w *WebsocketManager) PushDepthFullMsg(subscriber Subscriber, info []byte) {
 	w.sendEncodeMsg(subscriber, DepthFull, info)
 }
@@ -364,3 +726,15 @@ func (w *WebsocketManager) PushValidatorCommissionInfo(subscriber Subscriber, in
 func (w *WebsocketManager) PushDelegationRewards(subscriber Subscriber, info []byte) {
 	w.sendEncodeMsg(subscriber, DelegationRewardsKey, info)
 }
+func (w *WebsocketManager) PushOpenOrder(subscriber Subscriber, info []byte) {
+	w.sendEncodeMsg(subscriber, OpenOrdersKey, info)
+}
+func (w *WebsocketManager) PushDepthL3(subscriber Subscriber, info []byte) {
+	w.sendEncodeMsg(subscriber, DepthL3Key, info)
+}
+func (w *WebsocketManager) PushAlert(subscriber Subscriber, info []byte) {
+	w.sendEncodeMsg(subscriber, AlertKey, info)
+}
+func (w *WebsocketManager) PushRebate(subscriber Subscriber, info []byte) {
+	w.sendEncodeMsg(subscriber, RebateKey, info)
+}
@@ -2,8 +2,11 @@ package core
 
 import (
 	"encoding/json"
+	"fmt"
+	"reflect"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/gorilla/websocket"
 
@@ -39,6 +42,11 @@ func assertZeroParamTopic(t *testing.T) {
 	// slash
 	require.True(t, checkTopicValid(SlashKey, []string{}))
 	require.False(t, checkTopicValid(SlashKey, []string{"time"}))
+
+	// create_market; create_market:<token>
+	require.True(t, checkTopicValid(CreateMarketInfoKey, []string{}))
+	require.True(t, checkTopicValid(CreateMarketInfoKey, []string{"abc"}))
+	require.False(t, checkTopicValid(CreateMarketInfoKey, []string{"abc", "cet"}))
 }
 
 func assertTicker(t *testing.T) {
@@ -52,12 +60,19 @@ func assertTicker(t *testing.T) {
 	require.True(t, checkTopicValid(TickerKey, []string{"B", "abc/cet"}))
 
 	require.False(t, checkTopicValid(TickerKey, []string{"abc", "cet", "btc"}))
+
+	// xticker:abc/cet; xticker:B:abc/cet
+	require.False(t, checkTopicValid(XTickerKey, []string{}))
+	require.True(t, checkTopicValid(XTickerKey, []string{"abc/cet"}))
+	require.True(t, checkTopicValid(XTickerKey, []string{"B", "abc/cet"}))
+	require.False(t, checkTopicValid(XTickerKey, []string{"abc", "cet"}))
 }
 
 func assertOneParamsTopic(t *testing.T) {
 	topics := []string{UnbondingKey, RedelegationKey, LockedKey,
 		UnlockKey, TxKey, IncomeKey, OrderKey, CommentKey,
-		BancorTradeKey, BancorKey, DealKey, BancorDealKey}
+		BancorTradeKey, BancorKey, DealKey, BancorDealKey,
+		DelegationRewardsKey, ValidatorCommissionKey}
 
 	//deal:<trading-pair>; income:<address> ...
 	// ../docs/websocket-streams.md
@@ -78,10 +93,14 @@ func assetKline(t *testing.T) {
 	require.True(t, checkTopicValid(KlineKey, []string{"B", "abc/cet", "1min"}))
 	require.True(t, checkTopicValid(KlineKey, []string{"B", "abc/cet", "1hour"}))
 	require.True(t, checkTopicValid(KlineKey, []string{"B", "abc/cet", "1day"}))
+	for _, span := range []string{"5min", "15min", "30min", "4hour", "1week", "1month"} {
+		require.True(t, checkTopicValid(KlineKey, []string{"abc/cet", span}))
+		require.True(t, checkTopicValid(KlineKey, []string{"B", "abc/cet", span}))
+	}
 
 	//invalid timespan
-	require.False(t, checkTopicValid(KlineKey, []string{"abc/cet", "1week"}))
-	require.False(t, checkTopicValid(KlineKey, []string{"B", "abc/cet", "1week"}))
+	require.False(t, checkTopicValid(KlineKey, []string{"abc/cet", "2week"}))
+	require.False(t, checkTopicValid(KlineKey, []string{"B", "abc/cet", "2week"}))
 
 	// invalid params number
 	require.False(t, checkTopicValid(KlineKey, []string{}))
@@ -198,3 +217,189 @@ func TestWebsocketManager_AddWsConnAndCloseConn_SigThread(t *testing.T) {
 	wsManager.CloseWsConn(c1)
 	require.EqualValues(t, 0, len(wsManager.wsConn2Conn))
 }
+
+func TestWebsocketManager_SubscribeIndex(t *testing.T) {
+	wsManager := NewWebSocketManager()
+	c1 := wsManager.AddWsConn(&MockWif{})
+	c2 := wsManager.AddWsConn(&MockWif{})
+	require.Nil(t, wsManager.AddSubscribeConn(c1, DepthKey, []string{"abc/cet", "0.1"}))
+	require.Nil(t, wsManager.AddSubscribeConn(c1, DepthKey, []string{"abc/cet"}))
+	require.Nil(t, wsManager.AddSubscribeConn(c2, KlineKey, []string{"B", "abc/cet", "1min"}))
+	require.Nil(t, wsManager.AddSubscribeConn(c1, CreateMarketInfoKey, nil))
+	require.Nil(t, wsManager.AddSubscribeConn(c2, CreateMarketInfoKey, nil))
+	require.Nil(t, wsManager.AddSubscribeConn(c2, CreateMarketInfoKey, []string{"abc"}))
+	require.Nil(t, wsManager.AddSubscribeConn(c1, DealKey, []string{"abc/cet"}))
+	require.Nil(t, wsManager.AddSubscribeConn(c2, TickerKey, []string{"abc/cet"}))
+
+	depth := wsManager.GetDepthSubscribeInfo()
+	require.EqualValues(t, 1, len(depth))
+	levels := []string{depth["abc/cet"][0].Detail().(string), depth["abc/cet"][1].Detail().(string)}
+	require.ElementsMatch(t, []string{"0.1", "all"}, levels)
+	kline := wsManager.GetCandleStickSubscribeInfo()
+	require.EqualValues(t, 1, len(kline["B:abc/cet"]))
+	require.EqualValues(t, "1min", kline["B:abc/cet"][0].Detail())
+	markets := wsManager.GetMarketSubscribeInfo()
+	require.EqualValues(t, []Subscriber{ImplSubscriber{Conn: c1}}, markets[""])
+	require.EqualValues(t, []Subscriber{ImplSubscriber{Conn: c2}}, markets["abc"])
+	tickers := wsManager.GetTickerSubscribeInfo()
+	require.EqualValues(t, 1, len(tickers))
+	require.EqualValues(t, map[string]struct{}{"abc/cet": {}}, tickers[0].Detail())
+
+	// the views are rebuilt only after the subscriptions change
+	deals := wsManager.GetDealSubscribeInfo()
+	require.EqualValues(t, reflect.ValueOf(deals).Pointer(), reflect.ValueOf(wsManager.GetDealSubscribeInfo()).Pointer())
+	require.Nil(t, wsManager.RemoveSubscribeConn(c1, DepthKey, []string{"abc/cet", "0.1"}))
+	depth = wsManager.GetDepthSubscribeInfo()
+	require.EqualValues(t, []Subscriber{ImplSubscriber{Conn: c1, value: "all"}}, depth["abc/cet"])
+
+	wsManager.CloseWsConn(c1)
+	require.EqualValues(t, 0, len(wsManager.GetDealSubscribeInfo()))
+	require.EqualValues(t, 0, len(wsManager.GetDepthSubscribeInfo()))
+	require.EqualValues(t, []Subscriber{ImplSubscriber{Conn: c2}}, wsManager.GetMarketSubscribeInfo()["abc"])
+	require.EqualValues(t, 0, len(wsManager.GetMarketSubscribeInfo()[""]))
+	require.EqualValues(t, 3, len(wsManager.subIndex))
+}
+
+func TestWebsocketManager_FanOut(t *testing.T) {
+	wsManager := NewWebSocketManager()
+	wsManager.pusher = newFanOutPusher(wsManager, 4)
+	wifs := make([]*MockWif, MinParallelPushes+10)
+	for i := range wifs {
+		wifs[i] = &MockWif{}
+		conn := wsManager.AddWsConn(wifs[i])
+		require.Nil(t, wsManager.AddSubscribeConn(conn, DealKey, []string{"abc/cet"}))
+	}
+	for seq := int64(1); seq <= 3; seq++ {
+		wsManager.SetPushMeta(seq, 10)
+		for _, sub := range wsManager.GetDealSubscribeInfo()["abc/cet"] {
+			wsManager.PushDeal(sub, []byte("{}"))
+		}
+	}
+	// the message is shared by all its subscribers
+	pending := wsManager.pusher.pending
+	require.True(t, pending[0].msg == pending[1].msg)
+	require.False(t, pending[0].msg == pending[len(pending)-1].msg)
+	wsManager.Flush()
+	require.EqualValues(t, 0, len(wsManager.pusher.pending))
+
+	for _, wif := range wifs {
+		// wait for the send goroutine of the connection
+		for i := 0; i < 100 && len(wif.GetRecords()) < 3; i++ {
+			time.Sleep(time.Millisecond)
+		}
+		records := wif.GetRecords()
+		require.EqualValues(t, 3, len(records))
+		for i, r := range records {
+			require.EqualValues(t, fmt.Sprintf(`{"type":"deal", "seq":%d, "height":10, "payload":{}}`, i+1), string(r))
+		}
+	}
+}
+
+func TestWebsocketManager_Labeled(t *testing.T) {
+	wsManager := NewWebSocketManager()
+	wif := &MockWif{}
+	conn := wsManager.AddWsConn(wif)
+	require.Nil(t, wsManager.AddSubscribeConn(conn, DealKey, []string{"abc/cet"}))
+	require.Nil(t, wsManager.AddSubscribeConn(conn, DealKey, []string{"*"}))
+	require.Nil(t, wsManager.AddSubscribeConn(conn, TickerKey, []string{"abc/cet"}))
+	require.Nil(t, wsManager.AddSubscribeConn(conn, TickerKey, []string{"xyz/cet"}))
+	require.EqualValues(t, 1, len(wsManager.GetTickerSubscribeInfo()))
+
+	// the views are rebuilt with the labels once the connection is labeled
+	wsManager.SetLabeled(conn, true)
+	subs := getMarketSubscribers(wsManager.GetDealSubscribeInfo(), "abc/cet")
+	require.ElementsMatch(t, []Subscriber{ImplSubscriber{Conn: conn, label: "deal:abc/cet"},
+		ImplSubscriber{Conn: conn, label: "deal:*"}}, subs)
+	require.ElementsMatch(t, []Subscriber{
+		ImplSubscriber{Conn: conn, value: map[string]struct{}{"abc/cet": {}}, label: "ticker:abc/cet"},
+		ImplSubscriber{Conn: conn, value: map[string]struct{}{"xyz/cet": {}}, label: "ticker:xyz/cet"},
+	}, wsManager.GetTickerSubscribeInfo())
+
+	wsManager.SetPushMeta(5, 10)
+	wsManager.PushDeal(subs[0], []byte("{}"))
+	wsManager.Flush()
+	time.Sleep(10 * time.Millisecond)
+	records := wif.GetRecords()
+	require.EqualValues(t, 1, len(records))
+	require.EqualValues(t, fmt.Sprintf(`{"type":"deal", "label":"%s", "seq":5, "height":10, "payload":{}}`,
+		subs[0].(ImplSubscriber).label), string(records[0]))
+
+	wsManager.SetLabeled(conn, false)
+	require.EqualValues(t, []Subscriber{ImplSubscriber{Conn: conn}}, wsManager.GetDealSubscribeInfo()["*"])
+}
+
+// discardWif drops all the written messages
+type discardWif struct {
+	// a zero-size struct may share its address with others
+	_ int
+}
+
+func (m discardWif) Close() error                                             { return nil }
+func (m discardWif) WriteMessage(msgType int, data []byte) error              { return nil }
+func (m discardWif) WriteControl(msgType int, data []byte, _ time.Time) error { return nil }
+func (m discardWif) ReadMessage() (messageType int, p []byte, err error)      { return 0, nil, nil }
+func (m discardWif) SetPingHandler(func(string) error)                        {}
+func (m discardWif) PingHandler() func(string) error                          { return nil }
+
+// Each connection subscribes the deals of one of the markets
+func prepareFanOut(b *testing.B, connCount, marketCount int) (*WebsocketManager, []string) {
+	wsManager := NewWebSocketManager()
+	markets := make([]string, marketCount)
+	for i := range markets {
+		markets[i] = fmt.Sprintf("t%d/cet", i)
+	}
+	for i := 0; i < connCount; i++ {
+		conn := wsManager.AddWsConn(&discardWif{})
+		require.Nil(b, wsManager.AddSubscribeConn(conn, DealKey, []string{markets[i%marketCount]}))
+	}
+	return wsManager, markets
+}
+
+// The way subscribers were found and messages were encoded before the subscriptions were indexed
+func rebuildNoDetailSubscribe(w *WebsocketManager, topic string) map[string][]Subscriber {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+	res := make(map[string][]Subscriber)
+	for conn := range w.topics2Conns[topic] {
+		for param := range conn.topicWithParams[topic] {
+			res[param] = append(res[param], ImplSubscriber{Conn: conn})
+		}
+	}
+	return res
+}
+
+func benchmarkFanOut(b *testing.B, connCount, marketCount int, indexed bool) {
+	wsManager, markets := prepareFanOut(b, connCount, marketCount)
+	payload := []byte(`{"market":"abc/cet","price":"1.2","amount":"100","side":1}`)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		market := markets[i%len(markets)]
+		if indexed {
+			wsManager.SetPushMeta(int64(i+1), 10)
+			for _, sub := range wsManager.GetDealSubscribeInfo()[market] {
+				wsManager.PushDeal(sub, payload)
+			}
+			wsManager.Flush()
+		} else {
+			for _, sub := range rebuildNoDetailSubscribe(wsManager, DealKey)[market] {
+				_ = sub.WriteMsg(encodePushMsg(DealKey, int64(i+1), 10, payload))
+			}
+		}
+	}
+}
+
+func BenchmarkFanOutRebuild_1000Conns_10Markets(b *testing.B) {
+	benchmarkFanOut(b, 1000, 10, false)
+}
+
+func BenchmarkFanOutIndexed_1000Conns_10Markets(b *testing.B) {
+	benchmarkFanOut(b, 1000, 10, true)
+}
+
+func BenchmarkFanOutRebuild_10000Conns_1Market(b *testing.B) {
+	benchmarkFanOut(b, 10000, 1, false)
+}
+
+func BenchmarkFanOutIndexed_10000Conns_1Market(b *testing.B) {
+	benchmarkFanOut(b, 10000, 1, true)
+}
@@ -0,0 +1,133 @@
+package core
+
+import (
+	"errors"
+	"strings"
+)
+
+// A market param can be a wildcard, such as "*", "*/cet" (all the markets whose money is cet)
+// or "abc/*" (all the markets whose stock is abc), which also matches the markets created later
+const Wildcard = "*"
+
+// The max number of wildcard subscriptions of one connection
+const MaxWildcardSubscriptions = 10
+
+var ErrTooManyWildcards = errors.New("too many wildcard subscriptions")
+
+// The topics whose market param can be a wildcard
+func isWildcardTopic(topic string) bool {
+	switch topic {
+	case DealKey, TickerKey, XTickerKey, KlineKey, DepthKey:
+		return true
+	}
+	return false
+}
+
+func isWildcardMarket(market string) bool {
+	return strings.Contains(market, Wildcard)
+}
+
+func isValidWildcardMarket(market string) bool {
+	if market == Wildcard {
+		return true
+	}
+	tokens := strings.Split(market, "/")
+	if len(tokens) != 2 || len(tokens[0]) == 0 || len(tokens[1]) == 0 {
+		return false
+	}
+	// only one of stock and money is a wildcard
+	return (tokens[0] == Wildcard && !isWildcardMarket(tokens[1])) ||
+		(tokens[1] == Wildcard && !isWildcardMarket(tokens[0]))
+}
+
+// Returns the index of the market in params, such as 'abc/cet' in 'kline:B:abc/cet:1min'
+func getMarketParamIndex(topic string, params []string) int {
+	switch topic {
+	case TickerKey, XTickerKey:
+		return len(params) - 1
+	case KlineKey:
+		return len(params) - 2
+	}
+	return 0
+}
+
+func hasWildcardParam(params []string) bool {
+	for _, p := range params {
+		if isWildcardMarket(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks the wildcards in params, which are only allowed in the market params of some topics
+func checkWildcardParams(topic string, params []string) bool {
+	if !hasWildcardParam(params) {
+		return true
+	}
+	if !isWildcardTopic(topic) {
+		return false
+	}
+	idx := getMarketParamIndex(topic, params)
+	for i, p := range params {
+		if i == idx {
+			if !isValidWildcardMarket(p) {
+				return false
+			}
+		} else if isWildcardMarket(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the market itself and the wildcards matching it.
+// The prefix of a bancor market is kept, so 'B:abc/cet' is matched by 'B:*' instead of '*'
+func getMarketPatterns(market string) []string {
+	prefix := ""
+	if i := strings.LastIndex(market, SeparateArgu); i >= 0 {
+		prefix, market = market[:i+1], market[i+1:]
+	}
+	patterns := []string{prefix + market, prefix + Wildcard}
+	if tokens := strings.Split(market, "/"); len(tokens) == 2 {
+		patterns = append(patterns, prefix+Wildcard+"/"+tokens[1], prefix+tokens[0]+"/"+Wildcard)
+	}
+	return patterns
+}
+
+// Returns the subscribers of a market, including the ones subscribing the wildcards matching it
+func getMarketSubscribers(info map[string][]Subscriber, market string) []Subscriber {
+	var (
+		res     []Subscriber
+		matched = 0
+	)
+	for _, pattern := range getMarketPatterns(market) {
+		if targets, ok := info[pattern]; ok {
+			res = append(res, targets...)
+			matched++
+		}
+	}
+	if matched <= 1 {
+		return res
+	}
+	// a connection may subscribe both a market and a wildcard matching it
+	pushed := make(map[Subscriber]struct{}, len(res))
+	targets := res[:0]
+	for _, target := range res {
+		if _, ok := pushed[target]; !ok {
+			pushed[target] = struct{}{}
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// Returns whether a market is in the market list of a ticker subscriber, directly or by a wildcard
+func subscribesMarket(marketList map[string]struct{}, market string) bool {
+	for _, pattern := range getMarketPatterns(market) {
+		if _, ok := marketList[pattern]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWildcardTopics(t *testing.T) {
+	for _, topic := range []string{"deal:*", "deal:*/cet", "deal:abc/*", "ticker:*", "ticker:B:*", "xticker:*/cet",
+		"kline:*:1min", "kline:B:*/cet:1hour", "depth:*", "depth:*:all", "depth:abc/*:0.1"} {
+		_, _, err := GetTopicAndParams(topic)
+		require.Nil(t, err, topic)
+	}
+	for _, topic := range []string{"deal:*/*", "deal:a*/cet", "deal:*cet", "deal:/*", "order:*", "comment:*",
+		"depth:abc/cet:*", "kline:*:*", "kline:*:1day:B", "ticker:*:abc/cet", "bancor:*"} {
+		_, _, err := GetTopicAndParams(topic)
+		require.NotNil(t, err, topic)
+	}
+}
+
+func TestGetMarketSubscribers(t *testing.T) {
+	require.EqualValues(t, []string{"abc/cet", "*", "*/cet", "abc/*"}, getMarketPatterns("abc/cet"))
+	require.EqualValues(t, []string{"B:abc/cet", "B:*", "B:*/cet", "B:abc/*"}, getMarketPatterns("B:abc/cet"))
+
+	s1, s2, s3 := &PlainSubscriber{ID: 1}, &PlainSubscriber{ID: 2}, &PlainSubscriber{ID: 3}
+	info := map[string][]Subscriber{
+		"abc/cet": {s1},
+		"*/cet":   {s1, s2},
+		"xyz/*":   {s3},
+	}
+	require.EqualValues(t, []Subscriber{s1, s2}, getMarketSubscribers(info, "abc/cet"))
+	require.EqualValues(t, []Subscriber{s1, s2, s3}, getMarketSubscribers(info, "xyz/cet"))
+	require.EqualValues(t, []Subscriber{s3}, getMarketSubscribers(info, "xyz/usdt"))
+	require.EqualValues(t, 0, len(getMarketSubscribers(info, "B:abc/cet")))
+
+	require.True(t, subscribesMarket(map[string]struct{}{"*": {}}, "abc/cet"))
+	require.False(t, subscribesMarket(map[string]struct{}{"*": {}}, "B:abc/cet"))
+	require.True(t, subscribesMarket(map[string]struct{}{"B:*/cet": {}}, "B:abc/cet"))
+	require.False(t, subscribesMarket(map[string]struct{}{"abc/*": {}}, "xyz/cet"))
+}
+
+func TestWildcardLimit(t *testing.T) {
+	wsManager := NewWebSocketManager()
+	wsManager.SetMaxWildcards(2)
+	conn := wsManager.AddWsConn(&MockWif{})
+	require.Nil(t, wsManager.AddSubscribeConn(conn, DealKey, []string{"*"}))
+	require.Nil(t, wsManager.AddSubscribeConn(conn, KlineKey, []string{"*/cet", "1min"}))
+	// subscribing again does not count
+	require.Nil(t, wsManager.AddSubscribeConn(conn, DealKey, []string{"*"}))
+	require.Equal(t, ErrTooManyWildcards, wsManager.AddSubscribeConn(conn, TickerKey, []string{"*"}))
+	require.Nil(t, wsManager.AddSubscribeConn(conn, TickerKey, []string{"abc/cet"}))
+	require.Nil(t, wsManager.RemoveSubscribeConn(conn, DealKey, []string{"*"}))
+	require.Nil(t, wsManager.AddSubscribeConn(conn, TickerKey, []string{"*"}))
+
+	require.EqualValues(t, 1, len(wsManager.GetCandleStickSubscribeInfo()["*/cet"]))
+	require.EqualValues(t, 0, len(wsManager.GetDealSubscribeInfo()))
+	// no full information is pushed for the wildcard subscriptions
+	require.Nil(t, wsManager.PushFullInfo(nil, conn, DealKey, []string{"*"}, 10))
+}
+
+func TestHub_PushToWildcards(t *testing.T) {
+	subMan := &MocSubscribeManager{}
+	subMan.DealSubscribeInfo = map[string][]Subscriber{
+		"*":       {&PlainSubscriber{ID: 1}},
+		"*/cet":   {&PlainSubscriber{ID: 2}},
+		"xyz/*":   {&PlainSubscriber{ID: 3}},
+		"abc/cet": {&PlainSubscriber{ID: 4}},
+	}
+	subMan.XTickerSubscribeInfo = []Subscriber{
+		NewTickerSubscriber(5, map[string]struct{}{"abc/*": {}}),
+		NewTickerSubscriber(6, map[string]struct{}{"*/usdt": {}}),
+	}
+	hub := getHub(t, subMan)
+	defer os.RemoveAll("tmp")
+	// the market is created after the subscriptions
+	hub.AddMarket("abc/cet")
+
+	fill := `{"order_id":"coinex1ughhs0eyames355v4tzq5nx2g806p55rna0d2x-9","trading_pair":"abc/cet","height":6,"side":2,"price":"1.000000000000000000","left_stock":0,"freeze":0,"deal_stock":100,"deal_money":200,"curr_stock":100,"curr_money":200,"fill_price":"2.000000000000000000"}`
+	hub.ConsumeMessage("height_info", []byte(`{"chain_id":"coinex-test","height":6,"timestamp":1563178030}`))
+	hub.ConsumeMessage("fill_order_info", []byte(fill))
+	fillCommitInfo(hub)
+	hub.ConsumeMessage("height_info", []byte(`{"chain_id":"coinex-test","height":7,"timestamp":1563178090}`))
+	fillCommitInfo(hub)
+	time.Sleep(10 * time.Millisecond)
+
+	xticker := `[{"market":"abc/cet","new":"2.000000000000000000","old":"0","minute_in_day":487,"high":"2.000000000000000000","low":"2.000000000000000000","volume":"100"}]`
+	subMan.CompareResult(t, fmt.Sprintf("1: %s\n2: %s\n4: %s\n5: %s", fill, fill, fill, xticker))
+}
@@ -41,6 +41,11 @@ func init() {
 	PruneableKeys[core.LockedByte] = struct{}{}
 	PruneableKeys[core.DonationByte] = struct{}{}
 	PruneableKeys[core.DelistByte] = struct{}{}
+	PruneableKeys[core.DelegatorRewardsByte] = struct{}{}
+	PruneableKeys[core.ValidatorCommissionByte] = struct{}{}
+	PruneableKeys[core.OrderIDByte] = struct{}{}
+	PruneableKeys[core.DepthSnapshotByte] = struct{}{}
+	PruneableKeys[core.DepthDeltaByte] = struct{}{}
 	PruneableKeys[core.DetailByte] = struct{}{} // it's special
 }
 
@@ -4,3 +4,13 @@ const (
 	serverCertName = "server.crt"
 	serverKeyName  = "server.key"
 )
+
+// The headers to access the address-scoped REST endpoints when authentication is required
+const (
+	HeaderAuthChallenge = "X-Auth-Challenge"
+	HeaderAuthPubKey    = "X-Auth-PubKey"
+	HeaderAuthSignature = "X-Auth-Signature"
+)
+
+// The header sent by the Server-Sent Events clients when they reconnect
+const HeaderLastEventID = "Last-Event-ID"
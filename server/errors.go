@@ -12,8 +12,35 @@ func ErrInvalidParams(params string) error {
 	return fmt.Errorf("%s must greater than 0", params)
 }
 func ErrInvalidTimespan() error {
-	return fmt.Errorf("timespan must be 1min/1hour/1day")
+	return fmt.Errorf("timespan must be 1min/5min/15min/30min/1hour/4hour/1day/1week/1month")
+}
+func ErrOrderNotFound(orderID string) error {
+	return fmt.Errorf("order %s not found", orderID)
+}
+func ErrMarketNotFound(market string) error {
+	return fmt.Errorf("market %s not found", market)
+}
+func ErrDepthNotRetained(market string, height int64) error {
+	return fmt.Errorf("depth of %s at height %d is not retained", market, height)
+}
+func ErrInvalidTimeRange() error {
+	return fmt.Errorf("from must not be greater than to")
+}
+func ErrInvalidDirection() error {
+	return fmt.Errorf("direction must be asc/desc")
+}
+func ErrInvalidCursor() error {
+	return fmt.Errorf("invalid cursor")
+}
+func ErrUnsupportedCursor() error {
+	return fmt.Errorf("cursor is not supported by this query")
+}
+func ErrInvalidFormat() error {
+	return fmt.Errorf("format must be ndjson/csv")
 }
 func ErrInvalidTag() error {
 	return fmt.Errorf("tag must be create/fill/cancel")
 }
+func ErrUnknownQuery(query string) error {
+	return fmt.Errorf("unknown query %s", query)
+}
@@ -0,0 +1,292 @@
+package server
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/types/rest"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/coinexchain/trade-server/core"
+)
+
+const (
+	queryKeyFormat = "format"
+
+	ExportFormatNDJSON = "ndjson"
+	ExportFormatCSV    = "csv"
+)
+
+// The columns of the exported records in CSV, which are the json names of their fields.
+// The block time of each record is exported as the first column, and the tag of each order as the second one
+var exportColumns = map[string][]string{
+	core.ExportDeals:        jsonFieldNames(core.FillOrderInfo{}),
+	core.ExportOrders:       jsonFieldNames(core.CreateOrderInfo{}, core.FillOrderInfo{}, core.CancelOrderInfo{}),
+	core.ExportTxs:          jsonFieldNames(core.NotificationTx{}),
+	core.ExportIncomes:      jsonFieldNames(core.NotificationTx{}),
+	core.ExportCandleSticks: jsonFieldNames(core.CandleStick{}),
+}
+
+var orderTagNames = map[byte]string{
+	core.CreateOrderEndByte: core.CreateOrderStr,
+	core.FillOrderEndByte:   core.FillOrderStr,
+	core.CancelOrderEndByte: core.CancelOrderStr,
+}
+
+// Returns the json names of the fields of the structs, without duplicates
+func jsonFieldNames(structs ...interface{}) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, s := range structs {
+		t := reflect.TypeOf(s)
+		for i := 0; i < t.NumField(); i++ {
+			name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+			if name == "" || name == "-" || seen[name] {
+				continue
+			}
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// The records are streamed page by page, without the limit of the count
+func ExportRequestHandlerFn(hub *core.Hub, kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, format, err := parseExportParams(r, kind)
+		if err != nil {
+			rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		contentType := "application/x-ndjson"
+		if format == ExportFormatCSV {
+			contentType = "text/csv"
+		}
+		stream, err := startExportStream(w, r, contentType, kind+"."+format)
+		if err != nil {
+			rest.WriteErrorResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		defer stream.close()
+
+		var enc exportEncoder = &ndjsonEncoder{w: stream}
+		if format == ExportFormatCSV {
+			enc = newCSVEncoder(stream, kind)
+			// the header is written before the records, even if there are no records
+			if err = stream.writePage(enc.flush); err != nil {
+				log.WithError(err).Error("export failed")
+				return
+			}
+		}
+		err = hub.ExportRecords(req, func(page core.ExportPage) error {
+			return stream.writePage(func() error {
+				for i, entry := range page.Data {
+					// the orders of the other tags are also read when the orders are filtered by tag
+					if len(entry) == 0 || (req.Tag != "" && orderTagNames[page.Tags[i]] != req.Tag) {
+						continue
+					}
+					var tag string
+					if kind == core.ExportOrders {
+						tag = orderTagNames[page.Tags[i]]
+					}
+					if err := enc.encode(page.TimeSid[2*i], tag, entry); err != nil {
+						return err
+					}
+				}
+				return enc.flush()
+			})
+		})
+		if err != nil {
+			log.WithError(err).Error("export failed")
+		}
+	}
+}
+
+func parseExportParams(r *http.Request, kind string) (req core.ExportRequest, format string, err error) {
+	if err = r.ParseForm(); err != nil {
+		return
+	}
+	req.Kind = kind
+	key := queryKeyAccount
+	if kind == core.ExportDeals || kind == core.ExportCandleSticks {
+		key = queryKeyMarket
+	}
+	if req.Key = r.FormValue(key); req.Key == "" {
+		return req, "", ErrNilParams(key)
+	}
+	if kind == core.ExportCandleSticks {
+		if req.Timespan, err = parseQueryTimespanParams(r.FormValue(queryKeyTimespan)); err != nil {
+			return
+		}
+	}
+	if kind == core.ExportOrders {
+		req.Tag = r.FormValue(queryKeyOrderTag)
+		if err = parseQueryTagParams(req.Tag); err != nil {
+			return
+		}
+	}
+	req.Token = strings.ToLower(r.FormValue(queryKeyToken))
+	if req.Range, err = parseQueryRangeParams(r.FormValue); err != nil {
+		return
+	}
+	switch format = r.FormValue(queryKeyFormat); format {
+	case "":
+		format = ExportFormatNDJSON
+	case ExportFormatNDJSON, ExportFormatCSV:
+	default:
+		return req, "", ErrInvalidFormat()
+	}
+	return
+}
+
+type exportEncoder interface {
+	encode(time int64, tag string, entry json.RawMessage) error
+	flush() error
+}
+
+// Every line is a record, with its block time and the tag of order as the leading fields
+type ndjsonEncoder struct {
+	w io.Writer
+}
+
+func (e *ndjsonEncoder) encode(time int64, tag string, entry json.RawMessage) error {
+	head := fmt.Sprintf("{\"time\":%d", time)
+	if tag != "" {
+		head += fmt.Sprintf(",\"tag\":\"%s\"", tag)
+	}
+	body := strings.TrimSpace(string(entry))
+	if len(body) < 2 || body[0] != '{' {
+		return fmt.Errorf("record is not an object: %s", body)
+	}
+	if body = strings.TrimSpace(body[1:]); body[0] != '}' {
+		head += ","
+	}
+	_, err := io.WriteString(e.w, head+body+"\n")
+	return err
+}
+
+func (e *ndjsonEncoder) flush() error {
+	return nil
+}
+
+// The strings are written without quotes, and the other values are written as json
+type csvEncoder struct {
+	w       *csv.Writer
+	columns []string
+}
+
+func newCSVEncoder(w io.Writer, kind string) *csvEncoder {
+	header := []string{"time"}
+	if kind == core.ExportOrders {
+		header = append(header, "tag")
+	}
+	e := &csvEncoder{w: csv.NewWriter(w), columns: exportColumns[kind]}
+	// the error is returned when the header is flushed
+	_ = e.w.Write(append(header, e.columns...))
+	return e
+}
+
+func (e *csvEncoder) encode(time int64, tag string, entry json.RawMessage) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(entry, &fields); err != nil {
+		return err
+	}
+	row := []string{strconv.FormatInt(time, 10)}
+	if tag != "" {
+		row = append(row, tag)
+	}
+	for _, col := range e.columns {
+		val := fields[col]
+		var str string
+		if len(val) != 0 && val[0] == '"' && json.Unmarshal(val, &str) == nil {
+			row = append(row, str)
+		} else {
+			row = append(row, string(val))
+		}
+	}
+	return e.w.Write(row)
+}
+
+func (e *csvEncoder) flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// exportStream is the body of an export. The connection is hijacked if possible, such that a long export
+// is not limited by the write timeout of the server, and every page has its own write deadline
+type exportStream struct {
+	netConn net.Conn
+	buf     *bufio.Writer
+	w       io.Writer
+	flush   func()
+}
+
+func startExportStream(w http.ResponseWriter, r *http.Request, contentType, filename string) (*exportStream, error) {
+	disposition := fmt.Sprintf("attachment; filename=\"%s\"", filename)
+	if hijacker, ok := w.(http.Hijacker); ok && r.ProtoMajor == 1 {
+		netConn, _, err := hijacker.Hijack()
+		if err != nil {
+			return nil, err
+		}
+		// clear the deadlines set by the http server
+		_ = netConn.SetDeadline(time.Time{})
+		_ = netConn.SetWriteDeadline(time.Now().Add(WriteTimeout * time.Second))
+		s := &exportStream{netConn: netConn, buf: bufio.NewWriter(netConn)}
+		s.w, s.flush = s.buf, func() {}
+		header := fmt.Sprintf("HTTP/1.1 200 OK\r\nContent-Type: %s\r\nContent-Disposition: %s\r\n"+
+			"Cache-Control: no-cache\r\nConnection: close\r\nX-Accel-Buffering: no\r\n\r\n", contentType, disposition)
+		if _, err := s.buf.WriteString(header); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+		return s, nil
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, fmt.Errorf("streaming is not supported")
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", disposition)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	return &exportStream{w: w, flush: flusher.Flush}, nil
+}
+
+func (s *exportStream) Write(bz []byte) (int, error) {
+	return s.w.Write(bz)
+}
+
+// Encodes a page and writes it to the client before the write deadline
+func (s *exportStream) writePage(encode func() error) error {
+	if s.netConn != nil {
+		_ = s.netConn.SetWriteDeadline(time.Now().Add(WriteTimeout * time.Second))
+	}
+	if err := encode(); err != nil {
+		return err
+	}
+	if s.buf != nil {
+		if err := s.buf.Flush(); err != nil {
+			return err
+		}
+	}
+	s.flush()
+	return nil
+}
+
+func (s *exportStream) close() {
+	if s.netConn != nil {
+		_ = s.netConn.SetWriteDeadline(time.Now().Add(WriteTimeout * time.Second))
+		_ = s.buf.Flush()
+		s.netConn.Close()
+	}
+}
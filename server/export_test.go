@@ -0,0 +1,72 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/coinexchain/trade-server/core"
+	"github.com/stretchr/testify/require"
+	dbm "github.com/tendermint/tm-db"
+)
+
+func TestExportDeals(t *testing.T) {
+	db := dbm.NewMemDB()
+	hub := core.NewHub(db, &core.MocSubscribeManager{}, 99999, 0, 0, 0, "", 0)
+	handler, _ := registerHandler(hub, core.NewWebSocketManager(), false, "", "", nil)
+	deals := []string{
+		`{"order_id":"a-1","trading_pair":"abc/cet","height":10,"side":2,"price":"1.5"}`,
+		`{"order_id":"a-2","trading_pair":"abc/cet","height":11,"side":1,"price":"1.6"}`,
+	}
+	for i, deal := range deals {
+		sid := int64(i + 1)
+		key := []byte{core.DealByte, byte(len("abc/cet"))}
+		key = append(key, []byte("abc/cet")...)
+		key = append(key, 0)
+		key = append(key, core.Int64ToBigEndianBytes(99+sid)...)
+		key = append(key, core.Int64ToBigEndianBytes(sid)...)
+		key = append(key, 0)
+		db.Set(key, []byte(deal))
+	}
+	// the connection is hijacked by a real server
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	get := func(params string) (*http.Response, string) {
+		resp, err := http.Get(server.URL + "/export/deals?market=abc/cet&" + params)
+		require.Nil(t, err)
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		require.Nil(t, err)
+		return resp, string(body)
+	}
+	resp, body := get("direction=asc")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+	require.Equal(t, `attachment; filename="deals.ndjson"`, resp.Header.Get("Content-Disposition"))
+	require.Equal(t, `{"time":100,"order_id":"a-1","trading_pair":"abc/cet","height":10,"side":2,"price":"1.5"}`+"\n"+
+		`{"time":101,"order_id":"a-2","trading_pair":"abc/cet","height":11,"side":1,"price":"1.6"}`+"\n", body)
+
+	resp, body = get("format=csv&from=101")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/csv", resp.Header.Get("Content-Type"))
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	require.Equal(t, 2, len(lines))
+	require.Equal(t, "time,"+strings.Join(exportColumns[core.ExportDeals], ","), lines[0])
+	require.True(t, strings.HasPrefix(lines[1], "101,a-2,"), lines[1])
+	require.True(t, strings.Contains(lines[1], ",1.6,"), lines[1])
+	// the header is written without any records
+	_, body = get("format=csv&from=102")
+	require.Equal(t, "time,"+strings.Join(exportColumns[core.ExportDeals], ",")+"\n", body)
+
+	for _, params := range []string{"format=xml", "from=102&to=101", "direction=up"} {
+		resp, _ = get(params)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode, params)
+	}
+	resp, err := http.Get(server.URL + "/export/deals")
+	require.Nil(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
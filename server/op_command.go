@@ -3,7 +3,10 @@ package server
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 
+	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/gorilla/websocket"
 
 	"github.com/coinexchain/trade-server/core"
@@ -14,12 +17,30 @@ const (
 	Subscribe   = "subscribe"
 	Unsubscribe = "unsubscribe"
 	Ping        = "ping"
+	Challenge   = "challenge"
+	Auth        = "auth"
+	Query       = "query"
+	Options     = "options"
+	Alert       = "alert"
+)
+
+// The actions of the alert command
+const (
+	AlertAdd    = "add"
+	AlertRemove = "remove"
 )
 
 type OpCommand struct {
 	Op    string   `json:"op"`
 	Args  []string `json:"args"`
 	Depth int      `json:"depth"`
+	// resume the subscriptions from the last received message, instead of pushing the full information
+	SinceSeq    int64 `json:"since_seq"`
+	SinceHeight int64 `json:"since_height"`
+	// the id, the name and the parameters of a query, the id is returned with the query result
+	ID     json.RawMessage `json:"id"`
+	Query  string          `json:"query"`
+	Params QueryParams     `json:"params"`
 }
 
 func NewCommand(msg []byte) *OpCommand {
@@ -40,6 +61,16 @@ func (op *OpCommand) HandleCommand(hub *core.Hub, wsManager *core.WebsocketManag
 		err = op.handleUnSubscribe(wsManager, wsConn)
 	case Ping:
 		err = op.handlePing(wsConn)
+	case Challenge:
+		err = op.handleChallenge(wsConn)
+	case Auth:
+		err = op.handleAuth(wsConn)
+	case Query:
+		err = op.handleQuery(hub, wsManager, wsConn)
+	case Options:
+		err = op.handleOptions(wsManager, wsConn)
+	case Alert:
+		err = op.handleAlert(hub, wsConn)
 	default:
 		op.defaultHandle()
 	}
@@ -47,17 +78,30 @@ func (op *OpCommand) HandleCommand(hub *core.Hub, wsManager *core.WebsocketManag
 }
 
 func (op *OpCommand) handleSubscribe(hub *core.Hub, wsManager *core.WebsocketManager, wsConn *core.Conn) (err error) {
+	subs := make([]core.Subscription, 0, len(op.Args))
 	for _, subTopic := range op.Args {
 		topic, params, err := core.GetTopicAndParams(subTopic)
 		if err != nil {
 			log.WithError(err).Error(fmt.Sprintf("Parse subscribe topic (%s) failed ", subTopic))
 			return err
 		}
-		if err = wsManager.PushFullInfo(hub, wsConn, topic, params, op.Depth); err != nil {
+		subs = append(subs, core.Subscription{Topic: topic, Params: params})
+	}
+	// all the topics are resumed together, such that their messages are replayed in the order of seqs
+	if op.SinceSeq > 0 || op.SinceHeight > 0 {
+		subs, err = wsManager.ResumeSubscribeConn(hub, wsConn, subs, op.SinceSeq, op.SinceHeight)
+		if err != nil {
+			log.WithError(err).Error(fmt.Sprintf("Resume subscription failed; topics (%s)", strings.Join(op.Args, ",")))
+			return err
+		}
+	}
+	for _, sub := range subs {
+		subTopic := getSubTopic(sub)
+		if err = wsManager.PushFullInfo(hub, wsConn, sub.Topic, sub.Params, op.Depth); err != nil {
 			log.WithError(err).Error(fmt.Sprintf("Push full info failed; topic (%s)", subTopic))
 			return err
 		}
-		if err = wsManager.AddSubscribeConn(wsConn, topic, params); err != nil {
+		if err = wsManager.AddSubscribeConn(wsConn, sub.Topic, sub.Params); err != nil {
 			log.WithError(err).Error(fmt.Sprintf("Add subscribe conn to wsManager failed; topic (%s)  ", subTopic))
 			return err
 		}
@@ -65,6 +109,10 @@ func (op *OpCommand) handleSubscribe(hub *core.Hub, wsManager *core.WebsocketMan
 	return nil
 }
 
+func getSubTopic(sub core.Subscription) string {
+	return strings.Join(append([]string{sub.Topic}, sub.Params...), core.SeparateArgu)
+}
+
 func (op *OpCommand) handleUnSubscribe(wsManager *core.WebsocketManager, wsConn *core.Conn) error {
 	for _, subTopic := range op.Args {
 		topic, params, err := core.GetTopicAndParams(subTopic)
@@ -88,6 +136,121 @@ func (op *OpCommand) handlePing(wsConn *core.Conn) (err error) {
 	return nil
 }
 
+func (op *OpCommand) handleChallenge(wsConn *core.Conn) error {
+	challenge := wsConn.NewChallenge()
+	return wsConn.WriteMsg([]byte(fmt.Sprintf("{\"type\":\"%s\", \"payload\":{\"challenge\":\"%s\"}}", Challenge, challenge)))
+}
+
+// args: the base64 encoded public key and the base64 encoded signature of the challenge
+func (op *OpCommand) handleAuth(wsConn *core.Conn) error {
+	if len(op.Args) != 2 {
+		return fmt.Errorf("auth needs a public key and a signature")
+	}
+	addr, err := wsConn.Authenticate(op.Args[0], op.Args[1])
+	if err != nil {
+		log.WithError(err).Error("auth failed")
+		return err
+	}
+	return wsConn.WriteMsg([]byte(fmt.Sprintf("{\"type\":\"%s\", \"payload\":{\"address\":\"%s\"}}", Auth, addr)))
+}
+
+func (op *OpCommand) handleQuery(hub *core.Hub, wsManager *core.WebsocketManager, wsConn *core.Conn) error {
+	result, err := runWsQuery(hub, wsManager, wsConn, op.Query, op.Params)
+	return op.writeQueryResult(wsConn, result, err)
+}
+
+// The errors of the query are returned with its id, instead of by handlerExecOpErr
+func (op *OpCommand) writeQueryResult(wsConn *core.Conn, result interface{}, err error) error {
+	var resp interface{}
+	if err != nil {
+		log.WithError(err).Error(fmt.Sprintf("query (%s) failed", op.Query))
+		resp = wsQueryError{ID: op.ID, Error: err.Error()}
+	} else {
+		resp = wsQueryResult{ID: op.ID, Result: result}
+	}
+	bz, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	return wsConn.WriteMsg(bz)
+}
+
+// args: the options in the form of 'key=value', which are 'encoding', and 'label' used by the relays.
+// The response and the following messages are sent in the new encoding
+func (op *OpCommand) handleOptions(wsManager *core.WebsocketManager, wsConn *core.Conn) error {
+	for _, arg := range op.Args {
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("unknown option %s", arg)
+		}
+		switch kv[0] {
+		case queryKeyEncoding:
+			if err := wsConn.SetEncoding(kv[1]); err != nil {
+				return err
+			}
+		case queryKeyLabel:
+			labeled, err := strconv.ParseBool(kv[1])
+			if err != nil {
+				return fmt.Errorf("invalid option %s", arg)
+			}
+			wsManager.SetLabeled(wsConn, labeled)
+		default:
+			return fmt.Errorf("unknown option %s", arg)
+		}
+	}
+	if wsConn.IsLabeled() {
+		return wsConn.WriteMsg([]byte(fmt.Sprintf("{\"type\":\"%s\", \"payload\":{\"encoding\":\"%s\", \"label\":true}}",
+			Options, wsConn.GetEncoding())))
+	}
+	return wsConn.WriteMsg([]byte(fmt.Sprintf("{\"type\":\"%s\", \"payload\":{\"encoding\":\"%s\"}}", Options, wsConn.GetEncoding())))
+}
+
+// args: 'add', the address, the market, the condition, the value, and the window in minutes for 'pct_change';
+// or 'remove', the address and the alert id. The address must be authenticated by the connection
+func (op *OpCommand) handleAlert(hub *core.Hub, wsConn *core.Conn) error {
+	if len(op.Args) < 2 {
+		return fmt.Errorf("alert needs an action and an address")
+	}
+	action, addr := op.Args[0], op.Args[1]
+	if !wsConn.IsAuthenticated(addr) {
+		return core.ErrAuthRequired
+	}
+	switch action {
+	case AlertAdd:
+		if len(op.Args) != 5 && len(op.Args) != 6 {
+			return fmt.Errorf("alert add needs a market, a condition, a value and an optional window")
+		}
+		value, decErr := sdk.NewDecFromStr(op.Args[4])
+		if decErr != nil {
+			return fmt.Errorf("invalid alert value %s", op.Args[4])
+		}
+		alert := core.PriceAlert{Address: addr, Market: op.Args[2], Condition: op.Args[3], Value: value}
+		var err error
+		if len(op.Args) == 6 {
+			if alert.Window, err = strconv.Atoi(op.Args[5]); err != nil {
+				return fmt.Errorf("invalid alert window %s", op.Args[5])
+			}
+		}
+		if alert, err = hub.AddPriceAlert(alert); err != nil {
+			return err
+		}
+		bz, err := json.Marshal(alert)
+		if err != nil {
+			return err
+		}
+		return wsConn.WriteMsg([]byte(fmt.Sprintf("{\"type\":\"%s_added\", \"payload\":%s}", Alert, string(bz))))
+	case AlertRemove:
+		if len(op.Args) != 3 {
+			return fmt.Errorf("alert remove needs an alert id")
+		}
+		if err := hub.RemovePriceAlert(addr, op.Args[2]); err != nil {
+			return err
+		}
+		return wsConn.WriteMsg([]byte(fmt.Sprintf("{\"type\":\"%s_removed\", \"payload\":{\"id\":\"%s\"}}", Alert, op.Args[2])))
+	}
+	return fmt.Errorf("unknown alert action %s", action)
+}
+
 func (op *OpCommand) defaultHandle() {
 	log.Errorf("Unknown operation : %v", op.Op)
 }
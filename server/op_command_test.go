@@ -0,0 +1,127 @@
+package server
+
+import (
+	"encoding/base64"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tendermint/crypto/secp256k1"
+	"github.com/tendermint/tendermint/libs/bech32"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/coinexchain/trade-server/core"
+)
+
+type mockWif struct {
+	mtx     sync.Mutex
+	records []string
+}
+
+func (m *mockWif) Close() error {
+	return nil
+}
+
+func (m *mockWif) WriteMessage(msgType int, data []byte) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.records = append(m.records, string(data))
+	return nil
+}
+
+func (m *mockWif) WriteControl(msgType int, data []byte, deadline time.Time) error {
+	return nil
+}
+
+func (m *mockWif) ReadMessage() (messageType int, p []byte, err error) {
+	panic("implement me")
+}
+
+func (m *mockWif) SetPingHandler(func(string) error) {}
+
+func (m *mockWif) PingHandler() func(string) error {
+	panic("implement me")
+}
+
+func (m *mockWif) getRecords() []string {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return append([]string{}, m.records...)
+}
+
+func TestQueryCommand(t *testing.T) {
+	db := dbm.NewMemDB()
+	hub := core.NewHub(db, &core.MocSubscribeManager{}, 99999, 0, 0, 0, "", 0)
+	wsManager := core.NewWebSocketManager()
+	storeHeightInfo(db, 5, 1992)
+	wif := &mockWif{}
+	conn := wsManager.AddWsConn(wif)
+
+	for _, msg := range []string{
+		`{"op":"query", "id":1, "query":"misc/height"}`,
+		`{"op":"query", "id":"a", "query":"misc/block-times", "params":{"height":5, "count":"2"}}`,
+		`{"op":"query", "id":2, "query":"market/deals", "params":{"market":"abc/cet", "time":100, "sid":0}}`,
+		`{"op":"query", "id":3, "query":"market/unknown"}`,
+		`{"op":"query", "query":"market/deals", "params":{"market":"abc/cet", "time":100, "sid":0, "count":10}}`,
+		`{"op":"query", "id":4, "query":"market/order", "params":{"order_id":"abc"}}`,
+	} {
+		require.True(t, NewCommand([]byte(msg)).HandleCommand(hub, wsManager, conn))
+	}
+	wsManager.SetAuthenticator(core.NewAuthenticator(true))
+	require.True(t, NewCommand([]byte(`{"op":"query", "id":5, "query":"tx/incomes", "params":{"account":"coinex1abc", "time":100, "sid":0, "count":10}}`)).
+		HandleCommand(hub, wsManager, conn))
+	time.Sleep(10 * time.Millisecond)
+
+	require.EqualValues(t, []string{
+		`{"id":1,"result":{"height":5,"timestamp":1992}}`,
+		`{"id":"a","result":[1992]}`,
+		`{"id":2,"error":"count can not be nil"}`,
+		`{"id":3,"error":"unknown query market/unknown"}`,
+		`{"id":null,"result":{"data":[],"cursor":""}}`,
+		`{"id":4,"error":"order abc not found"}`,
+		`{"id":5,"error":"authentication required"}`,
+	}, wif.getRecords())
+}
+
+func TestAlertCommand(t *testing.T) {
+	hub := core.NewHub(dbm.NewMemDB(), &core.MocSubscribeManager{}, 99999, 0, 0, 0, "", 0)
+	hub.AddMarket("abc/cet")
+	wsManager := core.NewWebSocketManager()
+	wif := &mockWif{}
+	conn := wsManager.AddWsConn(wif)
+
+	key := secp256k1.GenPrivKey()
+	addr, err := bech32.ConvertAndEncode(core.Bech32PrefixAccAddr, key.PubKey().Address())
+	require.Nil(t, err)
+	add := `{"op":"alert", "args":["add", "` + addr + `", "abc/cet", "pct_change", "5", "60"]}`
+	// the address must be authenticated even if authentication is not required
+	require.True(t, NewCommand([]byte(add)).HandleCommand(hub, wsManager, conn))
+	sig, err := key.Sign([]byte(conn.NewChallenge()))
+	require.Nil(t, err)
+	pubKey := key.PubKey().(secp256k1.PubKeySecp256k1)
+	_, err = conn.Authenticate(base64.StdEncoding.EncodeToString(pubKey[:]), base64.StdEncoding.EncodeToString(sig))
+	require.Nil(t, err)
+
+	require.True(t, NewCommand([]byte(add)).HandleCommand(hub, wsManager, conn))
+	require.True(t, NewCommand([]byte(`{"op":"alert", "args":["add", "`+addr+`", "abc/cet", "price_above", "x"]}`)).
+		HandleCommand(hub, wsManager, conn))
+	require.True(t, NewCommand([]byte(`{"op":"alert", "args":["add", "`+addr+`", "xyz/cet", "price_above", "1"]}`)).
+		HandleCommand(hub, wsManager, conn))
+	alerts := hub.QueryPriceAlerts(addr)
+	require.EqualValues(t, 1, len(alerts))
+	require.EqualValues(t, 60, alerts[0].Window)
+	require.True(t, NewCommand([]byte(`{"op":"alert", "args":["remove", "`+addr+`", "`+alerts[0].ID+`"]}`)).
+		HandleCommand(hub, wsManager, conn))
+	require.EqualValues(t, 0, len(hub.QueryPriceAlerts(addr)))
+	time.Sleep(10 * time.Millisecond)
+
+	require.EqualValues(t, []string{
+		`{"error": "authentication required"}`,
+		`{"type":"alert_added", "payload":{"id":"` + alerts[0].ID + `","address":"` + addr + `","market":"abc/cet",` +
+			`"condition":"pct_change","value":"5.000000000000000000","window":60}}`,
+		`{"error": "invalid alert value x"}`,
+		`{"error": "market xyz/cet not found"}`,
+		`{"type":"alert_removed", "payload":{"id":"` + alerts[0].ID + `"}}`,
+	}, wif.getRecords())
+}
@@ -1,7 +1,9 @@
 package server
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
@@ -22,9 +24,18 @@ const (
 	queryKeyCount      = "count"
 	queryKeyMarket     = "market"
 	queryKeyAccount    = "account"
+	queryKeyReferee    = "referee"
+	queryKeyOrderID    = "order_id"
 	queryKeyToken      = "token"
 	queryKeyMarketList = "market_list"
 	queryKeyOrderTag   = "tag"
+	queryKeyCursor     = "cursor"
+	queryKeyFrom       = "from"
+	queryKeyTo         = "to"
+	queryKeyDirection  = "direction"
+
+	directionAsc  = "asc"
+	directionDesc = "desc"
 )
 
 func QueryLatestHeight(hub *core.Hub) http.HandlerFunc {
@@ -33,6 +44,62 @@ func QueryLatestHeight(hub *core.Hub) http.HandlerFunc {
 	}
 }
 
+func QueryWsConnectionsRequestHandlerFn(wsManager *core.WebsocketManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		postQueryResponse(w, wsManager.GetConnStats())
+	}
+}
+
+// The challenges are limited by the IP address of the client
+func QueryAuthChallengeRequestHandlerFn(auth *core.Authenticator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		client, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			client = r.RemoteAddr
+		}
+		challenge, err := auth.NewChallenge(client)
+		if err != nil {
+			rest.WriteErrorResponse(w, http.StatusTooManyRequests, err.Error())
+			return
+		}
+		postQueryResponse(w, map[string]string{"challenge": challenge})
+	}
+}
+
+// The account of an address-scoped endpoint must be authenticated by the auth headers if authentication is required
+func authAccountHandler(auth *core.Authenticator, handler http.HandlerFunc) http.HandlerFunc {
+	return authAddressHandler(auth, queryKeyAccount, handler)
+}
+
+// The same as authAccountHandler, but the address is passed as the parameter 'key'
+func authAddressHandler(auth *core.Authenticator, key string, handler http.HandlerFunc) http.HandlerFunc {
+	return authHandler(auth, func(r *http.Request) string { return r.FormValue(key) }, handler)
+}
+
+// The sender of the order in the path must be authenticated
+func authOrderHandler(auth *core.Authenticator, handler http.HandlerFunc) http.HandlerFunc {
+	return authHandler(auth, func(r *http.Request) string { return getOrderSender(mux.Vars(r)[queryKeyOrderID]) }, handler)
+}
+
+func authHandler(auth *core.Authenticator, getAddress func(r *http.Request) string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if auth.Required {
+			err := auth.VerifyAccount(getAddress(r), r.Header.Get(HeaderAuthChallenge),
+				r.Header.Get(HeaderAuthPubKey), r.Header.Get(HeaderAuthSignature))
+			if err != nil {
+				rest.WriteErrorResponse(w, http.StatusUnauthorized, err.Error())
+				return
+			}
+		}
+		handler(w, r)
+	}
+}
+
+// The order id is made of the sender's address and its sequence, such as "coinex1...-9"
+func getOrderSender(orderID string) string {
+	return strings.Split(orderID, "-")[0]
+}
+
 func QueryBlockTimesRequestHandlerFn(hub *core.Hub) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		err := r.ParseForm()
@@ -68,6 +135,17 @@ func QueryTickersRequestHandlerFn(hub *core.Hub) http.HandlerFunc {
 	}
 }
 
+func QueryXTickersRequestHandlerFn(hub *core.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := r.URL.Query()
+		marketStr := vars.Get(queryKeyMarketList)
+
+		xtickers := hub.QueryXTickers(strings.Split(marketStr, ","))
+
+		postQueryResponse(w, xtickers)
+	}
+}
+
 func QueryDepthsRequestHandlerFn(hub *core.Hub) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		err := r.ParseForm()
@@ -83,10 +161,46 @@ func QueryDepthsRequestHandlerFn(hub *core.Hub) http.HandlerFunc {
 			return
 		}
 
-		sell, buy := hub.QueryDepth(market, count)
-
+		if len(r.FormValue(queryKeyHeight)) == 0 {
+			sell, buy := hub.QueryDepth(market, count)
+			postQueryResponse(w, NewDepthResponse(sell, buy))
+			return
+		}
+		height, err := parseQueryHeightParams(r.FormValue(queryKeyHeight))
+		if err != nil {
+			rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		sell, buy, ok := hub.QueryDepthAtHeight(market, height, count)
+		if !ok {
+			rest.WriteErrorResponse(w, http.StatusNotFound, ErrDepthNotRetained(market, height).Error())
+			return
+		}
 		postQueryResponse(w, NewDepthResponse(sell, buy))
+	}
+}
 
+func QueryOrderBookL3RequestHandlerFn(hub *core.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseForm()
+		if err != nil {
+			rest.WriteErrorResponse(w, http.StatusBadRequest,
+				sdk.AppendMsgToErr("could not parse query parameters", err.Error()))
+			return
+		}
+		market := r.FormValue(queryKeyMarket)
+		count, err := parseQueryCountParams(r.FormValue(queryKeyCount))
+		if err != nil {
+			rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		data := hub.QueryOrderBookL3(market, count)
+		if data == nil {
+			rest.WriteErrorResponse(w, http.StatusNotFound, ErrMarketNotFound(market).Error())
+			return
+		}
+		postQueryResponse(w, data)
 	}
 }
 
@@ -100,16 +214,16 @@ func QueryLockedRequestHandlerFn(hub *core.Hub) http.HandlerFunc {
 		}
 
 		account := r.FormValue(queryKeyAccount)
-		time, sid, count, err := parseQueryKVStoreParams(r)
+		qr, err := parseQueryKVStoreParams(r)
 		if err != nil {
 			rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
 			return
 		}
 
 		token := r.FormValue(queryKeyToken)
-		data, timesid := hub.QueryLockedAboutToken(strings.ToLower(token), account, time, sid, count)
+		data, timesid := hub.QueryLockedAboutToken(strings.ToLower(token), account, qr)
 
-		postQueryKVStoreResponse(w, data, timesid)
+		postQueryKVStoreResponse(w, data, timesid, qr.Count)
 	}
 }
 
@@ -129,13 +243,13 @@ func QueryCandleSticksRequestHandlerFn(hub *core.Hub) http.HandlerFunc {
 			return
 		}
 
-		time, sid, count, err := parseQueryKVStoreParams(r)
+		qr, err := parseQueryKVStoreParams(r)
 		if err != nil {
 			rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
 			return
 		}
 
-		data := hub.QueryCandleStick(market, timespan, time, sid, count)
+		data := hub.QueryCandleStick(market, timespan, qr)
 
 		postQueryResponse(w, data)
 	}
@@ -151,7 +265,7 @@ func QueryOrdersRequestHandlerFn(hub *core.Hub) http.HandlerFunc {
 		}
 
 		account := r.FormValue(queryKeyAccount)
-		time, sid, count, err := parseQueryKVStoreParams(r)
+		qr, err := parseQueryKVStoreParams(r)
 		if err != nil {
 			rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
 			return
@@ -163,50 +277,158 @@ func QueryOrdersRequestHandlerFn(hub *core.Hub) http.HandlerFunc {
 			rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
 			return
 		}
-		data, tags, timesid := hub.QueryOrderAboutToken(tag, strings.ToLower(token), account, time, sid, count)
+		data, tags, timesid := hub.QueryOrderAboutToken(tag, strings.ToLower(token), account, qr)
 
-		createOrders := make([]json.RawMessage, 0)
-		fillOrders := make([]json.RawMessage, 0)
-		cancelOrders := make([]json.RawMessage, 0)
-		createTimeSid := make([]int64, 0)
-		fillTimeSid := make([]int64, 0)
-		cancelTimeSid := make([]int64, 0)
-		for i, tag := range tags {
-			if tag == core.CreateOrderEndByte {
-				createOrders = append(createOrders, data[i])
-				createTimeSid = append(createTimeSid, timesid[i*2])
-				createTimeSid = append(createTimeSid, timesid[i*2+1])
-			}
-			if tag == core.FillOrderEndByte {
-				fillOrders = append(fillOrders, data[i])
-				fillTimeSid = append(fillTimeSid, timesid[i*2])
-				fillTimeSid = append(fillTimeSid, timesid[i*2+1])
-			}
-			if tag == core.CancelOrderEndByte {
-				cancelOrders = append(cancelOrders, data[i])
-				cancelTimeSid = append(cancelTimeSid, timesid[i*2])
-				cancelTimeSid = append(cancelTimeSid, timesid[i*2+1])
-			}
+		postQueryResponse(w, newOrdersResponse(tag, data, tags, timesid, qr.Count))
+	}
+}
+
+// Splits the orders by their tags, only the orders of the tag are returned if it is not empty.
+// The orders of all the tags are paged together, so they have the same cursor
+func newOrdersResponse(tag string, data []json.RawMessage, tags []byte, timesid []int64, count int) interface{} {
+	createOrders := make([]json.RawMessage, 0)
+	fillOrders := make([]json.RawMessage, 0)
+	cancelOrders := make([]json.RawMessage, 0)
+	for i, tag := range tags {
+		if tag == core.CreateOrderEndByte {
+			createOrders = append(createOrders, data[i])
+		}
+		if tag == core.FillOrderEndByte {
+			fillOrders = append(fillOrders, data[i])
 		}
+		if tag == core.CancelOrderEndByte {
+			cancelOrders = append(cancelOrders, data[i])
+		}
+	}
 
-		var orders interface{}
-		switch tag {
-		case core.CreateOrderStr:
-			orders = core.OrderResponse{Data: createOrders, Timesid: createTimeSid}
-		case core.FillOrderStr:
-			orders = core.OrderResponse{Data: fillOrders, Timesid: fillTimeSid}
-		case core.CancelOrderStr:
-			orders = core.OrderResponse{Data: cancelOrders, Timesid: cancelTimeSid}
-		case "":
-			orders = core.OrderInfo{
-				CreateOrderInfo: core.OrderResponse{Data: createOrders, Timesid: createTimeSid},
-				FillOrderInfo:   core.OrderResponse{Data: fillOrders, Timesid: fillTimeSid},
-				CancelOrderInfo: core.OrderResponse{Data: cancelOrders, Timesid: cancelTimeSid},
-			}
+	cursor := getNextCursor(timesid, count)
+	var orders interface{}
+	switch tag {
+	case core.CreateOrderStr:
+		orders = core.OrderResponse{Data: createOrders, Cursor: cursor}
+	case core.FillOrderStr:
+		orders = core.OrderResponse{Data: fillOrders, Cursor: cursor}
+	case core.CancelOrderStr:
+		orders = core.OrderResponse{Data: cancelOrders, Cursor: cursor}
+	case "":
+		orders = core.OrderInfo{
+			CreateOrderInfo: core.OrderResponse{Data: createOrders, Cursor: cursor},
+			FillOrderInfo:   core.OrderResponse{Data: fillOrders, Cursor: cursor},
+			CancelOrderInfo: core.OrderResponse{Data: cancelOrders, Cursor: cursor},
+		}
+	}
+	return orders
+}
+
+func QueryOrderByIDRequestHandlerFn(hub *core.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		orderID := vars[queryKeyOrderID]
+		data := hub.QueryOrderLifecycle(orderID)
+		if data == nil {
+			rest.WriteErrorResponse(w, http.StatusNotFound, ErrOrderNotFound(orderID).Error())
+			return
+		}
+		postQueryResponse(w, data)
+	}
+}
+
+func QueryOpenOrdersRequestHandlerFn(hub *core.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseForm()
+		if err != nil {
+			rest.WriteErrorResponse(w, http.StatusBadRequest,
+				sdk.AppendMsgToErr("could not parse query parameters", err.Error()))
+			return
+		}
+
+		account := r.FormValue(queryKeyAccount)
+		if len(account) == 0 {
+			rest.WriteErrorResponse(w, http.StatusBadRequest, ErrNilParams(queryKeyAccount).Error())
+			return
+		}
+		market := r.FormValue(queryKeyMarket)
+		data := hub.QueryOpenOrders(account, market)
+
+		postQueryResponse(w, data)
+	}
+}
+
+func QueryAccountStatsRequestHandlerFn(hub *core.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseForm()
+		if err != nil {
+			rest.WriteErrorResponse(w, http.StatusBadRequest,
+				sdk.AppendMsgToErr("could not parse query parameters", err.Error()))
+			return
+		}
+
+		data, err := queryAccountStats(hub, r.FormValue)
+		if err != nil {
+			rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
 		}
 
-		postQueryResponse(w, orders)
+		postQueryResponse(w, data)
+	}
+}
+
+// The stats of all the markets are returned if the market is not given
+func queryAccountStats(hub *core.Hub, getParam func(key string) string) ([]core.AccountStats, error) {
+	account := getParam(queryKeyAccount)
+	if len(account) == 0 {
+		return nil, ErrNilParams(queryKeyAccount)
+	}
+	from, to, err := parseQueryTimeRangeParams(getParam)
+	if err != nil {
+		return nil, err
 	}
+	return hub.QueryAccountStats(account, getParam(queryKeyMarket), from, to), nil
+}
+
+// Both from and to are optional
+func parseQueryTimeRangeParams(getParam func(key string) string) (from, to int64, err error) {
+	if from, err = parseQueryTimeBoundParams(getParam(queryKeyFrom), queryKeyFrom); err != nil {
+		return
+	}
+	if to, err = parseQueryTimeBoundParams(getParam(queryKeyTo), queryKeyTo); err != nil {
+		return
+	}
+	if from != 0 && to != 0 && from > to {
+		err = ErrInvalidTimeRange()
+	}
+	return
+}
+
+func QueryRebatesRequestHandlerFn(hub *core.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseForm()
+		if err != nil {
+			rest.WriteErrorResponse(w, http.StatusBadRequest,
+				sdk.AppendMsgToErr("could not parse query parameters", err.Error()))
+			return
+		}
+
+		data, err := queryRebates(hub, r.FormValue)
+		if err != nil {
+			rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		postQueryResponse(w, data)
+	}
+}
+
+func queryRebates(hub *core.Hub, getParam func(key string) string) ([]core.RebateStats, error) {
+	referee := getParam(queryKeyReferee)
+	if len(referee) == 0 {
+		return nil, ErrNilParams(queryKeyReferee)
+	}
+	from, to, err := parseQueryTimeRangeParams(getParam)
+	if err != nil {
+		return nil, err
+	}
+	return hub.QueryRebates(referee, from, to), nil
 }
 
 func QueryDealsRequestHandlerFn(hub *core.Hub) http.HandlerFunc {
@@ -219,15 +441,15 @@ func QueryDealsRequestHandlerFn(hub *core.Hub) http.HandlerFunc {
 		}
 
 		market := r.FormValue(queryKeyMarket)
-		time, sid, count, err := parseQueryKVStoreParams(r)
+		qr, err := parseQueryKVStoreParams(r)
 		if err != nil {
 			rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
 			return
 		}
 
-		data, timesid := hub.QueryDeal(market, time, sid, count)
+		data, timesid := hub.QueryDeal(market, qr)
 
-		postQueryKVStoreResponse(w, data, timesid)
+		postQueryKVStoreResponse(w, data, timesid, qr.Count)
 	}
 }
 
@@ -241,15 +463,15 @@ func QueryBancorDealsRequestHandlerFn(hub *core.Hub) http.HandlerFunc {
 		}
 
 		market := r.FormValue(queryKeyMarket)
-		time, sid, count, err := parseQueryKVStoreParams(r)
+		qr, err := parseQueryKVStoreParams(r)
 		if err != nil {
 			rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
 			return
 		}
 
-		data, timesid := hub.QueryBancorDeal(market, time, sid, count)
+		data, timesid := hub.QueryBancorDeal(market, qr)
 
-		postQueryKVStoreResponse(w, data, timesid)
+		postQueryKVStoreResponse(w, data, timesid, qr.Count)
 	}
 }
 
@@ -263,15 +485,15 @@ func QueryBancorInfosRequestHandlerFn(hub *core.Hub) http.HandlerFunc {
 		}
 
 		market := r.FormValue(queryKeyMarket)
-		time, sid, count, err := parseQueryKVStoreParams(r)
+		qr, err := parseQueryKVStoreParams(r)
 		if err != nil {
 			rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
 			return
 		}
 
-		data, timesid := hub.QueryBancorInfo(market, time, sid, count)
+		data, timesid := hub.QueryBancorInfo(market, qr)
 
-		postQueryKVStoreResponse(w, data, timesid)
+		postQueryKVStoreResponse(w, data, timesid, qr.Count)
 	}
 }
 
@@ -285,16 +507,16 @@ func QueryBancorTradesRequestHandlerFn(hub *core.Hub) http.HandlerFunc {
 		}
 
 		account := r.FormValue(queryKeyAccount)
-		time, sid, count, err := parseQueryKVStoreParams(r)
+		qr, err := parseQueryKVStoreParams(r)
 		if err != nil {
 			rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
 			return
 		}
 
 		token := r.FormValue(queryKeyToken)
-		data, timesid := hub.QueryBancorTradeAboutToken(strings.ToLower(token), account, time, sid, count)
+		data, timesid := hub.QueryBancorTradeAboutToken(strings.ToLower(token), account, qr)
 
-		postQueryKVStoreResponse(w, data, timesid)
+		postQueryKVStoreResponse(w, data, timesid, qr.Count)
 	}
 }
 
@@ -308,15 +530,15 @@ func QueryRedelegationsRequestHandlerFn(hub *core.Hub) http.HandlerFunc {
 		}
 
 		account := r.FormValue(queryKeyAccount)
-		time, sid, count, err := parseQueryKVStoreParams(r)
+		qr, err := parseQueryKVStoreParams(r)
 		if err != nil {
 			rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
 			return
 		}
 
-		data, timesid := hub.QueryRedelegation(account, time, sid, count)
+		data, timesid := hub.QueryRedelegation(account, qr)
 
-		postQueryKVStoreResponse(w, data, timesid)
+		postQueryKVStoreResponse(w, data, timesid, qr.Count)
 	}
 }
 
@@ -330,15 +552,15 @@ func QueryUnbondingsRequestHandlerFn(hub *core.Hub) http.HandlerFunc {
 		}
 
 		account := r.FormValue(queryKeyAccount)
-		time, sid, count, err := parseQueryKVStoreParams(r)
+		qr, err := parseQueryKVStoreParams(r)
 		if err != nil {
 			rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
 			return
 		}
 
-		data, timesid := hub.QueryUnbonding(account, time, sid, count)
+		data, timesid := hub.QueryUnbonding(account, qr)
 
-		postQueryKVStoreResponse(w, data, timesid)
+		postQueryKVStoreResponse(w, data, timesid, qr.Count)
 	}
 }
 
@@ -352,16 +574,16 @@ func QueryUnlocksRequestHandlerFn(hub *core.Hub) http.HandlerFunc {
 		}
 
 		account := r.FormValue(queryKeyAccount)
-		time, sid, count, err := parseQueryKVStoreParams(r)
+		qr, err := parseQueryKVStoreParams(r)
 		if err != nil {
 			rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
 			return
 		}
 
 		token := r.FormValue(queryKeyToken)
-		data, timesid := hub.QueryUnlockAboutToken(strings.ToLower(token), account, time, sid, count)
+		data, timesid := hub.QueryUnlockAboutToken(strings.ToLower(token), account, qr)
 
-		postQueryKVStoreResponse(w, data, timesid)
+		postQueryKVStoreResponse(w, data, timesid, qr.Count)
 	}
 }
 
@@ -375,16 +597,16 @@ func QueryIncomesRequestHandlerFn(hub *core.Hub) http.HandlerFunc {
 		}
 
 		account := r.FormValue(queryKeyAccount)
-		time, sid, count, err := parseQueryKVStoreParams(r)
+		qr, err := parseQueryKVStoreParams(r)
 		if err != nil {
 			rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
 			return
 		}
 
 		token := r.FormValue(queryKeyToken)
-		data, timesid := hub.QueryIncomeAboutToken(strings.ToLower(token), account, time, sid, count)
+		data, timesid := hub.QueryIncomeAboutToken(strings.ToLower(token), account, qr)
 
-		postQueryKVStoreResponse(w, data, timesid)
+		postQueryKVStoreResponse(w, data, timesid, qr.Count)
 	}
 }
 
@@ -398,16 +620,16 @@ func QueryTxsRequestHandlerFn(hub *core.Hub) http.HandlerFunc {
 		}
 
 		account := r.FormValue(queryKeyAccount)
-		time, sid, count, err := parseQueryKVStoreParams(r)
+		qr, err := parseQueryKVStoreParams(r)
 		if err != nil {
 			rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
 			return
 		}
 
 		token := r.FormValue(queryKeyToken)
-		data, timesid := hub.QueryTxAboutToken(strings.ToLower(token), account, time, sid, count)
+		data, timesid := hub.QueryTxAboutToken(strings.ToLower(token), account, qr)
 
-		postQueryKVStoreResponse(w, data, timesid)
+		postQueryKVStoreResponse(w, data, timesid, qr.Count)
 	}
 }
 func QueryTxsByHashRequestHandlerFn(hub *core.Hub) http.HandlerFunc {
@@ -429,15 +651,15 @@ func QueryCommentsRequestHandlerFn(hub *core.Hub) http.HandlerFunc {
 		}
 
 		token := r.FormValue(queryKeyToken)
-		time, sid, count, err := parseQueryKVStoreParams(r)
+		qr, err := parseQueryKVStoreParams(r)
 		if err != nil {
 			rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
 			return
 		}
 
-		data, timesid := hub.QueryComment(token, time, sid, count)
+		data, timesid := hub.QueryComment(token, qr)
 
-		postQueryKVStoreResponse(w, data, timesid)
+		postQueryKVStoreResponse(w, data, timesid, qr.Count)
 	}
 }
 
@@ -450,15 +672,15 @@ func QuerySlashingsRequestHandlerFn(hub *core.Hub) http.HandlerFunc {
 			return
 		}
 
-		time, sid, count, err := parseQueryKVStoreParams(r)
+		qr, err := parseQueryKVStoreParams(r)
 		if err != nil {
 			rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
 			return
 		}
 
-		data, timesid := hub.QuerySlash(time, sid, count)
+		data, timesid := hub.QuerySlash(qr)
 
-		postQueryKVStoreResponse(w, data, timesid)
+		postQueryKVStoreResponse(w, data, timesid, qr.Count)
 	}
 }
 
@@ -471,15 +693,37 @@ func QueryDonationsRequestHandlerFn(hub *core.Hub) http.HandlerFunc {
 			return
 		}
 
-		time, sid, count, err := parseQueryKVStoreParams(r)
+		qr, err := parseQueryKVStoreParams(r)
+		if err != nil {
+			rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		data, timesid := hub.QueryDonation(qr)
+
+		postQueryKVStoreResponse(w, data, timesid, qr.Count)
+	}
+}
+
+func QueryMarketsRequestHandlerFn(hub *core.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseForm()
+		if err != nil {
+			rest.WriteErrorResponse(w, http.StatusBadRequest,
+				sdk.AppendMsgToErr("could not parse query parameters", err.Error()))
+			return
+		}
+
+		token := r.FormValue(queryKeyToken)
+		qr, err := parseQueryKVStoreParams(r)
 		if err != nil {
 			rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
 			return
 		}
 
-		data, timesid := hub.QueryDonation(time, sid, count)
+		data, timesid := hub.QueryMarkets(strings.ToLower(token), qr)
 
-		postQueryKVStoreResponse(w, data, timesid)
+		postQueryKVStoreResponse(w, data, timesid, qr.Count)
 	}
 }
 
@@ -493,22 +737,24 @@ func QueryDelistRequestHandlerFn(hub *core.Hub) http.HandlerFunc {
 		}
 
 		market := r.FormValue(queryKeyMarket)
-		time, sid, count, err := parseQueryKVStoreParams(r)
+		qr, err := parseQueryKVStoreParams(r)
 		if err != nil {
 			rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
 			return
 		}
 
-		data, timesid := hub.QueryDelist(market, time, sid, count)
-		var cancelTime int64
-		if len(data) > 0 {
-			cancelTime = core.BigEndianBytesToInt64(data[0])
-			postQueryKVStoreResponse(w, []int64{cancelTime}, timesid)
-		} else {
-			postQueryKVStoreResponse(w, []struct{}{}, timesid)
-		}
+		data, timesid := hub.QueryDelist(market, qr)
+		postQueryKVStoreResponse(w, newDelistResponse(data), timesid, qr.Count)
+	}
+}
+
+func newDelistResponse(data []json.RawMessage) interface{} {
+	if len(data) > 0 {
+		return []int64{core.BigEndianBytesToInt64(data[0])}
 	}
+	return []struct{}{}
 }
+
 func QueryDelistsRequestHandlerFn(hub *core.Hub) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		err := r.ParseForm()
@@ -518,23 +764,82 @@ func QueryDelistsRequestHandlerFn(hub *core.Hub) http.HandlerFunc {
 			return
 		}
 
-		time, sid, count, err := parseQueryKVStoreParams(r)
+		qr, err := parseDelistsParams(r.FormValue)
 		if err != nil {
 			rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
 			return
 		}
 
-		data, timesid := hub.QueryDelists(time, sid, count)
-		type CancelTradingPair struct {
-			TradingPair string `json:"trading_pair"`
-			CancelTime  int64  `json:"cancel_time"`
+		data, _ := hub.QueryDelists(qr)
+		postQueryKVStoreResponse(w, newDelistsResponse(data), nil, qr.Count)
+	}
+}
+
+// The delists of all the markets are ordered by market, so they are only bounded by from and to, and
+// they can not be paged by a cursor. The time and the sid are accepted for compatibility, but ignored
+func parseDelistsParams(getParam func(key string) string) (core.QueryRange, error) {
+	if getParam(queryKeyCursor) != "" {
+		return core.QueryRange{}, ErrUnsupportedCursor()
+	}
+	return parseKVStoreParams(getParam)
+}
+
+type CancelTradingPair struct {
+	TradingPair string `json:"trading_pair"`
+	CancelTime  int64  `json:"cancel_time"`
+}
+
+// data contains the pairs of market and cancel time
+func newDelistsResponse(data []json.RawMessage) []CancelTradingPair {
+	vals := make([]CancelTradingPair, len(data)/2)
+	for i := 0; i < len(data); i += 2 {
+		vals[i/2].TradingPair = string(data[i])
+		vals[i/2].CancelTime = core.BigEndianBytesToInt64(data[i+1])
+	}
+	return vals
+}
+
+func QueryDelegatorRewardsRequestHandlerFn(hub *core.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseForm()
+		if err != nil {
+			rest.WriteErrorResponse(w, http.StatusBadRequest,
+				sdk.AppendMsgToErr("could not parse query parameters", err.Error()))
+			return
 		}
-		vals := make([]CancelTradingPair, len(data)/2)
-		for i := 0; i < len(data); i += 2 {
-			vals[i/2].TradingPair = string(data[i])
-			vals[i/2].CancelTime = core.BigEndianBytesToInt64(data[i+1])
+
+		account := r.FormValue(queryKeyAccount)
+		qr, err := parseQueryKVStoreParams(r)
+		if err != nil {
+			rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		data, timesid := hub.QueryDelegatorRewards(account, qr)
+
+		postQueryKVStoreResponse(w, data, timesid, qr.Count)
+	}
+}
+
+func QueryValidatorCommissionsRequestHandlerFn(hub *core.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseForm()
+		if err != nil {
+			rest.WriteErrorResponse(w, http.StatusBadRequest,
+				sdk.AppendMsgToErr("could not parse query parameters", err.Error()))
+			return
 		}
-		postQueryKVStoreResponse(w, vals, timesid)
+
+		account := r.FormValue(queryKeyAccount)
+		qr, err := parseQueryKVStoreParams(r)
+		if err != nil {
+			rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		data, timesid := hub.QueryValidatorCommission(account, qr)
+
+		postQueryKVStoreResponse(w, data, timesid, qr.Count)
 	}
 }
 
@@ -563,8 +868,8 @@ func writeData(w http.ResponseWriter, data []byte) {
 	}
 }
 
-func postQueryKVStoreResponse(w http.ResponseWriter, data interface{}, timesid []int64) {
-	wrappedData := NewDataWrapped(data, timesid)
+func postQueryKVStoreResponse(w http.ResponseWriter, data interface{}, timesid []int64, count int) {
+	wrappedData := NewDataWrapped(data, timesid, count)
 	output, err := json.Marshal(wrappedData)
 	if err != nil {
 		rest.WriteErrorResponse(w, http.StatusInternalServerError, err.Error())
@@ -616,55 +921,132 @@ func parseQueryTagParams(str string) error {
 	return nil
 }
 
-func parseQueryKVStoreParams(r *http.Request) (time int64, sid int64, count int, err error) {
-	timeStr := r.FormValue(queryKeyTime)
-	if timeStr == "" {
-		return time, sid, count, ErrNilParams(queryKeyTime)
+func parseQueryKVStoreParams(r *http.Request) (core.QueryRange, error) {
+	return parseKVStoreParams(r.FormValue)
+}
+
+// getParam returns the value of a query parameter, such as the FormValue of a http request.
+func parseKVStoreParams(getParam func(key string) string) (qr core.QueryRange, err error) {
+	if qr, err = parseQueryRangeParams(getParam); err != nil {
+		return
+	}
+
+	countStr := getParam(queryKeyCount)
+	if countStr == "" {
+		return qr, ErrNilParams(queryKeyCount)
 	}
 
-	time, err = strconv.ParseInt(timeStr, 10, 64)
+	qr.Count, err = strconv.Atoi(countStr)
 	if err != nil {
-		return time, sid, count, err
-	} else if time <= 0 {
-		return time, sid, count, ErrInvalidParams(queryKeyTime)
+		return qr, err
+	} else if qr.Count <= 0 {
+		return qr, ErrInvalidParams(queryKeyCount)
 	}
 
-	sidStr := r.FormValue(queryKeySid)
-	if sidStr == "" {
-		return time, sid, count, ErrNilParams(queryKeySid)
+	return
+}
+
+// The next page starts from the cursor returned by the previous page, or from the time and sid of a record.
+// Without both of them, the first page starts from the newest record, or from the oldest one if ascending
+func parseQueryRangeParams(getParam func(key string) string) (qr core.QueryRange, err error) {
+	if cursor := getParam(queryKeyCursor); cursor != "" {
+		if qr.Time, qr.Sid, err = decodeCursor(cursor); err != nil {
+			return qr, err
+		}
+	} else if timeStr := getParam(queryKeyTime); timeStr != "" {
+		qr.Time, err = strconv.ParseInt(timeStr, 10, 64)
+		if err != nil {
+			return qr, err
+		} else if qr.Time <= 0 {
+			return qr, ErrInvalidParams(queryKeyTime)
+		}
+
+		sidStr := getParam(queryKeySid)
+		if sidStr == "" {
+			return qr, ErrNilParams(queryKeySid)
+		}
+
+		qr.Sid, err = strconv.ParseInt(sidStr, 10, 64)
+		if err != nil {
+			return qr, err
+		} else if qr.Sid < 0 {
+			return qr, ErrNegativeParams(queryKeySid)
+		}
 	}
 
-	sid, err = strconv.ParseInt(sidStr, 10, 64)
-	if err != nil {
-		return time, sid, count, err
-	} else if sid < 0 {
-		return time, sid, count, ErrNegativeParams(queryKeySid)
+	if qr.From, err = parseQueryTimeBoundParams(getParam(queryKeyFrom), queryKeyFrom); err != nil {
+		return qr, err
+	}
+	if qr.To, err = parseQueryTimeBoundParams(getParam(queryKeyTo), queryKeyTo); err != nil {
+		return qr, err
+	}
+	if qr.To > 0 && qr.From > qr.To {
+		return qr, ErrInvalidTimeRange()
 	}
 
-	countStr := r.FormValue(queryKeyCount)
-	if countStr == "" {
-		return time, sid, count, ErrNilParams(queryKeyCount)
+	switch getParam(queryKeyDirection) {
+	case "", directionDesc:
+	case directionAsc:
+		qr.Asc = true
+	default:
+		return qr, ErrInvalidDirection()
 	}
+	return
+}
 
-	count, err = strconv.Atoi(countStr)
-	if err != nil {
-		return time, sid, count, err
-	} else if count <= 0 {
-		return time, sid, count, ErrInvalidParams(queryKeyCount)
+// The bounds are optional unix timestamps, 0 means no bound
+func parseQueryTimeBoundParams(str string, key string) (t int64, err error) {
+	if str == "" {
+		return 0, nil
+	}
+	if t, err = strconv.ParseInt(str, 10, 64); err != nil {
+		return
 	}
+	if t < 0 {
+		return t, ErrNegativeParams(key)
+	}
+	return
+}
 
+// The cursor is the opaque encoding of the time and sid of the last record in a page
+func encodeCursor(time, sid int64) string {
+	bz := append(core.Int64ToBigEndianBytes(time), core.Int64ToBigEndianBytes(sid)...)
+	return base64.RawURLEncoding.EncodeToString(bz)
+}
+
+func decodeCursor(cursor string) (time, sid int64, err error) {
+	bz, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(bz) != 16 {
+		return 0, 0, ErrInvalidCursor()
+	}
+	time, sid = core.BigEndianBytesToInt64(bz[:8]), core.BigEndianBytesToInt64(bz[8:])
+	if time <= 0 || sid < 0 {
+		return 0, 0, ErrInvalidCursor()
+	}
 	return
 }
 
+// Returns the cursor of the next page, which is empty if the page is not full and there are no more records
+func getNextCursor(timesid []int64, count int) string {
+	if count > core.MaxCount {
+		count = core.MaxCount
+	}
+	if len(timesid) < 2 || len(timesid)/2 < count {
+		return ""
+	}
+	return encodeCursor(timesid[len(timesid)-2], timesid[len(timesid)-1])
+}
+
 type DataWrapped struct {
-	Data    interface{} `json:"data"`
-	TimeSid []int64     `json:"timesid"`
+	Data   interface{} `json:"data"`
+	Cursor string      `json:"cursor"`
 }
 
-func NewDataWrapped(data interface{}, timesid []int64) DataWrapped {
+// timesid contains the pairs of the time and sid of the records, and count is the requested count of the page
+func NewDataWrapped(data interface{}, timesid []int64, count int) DataWrapped {
 	return DataWrapped{
-		Data:    data,
-		TimeSid: timesid,
+		Data:   data,
+		Cursor: getNextCursor(timesid, count),
 	}
 }
 
@@ -0,0 +1,388 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/coinexchain/trade-server/core"
+)
+
+// The interval between the attempts to reconnect to the primary
+var RelayRetryInterval = time.Second
+
+const relayHandshakeTimeout = 10 * time.Second
+
+// The response of the primary to the 'ping' command
+const pongType = "pong"
+
+var (
+	errRelayClosed = errors.New("relay is closed")
+	errRelayAlert  = errors.New("alerts are not supported by the relay")
+	errRelayExport = errors.New("exports are not supported by the relay")
+)
+
+// Relay serves the websocket and Server-Sent Events clients of a relay node, which has no DB or consumer.
+// The topics subscribed by the local clients are subscribed from the primary through one shared websocket
+// connection, whose pushed messages are labeled with the subscriptions they match and pushed to all the local
+// subscribers of these subscriptions. The REST queries, including the 'query' command, are forwarded to the primary
+type Relay struct {
+	wsURL         string // the websocket endpoint of the primary
+	wsManager     *core.WebsocketManager
+	forwarder     *restForwarder
+	dialer        *websocket.Dialer
+	retryInterval time.Duration
+
+	mtx  sync.Mutex
+	conn *websocket.Conn // nil when it is not connected
+	// whether the goroutine connecting to the primary is running, which exits when no topic is subscribed
+	running bool
+	subs    map[string]map[*core.Conn]int // topic --> subscriber --> depth
+	// the full information of a topic is requested for its new subscribers with a 'subscribe' command followed by
+	// a 'ping' command, so the messages without seqs are sent to the earliest request whose pong is not received
+	pending []fullInfoRequest
+	// the payloads of the last seq relayed to each subscriber, since a message is received once for each
+	// subscription it matches, and a subscriber may have several of them, such as 'deal:abc/cet' and 'deal:*'
+	lastSeq   int64
+	delivered map[*core.Conn][][]byte
+	closed    bool
+}
+
+type fullInfoRequest struct {
+	topic string
+	conns []*core.Conn
+}
+
+// upstream is the base URL of the primary, such as 'http://localhost:8000',
+// and its REST responses are cached for cacheTTL if it is positive
+func NewRelay(upstream string, wsManager *core.WebsocketManager, cacheTTL time.Duration) *Relay {
+	upstream = strings.TrimSuffix(upstream, "/")
+	return &Relay{
+		wsURL:         "ws" + strings.TrimPrefix(upstream, "http") + "/ws",
+		wsManager:     wsManager,
+		forwarder:     newRestForwarder(upstream, cacheTTL),
+		dialer:        &websocket.Dialer{HandshakeTimeout: relayHandshakeTimeout},
+		retryInterval: RelayRetryInterval,
+		subs:          make(map[string]map[*core.Conn]int),
+	}
+}
+
+// The messages from the primary, only the pushed messages have seqs and labels
+type relayedMsg struct {
+	Type    string          `json:"type"`
+	Label   string          `json:"label"`
+	Seq     *int64          `json:"seq"`
+	Height  int64           `json:"height"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+func (r *Relay) dial() (*websocket.Conn, error) {
+	conn, _, err := r.dialer.Dial(r.wsURL, nil)
+	return conn, err
+}
+
+func (r *Relay) handleCommand(command *OpCommand, wsConn *core.Conn) bool {
+	var err error
+	switch command.Op {
+	case Subscribe:
+		err = r.subscribe(command, wsConn)
+	case Unsubscribe:
+		err = r.unsubscribe(command, wsConn)
+	case Query:
+		result, qErr := r.query(command, wsConn)
+		if qErr != nil {
+			log.WithError(qErr).Error(fmt.Sprintf("query (%s) failed", command.Query))
+		}
+		err = command.writeQueryResult(wsConn, result, qErr)
+	case Alert:
+		// the alerts are kept by the primary, whose connections are not authenticated by the relay
+		err = errRelayAlert
+	default:
+		// the other commands do not need the hub
+		return command.HandleCommand(nil, r.wsManager, wsConn)
+	}
+	return command.handlerExecOpErr(err, r.wsManager, wsConn)
+}
+
+// The subscriptions are not resumed from since_seq or since_height, since the relay does not keep the pushed messages.
+// The full information is pushed instead, as a new subscription
+func (r *Relay) subscribe(command *OpCommand, wsConn *core.Conn) error {
+	for _, subTopic := range command.Args {
+		topic, params, err := core.GetTopicAndParams(subTopic)
+		if err != nil {
+			log.WithError(err).Error(fmt.Sprintf("Parse subscribe topic (%s) failed ", subTopic))
+			return err
+		}
+		if err = r.wsManager.AddSubscribeConn(wsConn, topic, params); err != nil {
+			log.WithError(err).Error(fmt.Sprintf("Add subscribe conn to wsManager failed; topic (%s)  ", subTopic))
+			return err
+		}
+		if err = r.addSubscriber(subTopic, wsConn, command.Depth); err != nil {
+			log.WithError(err).Error(fmt.Sprintf("Subscribe from the primary failed; topic (%s)", subTopic))
+			_ = r.wsManager.RemoveSubscribeConn(wsConn, topic, params)
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Relay) unsubscribe(command *OpCommand, wsConn *core.Conn) error {
+	for _, subTopic := range command.Args {
+		topic, params, err := core.GetTopicAndParams(subTopic)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+		if err = r.wsManager.RemoveSubscribeConn(wsConn, topic, params); err != nil {
+			log.WithError(err).Error(fmt.Sprintf("Unsubscribe topic (%s) failed ", subTopic))
+			return err
+		}
+		r.removeSubscriber(subTopic, wsConn)
+	}
+	return nil
+}
+
+func (r *Relay) query(command *OpCommand, wsConn *core.Conn) (interface{}, error) {
+	if err := checkWsQuery(r.wsManager, wsConn, command.Query, command.Params); err != nil {
+		return nil, err
+	}
+	return r.forwarder.query(command.Query, command.Params)
+}
+
+func (r *Relay) connClosed(wsConn *core.Conn) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	for topic := range r.subs {
+		r.removeSubscriberLocked(topic, wsConn)
+	}
+}
+
+// The topic is subscribed from the primary when it gets its first subscriber,
+// and the connection to the primary is made in another goroutine if there is not one
+func (r *Relay) addSubscriber(topic string, wsConn *core.Conn, depth int) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.closed {
+		return errRelayClosed
+	}
+	subscribers, ok := r.subs[topic]
+	if !ok {
+		subscribers = make(map[*core.Conn]int)
+		r.subs[topic] = subscribers
+	}
+	subscribers[wsConn] = depth
+	// the full information is requested after connecting if there is no connection
+	if r.conn != nil {
+		r.requestFullInfo(topic, []*core.Conn{wsConn}, depth)
+	} else if !r.running {
+		r.running = true
+		go r.run()
+	}
+	return nil
+}
+
+// The topic is unsubscribed from the primary when it has no subscriber,
+// and the connection to the primary is closed when no topic is subscribed
+func (r *Relay) removeSubscriber(topic string, wsConn *core.Conn) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.removeSubscriberLocked(topic, wsConn)
+}
+
+func (r *Relay) removeSubscriberLocked(topic string, wsConn *core.Conn) {
+	subscribers, ok := r.subs[topic]
+	if !ok {
+		return
+	}
+	delete(subscribers, wsConn)
+	for i, req := range r.pending {
+		remaining := make([]*core.Conn, 0, len(req.conns))
+		for _, c := range req.conns {
+			if c != wsConn {
+				remaining = append(remaining, c)
+			}
+		}
+		r.pending[i].conns = remaining
+	}
+	if len(subscribers) != 0 {
+		return
+	}
+	delete(r.subs, topic)
+	if r.conn == nil {
+		return
+	}
+	if len(r.subs) == 0 {
+		r.conn.Close()
+		return
+	}
+	r.sendCommand(map[string]interface{}{"op": Unsubscribe, "args": []string{topic}})
+}
+
+// Closes the connection to the primary
+func (r *Relay) Close() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.closed = true
+	r.subs = make(map[string]map[*core.Conn]int)
+	if r.conn != nil {
+		r.conn.Close()
+	}
+}
+
+// Sends a command to the primary. If it can not be sent, the connection is closed and
+// the full information of all the topics is requested again after reconnecting
+func (r *Relay) sendCommand(command interface{}) bool {
+	bz, err := json.Marshal(command)
+	if err == nil {
+		_ = r.conn.SetWriteDeadline(time.Now().Add(WriteTimeout * time.Second))
+		err = r.conn.WriteMessage(websocket.TextMessage, bz)
+	}
+	if err != nil {
+		log.WithError(err).Error("send command to the primary failed")
+		r.conn.Close()
+		return false
+	}
+	return true
+}
+
+// Requests the full information for the subscribers, which ends with the pong of the following ping
+func (r *Relay) requestFullInfo(topic string, conns []*core.Conn, depth int) {
+	if r.sendCommand(map[string]interface{}{"op": Subscribe, "args": []string{topic}, "depth": depth}) &&
+		r.sendCommand(map[string]interface{}{"op": Ping}) {
+		r.pending = append(r.pending, fullInfoRequest{topic: topic, conns: conns})
+	}
+}
+
+// Connects to the primary and dispatches its messages, until the relay is closed or no topic is subscribed.
+// The connection is dialed without holding the lock, and it is made again after it is lost
+func (r *Relay) run() {
+	for {
+		conn, err := r.dial()
+		if err != nil {
+			log.WithError(err).Error("connect to the primary failed")
+		} else if r.setConn(conn) {
+			for {
+				_, bz, err := conn.ReadMessage()
+				if err != nil {
+					break
+				}
+				r.dispatch(bz)
+			}
+		}
+		if !r.resetConn() {
+			return
+		}
+		time.Sleep(r.retryInterval)
+	}
+}
+
+// Labels the messages of the new connection, and requests the full information for all the subscribers.
+// Returns false if the connection is not needed any more
+func (r *Relay) setConn(conn *websocket.Conn) bool {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.closed || len(r.subs) == 0 {
+		conn.Close()
+		return false
+	}
+	r.conn = conn
+	if !r.sendCommand(map[string]interface{}{"op": Options, "args": []string{queryKeyLabel + "=true"}}) {
+		return true
+	}
+	for topic, subscribers := range r.subs {
+		depths := make(map[int][]*core.Conn)
+		for c, depth := range subscribers {
+			depths[depth] = append(depths[depth], c)
+		}
+		for depth, conns := range depths {
+			r.requestFullInfo(topic, conns, depth)
+		}
+	}
+	return true
+}
+
+// Drops the lost connection. Returns false and stops connecting if the connection is not needed any more
+func (r *Relay) resetConn() bool {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.conn != nil {
+		r.conn.Close()
+		r.conn = nil
+		log.Error("the connection to the primary is closed")
+	}
+	r.pending = nil
+	if r.closed || len(r.subs) == 0 {
+		r.running = false
+		return false
+	}
+	return true
+}
+
+func (r *Relay) dispatch(bz []byte) {
+	var msg relayedMsg
+	if err := json.Unmarshal(bz, &msg); err != nil {
+		log.WithError(err).Error("unmarshal message from the primary failed")
+		return
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	switch {
+	case msg.Seq != nil:
+		r.wsManager.RelayMsg(r.getRelayTargets(msg), msg.Type, *msg.Seq, msg.Height, msg.Payload)
+	case msg.Type == pongType:
+		if len(r.pending) != 0 {
+			r.pending = r.pending[1:]
+		}
+	case msg.Type == Options:
+	case msg.Type == core.DroppedKey:
+		// all the subscribers miss the messages dropped by the primary
+		sent := make(map[*core.Conn]struct{})
+		for _, subscribers := range r.subs {
+			for c := range subscribers {
+				if _, ok := sent[c]; !ok {
+					sent[c] = struct{}{}
+					_ = c.WriteMsg(bz)
+				}
+			}
+		}
+	default:
+		if len(r.pending) != 0 {
+			for _, c := range r.pending[0].conns {
+				_ = c.WriteMsg(bz)
+			}
+		}
+	}
+}
+
+// Returns the subscribers of the message's label which have not received it
+func (r *Relay) getRelayTargets(msg relayedMsg) []*core.Conn {
+	if *msg.Seq != r.lastSeq {
+		r.lastSeq = *msg.Seq
+		r.delivered = make(map[*core.Conn][][]byte)
+	}
+	subscribers := r.subs[msg.Label]
+	conns := make([]*core.Conn, 0, len(subscribers))
+	for c := range subscribers {
+		if !containsPayload(r.delivered[c], msg.Payload) {
+			r.delivered[c] = append(r.delivered[c], msg.Payload)
+			conns = append(conns, c)
+		}
+	}
+	return conns
+}
+
+func containsPayload(payloads [][]byte, payload []byte) bool {
+	for _, p := range payloads {
+		if bytes.Equal(p, payload) {
+			return true
+		}
+	}
+	return false
+}
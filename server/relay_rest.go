@@ -0,0 +1,162 @@
+package server
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/types/rest"
+	log "github.com/sirupsen/logrus"
+)
+
+// The least recently used responses are removed when there are more cached responses than this number
+const maxCachedResponses = 10000
+
+type cachedResponse struct {
+	contentType string
+	body        []byte
+	expiry      time.Time
+}
+
+// restForwarder forwards the REST queries of a relay to its primary, and caches the successful responses for a while.
+// The accounts are authenticated by the relay before their queries are forwarded, so the cached responses
+// are only returned to the authenticated requests
+type restForwarder struct {
+	upstream string
+	client   *http.Client
+	cacheTTL time.Duration
+
+	mtx        sync.Mutex
+	maxEntries int
+	cache      map[string]*list.Element // request uri --> the element of cachedEntry in lru
+	lru        *list.List               // the most recently used at front
+}
+
+type cachedEntry struct {
+	uri  string
+	resp cachedResponse
+}
+
+func newRestForwarder(upstream string, cacheTTL time.Duration) *restForwarder {
+	return &restForwarder{
+		upstream:   strings.TrimSuffix(upstream, "/"),
+		client:     &http.Client{Timeout: ReadTimeout * time.Second},
+		cacheTTL:   cacheTTL,
+		maxEntries: maxCachedResponses,
+		cache:      make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+func (f *restForwarder) getCached(uri string) (cachedResponse, bool) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	elem, ok := f.cache[uri]
+	if !ok {
+		return cachedResponse{}, false
+	}
+	entry := elem.Value.(*cachedEntry)
+	if time.Now().After(entry.resp.expiry) {
+		f.lru.Remove(elem)
+		delete(f.cache, uri)
+		return cachedResponse{}, false
+	}
+	f.lru.MoveToFront(elem)
+	return entry.resp, true
+}
+
+func (f *restForwarder) setCached(uri string, resp cachedResponse) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	resp.expiry = time.Now().Add(f.cacheTTL)
+	if elem, ok := f.cache[uri]; ok {
+		elem.Value.(*cachedEntry).resp = resp
+		f.lru.MoveToFront(elem)
+		return
+	}
+	f.cache[uri] = f.lru.PushFront(&cachedEntry{uri: uri, resp: resp})
+	for f.lru.Len() > f.maxEntries {
+		oldest := f.lru.Back()
+		f.lru.Remove(oldest)
+		delete(f.cache, oldest.Value.(*cachedEntry).uri)
+	}
+}
+
+// Gets the response of the uri from the primary or the cache
+func (f *restForwarder) get(uri string) (int, cachedResponse, error) {
+	if f.cacheTTL > 0 {
+		if resp, ok := f.getCached(uri); ok {
+			return http.StatusOK, resp, nil
+		}
+	}
+	r, err := f.client.Get(f.upstream + uri)
+	if err != nil {
+		return 0, cachedResponse{}, err
+	}
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return 0, cachedResponse{}, err
+	}
+	resp := cachedResponse{contentType: r.Header.Get("Content-Type"), body: body}
+	if f.cacheTTL > 0 && r.StatusCode == http.StatusOK {
+		f.setCached(uri, resp)
+	}
+	return r.StatusCode, resp, nil
+}
+
+func (f *restForwarder) handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, resp, err := f.get(r.URL.RequestURI())
+		if err != nil {
+			log.WithError(err).Error("forward request to the primary failed")
+			rest.WriteErrorResponse(w, http.StatusBadGateway, err.Error())
+			return
+		}
+		if len(resp.contentType) != 0 {
+			w.Header().Set("Content-Type", resp.contentType)
+		}
+		w.WriteHeader(status)
+		if _, err = w.Write(resp.body); err != nil {
+			log.WithError(err).Error("write response failed")
+		}
+	}
+}
+
+// Runs a websocket query with the REST endpoint of the primary, whose path is the name of the query
+func (f *restForwarder) query(name string, p QueryParams) (interface{}, error) {
+	path := "/" + name
+	values := url.Values{}
+	for key, val := range p {
+		values.Set(key, val)
+	}
+	switch name {
+	case "market/order":
+		path = "/market/orders/" + url.PathEscape(p.get(queryKeyOrderID))
+		values.Del(queryKeyOrderID)
+	case "tx/tx":
+		path = "/tx/txs/" + url.PathEscape(p.get("hash"))
+		values.Del("hash")
+	}
+	if len(values) != 0 {
+		path += "?" + values.Encode()
+	}
+	status, resp, err := f.get(path)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		var errResp rest.ErrorResponse
+		if err = json.Unmarshal(resp.body, &errResp); err == nil && len(errResp.Error) != 0 {
+			return nil, fmt.Errorf("%s", errResp.Error)
+		}
+		return nil, fmt.Errorf("%s", http.StatusText(status))
+	}
+	return json.RawMessage(resp.body), nil
+}
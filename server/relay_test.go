@@ -0,0 +1,166 @@
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/coinexchain/trade-server/core"
+)
+
+// Waits at most one second until cond is true
+func waitFor(t *testing.T, cond func() bool) {
+	for i := 0; i < 100 && !cond(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.True(t, cond())
+}
+
+func readWsMsg(t *testing.T, c *websocket.Conn) string {
+	_, bz, err := c.ReadMessage()
+	require.Nil(t, err)
+	return string(bz)
+}
+
+func getBody(t *testing.T, url string) string {
+	resp, err := http.Get(url)
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	bz, err := ioutil.ReadAll(resp.Body)
+	require.Nil(t, err)
+	return string(bz)
+}
+
+func TestRelay(t *testing.T) {
+	db := dbm.NewMemDB()
+	storeHeightInfo(db, 3, 1990)
+	hub := core.NewHub(db, &core.MocSubscribeManager{}, 99999, 0, 0, 0, "", 0)
+	wsManager := core.NewWebSocketManager()
+	handler, _ := registerHandler(hub, wsManager, false, "", "", nil)
+	primary := httptest.NewServer(handler)
+	defer primary.Close()
+
+	relayManager := core.NewWebSocketManager()
+	relay := NewRelay(primary.URL, relayManager, time.Minute)
+	defer relay.Close()
+	server := httptest.NewServer(registerRelayHandler(relay, relayManager))
+	defer server.Close()
+
+	// both the clients get the full information, but the topic is subscribed only once from the primary
+	clients := []*websocket.Conn{dialWs(t, server.URL+"/ws", false), dialWs(t, server.URL+"/ws", false)}
+	for _, c := range clients {
+		require.Nil(t, c.WriteMessage(websocket.TextMessage, []byte(`{"op":"subscribe", "args":["deal:abc/cet"]}`)))
+		require.Equal(t, `{"type":"deal", "payload":[]}`, readWsMsg(t, c))
+	}
+	require.EqualValues(t, 1, len(wsManager.GetDealSubscribeInfo()["abc/cet"]))
+	require.EqualValues(t, 2, len(relayManager.GetDealSubscribeInfo()["abc/cet"]))
+	// all the topics are subscribed through one connection
+	require.Nil(t, clients[1].WriteMessage(websocket.TextMessage, []byte(`{"op":"subscribe", "args":["deal:*"]}`)))
+	waitFor(t, func() bool {
+		return len(wsManager.GetDealSubscribeInfo()["*"]) == 1
+	})
+	require.EqualValues(t, 1, len(wsManager.GetConnStats()))
+
+	// the message matching both the subscriptions of a client is relayed to it once
+	for seq := int64(5); seq <= 6; seq++ {
+		wsManager.SetPushMeta(seq, 100)
+		for _, market := range []string{"abc/cet", "*"} {
+			for _, sub := range wsManager.GetDealSubscribeInfo()[market] {
+				wsManager.PushDeal(sub, []byte(`{"id":1}`))
+			}
+		}
+		wsManager.Flush()
+		for _, c := range clients {
+			require.Equal(t, fmt.Sprintf(`{"type":"deal", "seq":%d, "height":100, "payload":{"id":1}}`, seq), readWsMsg(t, c))
+		}
+	}
+
+	// the REST responses are cached, and shared by the query results
+	require.Equal(t, `{"height":3,"timestamp":1990}`, getBody(t, server.URL+"/misc/height"))
+	storeHeightInfo(db, 4, 1991)
+	require.Equal(t, `{"height":4,"timestamp":1991}`, getBody(t, primary.URL+"/misc/height"))
+	require.Equal(t, `{"height":3,"timestamp":1990}`, getBody(t, server.URL+"/misc/height"))
+	require.Nil(t, clients[0].WriteMessage(websocket.TextMessage, []byte(`{"op":"query", "id":1, "query":"misc/height"}`)))
+	require.Equal(t, `{"id":1,"result":{"height":3,"timestamp":1990}}`, readWsMsg(t, clients[0]))
+	require.Nil(t, clients[0].WriteMessage(websocket.TextMessage, []byte(`{"op":"query", "id":2, "query":"market/order", "params":{"order_id":"x"}}`)))
+	require.Equal(t, `{"id":2,"error":"order x not found"}`, readWsMsg(t, clients[0]))
+
+	// the subscription from the primary is closed after all the subscribers leave
+	clients[0].Close()
+	require.Nil(t, clients[1].WriteMessage(websocket.TextMessage, []byte(`{"op":"unsubscribe", "args":["deal:abc/cet"]}`)))
+	waitFor(t, func() bool {
+		return len(wsManager.GetDealSubscribeInfo()["abc/cet"]) == 0
+	})
+	require.EqualValues(t, 1, len(wsManager.GetConnStats()))
+	require.Nil(t, clients[1].WriteMessage(websocket.TextMessage, []byte(`{"op":"unsubscribe", "args":["deal:*"]}`)))
+	waitFor(t, func() bool {
+		return len(wsManager.GetConnStats()) == 0
+	})
+	clients[1].Close()
+}
+
+func TestRelayReconnect(t *testing.T) {
+	interval := RelayRetryInterval
+	RelayRetryInterval = 10 * time.Millisecond
+	defer func() { RelayRetryInterval = interval }()
+
+	db := dbm.NewMemDB()
+	hub := core.NewHub(db, &core.MocSubscribeManager{}, 99999, 0, 0, 0, "", 0)
+	wsManager := core.NewWebSocketManager()
+	handler, _ := registerHandler(hub, wsManager, false, "", "", nil)
+	primary := httptest.NewServer(handler)
+	defer primary.Close()
+
+	relayManager := core.NewWebSocketManager()
+	relay := NewRelay(primary.URL, relayManager, 0)
+	defer relay.Close()
+	server := httptest.NewServer(registerRelayHandler(relay, relayManager))
+	defer server.Close()
+
+	c := dialWs(t, server.URL+"/ws", false)
+	defer c.Close()
+	require.Nil(t, c.WriteMessage(websocket.TextMessage, []byte(`{"op":"subscribe", "args":["deal:abc/cet"]}`)))
+	require.Equal(t, `{"type":"deal", "payload":[]}`, readWsMsg(t, c))
+
+	// the full information is pushed again after the relay reconnects to the primary
+	for _, sub := range wsManager.GetDealSubscribeInfo()["abc/cet"] {
+		wsManager.CloseWsConn(sub.(core.ImplSubscriber).Conn)
+	}
+	require.Equal(t, `{"type":"deal", "payload":[]}`, readWsMsg(t, c))
+	waitFor(t, func() bool {
+		return len(wsManager.GetDealSubscribeInfo()["abc/cet"]) == 1
+	})
+}
+
+func TestRestForwarderCache(t *testing.T) {
+	f := newRestForwarder("http://localhost", time.Minute)
+	f.maxEntries = 2
+	f.setCached("/a", cachedResponse{body: []byte("a")})
+	f.setCached("/b", cachedResponse{body: []byte("b")})
+	_, ok := f.getCached("/a")
+	require.True(t, ok)
+	// the least recently used response is removed
+	f.setCached("/c", cachedResponse{body: []byte("c")})
+	_, ok = f.getCached("/b")
+	require.False(t, ok)
+	resp, ok := f.getCached("/a")
+	require.True(t, ok)
+	require.Equal(t, "a", string(resp.body))
+	_, ok = f.getCached("/c")
+	require.True(t, ok)
+	require.EqualValues(t, 2, f.lru.Len())
+
+	f.cacheTTL = -time.Second
+	f.setCached("/c", cachedResponse{body: []byte("c")})
+	_, ok = f.getCached("/c")
+	require.False(t, ok)
+	require.EqualValues(t, 1, len(f.cache))
+}
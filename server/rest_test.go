@@ -1,13 +1,18 @@
 package server
 
 import (
+	"encoding/base64"
 	"encoding/binary"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 
 	"github.com/coinexchain/trade-server/core"
 	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tendermint/crypto/secp256k1"
+	"github.com/tendermint/tendermint/libs/bech32"
 	dbm "github.com/tendermint/tm-db"
 )
 
@@ -82,3 +87,176 @@ func storeHeightInfo(db dbm.DB, height, timestamp uint64) {
 	binary.LittleEndian.PutUint64(val[:], timestamp)
 	db.Set(key, val)
 }
+
+func TestHandlerAuth(t *testing.T) {
+	db := dbm.NewMemDB()
+	subMan := &core.MocSubscribeManager{}
+	hub := core.NewHub(db, subMan, 99999, 0, 0, 0, "", 0)
+	wsManager := core.NewWebSocketManager()
+	wsManager.SetAuthenticator(core.NewAuthenticator(true))
+	handler, _ := registerHandler(hub, wsManager, false, "", "", nil)
+
+	key := secp256k1.GenPrivKey()
+	addr, err := bech32.ConvertAndEncode(core.Bech32PrefixAccAddr, key.PubKey().Address())
+	require.Nil(t, err)
+	url := "/tx/incomes?account=" + addr + "&time=9999999999&sid=0&count=10"
+
+	req, _ := http.NewRequest("GET", url, nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	req, _ = http.NewRequest("GET", "/misc/auth-challenge", nil)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var res struct {
+		Challenge string `json:"challenge"`
+	}
+	require.Nil(t, json.Unmarshal(rr.Body.Bytes(), &res))
+	sig, err := key.Sign([]byte(res.Challenge))
+	require.Nil(t, err)
+	pubKey := key.PubKey().(secp256k1.PubKeySecp256k1)
+
+	req, _ = http.NewRequest("GET", url, nil)
+	req.Header.Set(HeaderAuthChallenge, res.Challenge)
+	req.Header.Set(HeaderAuthPubKey, base64.StdEncoding.EncodeToString(pubKey[:]))
+	req.Header.Set(HeaderAuthSignature, base64.StdEncoding.EncodeToString(sig))
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	// the signature of another account is rejected
+	other, _ := bech32.ConvertAndEncode(core.Bech32PrefixAccAddr, secp256k1.GenPrivKey().PubKey().Address())
+	req.URL.RawQuery = "account=" + other + "&time=9999999999&sid=0&count=10"
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	// the sender of the order must be authenticated
+	orderURL := "/market/orders/" + addr + "-1"
+	req, _ = http.NewRequest("GET", orderURL, nil)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusUnauthorized, rr.Code)
+	req, _ = http.NewRequest("GET", "/misc/auth-challenge", nil)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Nil(t, json.Unmarshal(rr.Body.Bytes(), &res))
+	sig, err = key.Sign([]byte(res.Challenge))
+	require.Nil(t, err)
+	req, _ = http.NewRequest("GET", "/market/orders/"+other+"-1", nil)
+	req.Header.Set(HeaderAuthChallenge, res.Challenge)
+	req.Header.Set(HeaderAuthPubKey, base64.StdEncoding.EncodeToString(pubKey[:]))
+	req.Header.Set(HeaderAuthSignature, base64.StdEncoding.EncodeToString(sig))
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusUnauthorized, rr.Code)
+	req.URL.Path = orderURL
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusNotFound, rr.Code)
+
+	// the challenges issued to a client are limited
+	for i := 1; i <= core.MaxChallengesPerClient; i++ {
+		req = httptest.NewRequest("GET", "/misc/auth-challenge", nil)
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+	}
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusTooManyRequests, rr.Code)
+}
+
+func TestHandlerPagination(t *testing.T) {
+	db := dbm.NewMemDB()
+	hub := core.NewHub(db, &core.MocSubscribeManager{}, 99999, 0, 0, 0, "", 0)
+	handler, _ := registerHandler(hub, core.NewWebSocketManager(), false, "", "", nil)
+	// the deals at the times 100, 101 and 102
+	for sid := int64(1); sid <= 3; sid++ {
+		key := []byte{core.DealByte, byte(len("abc/cet"))}
+		key = append(key, []byte("abc/cet")...)
+		key = append(key, 0)
+		key = append(key, core.Int64ToBigEndianBytes(99+sid)...)
+		key = append(key, core.Int64ToBigEndianBytes(sid)...)
+		key = append(key, 0)
+		db.Set(key, []byte(strconv.FormatInt(sid, 10)))
+	}
+
+	query := func(params string) (int, DataWrapped) {
+		req, _ := http.NewRequest("GET", "/market/deals?market=abc/cet&"+params, nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		var res DataWrapped
+		if rr.Code == http.StatusOK {
+			require.Nil(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		}
+		return rr.Code, res
+	}
+	code, res := query("direction=asc&count=2")
+	require.Equal(t, http.StatusOK, code)
+	require.EqualValues(t, []interface{}{1.0, 2.0}, res.Data)
+	require.Equal(t, encodeCursor(101, 2), res.Cursor)
+	// the last page is not full
+	_, res = query("direction=asc&count=2&cursor=" + res.Cursor)
+	require.EqualValues(t, []interface{}{3.0}, res.Data)
+	require.Equal(t, "", res.Cursor)
+
+	_, res = query("from=101&to=101&count=2")
+	require.EqualValues(t, []interface{}{2.0}, res.Data)
+	_, res = query("time=102&sid=3&count=2")
+	require.EqualValues(t, []interface{}{2.0, 1.0}, res.Data)
+	require.Equal(t, encodeCursor(100, 1), res.Cursor)
+
+	for _, params := range []string{"count=2&direction=up", "count=2&cursor=abc", "count=2&from=102&to=101", "count=2&from=-1"} {
+		code, _ = query(params)
+		require.Equal(t, http.StatusBadRequest, code, params)
+	}
+
+	// the delists of all the markets can not be paged by a cursor
+	req, _ := http.NewRequest("GET", "/market/delists?count=2&cursor="+encodeCursor(101, 2), nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestHandlerAccountStats(t *testing.T) {
+	hub := core.NewHub(dbm.NewMemDB(), &core.MocSubscribeManager{}, 99999, 0, 0, 0, "", 0)
+	handler, _ := registerHandler(hub, core.NewWebSocketManager(), false, "", "", nil)
+	query := func(params string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/account/stats?"+params, nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+	rr := query("account=coinex1&market=abc/cet&from=100&to=200")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "[]", rr.Body.String())
+	for _, params := range []string{"market=abc/cet", "account=coinex1&from=-1", "account=coinex1&from=200&to=100"} {
+		require.Equal(t, http.StatusBadRequest, query(params).Code, params)
+	}
+}
+
+func TestHandlerRebates(t *testing.T) {
+	hub := core.NewHub(dbm.NewMemDB(), &core.MocSubscribeManager{}, 99999, 0, 0, 0, "", 0)
+	wsManager := core.NewWebSocketManager()
+	handler, _ := registerHandler(hub, wsManager, false, "", "", nil)
+	query := func(params string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/rebates?"+params, nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+	rr := query("referee=coinex1&from=100&to=200")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "[]", rr.Body.String())
+	for _, params := range []string{"account=coinex1", "referee=coinex1&to=-1", "referee=coinex1&from=200&to=100"} {
+		require.Equal(t, http.StatusBadRequest, query(params).Code, params)
+	}
+
+	// the referee is authenticated instead of the account
+	wsManager.SetAuthenticator(core.NewAuthenticator(true))
+	handler, _ = registerHandler(hub, wsManager, false, "", "", nil)
+	require.Equal(t, http.StatusUnauthorized, query("referee=coinex1").Code)
+}
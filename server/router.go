@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/coinexchain/trade-server/core"
+	"github.com/cosmos/cosmos-sdk/types/rest"
 	"github.com/gorilla/mux"
 )
 
@@ -27,35 +28,81 @@ func registerHandler(hub *core.Hub, wsManager *core.WebsocketManager, proxy bool
 	}
 
 	// REST
+	auth := wsManager.GetAuthenticator()
+	router.HandleFunc("/misc/auth-challenge", QueryAuthChallengeRequestHandlerFn(auth)).Methods("GET")
 	router.HandleFunc("/misc/height", QueryLatestHeight(hub)).Methods("GET")
 	router.HandleFunc("/misc/block-times", QueryBlockTimesRequestHandlerFn(hub)).Methods("GET")
 	router.HandleFunc("/misc/donations", QueryDonationsRequestHandlerFn(hub)).Methods("GET")
+	router.HandleFunc("/misc/ws-connections", QueryWsConnectionsRequestHandlerFn(wsManager)).Methods("GET")
 	router.HandleFunc("/market/tickers", QueryTickersRequestHandlerFn(hub)).Methods("GET")
+	router.HandleFunc("/market/xtickers", QueryXTickersRequestHandlerFn(hub)).Methods("GET")
 	router.HandleFunc("/market/depths", QueryDepthsRequestHandlerFn(hub)).Methods("GET")
+	router.HandleFunc("/market/orderbook-l3", QueryOrderBookL3RequestHandlerFn(hub)).Methods("GET")
 	router.HandleFunc("/market/candle-sticks", QueryCandleSticksRequestHandlerFn(hub)).Methods("GET")
-	router.HandleFunc("/market/user-orders", QueryOrdersRequestHandlerFn(hub)).Methods("GET")
+	router.HandleFunc("/market/user-orders", authAccountHandler(auth, QueryOrdersRequestHandlerFn(hub))).Methods("GET")
+	router.HandleFunc("/market/orders/{order_id}", authOrderHandler(auth, QueryOrderByIDRequestHandlerFn(hub))).Methods("GET")
+	router.HandleFunc("/market/open-orders", authAccountHandler(auth, QueryOpenOrdersRequestHandlerFn(hub))).Methods("GET")
 	router.HandleFunc("/market/deals", QueryDealsRequestHandlerFn(hub)).Methods("GET")
+	router.HandleFunc("/market/markets", QueryMarketsRequestHandlerFn(hub)).Methods("GET")
 	router.HandleFunc("/market/delist", QueryDelistRequestHandlerFn(hub)).Methods("GET")
 	router.HandleFunc("/market/delists", QueryDelistsRequestHandlerFn(hub)).Methods("GET")
 	router.HandleFunc("/bancorlite/infos", QueryBancorInfosRequestHandlerFn(hub)).Methods("GET")
-	router.HandleFunc("/bancorlite/trades", QueryBancorTradesRequestHandlerFn(hub)).Methods("GET")
+	router.HandleFunc("/bancorlite/trades", authAccountHandler(auth, QueryBancorTradesRequestHandlerFn(hub))).Methods("GET")
 	router.HandleFunc("/bancorlite/deals", QueryBancorDealsRequestHandlerFn(hub)).Methods("GET")
-	router.HandleFunc("/expiry/redelegations", QueryRedelegationsRequestHandlerFn(hub)).Methods("GET")
-	router.HandleFunc("/expiry/unbondings", QueryUnbondingsRequestHandlerFn(hub)).Methods("GET")
-	router.HandleFunc("/expiry/lockeds", QueryLockedRequestHandlerFn(hub)).Methods("GET")
-	router.HandleFunc("/expiry/unlocks", QueryUnlocksRequestHandlerFn(hub)).Methods("GET")
-	router.HandleFunc("/tx/incomes", QueryIncomesRequestHandlerFn(hub)).Methods("GET")
-	router.HandleFunc("/tx/txs", QueryTxsRequestHandlerFn(hub)).Methods("GET")
+	router.HandleFunc("/expiry/redelegations", authAccountHandler(auth, QueryRedelegationsRequestHandlerFn(hub))).Methods("GET")
+	router.HandleFunc("/expiry/unbondings", authAccountHandler(auth, QueryUnbondingsRequestHandlerFn(hub))).Methods("GET")
+	router.HandleFunc("/expiry/lockeds", authAccountHandler(auth, QueryLockedRequestHandlerFn(hub))).Methods("GET")
+	router.HandleFunc("/expiry/unlocks", authAccountHandler(auth, QueryUnlocksRequestHandlerFn(hub))).Methods("GET")
+	router.HandleFunc("/tx/incomes", authAccountHandler(auth, QueryIncomesRequestHandlerFn(hub))).Methods("GET")
+	router.HandleFunc("/tx/txs", authAccountHandler(auth, QueryTxsRequestHandlerFn(hub))).Methods("GET")
 	router.HandleFunc("/tx/txs/{hash}", QueryTxsByHashRequestHandlerFn(hub)).Methods("GET")
 	router.HandleFunc("/comment/comments", QueryCommentsRequestHandlerFn(hub)).Methods("GET")
 	router.HandleFunc("/slash/slashings", QuerySlashingsRequestHandlerFn(hub)).Methods("GET")
+	router.HandleFunc("/distribution/rewards", authAccountHandler(auth, QueryDelegatorRewardsRequestHandlerFn(hub))).Methods("GET")
+	router.HandleFunc("/distribution/commissions", authAccountHandler(auth, QueryValidatorCommissionsRequestHandlerFn(hub))).Methods("GET")
+	router.HandleFunc("/account/stats", authAccountHandler(auth, QueryAccountStatsRequestHandlerFn(hub))).Methods("GET")
+	router.HandleFunc("/rebates", authAddressHandler(auth, queryKeyReferee, QueryRebatesRequestHandlerFn(hub))).Methods("GET")
+
+	// bulk export
+	router.HandleFunc("/export/deals", ExportRequestHandlerFn(hub, core.ExportDeals)).Methods("GET")
+	router.HandleFunc("/export/orders", authAccountHandler(auth, ExportRequestHandlerFn(hub, core.ExportOrders))).Methods("GET")
+	router.HandleFunc("/export/txs", authAccountHandler(auth, ExportRequestHandlerFn(hub, core.ExportTxs))).Methods("GET")
+	router.HandleFunc("/export/incomes", authAccountHandler(auth, ExportRequestHandlerFn(hub, core.ExportIncomes))).Methods("GET")
+	router.HandleFunc("/export/candle-sticks", ExportRequestHandlerFn(hub, core.ExportCandleSticks)).Methods("GET")
 
 	// websocket
 	router.HandleFunc("/ws", ServeWsHandleFn(wsManager, hub))
+	// Server-Sent Events
+	router.HandleFunc("/stream", ServeSSEHandleFn(wsManager, hub)).Methods("GET")
 
 	return router, nil
 }
 
+// The REST queries of a relay are forwarded to its primary, and the accounts are authenticated by the relay
+func registerRelayHandler(relay *Relay, wsManager *core.WebsocketManager) http.Handler {
+	router := mux.NewRouter()
+	auth := wsManager.GetAuthenticator()
+	forward := relay.forwarder.handler()
+	router.HandleFunc("/misc/auth-challenge", QueryAuthChallengeRequestHandlerFn(auth)).Methods("GET")
+	router.HandleFunc("/misc/ws-connections", QueryWsConnectionsRequestHandlerFn(wsManager)).Methods("GET")
+	for name, key := range wsAccountQueries {
+		router.HandleFunc("/"+name, authAddressHandler(auth, key, forward)).Methods("GET")
+	}
+	router.HandleFunc("/market/orders/{order_id}", authOrderHandler(auth, forward)).Methods("GET")
+
+	// websocket
+	router.HandleFunc("/ws", serveWs(wsManager, relay))
+	// Server-Sent Events
+	router.HandleFunc("/stream", serveSSE(wsManager, relay)).Methods("GET")
+
+	// the exports are too large to be forwarded
+	router.PathPrefix("/export/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest.WriteErrorResponse(w, http.StatusNotImplemented, errRelayExport.Error())
+	}).Methods("GET")
+	router.PathPrefix("/").Handler(forward).Methods("GET")
+	return router
+}
+
 func isEnableProxy(register RegisterRouter) bool {
 	return register == nil
 }
@@ -36,6 +36,7 @@ type TradeServer struct {
 	hub      *core.Hub
 	consumer Consumer
 	pw       WorkerCloser
+	relay    *Relay
 }
 
 func NewTradeServer(svrConfig *toml.Tree, register RegisterRouter) *TradeServer {
@@ -47,6 +48,9 @@ func NewTradeServer(svrConfig *toml.Tree, register RegisterRouter) *TradeServer
 }
 
 func NewServer(svrConfig *toml.Tree, register RegisterRouter) *TradeServer {
+	if svrConfig.GetDefault("relay", false).(bool) {
+		return newRelayServer(svrConfig)
+	}
 	var (
 		db        dbm.DB
 		err       error
@@ -59,6 +63,10 @@ func NewServer(svrConfig *toml.Tree, register RegisterRouter) *TradeServer {
 		log.WithError(err).Fatal("init db failed")
 		return nil
 	}
+	if err = initWsManager(svrConfig, wsManager); err != nil {
+		log.WithError(err).Error("init websocket manager failed")
+		return nil
+	}
 	if hub, err = initHub(svrConfig, db, wsManager); err != nil {
 		log.WithError(err).Error("init hub failed")
 		return nil
@@ -80,6 +88,30 @@ func NewServer(svrConfig *toml.Tree, register RegisterRouter) *TradeServer {
 	return server
 }
 
+// A relay serves the clients with the data of its primary, without DB, hub or consumer
+func newRelayServer(svrConfig *toml.Tree) *TradeServer {
+	wsManager := core.NewWebSocketManager()
+	if err := initWsManager(svrConfig, wsManager); err != nil {
+		log.WithError(err).Error("init websocket manager failed")
+		return nil
+	}
+	if err := checkHTTPSOption(svrConfig); err != nil {
+		log.WithError(err).Error("check https required cert file failed")
+		return nil
+	}
+	upstream := svrConfig.GetDefault("relay-upstream", "").(string)
+	if len(upstream) == 0 {
+		log.Error("the upstream of the relay is empty")
+		return nil
+	}
+	cacheTTL := time.Duration(svrConfig.GetDefault("relay-cache-ttl", int64(1)).(int64)) * time.Second
+	relay := NewRelay(upstream, wsManager, cacheTTL)
+	return &TradeServer{
+		httpSvr: newHTTPServer(svrConfig, registerRelayHandler(relay, wsManager)),
+		relay:   relay,
+	}
+}
+
 func CreateHub(svrConfig *toml.Tree) (*core.Hub, error) {
 	var (
 		db  dbm.DB
@@ -131,6 +163,18 @@ func initHub(svrConfig *toml.Tree, db dbm.DB, wsManager *core.WebsocketManager)
 	return hub, nil
 }
 
+func initWsManager(svrConfig *toml.Tree, wsManager *core.WebsocketManager) error {
+	cfg := core.DefaultSendQueueConfig()
+	cfg.Size = int(svrConfig.GetDefault("ws-queue-size", int64(cfg.Size)).(int64))
+	cfg.Policies[core.ClassDepth] = svrConfig.GetDefault("ws-depth-policy", cfg.Policies[core.ClassDepth]).(string)
+	cfg.Policies[core.ClassTicker] = svrConfig.GetDefault("ws-ticker-policy", cfg.Policies[core.ClassTicker]).(string)
+	cfg.Policies[core.ClassDefault] = svrConfig.GetDefault("ws-default-policy", cfg.Policies[core.ClassDefault]).(string)
+	wsManager.SetMaxWildcards(int(svrConfig.GetDefault("ws-max-wildcards", int64(core.MaxWildcardSubscriptions)).(int64)))
+	wsManager.SetAuthenticator(core.NewAuthenticator(svrConfig.GetDefault("auth-required", false).(bool)))
+	SSEHeartbeatInterval = time.Duration(svrConfig.GetDefault("sse-heartbeat-interval", int64(SSEHeartbeatInterval/time.Second)).(int64)) * time.Second
+	return wsManager.SetSendQueueConfig(cfg)
+}
+
 func initWebService(svrConfig *toml.Tree, hub *core.Hub, wsManager *core.WebsocketManager, register func(route *mux.Router)) (*http.Server, error) {
 	if err := checkHTTPSOption(svrConfig); err != nil {
 		log.WithError(err).Error("check https required cert file failed")
@@ -159,18 +203,24 @@ func initHTTPService(svrConfig *toml.Tree, hub *core.Hub, wsManager *core.Websoc
 	if err != nil {
 		return nil, err
 	}
-	httpSvr := &http.Server{
+	return newHTTPServer(svrConfig, router), nil
+}
+
+func newHTTPServer(svrConfig *toml.Tree, handler http.Handler) *http.Server {
+	return &http.Server{
 		Addr:         fmt.Sprintf(":%d", svrConfig.GetDefault("port", 8000).(int64)),
-		Handler:      router,
+		Handler:      handler,
 		ReadTimeout:  ReadTimeout * time.Second,
 		WriteTimeout: WriteTimeout * time.Second,
 	}
-	return httpSvr, nil
 }
 
 func (ts *TradeServer) Start(svrConfig *toml.Tree) {
 	log.WithField("addr", ts.httpSvr.Addr).Info("Trade-Server start...")
 	go ts.startHTTPServer(svrConfig)
+	if ts.relay != nil {
+		return
+	}
 	go ts.consume()
 	ts.pw.Run()
 }
@@ -206,6 +256,11 @@ func (ts *TradeServer) Stop() {
 	if err := ts.httpSvr.Shutdown(ctx); err != nil {
 		log.WithError(err).Error("http server shutdown failed")
 	}
+	if ts.relay != nil {
+		ts.relay.Close()
+		log.Info("Server stop...")
+		return
+	}
 
 	// stop hub (before closing consumer)
 	ts.hub.Close()
@@ -231,7 +286,9 @@ func restoreHub(hub *core.Hub) error {
 	if err != nil || hub4jo == nil {
 		return err
 	}
-	hub.Load(hub4jo)
+	if err = hub.Load(hub4jo); err != nil {
+		return err
+	}
 	log.Info("restore hub finish")
 	return nil
 }
@@ -0,0 +1,307 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/types/rest"
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/coinexchain/trade-server/core"
+)
+
+const (
+	queryKeyTopics      = "topics"
+	queryKeyDepth       = "depth"
+	queryKeyLastEventID = "last_event_id"
+)
+
+// A comment line is sent to an idle stream every interval, such that the proxies do not close it
+var SSEHeartbeatInterval = 15 * time.Second
+
+var errStreamClosed = errors.New("stream closed")
+
+// sseConn serves a websocket connection over Server-Sent Events, which is one-way.
+// The pushed messages are sent as events whose ids are their seqs, and the other messages are sent without ids.
+// An HTTP/1.x connection is hijacked, since the write timeout of the http server would end the stream.
+type sseConn struct {
+	mtx       sync.Mutex
+	r         *http.Request
+	w         io.Writer
+	flush     func()
+	netConn   net.Conn // nil if the stream is written by the http.ResponseWriter
+	lastWrite time.Time
+	closed    bool
+
+	heartbeatInterval time.Duration
+
+	// the writes are blocked until the stream is started
+	ready chan struct{}
+	done  chan struct{}
+
+	pingHandler func(string) error
+}
+
+func newSSEConn(r *http.Request) *sseConn {
+	return &sseConn{
+		r:                 r,
+		heartbeatInterval: SSEHeartbeatInterval,
+		ready:             make(chan struct{}),
+		done:              make(chan struct{}),
+	}
+}
+
+// Sends the response header, after which the queued messages can be written
+func (s *sseConn) start(w http.ResponseWriter) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if hijacker, ok := w.(http.Hijacker); ok && s.r.ProtoMajor == 1 {
+		netConn, _, err := hijacker.Hijack()
+		if err != nil {
+			return err
+		}
+		// clear the deadlines set by the http server
+		_ = netConn.SetDeadline(time.Time{})
+		s.netConn, s.w, s.flush = netConn, netConn, func() {}
+		header := "HTTP/1.1 200 OK\r\nContent-Type: text/event-stream\r\nCache-Control: no-cache\r\n" +
+			"Connection: close\r\nX-Accel-Buffering: no\r\n\r\n"
+		if err := s.writeLocked([]byte(header)); err != nil {
+			netConn.Close()
+			return err
+		}
+	} else {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			return fmt.Errorf("streaming is not supported")
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		s.w, s.flush = w, flusher.Flush
+		s.flush()
+	}
+	s.lastWrite = time.Now()
+	close(s.ready)
+	go s.heartbeat()
+	return nil
+}
+
+func (s *sseConn) heartbeat() {
+	ticker := time.NewTicker(s.heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.mtx.Lock()
+			if time.Since(s.lastWrite) >= s.heartbeatInterval {
+				_ = s.writeLocked([]byte(": heartbeat\n\n"))
+			}
+			s.mtx.Unlock()
+		}
+	}
+}
+
+func (s *sseConn) write(bz []byte) error {
+	select {
+	case <-s.ready:
+	case <-s.done:
+		return errStreamClosed
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.writeLocked(bz)
+}
+
+func (s *sseConn) writeLocked(bz []byte) error {
+	if s.closed {
+		return errStreamClosed
+	}
+	if s.netConn != nil {
+		_ = s.netConn.SetWriteDeadline(time.Now().Add(WriteTimeout * time.Second))
+	}
+	if _, err := s.w.Write(bz); err != nil {
+		return err
+	}
+	s.flush()
+	s.lastWrite = time.Now()
+	return nil
+}
+
+func (s *sseConn) WriteMessage(msgType int, data []byte) error {
+	return s.write(encodeSSEEvent("", "", data))
+}
+
+func (s *sseConn) WriteEvent(seq int64, data []byte) error {
+	return s.write(encodeSSEEvent(strconv.FormatInt(seq, 10), "", data))
+}
+
+// The close message is sent as a 'close' event
+func (s *sseConn) WriteControl(msgType int, data []byte, deadline time.Time) error {
+	if msgType != websocket.CloseMessage || len(data) < 2 {
+		return nil
+	}
+	bz, err := json.Marshal(map[string]interface{}{
+		"code":   binary.BigEndian.Uint16(data),
+		"reason": string(data[2:]),
+	})
+	if err != nil {
+		return err
+	}
+	return s.write(encodeSSEEvent("", "close", bz))
+}
+
+// Blocks until the client goes away or the stream is closed, the clients send nothing over the stream
+func (s *sseConn) ReadMessage() (messageType int, p []byte, err error) {
+	select {
+	case <-s.ready:
+	case <-s.done:
+		return 0, nil, errStreamClosed
+	}
+	if s.netConn != nil {
+		_, err = io.Copy(ioutil.Discard, s.netConn)
+		if err == nil {
+			err = io.EOF
+		}
+		return 0, nil, err
+	}
+	select {
+	case <-s.r.Context().Done():
+		return 0, nil, s.r.Context().Err()
+	case <-s.done:
+		return 0, nil, errStreamClosed
+	}
+}
+
+func (s *sseConn) SetPingHandler(h func(string) error) {
+	s.pingHandler = h
+}
+
+func (s *sseConn) PingHandler() func(string) error {
+	return s.pingHandler
+}
+
+func (s *sseConn) Close() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+	if s.netConn != nil {
+		return s.netConn.Close()
+	}
+	return nil
+}
+
+// The multiple lines of data are sent as multiple 'data' fields
+func encodeSSEEvent(id, event string, data []byte) []byte {
+	var buf bytes.Buffer
+	if len(id) != 0 {
+		buf.WriteString("id: " + id + "\n")
+	}
+	if len(event) != 0 {
+		buf.WriteString("event: " + event + "\n")
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		buf.WriteString("data: ")
+		buf.Write(line)
+		buf.WriteString("\n")
+	}
+	buf.WriteString("\n")
+	return buf.Bytes()
+}
+
+// Serves the topics in 'topics' over Server-Sent Events, which are subscribed as the websocket 'subscribe' command.
+// The stream is resumed from the 'Last-Event-ID' header (or the 'last_event_id' parameter) if it is present,
+// and the address-scoped topics can be authenticated by the auth headers of the REST endpoints.
+func ServeSSEHandleFn(wsManager *core.WebsocketManager, hub *core.Hub) http.HandlerFunc {
+	return serveSSE(wsManager, &hubConnHandler{hub: hub, wsManager: wsManager})
+}
+
+func serveSSE(wsManager *core.WebsocketManager, handler connHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		command, err := parseSSESubscribe(r)
+		if err != nil {
+			rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		stream := newSSEConn(r)
+		wsConn := wsManager.AddWsConn(stream)
+		if challenge := r.Header.Get(HeaderAuthChallenge); len(challenge) != 0 {
+			_, err = wsManager.AuthenticateConn(wsConn, challenge, r.Header.Get(HeaderAuthPubKey), r.Header.Get(HeaderAuthSignature))
+			if err != nil {
+				wsManager.CloseWsConn(wsConn)
+				rest.WriteErrorResponse(w, http.StatusUnauthorized, err.Error())
+				return
+			}
+		}
+		// the full information and the replayed messages are queued until the stream is started
+		if err = handler.subscribe(command, wsConn); err != nil {
+			wsManager.CloseWsConn(wsConn)
+			handler.connClosed(wsConn)
+			rest.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		defer handler.connClosed(wsConn)
+		if err = stream.start(w); err != nil {
+			log.WithError(err).Error("start event stream failed")
+			wsManager.CloseWsConn(wsConn)
+			return
+		}
+		for {
+			if _, err = wsConn.ReadMsg(wsManager); err != nil {
+				break
+			}
+		}
+	}
+}
+
+func parseSSESubscribe(r *http.Request) (*OpCommand, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+	topics := r.FormValue(queryKeyTopics)
+	if len(topics) == 0 {
+		return nil, ErrNilParams(queryKeyTopics)
+	}
+	command := &OpCommand{Op: Subscribe, Args: strings.Split(topics, ",")}
+	for _, subTopic := range command.Args {
+		if _, _, err := core.GetTopicAndParams(subTopic); err != nil {
+			return nil, err
+		}
+	}
+	if depth := r.FormValue(queryKeyDepth); len(depth) != 0 {
+		count, err := parseQueryCountParams(depth)
+		if err != nil {
+			return nil, err
+		}
+		command.Depth = count
+	}
+	lastEventID := r.Header.Get(HeaderLastEventID)
+	if len(lastEventID) == 0 {
+		lastEventID = r.FormValue(queryKeyLastEventID)
+	}
+	if len(lastEventID) != 0 {
+		seq, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || seq < 0 {
+			return nil, ErrNegativeParams(HeaderLastEventID)
+		}
+		command.SinceSeq = seq
+	}
+	return command, nil
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/coinexchain/trade-server/core"
+)
+
+// Reads the lines of n events, the comment lines are skipped
+func readSSEEvents(t *testing.T, reader *bufio.Reader, n int) []string {
+	var events []string
+	event := ""
+	for len(events) < n {
+		line, err := reader.ReadString('\n')
+		require.Nil(t, err)
+		switch {
+		case line == "\n":
+			if len(event) != 0 {
+				events = append(events, event)
+			}
+			event = ""
+		case strings.HasPrefix(line, ":"):
+		default:
+			event += line
+		}
+	}
+	return events
+}
+
+func TestServeSSE(t *testing.T) {
+	db := dbm.NewMemDB()
+	hub := core.NewHub(db, &core.MocSubscribeManager{}, 99999, 0, 0, 0, "", 0)
+	wsManager := core.NewWebSocketManager()
+	handler, _ := registerHandler(hub, wsManager, false, "", "", nil)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/stream?topics=deal:abc/cet,unknown")
+	require.Nil(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp.Body.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequest("GET", server.URL+"/stream?topics=blockinfo,ticker:abc/cet", nil)
+	req = req.WithContext(ctx)
+	req.Header.Set(HeaderLastEventID, "1")
+	resp, err = http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	reader := bufio.NewReader(resp.Body)
+	require.EqualValues(t, []string{
+		"data: {\"type\":\"gap\", \"payload\":{\"topic\":\"blockinfo\",\"seq\":0,\"height\":0}}\n",
+		"data: {\"type\":\"gap\", \"payload\":{\"topic\":\"ticker:abc/cet\",\"seq\":0,\"height\":0}}\n",
+		"data: {\"type\":\"ticker\", \"payload\":[]}\n",
+	}, readSSEEvents(t, reader, 3))
+
+	wsManager.SetPushMeta(7, 100)
+	for _, sub := range wsManager.GetHeightSubscribeInfo() {
+		wsManager.PushHeight(sub, []byte(`{"height":100}`))
+	}
+	wsManager.Flush()
+	require.EqualValues(t, []string{
+		"id: 7\ndata: {\"type\":\"blockinfo\", \"seq\":7, \"height\":100, \"payload\":{\"height\":100}}\n",
+	}, readSSEEvents(t, reader, 1))
+	require.EqualValues(t, 1, len(wsManager.GetConnStats()))
+
+	// the stream is removed after the client goes away
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	require.EqualValues(t, 0, len(wsManager.GetConnStats()))
+}
+
+func TestSSEHeartbeat(t *testing.T) {
+	interval := SSEHeartbeatInterval
+	SSEHeartbeatInterval = 10 * time.Millisecond
+	defer func() { SSEHeartbeatInterval = interval }()
+
+	db := dbm.NewMemDB()
+	hub := core.NewHub(db, &core.MocSubscribeManager{}, 99999, 0, 0, 0, "", 0)
+	wsManager := core.NewWebSocketManager()
+	handler, _ := registerHandler(hub, wsManager, false, "", "", nil)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequest("GET", server.URL+"/stream?topics=blockinfo", nil)
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	require.Nil(t, err)
+	require.Equal(t, ": heartbeat\n", line)
+}
+
+func TestEncodeSSEEvent(t *testing.T) {
+	require.Equal(t, "id: 3\ndata: {}\n\n", string(encodeSSEEvent("3", "", []byte("{}"))))
+	require.Equal(t, "event: close\ndata: a\ndata: b\n\n", string(encodeSSEEvent("", "close", []byte("a\nb"))))
+}
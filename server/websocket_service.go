@@ -2,6 +2,7 @@ package server
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/gorilla/websocket"
 	log "github.com/sirupsen/logrus"
@@ -9,28 +10,106 @@ import (
 	"github.com/coinexchain/trade-server/core"
 )
 
+const (
+	queryKeyEncoding = "encoding"
+	// labels the pushed messages with the subscriptions they match
+	queryKeyLabel = "label"
+)
+
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
 	CheckOrigin: func(r *http.Request) bool {
 		return true
 	},
+	// permessage-deflate is negotiated if the client offers it, but it is only used by the 'deflate' encoding
+	EnableCompression: true,
+}
+
+// wsConnection remembers whether permessage-deflate is negotiated
+type wsConnection struct {
+	*websocket.Conn
+	deflate bool
+}
+
+func (c *wsConnection) CompressionNegotiated() bool {
+	return c.deflate
+}
+
+// The upgrader negotiates permessage-deflate if it is one of the extensions offered by the client
+func isDeflateOffered(r *http.Request) bool {
+	for _, header := range r.Header["Sec-Websocket-Extensions"] {
+		for _, ext := range strings.Split(header, ",") {
+			if strings.TrimSpace(strings.Split(ext, ";")[0]) == "permessage-deflate" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// connHandler serves the commands of the websocket and Server-Sent Events connections,
+// with the data of the local hub or of the primary of a relay
+type connHandler interface {
+	// returns false if the connection is closed
+	handleCommand(command *OpCommand, wsConn *core.Conn) bool
+	subscribe(command *OpCommand, wsConn *core.Conn) error
+	// called after the connection is closed
+	connClosed(wsConn *core.Conn)
+}
+
+type hubConnHandler struct {
+	hub       *core.Hub
+	wsManager *core.WebsocketManager
+}
+
+func (h *hubConnHandler) handleCommand(command *OpCommand, wsConn *core.Conn) bool {
+	return command.HandleCommand(h.hub, h.wsManager, wsConn)
 }
 
+func (h *hubConnHandler) subscribe(command *OpCommand, wsConn *core.Conn) error {
+	return command.handleSubscribe(h.hub, h.wsManager, wsConn)
+}
+
+func (h *hubConnHandler) connClosed(wsConn *core.Conn) {}
+
+// The encoding of the messages can be chosen by the 'encoding' parameter, such as '/ws?encoding=msgpack'
 func ServeWsHandleFn(wsManager *core.WebsocketManager, hub *core.Hub) http.HandlerFunc {
+	return serveWs(wsManager, &hubConnHandler{hub: hub, wsManager: wsManager})
+}
+
+func serveWs(wsManager *core.WebsocketManager, handler connHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		encoding := r.URL.Query().Get(queryKeyEncoding)
+		if len(encoding) != 0 && !core.IsValidEncoding(encoding) {
+			http.Error(w, core.ErrUnknownEncoding.Error(), http.StatusBadRequest)
+			return
+		}
+		deflate := isDeflateOffered(r)
+		if encoding == core.EncodingDeflate && !deflate {
+			http.Error(w, core.ErrDeflateNotNegotiated.Error(), http.StatusBadRequest)
+			return
+		}
 		c, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
 			log.WithError(err).Errorf("upgrader http request to websocket failed")
 			return
 		}
-		wsConn := wsManager.AddWsConn(c)
+		// the messages are not compressed until the 'deflate' encoding is chosen
+		c.EnableWriteCompression(false)
+		wsConn := wsManager.AddWsConn(&wsConnection{Conn: c, deflate: deflate})
+		if len(encoding) != 0 {
+			if err = wsConn.SetEncoding(encoding); err != nil {
+				log.WithError(err).Errorf("set encoding failed")
+			}
+		}
 
-		go handleConnIncomingMsg(hub, wsManager, wsConn)
+		go handleConnIncomingMsg(wsManager, handler, wsConn)
 	}
 }
 
-func handleConnIncomingMsg(hub *core.Hub, wsManager *core.WebsocketManager, wsConn *core.Conn) {
+func handleConnIncomingMsg(wsManager *core.WebsocketManager, handler connHandler, wsConn *core.Conn) {
+	defer handler.connClosed(wsConn)
 	for {
 		var (
 			err     error
@@ -43,7 +122,7 @@ func handleConnIncomingMsg(hub *core.Hub, wsManager *core.WebsocketManager, wsCo
 		if command = NewCommand(message); command == nil {
 			continue
 		}
-		if !command.HandleCommand(hub, wsManager, wsConn) {
+		if !handler.handleCommand(command, wsConn) {
 			break
 		}
 	}
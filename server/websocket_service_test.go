@@ -0,0 +1,75 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/coinexchain/trade-server/core"
+)
+
+func dialWs(t *testing.T, url string, compress bool) *websocket.Conn {
+	dialer := websocket.Dialer{EnableCompression: compress}
+	c, _, err := dialer.Dial("ws"+strings.TrimPrefix(url, "http"), nil)
+	require.Nil(t, err)
+	return c
+}
+
+func TestWsEncoding(t *testing.T) {
+	db := dbm.NewMemDB()
+	hub := core.NewHub(db, &core.MocSubscribeManager{}, 99999, 0, 0, 0, "", 0)
+	wsManager := core.NewWebSocketManager()
+	handler, _ := registerHandler(hub, wsManager, false, "", "", nil)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	_, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws?encoding=xml", nil)
+	require.NotNil(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	_, resp, err = websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws?encoding=deflate", nil)
+	require.NotNil(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// gzip-compressed binary frames
+	c := dialWs(t, server.URL+"/ws?encoding=gzip", false)
+	require.Nil(t, c.WriteMessage(websocket.TextMessage, []byte(`{"op":"ping"}`)))
+	msgType, bz, err := c.ReadMessage()
+	require.Nil(t, err)
+	require.Equal(t, websocket.BinaryMessage, msgType)
+	r, err := gzip.NewReader(bytes.NewReader(bz))
+	require.Nil(t, err)
+	bz, err = ioutil.ReadAll(r)
+	require.Nil(t, err)
+	require.Equal(t, `{"type":"pong"}`, string(bz))
+	c.Close()
+
+	// permessage-deflate can not be used if it is not negotiated
+	c = dialWs(t, server.URL+"/ws", false)
+	require.Nil(t, c.WriteMessage(websocket.TextMessage, []byte(`{"op":"options", "args":["encoding=deflate"]}`)))
+	_, bz, err = c.ReadMessage()
+	require.Nil(t, err)
+	require.Equal(t, `{"error": "permessage-deflate is not negotiated"}`, string(bz))
+	require.Nil(t, c.WriteMessage(websocket.TextMessage, []byte(`{"op":"options", "args":["encoding=msgpack"]}`)))
+	msgType, bz, err = c.ReadMessage()
+	require.Nil(t, err)
+	require.Equal(t, websocket.BinaryMessage, msgType)
+	require.Equal(t, append([]byte{0x82, 0xa4, 't', 'y', 'p', 'e', 0xa7, 'o', 'p', 't', 'i', 'o', 'n', 's', 0xa7, 'p', 'a', 'y', 'l', 'o', 'a', 'd',
+		0x81, 0xa8, 'e', 'n', 'c', 'o', 'd', 'i', 'n', 'g', 0xa7}, "msgpack"...), bz)
+	c.Close()
+
+	c = dialWs(t, server.URL+"/ws", true)
+	require.Nil(t, c.WriteMessage(websocket.TextMessage, []byte(`{"op":"options", "args":["encoding=deflate"]}`)))
+	msgType, bz, err = c.ReadMessage()
+	require.Nil(t, err)
+	require.Equal(t, websocket.TextMessage, msgType)
+	require.Equal(t, `{"type":"options", "payload":{"encoding":"deflate"}}`, string(bz))
+	c.Close()
+}
@@ -0,0 +1,286 @@
+package server
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/coinexchain/trade-server/core"
+)
+
+// The parameters of a query over websocket, which are named as the parameters of the REST API.
+// The values can be strings or numbers, such as {"market":"abc/cet", "count":10}
+type QueryParams map[string]string
+
+func (p *QueryParams) UnmarshalJSON(bz []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(bz, &raw); err != nil {
+		return err
+	}
+	*p = make(QueryParams, len(raw))
+	for key, val := range raw {
+		var str string
+		if err := json.Unmarshal(val, &str); err == nil {
+			(*p)[key] = str
+		} else {
+			(*p)[key] = string(val)
+		}
+	}
+	return nil
+}
+
+func (p QueryParams) get(key string) string {
+	return p[key]
+}
+
+type wsQueryFn func(hub *core.Hub, p QueryParams) (interface{}, error)
+
+// The queries over websocket, which are named as the REST paths without the leading '/'.
+// The path variables of 'market/orders/{order_id}' and 'tx/txs/{hash}' are passed as parameters to 'market/order' and 'tx/tx'
+var wsQueries = map[string]wsQueryFn{
+	"misc/height":      queryHeight,
+	"misc/block-times": queryBlockTimes,
+	"misc/donations": kvStoreQuery(func(hub *core.Hub, p QueryParams, qr core.QueryRange) ([]json.RawMessage, []int64) {
+		return hub.QueryDonation(qr)
+	}),
+	"market/tickers": func(hub *core.Hub, p QueryParams) (interface{}, error) {
+		return hub.QueryTickers(strings.Split(p.get(queryKeyMarketList), ",")), nil
+	},
+	"market/xtickers": func(hub *core.Hub, p QueryParams) (interface{}, error) {
+		return hub.QueryXTickers(strings.Split(p.get(queryKeyMarketList), ",")), nil
+	},
+	"market/depths":        queryDepths,
+	"market/orderbook-l3":  queryOrderBookL3,
+	"market/candle-sticks": queryCandleSticks,
+	"market/user-orders":   queryUserOrders,
+	"market/order":         queryOrder,
+	"market/open-orders":   queryOpenOrders,
+	"market/deals": kvStoreQuery(func(hub *core.Hub, p QueryParams, qr core.QueryRange) ([]json.RawMessage, []int64) {
+		return hub.QueryDeal(p.get(queryKeyMarket), qr)
+	}),
+	"market/markets": kvStoreQuery(func(hub *core.Hub, p QueryParams, qr core.QueryRange) ([]json.RawMessage, []int64) {
+		return hub.QueryMarkets(strings.ToLower(p.get(queryKeyToken)), qr)
+	}),
+	"market/delist":  queryDelist,
+	"market/delists": queryDelists,
+	"bancorlite/infos": kvStoreQuery(func(hub *core.Hub, p QueryParams, qr core.QueryRange) ([]json.RawMessage, []int64) {
+		return hub.QueryBancorInfo(p.get(queryKeyMarket), qr)
+	}),
+	"bancorlite/trades": kvStoreQuery(func(hub *core.Hub, p QueryParams, qr core.QueryRange) ([]json.RawMessage, []int64) {
+		return hub.QueryBancorTradeAboutToken(strings.ToLower(p.get(queryKeyToken)), p.get(queryKeyAccount), qr)
+	}),
+	"bancorlite/deals": kvStoreQuery(func(hub *core.Hub, p QueryParams, qr core.QueryRange) ([]json.RawMessage, []int64) {
+		return hub.QueryBancorDeal(p.get(queryKeyMarket), qr)
+	}),
+	"expiry/redelegations": kvStoreQuery(func(hub *core.Hub, p QueryParams, qr core.QueryRange) ([]json.RawMessage, []int64) {
+		return hub.QueryRedelegation(p.get(queryKeyAccount), qr)
+	}),
+	"expiry/unbondings": kvStoreQuery(func(hub *core.Hub, p QueryParams, qr core.QueryRange) ([]json.RawMessage, []int64) {
+		return hub.QueryUnbonding(p.get(queryKeyAccount), qr)
+	}),
+	"expiry/lockeds": kvStoreQuery(func(hub *core.Hub, p QueryParams, qr core.QueryRange) ([]json.RawMessage, []int64) {
+		return hub.QueryLockedAboutToken(strings.ToLower(p.get(queryKeyToken)), p.get(queryKeyAccount), qr)
+	}),
+	"expiry/unlocks": kvStoreQuery(func(hub *core.Hub, p QueryParams, qr core.QueryRange) ([]json.RawMessage, []int64) {
+		return hub.QueryUnlockAboutToken(strings.ToLower(p.get(queryKeyToken)), p.get(queryKeyAccount), qr)
+	}),
+	"tx/incomes": kvStoreQuery(func(hub *core.Hub, p QueryParams, qr core.QueryRange) ([]json.RawMessage, []int64) {
+		return hub.QueryIncomeAboutToken(strings.ToLower(p.get(queryKeyToken)), p.get(queryKeyAccount), qr)
+	}),
+	"tx/txs": kvStoreQuery(func(hub *core.Hub, p QueryParams, qr core.QueryRange) ([]json.RawMessage, []int64) {
+		return hub.QueryTxAboutToken(strings.ToLower(p.get(queryKeyToken)), p.get(queryKeyAccount), qr)
+	}),
+	"tx/tx": func(hub *core.Hub, p QueryParams) (interface{}, error) {
+		return hub.QueryTxByHashID(p.get("hash")), nil
+	},
+	"comment/comments": kvStoreQuery(func(hub *core.Hub, p QueryParams, qr core.QueryRange) ([]json.RawMessage, []int64) {
+		return hub.QueryComment(p.get(queryKeyToken), qr)
+	}),
+	"slash/slashings": kvStoreQuery(func(hub *core.Hub, p QueryParams, qr core.QueryRange) ([]json.RawMessage, []int64) {
+		return hub.QuerySlash(qr)
+	}),
+	"distribution/rewards": kvStoreQuery(func(hub *core.Hub, p QueryParams, qr core.QueryRange) ([]json.RawMessage, []int64) {
+		return hub.QueryDelegatorRewards(p.get(queryKeyAccount), qr)
+	}),
+	"distribution/commissions": kvStoreQuery(func(hub *core.Hub, p QueryParams, qr core.QueryRange) ([]json.RawMessage, []int64) {
+		return hub.QueryValidatorCommission(p.get(queryKeyAccount), qr)
+	}),
+	"account/stats": func(hub *core.Hub, p QueryParams) (interface{}, error) {
+		return queryAccountStats(hub, p.get)
+	},
+	"rebates": func(hub *core.Hub, p QueryParams) (interface{}, error) {
+		return queryRebates(hub, p.get)
+	},
+}
+
+// The queries whose address must be authenticated if authentication is required, as their REST endpoints.
+// The values are the parameters of the addresses
+var wsAccountQueries = map[string]string{
+	"market/user-orders":       queryKeyAccount,
+	"market/open-orders":       queryKeyAccount,
+	"bancorlite/trades":        queryKeyAccount,
+	"expiry/redelegations":     queryKeyAccount,
+	"expiry/unbondings":        queryKeyAccount,
+	"expiry/lockeds":           queryKeyAccount,
+	"expiry/unlocks":           queryKeyAccount,
+	"tx/incomes":               queryKeyAccount,
+	"tx/txs":                   queryKeyAccount,
+	"distribution/rewards":     queryKeyAccount,
+	"distribution/commissions": queryKeyAccount,
+	"account/stats":            queryKeyAccount,
+	"rebates":                  queryKeyReferee,
+}
+
+// The result of a query, 'id' is the id of the query op
+type wsQueryResult struct {
+	ID     json.RawMessage `json:"id"`
+	Result interface{}     `json:"result"`
+}
+
+type wsQueryError struct {
+	ID    json.RawMessage `json:"id"`
+	Error string          `json:"error"`
+}
+
+// Runs a query over websocket, the result is the same as the response of its REST endpoint
+func runWsQuery(hub *core.Hub, wsManager *core.WebsocketManager, wsConn *core.Conn, name string, p QueryParams) (interface{}, error) {
+	if err := checkWsQuery(wsManager, wsConn, name, p); err != nil {
+		return nil, err
+	}
+	return wsQueries[name](hub, p)
+}
+
+// Checks whether the query is known, and its account is authenticated if needed
+func checkWsQuery(wsManager *core.WebsocketManager, wsConn *core.Conn, name string, p QueryParams) error {
+	if _, ok := wsQueries[name]; !ok {
+		return ErrUnknownQuery(name)
+	}
+	if key, ok := wsAccountQueries[name]; ok {
+		return wsManager.CheckAccountAuth(wsConn, p.get(key))
+	}
+	if name == "market/order" {
+		return wsManager.CheckAccountAuth(wsConn, getOrderSender(p.get(queryKeyOrderID)))
+	}
+	return nil
+}
+
+// kvStoreQuery wraps a query paged by a cursor, whose result is wrapped with the cursor of the next page
+func kvStoreQuery(query func(hub *core.Hub, p QueryParams, qr core.QueryRange) ([]json.RawMessage, []int64)) wsQueryFn {
+	return func(hub *core.Hub, p QueryParams) (interface{}, error) {
+		qr, err := parseKVStoreParams(p.get)
+		if err != nil {
+			return nil, err
+		}
+		data, timesid := query(hub, p, qr)
+		return NewDataWrapped(data, timesid, qr.Count), nil
+	}
+}
+
+func queryHeight(hub *core.Hub, p QueryParams) (interface{}, error) {
+	return hub.QueryBlockInfo(), nil
+}
+
+func queryBlockTimes(hub *core.Hub, p QueryParams) (interface{}, error) {
+	height, err := parseQueryHeightParams(p.get(queryKeyHeight))
+	if err != nil {
+		return nil, err
+	}
+	count, err := parseQueryCountParams(p.get(queryKeyCount))
+	if err != nil {
+		return nil, err
+	}
+	return hub.QueryBlockTime(height, count), nil
+}
+
+func queryDepths(hub *core.Hub, p QueryParams) (interface{}, error) {
+	count, err := parseQueryCountParams(p.get(queryKeyCount))
+	if err != nil {
+		return nil, err
+	}
+	market := p.get(queryKeyMarket)
+	if len(p.get(queryKeyHeight)) == 0 {
+		sell, buy := hub.QueryDepth(market, count)
+		return NewDepthResponse(sell, buy), nil
+	}
+	height, err := parseQueryHeightParams(p.get(queryKeyHeight))
+	if err != nil {
+		return nil, err
+	}
+	sell, buy, ok := hub.QueryDepthAtHeight(market, height, count)
+	if !ok {
+		return nil, ErrDepthNotRetained(market, height)
+	}
+	return NewDepthResponse(sell, buy), nil
+}
+
+func queryOrderBookL3(hub *core.Hub, p QueryParams) (interface{}, error) {
+	market := p.get(queryKeyMarket)
+	count, err := parseQueryCountParams(p.get(queryKeyCount))
+	if err != nil {
+		return nil, err
+	}
+	data := hub.QueryOrderBookL3(market, count)
+	if data == nil {
+		return nil, ErrMarketNotFound(market)
+	}
+	return data, nil
+}
+
+func queryCandleSticks(hub *core.Hub, p QueryParams) (interface{}, error) {
+	timespan, err := parseQueryTimespanParams(p.get(queryKeyTimespan))
+	if err != nil {
+		return nil, err
+	}
+	qr, err := parseKVStoreParams(p.get)
+	if err != nil {
+		return nil, err
+	}
+	return hub.QueryCandleStick(p.get(queryKeyMarket), timespan, qr), nil
+}
+
+func queryUserOrders(hub *core.Hub, p QueryParams) (interface{}, error) {
+	qr, err := parseKVStoreParams(p.get)
+	if err != nil {
+		return nil, err
+	}
+	tag := p.get(queryKeyOrderTag)
+	if err := parseQueryTagParams(tag); err != nil {
+		return nil, err
+	}
+	data, tags, timesid := hub.QueryOrderAboutToken(tag, strings.ToLower(p.get(queryKeyToken)), p.get(queryKeyAccount), qr)
+	return newOrdersResponse(tag, data, tags, timesid, qr.Count), nil
+}
+
+func queryOrder(hub *core.Hub, p QueryParams) (interface{}, error) {
+	orderID := p.get(queryKeyOrderID)
+	data := hub.QueryOrderLifecycle(orderID)
+	if data == nil {
+		return nil, ErrOrderNotFound(orderID)
+	}
+	return data, nil
+}
+
+func queryOpenOrders(hub *core.Hub, p QueryParams) (interface{}, error) {
+	account := p.get(queryKeyAccount)
+	if len(account) == 0 {
+		return nil, ErrNilParams(queryKeyAccount)
+	}
+	return hub.QueryOpenOrders(account, p.get(queryKeyMarket)), nil
+}
+
+func queryDelist(hub *core.Hub, p QueryParams) (interface{}, error) {
+	qr, err := parseKVStoreParams(p.get)
+	if err != nil {
+		return nil, err
+	}
+	data, timesid := hub.QueryDelist(p.get(queryKeyMarket), qr)
+	return NewDataWrapped(newDelistResponse(data), timesid, qr.Count), nil
+}
+
+func queryDelists(hub *core.Hub, p QueryParams) (interface{}, error) {
+	qr, err := parseDelistsParams(p.get)
+	if err != nil {
+		return nil, err
+	}
+	data, _ := hub.QueryDelists(qr)
+	return NewDataWrapped(newDelistsResponse(data), nil, qr.Count), nil
+}
@@ -163,7 +163,7 @@ func testCandleStick(recList []dealRec) {
 				impDList = append(impDList, *cs)
 			}
 		}
-		impMan.GetRecord("").Update(t, deal.price, deal.amount)
+		impMan.GetRecord("").Update(t, deal.price, deal.amount, 0) // the quote volume is not checked
 	}
 	//flush
 	t = time.Unix(t.Unix()+60*60*24, 0)